@@ -3,11 +3,19 @@ package main
 import (
 	"fmt"
 	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
+	"url-shortener-backend/internal/billing"
 	"url-shortener-backend/internal/config"
 	"url-shortener-backend/internal/database"
 	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/identity"
+	"url-shortener-backend/internal/mail"
 	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
 	"url-shortener-backend/internal/services"
 
 	"github.com/gofiber/fiber/v2"
@@ -17,6 +25,9 @@ import (
 
 func main() {
 	cfg := config.LoadConfig()
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
 	
 	if err := database.Connect(cfg.DatabaseURL); err != nil {
 		log.Fatal("Failed to connect to database:", err)
@@ -24,10 +35,92 @@ func main() {
 	
 	authService := services.NewAuthService()
 	urlService := services.NewURLService()
+	analyticsService := services.NewAnalyticsService()
+	tokenService := services.NewTokenService()
+	retentionService := services.NewRetentionService(cfg)
+	retentionService.Start()
+	
+	usageService := services.NewUsageService(cfg, billing.NewSink(cfg))
+	if seeded, err := usageService.SeedPlans(); err != nil {
+		log.Fatal("Failed to seed plans:", err)
+	} else if seeded > 0 {
+		log.Printf("Plans: created %d default plans", seeded)
+	}
+	usageService.Start()
+	webhookService := services.NewWebhookService(cfg)
+	webhookService.Start()
+	
+	mailer := mail.NewMailer(cfg)
+	workspaceService := services.NewWorkspaceService(cfg, mailer)
+	if migrated, err := workspaceService.MigratePersonalWorkspaces(); err != nil {
+		log.Fatal("Failed to migrate links into workspaces:", err)
+	} else if migrated > 0 {
+		log.Printf("Workspace migration: moved %d links into personal workspaces", migrated)
+	}
+	if backfilled, err := urlService.BackfillRevisions(); err != nil {
+		log.Fatal("Failed to backfill link revisions:", err)
+	} else if backfilled > 0 {
+		log.Printf("Revision backfill: recorded the first revision of %d links", backfilled)
+	}
+	if backfilled, err := urlService.BackfillClickCounts(); err != nil {
+		log.Fatal("Failed to backfill click counts:", err)
+	} else if backfilled > 0 {
+		log.Printf("Click count backfill: summarized the clicks of %d links", backfilled)
+	}
+	
+	go func() {
+		var internalHosts []string
+		if frontend, err := url.Parse(cfg.FrontendURL); err == nil {
+			internalHosts = append(internalHosts, frontend.Host)
+		}
+		if backfilled, err := urlService.BackfillReferrerSources(internalHosts...); err != nil {
+			log.Printf("Referrer backfill failed: %v", err)
+		} else if backfilled > 0 {
+			log.Printf("Referrer backfill: classified %d click events", backfilled)
+		}
+	}()
 	
-	sessionStore := middleware.NewSimpleSessionStore(cfg)
-	oauthHandler := handlers.NewOAuthHandler(authService, cfg, sessionStore)
-	urlHandler := handlers.NewURLHandler(urlService)
+	var sessionStore middleware.SessionStore
+	switch cfg.SessionStore {
+	case "memory":
+		sessionStore = middleware.NewSimpleSessionStore()
+	default:
+		sessionStore = middleware.NewSQLSessionStore(database.GetDB(), time.Hour)
+	}
+	sessions := middleware.NewSessionManager(sessionStore, cfg)
+	sessions.SetTokenAuthenticator(tokenService)
+	authService.SetSessionRevoker(sessions)
+	passwordService := services.NewPasswordAuthService(cfg, mailer)
+	passwordService.SetSessionRevoker(sessions)
+	accountService := services.NewAccountService(cfg)
+	accountService.SetSessions(sessions)
+	accountService.Start()
+	adminService := services.NewAdminService(cfg)
+	adminService.SetSessionRevoker(sessions)
+	if promoted, err := adminService.BootstrapAdmins(); err != nil {
+		log.Fatal("Failed to bootstrap administrators:", err)
+	} else if promoted > 0 {
+		log.Printf("Admin bootstrap: promoted %d users from ADMIN_EMAILS", promoted)
+	}
+	auditService := services.NewAuditService()
+	oauthHandler := handlers.NewOAuthHandler(authService, cfg, sessions, identity.NewRegistry(cfg), auditService)
+	clickHub := services.NewClickHub(0)
+	urlHandler := handlers.NewURLHandler(urlService, clickHub, cfg)
+	accountHandler := handlers.NewAccountHandler(retentionService, accountService, auditService)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
+	tokenHandler := handlers.NewTokenHandler(tokenService, auditService)
+	sessionHandler := handlers.NewSessionHandler(sessions, auditService)
+	passwordHandler := handlers.NewPasswordAuthHandler(passwordService, sessions, auditService)
+	csrf := middleware.NewCSRFProtection(cfg, sessions)
+	csrfHandler := handlers.NewCSRFHandler(csrf)
+	workspaceHandler := handlers.NewWorkspaceHandler(workspaceService, auditService)
+	adminHandler := handlers.NewAdminHandler(adminService, sessions)
+	abuseHandler := handlers.NewAbuseHandler(services.NewAbuseService(cfg, mailer))
+	usageHandler := handlers.NewUsageHandler(usageService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	tagHandler := handlers.NewTagHandler(services.NewTagService(), analyticsService)
+	folderHandler := handlers.NewFolderHandler(services.NewFolderService())
+	webhookHandler := handlers.NewWebhookHandler(webhookService, auditService)
 	
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
@@ -46,7 +139,6 @@ func main() {
 	app.Use(logger.New())
 	app.Use(recover.New())
 	app.Use(middleware.CORSMiddleware(cfg))
-	app.Use(middleware.RateLimitMiddleware(cfg))
 	
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -55,26 +147,159 @@ func main() {
 		})
 	})
 	
-	apiV1 := app.Group("/api/v1")
+	rateLimitStore, err := middleware.NewRateLimitStore(cfg)
+	if err != nil {
+		log.Fatal("Failed to configure rate limiting:", err)
+	}
+	limits := middleware.NewRateLimits(cfg, rateLimitStore, middleware.DefaultRateLimitPolicies(cfg)...)
+	apiLimit := limits.Handler(middleware.RateLimitAPI)
+	authLimit := limits.Handler(middleware.RateLimitAuth)
+	
+	apiV1 := app.Group("/api/v1", csrf.Middleware())
 	
 	auth := apiV1.Group("/auth")
-	auth.Get("/login", oauthHandler.Login)
-	auth.Get("/callback", oauthHandler.Callback)
-	auth.Post("/logout", oauthHandler.Logout)
-	auth.Get("/profile", sessionStore.AuthMiddleware(), oauthHandler.GetProfile)
+	auth.Get("/csrf", apiLimit, csrfHandler.GetToken)
+	auth.Get("/providers", apiLimit, oauthHandler.Providers)
+	auth.Get("/login", authLimit, oauthHandler.Login)
+	auth.Get("/callback", authLimit, oauthHandler.Callback)
+	auth.Post("/logout", apiLimit, oauthHandler.Logout)
+	auth.Get("/profile", sessions.AuthMiddleware(), apiLimit, oauthHandler.GetProfile)
+	auth.Post("/register", authLimit, passwordHandler.Register)
+	auth.Post("/login", authLimit, passwordHandler.Login)
+	auth.Post("/verify-email", authLimit, passwordHandler.VerifyEmail)
+	auth.Post("/verify-email/resend", authLimit, passwordHandler.ResendVerification)
+	auth.Post("/password/forgot", authLimit, passwordHandler.ForgotPassword)
+	auth.Post("/password/reset", authLimit, passwordHandler.ResetPassword)
+	
+	me := apiV1.Group("/me", sessions.AuthMiddleware(), middleware.SessionOnly(), apiLimit)
+	me.Delete("/", accountHandler.DeleteAccount)
+	me.Post("/deletion/cancel", accountHandler.CancelDeletion)
+	me.Get("/export", accountHandler.GetExport)
+	me.Post("/export", accountHandler.RequestExport)
+	me.Get("/export/download", accountHandler.DownloadExport)
+	me.Get("/usage", usageHandler.GetUsage)
+	me.Get("/audit", auditHandler.ListAccountEvents)
+	me.Get("/retention", accountHandler.GetRetention)
+	me.Put("/retention", accountHandler.UpdateRetention)
+	me.Get("/tokens", tokenHandler.ListTokens)
+	me.Post("/tokens", tokenHandler.CreateToken)
+	me.Delete("/tokens/:id", tokenHandler.RevokeToken)
+	me.Get("/identities", oauthHandler.ListIdentities)
+	me.Get("/identities/link", oauthHandler.LinkIdentity)
+	me.Delete("/identities/:id", oauthHandler.UnlinkIdentity)
+	me.Get("/sessions", sessionHandler.ListSessions)
+	me.Delete("/sessions", sessionHandler.RevokeAllSessions)
+	me.Delete("/sessions/:id", sessionHandler.RevokeSession)
+	
+	workspaces := apiV1.Group("/workspaces", sessions.AuthMiddleware(), middleware.SessionOnly(), apiLimit)
+	workspaces.Get("/", workspaceHandler.ListWorkspaces)
+	workspaces.Post("/", workspaceHandler.CreateWorkspace)
+	workspaces.Post("/invitations/accept", workspaceHandler.AcceptInvitation)
+	workspaces.Get("/:id", workspaceHandler.GetWorkspace)
+	workspaces.Put("/:id", workspaceHandler.UpdateWorkspace)
+	workspaces.Delete("/:id", workspaceHandler.DeleteWorkspace)
+	workspaces.Get("/:id/members", workspaceHandler.ListMembers)
+	workspaces.Put("/:id/members/:userId", workspaceHandler.UpdateMember)
+	workspaces.Delete("/:id/members/:userId", workspaceHandler.RemoveMember)
+	workspaces.Get("/:id/invitations", workspaceHandler.ListInvitations)
+	workspaces.Post("/:id/invitations", workspaceHandler.Invite)
+	workspaces.Delete("/:id/invitations/:invitationId", workspaceHandler.RevokeInvitation)
+	workspaces.Get("/:id/audit", auditHandler.ListWorkspaceEvents)
+	
+	webhooks := apiV1.Group("/webhooks", sessions.AuthMiddleware(), middleware.SessionOnly(), apiLimit)
+	webhooks.Get("/", webhookHandler.ListWebhooks)
+	webhooks.Post("/", webhookHandler.CreateWebhook)
+	webhooks.Get("/:id", webhookHandler.GetWebhook)
+	webhooks.Put("/:id", webhookHandler.UpdateWebhook)
+	webhooks.Delete("/:id", webhookHandler.DeleteWebhook)
+	webhooks.Get("/:id/deliveries", webhookHandler.ListDeliveries)
+	webhooks.Post("/:id/deliveries/redeliver", webhookHandler.RedeliverDead)
+	webhooks.Post("/:id/deliveries/:deliveryId/redeliver", webhookHandler.Redeliver)
+	
+	admin := apiV1.Group("/admin", sessions.AuthMiddleware(), middleware.SessionOnly(), apiLimit, middleware.RequireAdmin(adminService))
+	admin.Get("/stats", adminHandler.Stats)
+	admin.Get("/users", adminHandler.SearchUsers)
+	admin.Get("/users/:id", adminHandler.GetUser)
+	admin.Post("/users/:id/disable", adminHandler.DisableUser)
+	admin.Post("/users/:id/enable", adminHandler.EnableUser)
+	admin.Put("/users/:id/plan", adminHandler.SetUserPlan)
+	admin.Post("/users/:id/impersonate", adminHandler.Impersonate)
+	admin.Get("/urls", adminHandler.SearchURLs)
+	admin.Post("/urls/:id/disable", adminHandler.DisableURL)
+	admin.Post("/urls/:id/enable", adminHandler.EnableURL)
+	admin.Get("/reports", abuseHandler.Queue)
+	admin.Get("/urls/:id/reports", abuseHandler.ListReports)
+	admin.Post("/urls/:id/reports/confirm", abuseHandler.ConfirmReports)
+	admin.Post("/urls/:id/reports/dismiss", abuseHandler.DismissReports)
+	admin.Get("/audit", adminHandler.ListActions)
+	
+	linksRead := middleware.RequireScope(models.ScopeLinksRead)
+	linksWrite := middleware.RequireScope(models.ScopeLinksWrite)
+	analyticsRead := middleware.RequireScope(models.ScopeAnalyticsRead)
 	
 	urls := apiV1.Group("/urls")
-	urls.Post("/", sessionStore.OptionalAuthMiddleware(), urlHandler.CreateURL)
-	urls.Get("/", sessionStore.AuthMiddleware(), urlHandler.GetUserURLs)
-	urls.Put("/:id", sessionStore.AuthMiddleware(), urlHandler.UpdateURL)
-	urls.Delete("/:id", sessionStore.AuthMiddleware(), urlHandler.DeleteURL)
-	urls.Get("/:id/analytics", sessionStore.AuthMiddleware(), urlHandler.GetURLAnalytics)
-	urls.Get("/:shortCode/info", urlHandler.GetURLInfo)
+	urls.Post("/", sessions.OptionalAuthMiddleware(), linksWrite, limits.Handler(middleware.RateLimitCreate), urlHandler.CreateURL)
+	urls.Get("/", sessions.AuthMiddleware(), apiLimit, linksRead, urlHandler.GetUserURLs)
+	urls.Post("/bulk/tag", sessions.AuthMiddleware(), apiLimit, linksWrite, tagHandler.TagURLs)
+	urls.Post("/bulk/untag", sessions.AuthMiddleware(), apiLimit, linksWrite, tagHandler.UntagURLs)
+	urls.Post("/bulk/move", sessions.AuthMiddleware(), apiLimit, linksWrite, folderHandler.MoveURLs)
+	urls.Put("/:id", sessions.AuthMiddleware(), apiLimit, linksWrite, urlHandler.UpdateURL)
+	urls.Delete("/:id", sessions.AuthMiddleware(), apiLimit, linksWrite, urlHandler.DeleteURL)
+	urls.Get("/:id/analytics", sessions.AuthMiddleware(), apiLimit, analyticsRead, urlHandler.GetURLAnalytics)
+	urls.Get("/:id/analytics/events", sessions.AuthMiddleware(), apiLimit, analyticsRead, urlHandler.GetClickEvents)
+	urls.Get("/:id/analytics/live", sessions.AuthMiddleware(), apiLimit, analyticsRead, urlHandler.StreamClickEvents)
+	urls.Get("/:id/revisions", sessions.AuthMiddleware(), apiLimit, linksRead, urlHandler.ListRevisions)
+	urls.Get("/:id/revisions/diff", sessions.AuthMiddleware(), apiLimit, linksRead, urlHandler.DiffRevisions)
+	urls.Post("/:id/revisions/:number/rollback", sessions.AuthMiddleware(), apiLimit, linksWrite, urlHandler.RollbackURL)
+	urls.Get("/:shortCode/info", apiLimit, urlHandler.GetURLInfo)
+	
+	tags := apiV1.Group("/tags", sessions.AuthMiddleware(), apiLimit)
+	tags.Get("/", linksRead, tagHandler.ListTags)
+	tags.Post("/", linksWrite, tagHandler.CreateTag)
+	tags.Put("/:id", linksWrite, tagHandler.UpdateTag)
+	tags.Delete("/:id", linksWrite, tagHandler.DeleteTag)
+	tags.Get("/:id/stats", analyticsRead, tagHandler.GetTagStats)
 	
-	app.Get("/:shortCode", urlHandler.RedirectURL)
+	folders := apiV1.Group("/folders", sessions.AuthMiddleware(), apiLimit)
+	folders.Get("/", linksRead, folderHandler.ListFolders)
+	folders.Post("/", linksWrite, folderHandler.CreateFolder)
+	folders.Put("/:id", linksWrite, folderHandler.UpdateFolder)
+	folders.Delete("/:id", linksWrite, folderHandler.DeleteFolder)
+	
+	analytics := apiV1.Group("/analytics", sessions.AuthMiddleware(), apiLimit, analyticsRead)
+	analytics.Get("/overview", analyticsHandler.GetOverview)
+	
+	app.Get("/:shortCode", limits.Handler(middleware.RateLimitRedirect), urlHandler.RedirectURL)
+	app.Post("/:shortCode/report", limits.Handler(middleware.RateLimitReport), sessions.OptionalAuthMiddleware(), abuseHandler.Report)
 	
 	// For now, just start HTTP server to avoid certificate complexity in Docker
 	port := fmt.Sprintf(":%s", cfg.Port)
 	log.Printf("Server starting on port %s", cfg.Port)
-	log.Fatal(app.Listen(port))
+	
+	// On SIGINT or SIGTERM, end the live click streams so open requests can
+	// finish, then stop the background jobs once the server has.
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+		
+		log.Printf("Server shutting down")
+		clickHub.Close()
+		if err := app.ShutdownWithTimeout(30 * time.Second); err != nil {
+			log.Printf("Server shutdown: %v", err)
+		}
+	}()
+	
+	if err := app.Listen(port); err != nil {
+		log.Fatal(err)
+	}
+	<-shutdown
+	
+	retentionService.Close()
+	usageService.Close()
+	webhookService.Close()
+	accountService.Close()
 }
\ No newline at end of file
@@ -0,0 +1,118 @@
+// Package billing hands metered usage to whatever system invoices accounts.
+package billing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+	"url-shortener-backend/internal/config"
+)
+
+// Event is a quantity of one metric used by an account. ID is unique and
+// stable across retries, so sinks can discard duplicates.
+type Event struct {
+	ID         uint      `json:"id"`
+	UserID     uint      `json:"user_id"`
+	Plan       string    `json:"plan"`
+	Metric     string    `json:"metric"`
+	Quantity   int64     `json:"quantity"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Sink receives usage events. Export is retried with the same events until
+// it succeeds, so it should be idempotent.
+type Sink interface {
+	Export(ctx context.Context, events []Event) error
+}
+
+// NewSink returns the sink selected by BILLING_SINK: "http", "log", or
+// "none" (the default), so usage only leaves the process when asked to.
+func NewSink(cfg *config.Config) Sink {
+	switch cfg.BillingSink {
+	case "http":
+		return &HTTPSink{URL: cfg.BillingSinkURL, Client: &http.Client{Timeout: 10 * time.Second}}
+	case "log":
+		return LogSink{}
+	default:
+		return NoopSink{}
+	}
+}
+
+// HTTPSink posts each batch as a JSON array to a billing endpoint.
+type HTTPSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *HTTPSink) Export(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send usage: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("billing endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// LogSink writes usage to the application log, for development.
+type LogSink struct{}
+
+func (LogSink) Export(_ context.Context, events []Event) error {
+	for _, event := range events {
+		log.Printf("Usage: user %d (%s) %s +%d", event.UserID, event.Plan, event.Metric, event.Quantity)
+	}
+	return nil
+}
+
+// NoopSink discards usage, for deployments that do not bill.
+type NoopSink struct{}
+
+func (NoopSink) Export(context.Context, []Event) error {
+	return nil
+}
+
+// MemorySink keeps exported events, for tests.
+type MemorySink struct {
+	mu     sync.Mutex
+	events []Event
+	// Err, when set, is returned instead of accepting events.
+	Err error
+}
+
+func (s *MemorySink) Export(_ context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+	s.events = append(s.events, events...)
+	return nil
+}
+
+// Events returns a copy of the events exported so far.
+func (s *MemorySink) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Event(nil), s.events...)
+}
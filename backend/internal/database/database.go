@@ -46,11 +46,51 @@ func Connect(databaseURL string) error {
 }
 
 func AutoMigrate() error {
-	return DB.AutoMigrate(
+	err := DB.AutoMigrate(
 		&models.User{},
 		&models.URL{},
 		&models.Analytics{},
+		&models.AnalyticsDaily{},
+		&models.APIToken{},
+		&models.Session{},
+		&models.UserIdentity{},
+		&models.Workspace{},
+		&models.WorkspaceMember{},
+		&models.WorkspaceInvitation{},
+		&models.AdminAction{},
+		&models.DataExport{},
+		&models.Plan{},
+		&models.UsageCounter{},
+		&models.UsageEvent{},
+		&models.AbuseReport{},
+		&models.AuditEvent{},
+		&models.URLRevision{},
+		&models.Tag{},
+		&models.URLTag{},
+		&models.Folder{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
 	)
+	if err != nil {
+		return err
+	}
+	return createSearchIndexes()
+}
+
+// createSearchIndexes adds the full-text and trigram indexes behind link
+// search on Postgres. Without the pg_trgm extension, substring matches still
+// work but scan the table.
+func createSearchIndexes() error {
+	if DB.Dialector.Name() != "postgres" {
+		return nil
+	}
+	
+	if err := DB.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		log.Printf("pg_trgm is unavailable; link search will not use a trigram index: %v", err)
+	} else if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_urls_search_trgm ON urls USING GIN ((" + models.URLSearchDocument + ") gin_trgm_ops)").Error; err != nil {
+		return err
+	}
+	return DB.Exec("CREATE INDEX IF NOT EXISTS idx_urls_search_fts ON urls USING GIN (to_tsvector('simple', " + models.URLSearchDocument + "))").Error
 }
 
 func GetDB() *gorm.DB {
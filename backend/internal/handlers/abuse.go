@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+	"url-shortener-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// quarantinePage is shown instead of redirecting to a quarantined link.
+var quarantinePage = template.Must(template.New("quarantine").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Warning: this link has been reported</title>
+</head>
+<body style="font-family: sans-serif; max-width: 40rem; margin: 4rem auto; padding: 0 1rem;">
+<h1>This link has been reported</h1>
+<p>Several people have reported this link as harmful, for example as phishing or malware. It is waiting for review by our moderators.</p>
+<p>It leads to:</p>
+<p><code>{{.Destination}}</code></p>
+<p>Do not enter passwords or payment details unless you trust this site.</p>
+<p><a href="{{.ProceedURL}}" rel="nofollow noreferrer">Continue anyway</a></p>
+</body>
+</html>
+`))
+
+type AbuseHandler struct {
+	abuseService *services.AbuseService
+}
+
+func NewAbuseHandler(abuseService *services.AbuseService) *AbuseHandler {
+	return &AbuseHandler{
+		abuseService: abuseService,
+	}
+}
+
+// Report accepts an abuse report about a link from anyone. Only JSON bodies
+// are accepted, so other sites cannot make visitors' browsers file reports.
+func (h *AbuseHandler) Report(c *fiber.Ctx) error {
+	if !c.Is("json") {
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Reports must be sent as JSON",
+		})
+	}
+
+	var req models.AbuseReportRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	var reporterID *uint
+	if id, ok := c.Locals("user_id").(uint); ok {
+		reporterID = &id
+	}
+
+	if err := h.abuseService.Report(c.Params("shortCode"), &req, c.IP(), reporterID); err != nil {
+		status := fiber.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrURLNotFound):
+			status = fiber.StatusNotFound
+		case errors.Is(err, services.ErrInvalidReportCategory):
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
+			Error:   "report_failed",
+			Message: err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(models.SuccessResponse{
+		Success: true,
+		Message: "Thank you, the report will be reviewed",
+	})
+}
+
+// Queue lists links with open reports for moderators.
+func (h *AbuseHandler) Queue(c *fiber.Ctx) error {
+	limit, offset := pageParams(c)
+
+	queue, total, err := h.abuseService.Queue(limit, offset)
+	if err != nil {
+		return adminError(c, err, "fetch_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"links":  queue,
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+		},
+	})
+}
+
+func (h *AbuseHandler) ListReports(c *fiber.Ctx) error {
+	urlID, ok := idParam(c, "id", "invalid_url_id", "Invalid URL ID")
+	if !ok {
+		return nil
+	}
+	limit, offset := pageParams(c)
+
+	reports, total, err := h.abuseService.ListReports(urlID, c.Query("status"), limit, offset)
+	if err != nil {
+		return adminError(c, err, "fetch_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"reports": reports,
+			"total":   total,
+			"limit":   limit,
+			"offset":  offset,
+		},
+	})
+}
+
+// ConfirmReports upholds a link's open reports and disables the link.
+func (h *AbuseHandler) ConfirmReports(c *fiber.Ctx) error {
+	return h.resolve(c, h.abuseService.Confirm, "Reports confirmed and link disabled")
+}
+
+// DismissReports rejects a link's open reports and lifts its quarantine.
+func (h *AbuseHandler) DismissReports(c *fiber.Ctx) error {
+	return h.resolve(c, h.abuseService.Dismiss, "Reports dismissed")
+}
+
+func (h *AbuseHandler) resolve(c *fiber.Ctx, decide func(adminID, urlID uint, reason, ip string) error, message string) error {
+	adminID := c.Locals("user_id").(uint)
+	urlID, ok := idParam(c, "id", "invalid_url_id", "Invalid URL ID")
+	if !ok {
+		return nil
+	}
+
+	var req models.ModerationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	if err := decide(adminID, urlID, req.Reason, c.IP()); err != nil {
+		if errors.Is(err, services.ErrNoOpenReports) {
+			return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+				Error:   "no_open_reports",
+				Message: err.Error(),
+			})
+		}
+		return adminError(c, err, "resolve_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: message,
+	})
+}
+
+// proceedTokenLifetime is how long the "Continue anyway" link on a
+// quarantine warning works.
+const proceedTokenLifetime = 10 * time.Minute
+
+// signProceedToken issues the token that lets a visitor past the warning
+// for one quarantined link. It is bound to the short code and expires, so a
+// link shared with the token attached soon shows the warning again.
+func signProceedToken(shortCode string, now time.Time, key []byte) string {
+	return utils.SignValue(key, shortCode+":"+strconv.FormatInt(now.Unix(), 10))
+}
+
+func verifyProceedToken(token, shortCode string, now time.Time, keys [][]byte) bool {
+	if token == "" {
+		return false
+	}
+	value, ok := utils.VerifySignedValue(token, keys...)
+	if !ok {
+		return false
+	}
+	i := strings.LastIndexByte(value, ':')
+	if i < 0 || value[:i] != shortCode {
+		return false
+	}
+	issued, err := strconv.ParseInt(value[i+1:], 10, 64)
+	if err != nil {
+		return false
+	}
+	age := now.Sub(time.Unix(issued, 0))
+	return age >= -time.Minute && age <= proceedTokenLifetime
+}
+
+// quarantineWarning renders the page shown in place of a redirect to a
+// quarantined link.
+func quarantineWarning(c *fiber.Ctx, url *models.URL, proceedToken string) error {
+	var page bytes.Buffer
+	err := quarantinePage.Execute(&page, struct {
+		Destination string
+		ProceedURL  string
+	}{
+		Destination: url.OriginalURL,
+		ProceedURL:  "/" + url.ShortCode + "?proceed=" + neturl.QueryEscape(proceedToken),
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set("X-Robots-Tag", "noindex")
+	c.Type("html", "utf-8")
+	return c.Send(page.Bytes())
+}
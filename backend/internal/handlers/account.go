@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"errors"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccountHandler struct {
+	retentionService *services.RetentionService
+	accountService   *services.AccountService
+	auditService     *services.AuditService
+}
+
+func NewAccountHandler(retentionService *services.RetentionService, accountService *services.AccountService, auditService *services.AuditService) *AccountHandler {
+	return &AccountHandler{
+		retentionService: retentionService,
+		accountService:   accountService,
+		auditService:     auditService,
+	}
+}
+
+func (h *AccountHandler) GetRetention(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	days, overridden, err := h.retentionService.GetUserRetention(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"days":       days,
+			"overridden": overridden,
+		},
+	})
+}
+
+func (h *AccountHandler) UpdateRetention(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req models.UpdateRetentionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	before, _, err := h.retentionService.GetUserRetention(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "update_failed",
+			Message: err.Error(),
+		})
+	}
+
+	if err := h.retentionService.SetUserRetention(userID, req.Days); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "update_failed",
+			Message: err.Error(),
+		})
+	}
+
+	if after, _, err := h.retentionService.GetUserRetention(userID); err == nil && after != before {
+		event := accountEvent(models.AuditAccountRetention, userID)
+		event.Changes = map[string]models.AuditChange{"retention_days": {From: before, To: after}}
+		h.auditService.Record(auditActor(c), event)
+	}
+
+	return h.GetRetention(c)
+}
+
+// rejectImpersonation stops an administrator viewing an account from taking
+// a copy of its data.
+func rejectImpersonation(c *fiber.Ctx) bool {
+	if c.Locals("impersonator_id") == nil {
+		return false
+	}
+	c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+		Error:   "impersonation_read_only",
+		Message: "Data exports are not available while impersonating",
+	})
+	return true
+}
+
+func exportResponse(c *fiber.Ctx, export *models.DataExport) error {
+	status := fiber.StatusOK
+	if export.Status == models.ExportStatusPending || export.Status == models.ExportStatusRunning {
+		status = fiber.StatusAccepted
+	}
+	return c.Status(status).JSON(models.SuccessResponse{
+		Success: true,
+		Data:    export,
+	})
+}
+
+// GetExport reports on the latest data export, starting one if there is
+// none that is in progress or downloadable.
+func (h *AccountHandler) GetExport(c *fiber.Ctx) error {
+	if rejectImpersonation(c) {
+		return nil
+	}
+	userID := c.Locals("user_id").(uint)
+
+	export, err := h.accountService.ReadyExport(userID)
+	if errors.Is(err, services.ErrExportNotReady) {
+		export, err = h.accountService.LatestExport(userID)
+		if err == nil && export.Status == models.ExportStatusFailed {
+			export, err = h.accountService.RequestExport(userID)
+		}
+	} else if errors.Is(err, services.ErrExportNotFound) {
+		export, err = h.accountService.RequestExport(userID)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "export_failed",
+			Message: err.Error(),
+		})
+	}
+
+	return exportResponse(c, export)
+}
+
+// RequestExport starts a fresh data export.
+func (h *AccountHandler) RequestExport(c *fiber.Ctx) error {
+	if rejectImpersonation(c) {
+		return nil
+	}
+	userID := c.Locals("user_id").(uint)
+
+	export, err := h.accountService.RequestExport(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "export_failed",
+			Message: err.Error(),
+		})
+	}
+	h.auditService.Record(auditActor(c), accountEvent(models.AuditAccountExport, userID))
+
+	return exportResponse(c, export)
+}
+
+func (h *AccountHandler) DownloadExport(c *fiber.Ctx) error {
+	if rejectImpersonation(c) {
+		return nil
+	}
+	userID := c.Locals("user_id").(uint)
+
+	export, err := h.accountService.ReadyExport(userID)
+	if err != nil {
+		status := fiber.StatusNotFound
+		if errors.Is(err, services.ErrExportNotReady) {
+			status = fiber.StatusConflict
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
+			Error:   "export_unavailable",
+			Message: err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Download(export.FilePath, "url-shortener-export.zip")
+}
+
+// DeleteAccount schedules the account for deletion after the grace period.
+func (h *AccountHandler) DeleteAccount(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	deletion, err := h.accountService.ScheduleDeletion(userID)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrOwnsSharedWorkspace) {
+			status = fiber.StatusConflict
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
+			Error:   "deletion_failed",
+			Message: err.Error(),
+		})
+	}
+	h.auditService.Record(auditActor(c), accountEvent(models.AuditAccountDelete, userID))
+
+	return c.Status(fiber.StatusAccepted).JSON(models.SuccessResponse{
+		Success: true,
+		Data:    deletion,
+		Message: "Account scheduled for deletion",
+	})
+}
+
+func (h *AccountHandler) CancelDeletion(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	if err := h.accountService.CancelDeletion(userID); err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrDeletionNotScheduled) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
+			Error:   "cancel_failed",
+			Message: err.Error(),
+		})
+	}
+	h.auditService.Record(auditActor(c), accountEvent(models.AuditAccountDeleteCancel, userID))
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "Account deletion cancelled",
+	})
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AdminHandler struct {
+	adminService *services.AdminService
+	sessions     *middleware.SessionManager
+}
+
+func NewAdminHandler(adminService *services.AdminService, sessions *middleware.SessionManager) *AdminHandler {
+	return &AdminHandler{
+		adminService: adminService,
+		sessions:     sessions,
+	}
+}
+
+// adminError maps moderation service errors to a response.
+func adminError(c *fiber.Ctx, err error, code string) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrUserNotFound), errors.Is(err, services.ErrURLNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrReasonRequired), errors.Is(err, services.ErrUnknownPlan):
+		status = fiber.StatusBadRequest
+	case errors.Is(err, services.ErrCannotModerateSelf), errors.Is(err, services.ErrCannotImpersonateAdmin):
+		status = fiber.StatusForbidden
+	}
+	return c.Status(status).JSON(models.ErrorResponse{
+		Error:   code,
+		Message: err.Error(),
+	})
+}
+
+// pageParams reads limit and offset, capping limit at 100.
+func pageParams(c *fiber.Ctx) (int, int) {
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func (h *AdminHandler) Stats(c *fiber.Ctx) error {
+	stats, err := h.adminService.Stats()
+	if err != nil {
+		return adminError(c, err, "fetch_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    stats,
+	})
+}
+
+func (h *AdminHandler) SearchUsers(c *fiber.Ctx) error {
+	limit, offset := pageParams(c)
+
+	users, total, err := h.adminService.SearchUsers(c.Query("q"), limit, offset)
+	if err != nil {
+		return adminError(c, err, "fetch_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"users":  users,
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+		},
+	})
+}
+
+func (h *AdminHandler) GetUser(c *fiber.Ctx) error {
+	userID, ok := idParam(c, "id", "invalid_user_id", "Invalid user ID")
+	if !ok {
+		return nil
+	}
+
+	user, err := h.adminService.GetUser(userID)
+	if err != nil {
+		return adminError(c, err, "fetch_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    user,
+	})
+}
+
+func (h *AdminHandler) DisableUser(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+	userID, ok := idParam(c, "id", "invalid_user_id", "Invalid user ID")
+	if !ok {
+		return nil
+	}
+
+	var req models.ModerationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	if err := h.adminService.DisableUser(adminID, userID, req.Reason, c.IP()); err != nil {
+		return adminError(c, err, "disable_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "User disabled",
+	})
+}
+
+func (h *AdminHandler) SetUserPlan(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+	userID, ok := idParam(c, "id", "invalid_user_id", "Invalid user ID")
+	if !ok {
+		return nil
+	}
+
+	var req models.UpdatePlanRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	if err := h.adminService.SetUserPlan(adminID, userID, req.Plan, c.IP()); err != nil {
+		return adminError(c, err, "plan_change_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "Plan changed",
+	})
+}
+
+func (h *AdminHandler) EnableUser(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+	userID, ok := idParam(c, "id", "invalid_user_id", "Invalid user ID")
+	if !ok {
+		return nil
+	}
+
+	if err := h.adminService.EnableUser(adminID, userID, c.IP()); err != nil {
+		return adminError(c, err, "enable_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "User enabled",
+	})
+}
+
+// Impersonate replaces the admin's session with a short, read-only session
+// as the user. Signing out ends it.
+func (h *AdminHandler) Impersonate(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+	userID, ok := idParam(c, "id", "invalid_user_id", "Invalid user ID")
+	if !ok {
+		return nil
+	}
+
+	var req models.ModerationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	user, err := h.adminService.StartImpersonation(adminID, userID, req.Reason, c.IP())
+	if err != nil {
+		return adminError(c, err, "impersonation_failed")
+	}
+
+	// The admin's own session ends here, so signing out of the impersonation
+	// leaves no session of theirs behind.
+	if sessionID, ok := c.Locals("session_id").(string); ok {
+		h.sessions.DestroySession(c, sessionID)
+	}
+	if _, err := h.sessions.CreateImpersonationSession(c, adminID, user.ID, user.Email); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "session_error",
+			Message: "Failed to create session",
+		})
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    user,
+		Message: "Viewing account read-only; sign out to return",
+	})
+}
+
+func (h *AdminHandler) SearchURLs(c *fiber.Ctx) error {
+	limit, offset := pageParams(c)
+
+	urls, total, err := h.adminService.SearchURLs(c.Query("q"), limit, offset)
+	if err != nil {
+		return adminError(c, err, "fetch_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"urls":   urls,
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+		},
+	})
+}
+
+func (h *AdminHandler) DisableURL(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+	urlID, ok := idParam(c, "id", "invalid_url_id", "Invalid URL ID")
+	if !ok {
+		return nil
+	}
+
+	var req models.ModerationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	if err := h.adminService.DisableURL(adminID, urlID, req.Reason, c.IP()); err != nil {
+		return adminError(c, err, "disable_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "URL disabled",
+	})
+}
+
+func (h *AdminHandler) EnableURL(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uint)
+	urlID, ok := idParam(c, "id", "invalid_url_id", "Invalid URL ID")
+	if !ok {
+		return nil
+	}
+
+	if err := h.adminService.EnableURL(adminID, urlID, c.IP()); err != nil {
+		return adminError(c, err, "enable_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "URL enabled",
+	})
+}
+
+func (h *AdminHandler) ListActions(c *fiber.Ctx) error {
+	limit, offset := pageParams(c)
+
+	var targetID uint
+	if raw := c.Query("target_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "invalid_target_id",
+				Message: "Invalid target ID",
+			})
+		}
+		targetID = uint(id)
+	}
+
+	actions, total, err := h.adminService.ListActions(c.Query("target_type"), targetID, limit, offset)
+	if err != nil {
+		return adminError(c, err, "fetch_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"actions": actions,
+			"total":   total,
+			"limit":   limit,
+			"offset":  offset,
+		},
+	})
+}
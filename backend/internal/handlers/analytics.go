@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"time"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const defaultOverviewDays = 30
+
+type AnalyticsHandler struct {
+	analyticsService *services.AnalyticsService
+}
+
+func NewAnalyticsHandler(analyticsService *services.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsService: analyticsService,
+	}
+}
+
+// periodParams reads a reporting period from the from and to query
+// parameters, defaulting to the last 30 days. It writes a 400 response if
+// either is malformed.
+func periodParams(c *fiber.Ctx) (time.Time, time.Time, bool) {
+	to, err := parseTimeQuery(c.Query("to"))
+	if err != nil {
+		c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_date",
+			Message: "to must be an RFC3339 timestamp or YYYY-MM-DD date",
+		})
+		return time.Time{}, time.Time{}, false
+	}
+	if to == nil {
+		now := time.Now().UTC()
+		to = &now
+	}
+
+	from, err := parseTimeQuery(c.Query("from"))
+	if err != nil {
+		c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_date",
+			Message: "from must be an RFC3339 timestamp or YYYY-MM-DD date",
+		})
+		return time.Time{}, time.Time{}, false
+	}
+	if from == nil {
+		start := to.AddDate(0, 0, -defaultOverviewDays)
+		from = &start
+	}
+
+	return *from, *to, true
+}
+
+func (h *AnalyticsHandler) GetOverview(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	from, to, ok := periodParams(c)
+	if !ok {
+		return nil
+	}
+
+	overview, err := h.analyticsService.GetOverview(userID, from, to)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    overview,
+	})
+}
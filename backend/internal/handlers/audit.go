@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// auditActor describes who is making the request, for the audit log.
+func auditActor(c *fiber.Ctx) models.Actor {
+	actor := models.Actor{
+		Type:      models.ActorAnonymous,
+		IPAddress: strings.Clone(c.IP()),
+		UserAgent: strings.Clone(c.Get(fiber.HeaderUserAgent)),
+	}
+	if len(actor.UserAgent) > 500 {
+		actor.UserAgent = actor.UserAgent[:500]
+	}
+	if userID, ok := c.Locals("user_id").(uint); ok {
+		actor.Type = models.ActorUser
+		actor.UserID = &userID
+	}
+	if tokenID, ok := c.Locals("token_id").(uint); ok {
+		actor.Type = models.ActorToken
+		actor.TokenID = &tokenID
+	}
+	return actor
+}
+
+// userActor describes a user who has just proven who they are, such as by
+// signing in, before the request carries their ID.
+func userActor(c *fiber.Ctx, userID uint) models.Actor {
+	actor := auditActor(c)
+	actor.Type = models.ActorUser
+	actor.UserID = &userID
+	return actor
+}
+
+// accountEvent is an audit event about a user's own account.
+func accountEvent(action string, userID uint) *models.AuditEvent {
+	return &models.AuditEvent{
+		Action:     action,
+		TargetType: models.AuditTargetUser,
+		TargetID:   userID,
+	}
+}
+
+// auditFilter reads the audit log filters from the query string.
+func auditFilter(c *fiber.Ctx) (*models.AuditFilter, bool) {
+	filter := &models.AuditFilter{
+		Action:     c.Query("action"),
+		ActorType:  c.Query("actor_type"),
+		TargetType: c.Query("target_type"),
+	}
+
+	for name, dest := range map[string]*uint{"actor_id": &filter.ActorID, "target_id": &filter.TargetID} {
+		if raw := c.Query(name); raw != "" {
+			id, err := strconv.ParseUint(raw, 10, 32)
+			if err != nil {
+				c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+					Error:   "invalid_filter",
+					Message: "Invalid " + name,
+				})
+				return nil, false
+			}
+			*dest = uint(id)
+		}
+	}
+
+	for name, dest := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if raw := c.Query(name); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+					Error:   "invalid_filter",
+					Message: name + " must be an RFC 3339 timestamp",
+				})
+				return nil, false
+			}
+			*dest = &t
+		}
+	}
+
+	return filter, true
+}
+
+func auditPage(c *fiber.Ctx, events []models.AuditEvent, total int64, limit, offset int) error {
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"events": events,
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+		},
+	})
+}
+
+// ListAccountEvents returns the signed-in user's audit log.
+func (h *AuditHandler) ListAccountEvents(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	filter, ok := auditFilter(c)
+	if !ok {
+		return nil
+	}
+	limit, offset := pageParams(c)
+
+	events, total, err := h.auditService.ListAccountEvents(userID, filter, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+	}
+
+	return auditPage(c, events, total, limit, offset)
+}
+
+// ListWorkspaceEvents returns a workspace's audit log to its admins.
+func (h *AuditHandler) ListWorkspaceEvents(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	workspaceID, ok := idParam(c, "id", "invalid_workspace_id", "Invalid workspace ID")
+	if !ok {
+		return nil
+	}
+	filter, ok := auditFilter(c)
+	if !ok {
+		return nil
+	}
+	limit, offset := pageParams(c)
+
+	events, total, err := h.auditService.ListWorkspaceEvents(workspaceID, userID, filter, limit, offset)
+	if err != nil {
+		return workspaceError(c, err, "fetch_failed")
+	}
+
+	return auditPage(c, events, total, limit, offset)
+}
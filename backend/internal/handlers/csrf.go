@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CSRFHandler struct {
+	csrf *middleware.CSRFProtection
+}
+
+func NewCSRFHandler(csrf *middleware.CSRFProtection) *CSRFHandler {
+	return &CSRFHandler{
+		csrf: csrf,
+	}
+}
+
+// GetToken issues a CSRF token for the caller's session. Clients send it back
+// in the X-CSRF-Token header and fetch a new one after signing in or out.
+func (h *CSRFHandler) GetToken(c *fiber.Ctx) error {
+	token, err := h.csrf.Token(c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "token_generation_failed",
+			Message: "Failed to generate CSRF token",
+		})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"csrf_token": token,
+			"header":     middleware.CSRFHeader,
+		},
+	})
+}
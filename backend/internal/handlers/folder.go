@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FolderHandler struct {
+	folderService *services.FolderService
+}
+
+func NewFolderHandler(folderService *services.FolderService) *FolderHandler {
+	return &FolderHandler{
+		folderService: folderService,
+	}
+}
+
+func (h *FolderHandler) ListFolders(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	workspaceID, ok := workspaceQuery(c)
+	if !ok {
+		return nil
+	}
+
+	folders, err := h.folderService.ListFolders(userID, workspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    folders,
+	})
+}
+
+func (h *FolderHandler) CreateFolder(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req models.FolderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	folder, err := h.folderService.CreateFolder(userID, &req)
+	if err != nil {
+		return organizeError(c, err, "create_failed")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.SuccessResponse{
+		Success: true,
+		Data:    folder,
+		Message: "Folder created successfully",
+	})
+}
+
+// UpdateFolder renames a folder and sets its parent; a null parent_id moves
+// it to the top level.
+func (h *FolderHandler) UpdateFolder(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	folderID, ok := idParam(c, "id", "invalid_folder_id", "Invalid folder ID")
+	if !ok {
+		return nil
+	}
+
+	var req models.FolderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	folder, err := h.folderService.UpdateFolder(folderID, userID, &req)
+	if err != nil {
+		return organizeError(c, err, "update_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    folder,
+		Message: "Folder updated successfully",
+	})
+}
+
+func (h *FolderHandler) DeleteFolder(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	folderID, ok := idParam(c, "id", "invalid_folder_id", "Invalid folder ID")
+	if !ok {
+		return nil
+	}
+
+	if err := h.folderService.WithActor(auditActor(c)).DeleteFolder(folderID, userID); err != nil {
+		return organizeError(c, err, "delete_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "Folder deleted successfully",
+	})
+}
+
+// MoveURLs files a selection of links into a folder, or out of their
+// folders when folder_id is null.
+func (h *FolderHandler) MoveURLs(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req models.MoveURLsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	moved, err := h.folderService.WithActor(auditActor(c)).MoveURLs(userID, &req)
+	if err != nil {
+		return organizeError(c, err, "move_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    fiber.Map{"changed": moved},
+	})
+}
@@ -2,42 +2,94 @@ package handlers
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"regexp"
+	"strconv"
 	"time"
 	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/identity"
 	"url-shortener-backend/internal/middleware"
 	"url-shortener-backend/internal/models"
 	"url-shortener-backend/internal/services"
-	"url-shortener-backend/internal/utils"
 
 	"github.com/gofiber/fiber/v2"
-	"golang.org/x/oauth2"
 )
 
+// providerTimeout bounds the calls made to an identity provider while
+// handling one request.
+const providerTimeout = 15 * time.Second
+
 type OAuthHandler struct {
-	authService   *services.AuthService
-	config        *config.Config
-	oauthConfig   *oauth2.Config
-	sessionStore  *middleware.SimpleSessionStore
+	authService  *services.AuthService
+	auditService *services.AuditService
+	config       *config.Config
+	providers    *identity.Registry
+	sessions     *middleware.SessionManager
 }
 
-func NewOAuthHandler(authService *services.AuthService, config *config.Config, sessionStore *middleware.SimpleSessionStore) *OAuthHandler {
-	oauthConfig := utils.NewGoogleOAuthConfig(
-		config.GoogleClientID,
-		config.GoogleClientSecret,
-		config.FrontendURL+"/auth/callback",
-	)
+// oauthFlow is kept in the short-lived oauth_state cookie between Login and
+// Callback.
+type oauthFlow struct {
+	Provider     string `json:"p"`
+	State        string `json:"s"`
+	Nonce        string `json:"n"`
+	CodeVerifier string `json:"v"`
+	LinkUserID   uint   `json:"l,omitempty"`
+}
 
+func NewOAuthHandler(authService *services.AuthService, config *config.Config, sessions *middleware.SessionManager, providers *identity.Registry, auditService *services.AuditService) *OAuthHandler {
 	return &OAuthHandler{
 		authService:  authService,
+		auditService: auditService,
 		config:       config,
-		oauthConfig:  oauthConfig,
-		sessionStore: sessionStore,
+		providers:    providers,
+		sessions:     sessions,
 	}
 }
 
+// Providers lists the identity providers users can sign in with.
+func (h *OAuthHandler) Providers(c *fiber.Ctx) error {
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"providers": h.providers.Names(),
+		},
+	})
+}
+
 func (h *OAuthHandler) Login(c *fiber.Ctx) error {
-	state, err := utils.GenerateStateToken()
+	return h.startFlow(c, 0)
+}
+
+// LinkIdentity starts a provider login that attaches the resulting identity
+// to the signed-in user instead of logging in. An administrator viewing the
+// account cannot link one, as it would let them sign in as the user.
+func (h *OAuthHandler) LinkIdentity(c *fiber.Ctx) error {
+	if c.Locals("impersonator_id") != nil {
+		return impersonatedLink(c)
+	}
+	return h.startFlow(c, c.Locals("user_id").(uint))
+}
+
+func impersonatedLink(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+		Error:   "impersonation_read_only",
+		Message: "Identities cannot be linked while impersonating",
+	})
+}
+
+func (h *OAuthHandler) startFlow(c *fiber.Ctx, linkUserID uint) error {
+	provider, err := h.providers.Get(c.Query("provider", "google"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "unknown_provider",
+			Message: err.Error(),
+		})
+	}
+
+	authRequest, err := identity.NewAuthRequest()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 			Error:   "state_generation_failed",
@@ -45,22 +97,39 @@ func (h *OAuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
+	ctx, cancel := context.WithTimeout(c.UserContext(), providerTimeout)
+	defer cancel()
+	authURL, err := provider.AuthCodeURL(ctx, authRequest)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(models.ErrorResponse{
+			Error:   "provider_unavailable",
+			Message: "Identity provider is unavailable",
+		})
+	}
+
+	flow, _ := json.Marshal(oauthFlow{
+		Provider:     provider.Name(),
+		State:        authRequest.State,
+		Nonce:        authRequest.Nonce,
+		CodeVerifier: authRequest.CodeVerifier,
+		LinkUserID:   linkUserID,
+	})
+
 	// Store state in a temporary session-like cookie
 	c.Cookie(&fiber.Cookie{
 		Name:     "oauth_state",
-		Value:    state,
+		Value:    base64.RawURLEncoding.EncodeToString(flow),
 		Expires:  time.Now().Add(10 * time.Minute), // Short-lived
 		HTTPOnly: true,
 		Secure:   h.config.Environment == "production",
 		SameSite: "Lax",
 	})
 
-	authURL := h.oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline)
-
 	return c.JSON(models.SuccessResponse{
 		Success: true,
 		Data: fiber.Map{
 			"auth_url": authURL,
+			"provider": provider.Name(),
 		},
 		Message: "OAuth login URL generated",
 	})
@@ -95,8 +164,8 @@ func (h *OAuthHandler) Callback(c *fiber.Ctx) error {
 	}
 
 	// Verify state token
-	storedState := c.Cookies("oauth_state")
-	if storedState == "" || storedState != state {
+	flow, ok := readOAuthFlow(c.Cookies("oauth_state"))
+	if !ok || flow.State != state {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
 			Error:   "invalid_state",
 			Message: "Invalid state parameter",
@@ -113,9 +182,22 @@ func (h *OAuthHandler) Callback(c *fiber.Ctx) error {
 		SameSite: "Lax",
 	})
 
-	// Exchange code for token
-	ctx := context.Background()
-	token, err := h.oauthConfig.Exchange(ctx, code)
+	provider, err := h.providers.Get(flow.Provider)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "unknown_provider",
+			Message: err.Error(),
+		})
+	}
+
+	// Exchange code for a verified identity
+	ctx, cancel := context.WithTimeout(c.UserContext(), providerTimeout)
+	defer cancel()
+	userInfo, err := provider.Exchange(ctx, code, &identity.AuthRequest{
+		State:        flow.State,
+		Nonce:        flow.Nonce,
+		CodeVerifier: flow.CodeVerifier,
+	})
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
 			Error:   "token_exchange_failed",
@@ -123,32 +205,42 @@ func (h *OAuthHandler) Callback(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get user info from Google
-	userInfo, err := utils.GetGoogleUserInfo(ctx, h.oauthConfig, token)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error:   "userinfo_failed",
-			Message: "Failed to get user information",
+	if userInfo.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "email_required",
+			Message: "Identity provider did not return an email address",
 		})
 	}
 
+	if flow.LinkUserID != 0 {
+		return h.completeLink(c, flow.LinkUserID, userInfo)
+	}
+
 	// Register or login user
-	user, err := h.authService.LoginOrRegisterOAuth(userInfo.Email, userInfo.Name, userInfo.Picture)
+	user, err := h.authService.LoginWithIdentity(userInfo)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error:   "login_failed",
+		status, code := fiber.StatusInternalServerError, "login_failed"
+		switch {
+		case errors.Is(err, services.ErrAccountDisabled):
+			status, code = fiber.StatusForbidden, "account_disabled"
+		case errors.Is(err, services.ErrEmailNotVerified):
+			status, code = fiber.StatusForbidden, "email_not_verified"
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
+			Error:   code,
 			Message: err.Error(),
 		})
 	}
 
 	// Create session
-	_, err = h.sessionStore.CreateSession(c, user.ID, user.Email)
+	_, err = h.sessions.CreateSession(c, user.ID, user.Email)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 			Error:   "session_creation_failed",
 			Message: "Failed to create session",
 		})
 	}
+	h.auditService.Record(userActor(c, user.ID), accountEvent(models.AuditLogin, user.ID))
 
 	return c.JSON(models.SuccessResponse{
 		Success: true,
@@ -159,10 +251,106 @@ func (h *OAuthHandler) Callback(c *fiber.Ctx) error {
 	})
 }
 
+// completeLink attaches a provider identity to the user who started the link
+// flow, provided they are still signed in to the same account and not being
+// impersonated.
+func (h *OAuthHandler) completeLink(c *fiber.Ctx, userID uint, userInfo *identity.UserInfo) error {
+	session := h.sessions.GetSession(h.sessions.SessionID(c))
+	if session == nil || session.UserID != userID {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error:   "unauthorized",
+			Message: "Sign in to link an identity",
+		})
+	}
+	if session.ImpersonatorID != 0 {
+		return impersonatedLink(c)
+	}
+
+	linked, err := h.authService.LinkIdentity(userID, userInfo)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrIdentityInUse) {
+			status = fiber.StatusConflict
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
+			Error:   "link_failed",
+			Message: err.Error(),
+		})
+	}
+	h.auditService.Record(userActor(c, userID), &models.AuditEvent{
+		Action:     models.AuditIdentityLink,
+		TargetType: models.AuditTargetIdentity,
+		TargetID:   linked.ID,
+	})
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"identity": linked,
+		},
+		Message: "Identity linked successfully",
+	})
+}
+
+func (h *OAuthHandler) ListIdentities(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	identities, err := h.authService.ListIdentities(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    identities,
+	})
+}
+
+func (h *OAuthHandler) UnlinkIdentity(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	identityID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_identity_id",
+			Message: "Invalid identity ID",
+		})
+	}
+
+	if err := h.authService.UnlinkIdentity(userID, uint(identityID)); err != nil {
+		status := fiber.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrIdentityNotFound):
+			status = fiber.StatusNotFound
+		case errors.Is(err, services.ErrLastIdentity):
+			status = fiber.StatusConflict
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
+			Error:   "unlink_failed",
+			Message: err.Error(),
+		})
+	}
+	h.auditService.Record(auditActor(c), &models.AuditEvent{
+		Action:     models.AuditIdentityUnlink,
+		TargetType: models.AuditTargetIdentity,
+		TargetID:   uint(identityID),
+	})
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "Identity unlinked successfully",
+	})
+}
+
 func (h *OAuthHandler) Logout(c *fiber.Ctx) error {
-	sessionID := c.Cookies("session_id")
-	if sessionID != "" {
-		h.sessionStore.DestroySession(c, sessionID)
+	if sessionID := h.sessions.SessionID(c); sessionID != "" {
+		if session := h.sessions.GetSession(sessionID); session != nil && session.ImpersonatorID == 0 {
+			h.auditService.Record(userActor(c, session.UserID), accountEvent(models.AuditLogout, session.UserID))
+		}
+		h.sessions.DestroySession(c, sessionID)
 	}
 
 	return c.JSON(models.SuccessResponse{
@@ -188,6 +376,23 @@ func (h *OAuthHandler) GetProfile(c *fiber.Ctx) error {
 	})
 }
 
+func readOAuthFlow(cookie string) (*oauthFlow, bool) {
+	if cookie == "" {
+		return nil, false
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cookie)
+	if err != nil {
+		return nil, false
+	}
+
+	var flow oauthFlow
+	if err := json.Unmarshal(data, &flow); err != nil || flow.State == "" {
+		return nil, false
+	}
+	return &flow, true
+}
+
 // isValidOAuthParam validates OAuth parameter format for security
 func isValidOAuthParam(param string) bool {
 	// Allow alphanumeric, dash, underscore, dot, forward slash (URL-safe characters)
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PasswordAuthHandler struct {
+	passwordService *services.PasswordAuthService
+	sessions        *middleware.SessionManager
+	auditService    *services.AuditService
+}
+
+func NewPasswordAuthHandler(passwordService *services.PasswordAuthService, sessions *middleware.SessionManager, auditService *services.AuditService) *PasswordAuthHandler {
+	return &PasswordAuthHandler{
+		passwordService: passwordService,
+		sessions:        sessions,
+		auditService:    auditService,
+	}
+}
+
+func (h *PasswordAuthHandler) Register(c *fiber.Ctx) error {
+	var req models.RegisterRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	user, err := h.passwordService.Register(&req)
+	if err != nil {
+		status := fiber.StatusBadRequest
+		if errors.Is(err, services.ErrEmailTaken) {
+			status = fiber.StatusConflict
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
+			Error:   "registration_failed",
+			Message: err.Error(),
+		})
+	}
+	h.auditService.Record(userActor(c, user.ID), accountEvent(models.AuditRegister, user.ID))
+
+	return c.Status(fiber.StatusCreated).JSON(models.SuccessResponse{
+		Success: true,
+		Data:    user,
+		Message: "Account created. Check your email to verify your address",
+	})
+}
+
+func (h *PasswordAuthHandler) Login(c *fiber.Ctx) error {
+	var req models.LoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	user, err := h.passwordService.Login(req.Email, req.Password, c.IP())
+	if err != nil {
+		status, code := fiber.StatusInternalServerError, "login_failed"
+		switch {
+		case errors.Is(err, services.ErrInvalidCredentials):
+			status, code = fiber.StatusUnauthorized, "invalid_credentials"
+		case errors.Is(err, services.ErrEmailNotVerified):
+			status, code = fiber.StatusForbidden, "email_not_verified"
+		case errors.Is(err, services.ErrAccountDisabled):
+			status, code = fiber.StatusForbidden, "account_disabled"
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
+			Error:   code,
+			Message: err.Error(),
+		})
+	}
+
+	if _, err := h.sessions.CreateSession(c, user.ID, user.Email); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "session_creation_failed",
+			Message: "Failed to create session",
+		})
+	}
+	h.auditService.Record(userActor(c, user.ID), accountEvent(models.AuditLogin, user.ID))
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"user": user,
+		},
+		Message: "Login successful",
+	})
+}
+
+func (h *PasswordAuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	var req models.VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	user, err := h.passwordService.VerifyEmail(req.Token)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_token",
+			Message: err.Error(),
+		})
+	}
+	h.auditService.Record(userActor(c, user.ID), accountEvent(models.AuditVerifyEmail, user.ID))
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "Email verified successfully",
+	})
+}
+
+func (h *PasswordAuthHandler) ResendVerification(c *fiber.Ctx) error {
+	var req models.EmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	h.passwordService.ResendVerification(req.Email)
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "If the account needs verification, a new link has been sent",
+	})
+}
+
+func (h *PasswordAuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req models.EmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	h.passwordService.RequestPasswordReset(req.Email)
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "If an account exists for this email, a reset link has been sent",
+	})
+}
+
+func (h *PasswordAuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req models.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	user, err := h.passwordService.ResetPassword(req.Token, req.Password)
+	if err != nil {
+		code := "reset_failed"
+		if errors.Is(err, services.ErrInvalidEmailToken) {
+			code = "invalid_token"
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   code,
+			Message: err.Error(),
+		})
+	}
+	h.auditService.Record(userActor(c, user.ID), accountEvent(models.AuditPasswordReset, user.ID))
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "Password updated. Sign in with your new password",
+	})
+}
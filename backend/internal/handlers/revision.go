@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// revisionError maps revision errors to HTTP responses.
+func revisionError(c *fiber.Ctx, err error, code string) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, services.ErrURLNotFound), errors.Is(err, services.ErrRevisionNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrInsufficientRole):
+		status = fiber.StatusForbidden
+	case errors.Is(err, services.ErrRevisionIsCurrent):
+		status = fiber.StatusConflict
+	}
+	return c.Status(status).JSON(models.ErrorResponse{
+		Error:   code,
+		Message: err.Error(),
+	})
+}
+
+// revisionQuery reads an optional revision number from the query string.
+func revisionQuery(c *fiber.Ctx, name string) (int, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, true
+	}
+	number, err := strconv.Atoi(raw)
+	if err != nil || number <= 0 {
+		c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_revision",
+			Message: name + " must be a positive revision number",
+		})
+		return 0, false
+	}
+	return number, true
+}
+
+// ListRevisions returns a link's revisions, newest first.
+func (h *URLHandler) ListRevisions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	urlID, ok := idParam(c, "id", "invalid_url_id", "Invalid URL ID")
+	if !ok {
+		return nil
+	}
+	limit, offset := pageParams(c)
+
+	revisions, total, err := h.urlService.ListRevisions(urlID, userID, limit, offset)
+	if err != nil {
+		return revisionError(c, err, "fetch_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"revisions": revisions,
+			"total":     total,
+			"limit":     limit,
+			"offset":    offset,
+		},
+	})
+}
+
+// DiffRevisions compares two revisions of a link, by default the live one
+// and the one before it.
+func (h *URLHandler) DiffRevisions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	urlID, ok := idParam(c, "id", "invalid_url_id", "Invalid URL ID")
+	if !ok {
+		return nil
+	}
+	from, ok := revisionQuery(c, "from")
+	if !ok {
+		return nil
+	}
+	to, ok := revisionQuery(c, "to")
+	if !ok {
+		return nil
+	}
+
+	diff, err := h.urlService.DiffRevisions(urlID, userID, from, to)
+	if err != nil {
+		return revisionError(c, err, "fetch_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    diff,
+	})
+}
+
+// RollbackURL restores a link to an earlier revision.
+func (h *URLHandler) RollbackURL(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	urlID, ok := idParam(c, "id", "invalid_url_id", "Invalid URL ID")
+	if !ok {
+		return nil
+	}
+	number, err := strconv.Atoi(c.Params("number"))
+	if err != nil || number <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_revision",
+			Message: "Invalid revision number",
+		})
+	}
+
+	url, err := h.urlService.WithActor(auditActor(c)).RollbackURL(urlID, userID, number)
+	if err != nil {
+		return revisionError(c, err, "rollback_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    url,
+		Message: "URL rolled back to revision " + strconv.Itoa(number),
+	})
+}
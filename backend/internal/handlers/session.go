@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SessionHandler struct {
+	sessions     *middleware.SessionManager
+	auditService *services.AuditService
+}
+
+func NewSessionHandler(sessions *middleware.SessionManager, auditService *services.AuditService) *SessionHandler {
+	return &SessionHandler{
+		sessions:     sessions,
+		auditService: auditService,
+	}
+}
+
+func (h *SessionHandler) ListSessions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	currentSessionID, _ := c.Locals("session_id").(string)
+
+	sessions, err := h.sessions.ListSessions(userID, currentSessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    sessions,
+	})
+}
+
+func (h *SessionHandler) RevokeSession(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	key := c.Params("id")
+
+	if err := h.sessions.RevokeSession(userID, key); err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, middleware.ErrSessionNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
+			Error:   "revoke_failed",
+			Message: err.Error(),
+		})
+	}
+	h.auditService.Record(auditActor(c), &models.AuditEvent{
+		Action:     models.AuditSessionRevoke,
+		TargetType: models.AuditTargetSession,
+	})
+
+	if currentSessionID, _ := c.Locals("session_id").(string); middleware.SessionKey(currentSessionID) == key {
+		h.sessions.ClearCookie(c)
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "Session revoked successfully",
+	})
+}
+
+// RevokeAllSessions logs the user out everywhere, including this browser.
+func (h *SessionHandler) RevokeAllSessions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	revoked, err := h.sessions.RevokeUserSessions(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "revoke_failed",
+			Message: err.Error(),
+		})
+	}
+
+	h.auditService.Record(auditActor(c), &models.AuditEvent{
+		Action:     models.AuditSessionRevokeAll,
+		TargetType: models.AuditTargetSession,
+		Changes:    map[string]models.AuditChange{"revoked": {To: revoked}},
+	})
+	h.sessions.ClearCookie(c)
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"revoked": revoked,
+		},
+		Message: "Logged out of all sessions",
+	})
+}
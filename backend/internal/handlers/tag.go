@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TagHandler struct {
+	tagService       *services.TagService
+	analyticsService *services.AnalyticsService
+}
+
+func NewTagHandler(tagService *services.TagService, analyticsService *services.AnalyticsService) *TagHandler {
+	return &TagHandler{
+		tagService:       tagService,
+		analyticsService: analyticsService,
+	}
+}
+
+// organizeError maps tag and folder service errors to a response.
+func organizeError(c *fiber.Ctx, err error, code string) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, services.ErrTagNotFound), errors.Is(err, services.ErrFolderNotFound),
+		errors.Is(err, services.ErrURLNotFound), errors.Is(err, services.ErrWorkspaceNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrInsufficientRole):
+		status = fiber.StatusForbidden
+	case errors.Is(err, services.ErrTagExists), errors.Is(err, services.ErrFolderExists):
+		status = fiber.StatusConflict
+	}
+	return c.Status(status).JSON(models.ErrorResponse{
+		Error:   code,
+		Message: err.Error(),
+	})
+}
+
+// workspaceQuery reads the optional workspace_id filter, writing a 400
+// response if it is malformed.
+func workspaceQuery(c *fiber.Ctx) (*uint, bool) {
+	workspaceID, err := optionalIDQuery(c, "workspace_id")
+	if err != nil {
+		c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_workspace_id",
+			Message: "Invalid workspace ID",
+		})
+		return nil, false
+	}
+	return workspaceID, true
+}
+
+func (h *TagHandler) ListTags(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	workspaceID, ok := workspaceQuery(c)
+	if !ok {
+		return nil
+	}
+
+	tags, err := h.tagService.ListTags(userID, workspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    tags,
+	})
+}
+
+func (h *TagHandler) CreateTag(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req models.TagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	tag, err := h.tagService.CreateTag(userID, &req)
+	if err != nil {
+		return organizeError(c, err, "create_failed")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.SuccessResponse{
+		Success: true,
+		Data:    tag,
+		Message: "Tag created successfully",
+	})
+}
+
+func (h *TagHandler) UpdateTag(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	tagID, ok := idParam(c, "id", "invalid_tag_id", "Invalid tag ID")
+	if !ok {
+		return nil
+	}
+
+	var req models.TagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	tag, err := h.tagService.UpdateTag(tagID, userID, &req)
+	if err != nil {
+		return organizeError(c, err, "update_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    tag,
+		Message: "Tag updated successfully",
+	})
+}
+
+func (h *TagHandler) DeleteTag(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	tagID, ok := idParam(c, "id", "invalid_tag_id", "Invalid tag ID")
+	if !ok {
+		return nil
+	}
+
+	if err := h.tagService.WithActor(auditActor(c)).DeleteTag(tagID, userID); err != nil {
+		return organizeError(c, err, "delete_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "Tag deleted successfully",
+	})
+}
+
+// GetTagStats aggregates clicks across every link carrying a tag.
+func (h *TagHandler) GetTagStats(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	tagID, ok := idParam(c, "id", "invalid_tag_id", "Invalid tag ID")
+	if !ok {
+		return nil
+	}
+	from, to, ok := periodParams(c)
+	if !ok {
+		return nil
+	}
+
+	stats, err := h.analyticsService.GetTagStats(tagID, userID, from, to)
+	if err != nil {
+		return organizeError(c, err, "fetch_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    stats,
+	})
+}
+
+// TagURLs adds tags to a selection of links.
+func (h *TagHandler) TagURLs(c *fiber.Ctx) error {
+	return h.bulk(c, (*services.TagService).TagURLs, "tag_failed")
+}
+
+// UntagURLs removes tags from a selection of links.
+func (h *TagHandler) UntagURLs(c *fiber.Ctx) error {
+	return h.bulk(c, (*services.TagService).UntagURLs, "untag_failed")
+}
+
+func (h *TagHandler) bulk(c *fiber.Ctx, apply func(*services.TagService, uint, *models.BulkTagRequest) (int64, error), code string) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req models.BulkTagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	changed, err := apply(h.tagService.WithActor(auditActor(c)), userID, &req)
+	if err != nil {
+		return organizeError(c, err, code)
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    fiber.Map{"changed": changed},
+	})
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"strconv"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TokenHandler struct {
+	tokenService *services.TokenService
+	auditService *services.AuditService
+}
+
+func NewTokenHandler(tokenService *services.TokenService, auditService *services.AuditService) *TokenHandler {
+	return &TokenHandler{
+		tokenService: tokenService,
+		auditService: auditService,
+	}
+}
+
+func (h *TokenHandler) CreateToken(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req models.CreateAPITokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	plaintext, token, err := h.tokenService.CreateToken(userID, &req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "token_creation_failed",
+			Message: err.Error(),
+		})
+	}
+	h.auditService.Record(auditActor(c), &models.AuditEvent{
+		Action:     models.AuditTokenCreate,
+		TargetType: models.AuditTargetToken,
+		TargetID:   token.ID,
+		Changes: map[string]models.AuditChange{
+			"name":   {To: token.Name},
+			"scopes": {To: token.Scopes},
+		},
+	})
+
+	return c.Status(fiber.StatusCreated).JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"token":     plaintext,
+			"api_token": token,
+		},
+		Message: "Token created. Copy it now, it will not be shown again",
+	})
+}
+
+func (h *TokenHandler) ListTokens(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	tokens, err := h.tokenService.ListTokens(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    tokens,
+	})
+}
+
+func (h *TokenHandler) RevokeToken(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	tokenID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_token_id",
+			Message: "Invalid token ID",
+		})
+	}
+
+	if err := h.tokenService.RevokeToken(uint(tokenID), userID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "revoke_failed",
+			Message: err.Error(),
+		})
+	}
+	h.auditService.Record(auditActor(c), &models.AuditEvent{
+		Action:     models.AuditTokenRevoke,
+		TargetType: models.AuditTargetToken,
+		TargetID:   uint(tokenID),
+	})
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "Token revoked successfully",
+	})
+}
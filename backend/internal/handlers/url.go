@@ -1,7 +1,15 @@
 package handlers
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
+	"url-shortener-backend/internal/config"
 	"url-shortener-backend/internal/models"
 	"url-shortener-backend/internal/services"
 	"url-shortener-backend/internal/utils"
@@ -9,13 +17,26 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
+const liveHeartbeatInterval = 15 * time.Second
+
 type URLHandler struct {
-	urlService *services.URLService
+	urlService  *services.URLService
+	clickHub    *services.ClickHub
+	config      *config.Config
+	proceedKeys [][]byte
 }
 
-func NewURLHandler(urlService *services.URLService) *URLHandler {
+func NewURLHandler(urlService *services.URLService, clickHub *services.ClickHub, config *config.Config) *URLHandler {
+	proceedKeys := [][]byte{utils.DeriveKey(config.SessionSecret, "quarantine-proceed")}
+	for _, secret := range config.SessionSecretPrevious {
+		proceedKeys = append(proceedKeys, utils.DeriveKey(secret, "quarantine-proceed"))
+	}
+
 	return &URLHandler{
-		urlService: urlService,
+		urlService:  urlService,
+		clickHub:    clickHub,
+		config:      config,
+		proceedKeys: proceedKeys,
 	}
 }
 
@@ -35,9 +56,22 @@ func (h *URLHandler) CreateURL(c *fiber.Ctx) error {
 		}
 	}
 	
-	url, err := h.urlService.CreateURL(&req, userID)
+	url, err := h.urlService.WithActor(auditActor(c)).CreateURL(&req, userID)
+	if errors.Is(err, services.ErrQuotaExceeded) {
+		return c.Status(fiber.StatusPaymentRequired).JSON(models.ErrorResponse{
+			Error:   "quota_exceeded",
+			Message: err.Error(),
+		})
+	}
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+		status := fiber.StatusBadRequest
+		switch {
+		case errors.Is(err, services.ErrWorkspaceNotFound), errors.Is(err, services.ErrFolderNotFound), errors.Is(err, services.ErrTagNotFound):
+			status = fiber.StatusNotFound
+		case errors.Is(err, services.ErrInsufficientRole):
+			status = fiber.StatusForbidden
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
 			Error:   "url_creation_failed",
 			Message: err.Error(),
 		})
@@ -61,16 +95,41 @@ func (h *URLHandler) RedirectURL(c *fiber.Ctx) error {
 		})
 	}
 	
-	analytics := &models.Analytics{
-		IPAddress: c.IP(),
-		UserAgent: c.Get("User-Agent"),
-		Referrer:  c.Get("Referer"),
+	// Reported links show a warning first; only visitors who choose to
+	// continue from it are redirected and counted.
+	if url.QuarantinedAt != nil && !verifyProceedToken(c.Query("proceed"), url.ShortCode, time.Now(), h.proceedKeys) {
+		return quarantineWarning(c, url, signProceedToken(url.ShortCode, time.Now(), h.proceedKeys[0]))
 	}
 	
-	device, os, browser := utils.ParseUserAgent(analytics.UserAgent)
-	analytics.Device = device
-	analytics.OS = os
-	analytics.Browser = browser
+	// Visitors who send DNT or Sec-GPC are still counted, but nothing that
+	// could identify them is stored with the click.
+	analytics := &models.Analytics{Revision: url.Revision}
+	if !utils.TrackingOptOut(c.Get("DNT"), c.Get("Sec-GPC")) {
+		analytics.IPAddress = c.IP()
+		if h.config.AnonymizeIPs {
+			analytics.IPAddress = utils.AnonymizeIP(analytics.IPAddress)
+		}
+		analytics.UserAgent = c.Get("User-Agent")
+		analytics.Referrer = c.Get("Referer")
+		analytics.ReferrerDomain, analytics.TrafficSource = utils.ClassifyReferrer(analytics.Referrer, h.internalHosts(c)...)
+		
+		device, os, browser := utils.ParseUserAgent(analytics.UserAgent)
+		analytics.Device = device
+		analytics.OS = os
+		analytics.Browser = browser
+		analytics.IsBot = utils.IsBotUserAgent(analytics.UserAgent)
+	}
+	
+	// Published first: RecordClick writes to analytics once it is running.
+	h.clickHub.Publish(models.ClickEvent{
+		URLID:          url.ID,
+		Device:         analytics.Device,
+		Country:        analytics.Country,
+		ReferrerDomain: analytics.ReferrerDomain,
+		TrafficSource:  analytics.TrafficSource,
+		IsBot:          analytics.IsBot,
+		ClickedAt:      time.Now().UTC(),
+	})
 	
 	go h.urlService.RecordClick(url.ID, analytics)
 	
@@ -104,9 +163,20 @@ func (h *URLHandler) GetUserURLs(c *fiber.Ctx) error {
 		limit = 100
 	}
 	
-	urls, total, err := h.urlService.GetUserURLs(userID, limit, offset)
+	filter, ok := urlFilter(c)
+	if !ok {
+		return nil
+	}
+	
+	urls, total, err := h.urlService.GetUserURLs(userID, filter, limit, offset)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrFolderNotFound) {
+			status = fiber.StatusNotFound
+		} else if errors.Is(err, services.ErrInvalidSort) {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
 			Error:   "fetch_failed",
 			Message: err.Error(),
 		})
@@ -123,6 +193,100 @@ func (h *URLHandler) GetUserURLs(c *fiber.Ctx) error {
 	})
 }
 
+// urlFilter reads the link listing filters from the query string, writing a
+// 400 response if one is malformed.
+func urlFilter(c *fiber.Ctx) (*models.URLFilter, bool) {
+	filter := &models.URLFilter{IncludeSubfolders: c.Query("subfolders") == "true"}
+	invalid := func(code, message string) (*models.URLFilter, bool) {
+		c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   code,
+			Message: message,
+		})
+		return nil, false
+	}
+	
+	var err error
+	if filter.WorkspaceID, err = optionalIDQuery(c, "workspace_id"); err != nil {
+		return invalid("invalid_workspace_id", "Invalid workspace ID")
+	}
+	
+	if c.Query("folder_id") == "none" {
+		filter.Unfiled = true
+	} else if filter.FolderID, err = optionalIDQuery(c, "folder_id"); err != nil {
+		return invalid("invalid_folder_id", "folder_id must be a folder ID or none")
+	}
+	
+	if raw := c.Query("tag_id"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+			if err != nil {
+				return invalid("invalid_tag_id", "tag_id must be a comma-separated list of tag IDs")
+			}
+			filter.TagIDs = append(filter.TagIDs, uint(id))
+		}
+	}
+	
+	filter.Query = strings.TrimSpace(c.Query("q"))
+	if len(filter.Query) > 200 {
+		return invalid("invalid_query", "q must be at most 200 characters")
+	}
+	
+	// Links cannot be password protected yet, so there is nothing for
+	// this filter to match.
+	if c.Query("protected") != "" {
+		return invalid("unsupported_filter", "Links cannot be protected, so the protected filter is not supported")
+	}
+	
+	for name, target := range map[string]**bool{
+		"active":     &filter.Active,
+		"expired":    &filter.Expired,
+		"has_clicks": &filter.HasClicks,
+	} {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return invalid("invalid_filter", name+" must be true or false")
+		}
+		*target = &value
+	}
+	
+	if filter.CreatedFrom, err = parseTimeQuery(c.Query("created_from")); err != nil {
+		return invalid("invalid_date", "created_from must be an RFC3339 timestamp or YYYY-MM-DD date")
+	}
+	if filter.CreatedTo, err = parseTimeQuery(c.Query("created_to")); err != nil {
+		return invalid("invalid_date", "created_to must be an RFC3339 timestamp or YYYY-MM-DD date")
+	}
+	
+	filter.Sort = c.Query("sort")
+	switch c.Query("order") {
+	case "", "desc":
+	case "asc":
+		filter.Ascending = true
+	default:
+		return invalid("invalid_order", "order must be asc or desc")
+	}
+	
+	return filter, true
+}
+
+// optionalIDQuery parses an ID from the query string, returning nil when it
+// is absent.
+func optionalIDQuery(c *fiber.Ctx, name string) (*uint, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	value := uint(id)
+	return &value, nil
+}
+
 func (h *URLHandler) UpdateURL(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 	
@@ -142,9 +306,13 @@ func (h *URLHandler) UpdateURL(c *fiber.Ctx) error {
 		})
 	}
 	
-	url, err := h.urlService.UpdateURL(uint(urlID), userID, &req)
+	url, err := h.urlService.WithActor(auditActor(c)).UpdateURL(uint(urlID), userID, &req)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+		status := fiber.StatusBadRequest
+		if errors.Is(err, services.ErrInsufficientRole) {
+			status = fiber.StatusForbidden
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
 			Error:   "update_failed",
 			Message: err.Error(),
 		})
@@ -168,8 +336,12 @@ func (h *URLHandler) DeleteURL(c *fiber.Ctx) error {
 		})
 	}
 	
-	if err := h.urlService.DeleteURL(uint(urlID), userID); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+	if err := h.urlService.WithActor(auditActor(c)).DeleteURL(uint(urlID), userID); err != nil {
+		status := fiber.StatusBadRequest
+		if errors.Is(err, services.ErrInsufficientRole) {
+			status = fiber.StatusForbidden
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
 			Error:   "delete_failed",
 			Message: err.Error(),
 		})
@@ -208,11 +380,217 @@ func (h *URLHandler) GetURLAnalytics(c *fiber.Ctx) error {
 		})
 	}
 	
+	referrerLimit, _ := strconv.Atoi(c.Query("referrer_limit", "10"))
+	if referrerLimit <= 0 || referrerLimit > 100 {
+		referrerLimit = 10
+	}
+	
+	topReferrers, err := h.urlService.GetTopReferrers(uint(urlID), userID, referrerLimit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+	}
+	
+	sources, err := h.urlService.GetTrafficSources(uint(urlID), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+	}
+	
 	return c.JSON(models.SuccessResponse{
 		Success: true,
 		Data: fiber.Map{
-			"analytics": analytics,
-			"stats":     stats,
+			"analytics":       analytics,
+			"stats":           stats,
+			"top_referrers":   topReferrers,
+			"traffic_sources": sources,
 		},
 	})
+}
+
+func (h *URLHandler) GetClickEvents(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	
+	urlID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_url_id",
+			Message: "Invalid URL ID",
+		})
+	}
+	
+	filter := &models.ClickEventFilter{
+		Device:         c.Query("device"),
+		OS:             c.Query("os"),
+		Browser:        c.Query("browser"),
+		Country:        c.Query("country"),
+		ReferrerDomain: c.Query("referrer_domain"),
+		Cursor:         c.Query("cursor"),
+	}
+	filter.Limit, _ = strconv.Atoi(c.Query("limit", "100"))
+	
+	if fields := c.Query("fields"); fields != "" {
+		for _, field := range strings.Split(fields, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				filter.Fields = append(filter.Fields, field)
+			}
+		}
+	}
+	
+	if filter.From, err = parseTimeQuery(c.Query("from")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_date",
+			Message: "from must be an RFC3339 timestamp or YYYY-MM-DD date",
+		})
+	}
+	if filter.To, err = parseTimeQuery(c.Query("to")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_date",
+			Message: "to must be an RFC3339 timestamp or YYYY-MM-DD date",
+		})
+	}
+	
+	if bot := c.Query("bot"); bot != "" {
+		isBot, err := strconv.ParseBool(bot)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "bot must be true or false",
+			})
+		}
+		filter.IsBot = &isBot
+	}
+	
+	if revision := c.Query("revision"); revision != "" {
+		if filter.Revision, err = strconv.Atoi(revision); err != nil || filter.Revision <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "invalid_request",
+				Message: "revision must be a positive integer",
+			})
+		}
+	}
+	
+	events, nextCursor, err := h.urlService.ListClickEvents(uint(urlID), userID, filter)
+	if err != nil {
+		status := fiber.StatusBadRequest
+		if errors.Is(err, services.ErrURLNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(models.ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+	}
+	
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"events":      events,
+			"next_cursor": nextCursor,
+		},
+	})
+}
+
+// StreamClickEvents streams a link's clicks as Server-Sent Events until the
+// client disconnects. A heartbeat event is sent periodically, and a dropped
+// event reports clicks skipped because the client fell behind.
+func (h *URLHandler) StreamClickEvents(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	
+	urlID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_url_id",
+			Message: "Invalid URL ID",
+		})
+	}
+	
+	if _, err := h.urlService.GetMemberURL(uint(urlID), userID, models.RoleViewer); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "url_not_found",
+			Message: err.Error(),
+		})
+	}
+	
+	sub := h.clickHub.Subscribe(uint(urlID))
+	
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+	
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer h.clickHub.Unsubscribe(sub)
+		
+		heartbeat := time.NewTicker(liveHeartbeatInterval)
+		defer heartbeat.Stop()
+		
+		fmt.Fprint(w, "retry: 3000\n\n")
+		writeSSE(w, "heartbeat", fiber.Map{"time": time.Now().UTC()})
+		if err := w.Flush(); err != nil {
+			return
+		}
+		
+		for {
+			select {
+			case event, ok := <-sub.Events:
+				if !ok {
+					return
+				}
+				if dropped := sub.TakeDropped(); dropped > 0 {
+					writeSSE(w, "dropped", fiber.Map{"count": dropped})
+				}
+				writeSSE(w, "click", event)
+			case t := <-heartbeat.C:
+				writeSSE(w, "heartbeat", fiber.Map{"time": t.UTC()})
+			}
+			
+			// A failed flush means the client has gone away.
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	
+	return nil
+}
+
+func writeSSE(w *bufio.Writer, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
+
+// parseTimeQuery accepts an RFC3339 timestamp or a YYYY-MM-DD date (UTC
+// midnight). An empty value yields nil.
+func parseTimeQuery(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// internalHosts lists the hosts whose referrals count as internal traffic:
+// the short link host itself and the frontend.
+func (h *URLHandler) internalHosts(c *fiber.Ctx) []string {
+	hosts := []string{c.Hostname()}
+	if frontend, err := url.Parse(h.config.FrontendURL); err == nil && frontend.Host != "" {
+		hosts = append(hosts, frontend.Host)
+	}
+	return hosts
 }
\ No newline at end of file
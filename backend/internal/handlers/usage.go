@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"time"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type UsageHandler struct {
+	usageService *services.UsageService
+}
+
+func NewUsageHandler(usageService *services.UsageService) *UsageHandler {
+	return &UsageHandler{
+		usageService: usageService,
+	}
+}
+
+// GetUsage reports the account's plan and its usage this month.
+func (h *UsageHandler) GetUsage(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	usage, err := h.usageService.GetUsage(userID, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    usage,
+	})
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"errors"
+	"reflect"
+	"time"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+	auditService   *services.AuditService
+}
+
+func NewWebhookHandler(webhookService *services.WebhookService, auditService *services.AuditService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+		auditService:   auditService,
+	}
+}
+
+// webhookError maps webhook service errors to a response.
+func webhookError(c *fiber.Ctx, err error, code string) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound), errors.Is(err, services.ErrDeliveryNotFound),
+		errors.Is(err, services.ErrWorkspaceNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrInsufficientRole):
+		status = fiber.StatusForbidden
+	case errors.Is(err, services.ErrDeliveryPending):
+		status = fiber.StatusConflict
+	}
+	return c.Status(status).JSON(models.ErrorResponse{
+		Error:   code,
+		Message: err.Error(),
+	})
+}
+
+// webhookAuditFields returns the settings of a webhook that the audit log
+// tracks.
+func webhookAuditFields(webhook *models.Webhook) map[string]interface{} {
+	return map[string]interface{}{
+		"url":               webhook.URL,
+		"events":            webhook.Events,
+		"click_sample_rate": webhook.ClickSampleRate,
+		"active":            webhook.Active,
+	}
+}
+
+func (h *WebhookHandler) audit(c *fiber.Ctx, webhook *models.Webhook, action string, before, after map[string]interface{}) {
+	changes := make(map[string]models.AuditChange)
+	for name, to := range after {
+		if from, ok := before[name]; !ok || !reflect.DeepEqual(from, to) {
+			changes[name] = models.AuditChange{From: before[name], To: to}
+		}
+	}
+	for name, from := range before {
+		if _, ok := after[name]; !ok {
+			changes[name] = models.AuditChange{From: from}
+		}
+	}
+	if action == models.AuditWebhookUpdate && len(changes) == 0 {
+		return
+	}
+
+	workspaceID := webhook.WorkspaceID
+	h.auditService.Record(auditActor(c), &models.AuditEvent{
+		Action:      action,
+		TargetType:  models.AuditTargetWebhook,
+		TargetID:    webhook.ID,
+		WorkspaceID: &workspaceID,
+		Changes:     changes,
+	})
+}
+
+func (h *WebhookHandler) ListWebhooks(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	workspaceID, ok := workspaceQuery(c)
+	if !ok {
+		return nil
+	}
+
+	webhooks, err := h.webhookService.ListWebhooks(userID, workspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    webhooks,
+	})
+}
+
+func (h *WebhookHandler) GetWebhook(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	webhookID, ok := idParam(c, "id", "invalid_webhook_id", "Invalid webhook ID")
+	if !ok {
+		return nil
+	}
+
+	webhook, err := h.webhookService.GetWebhook(webhookID, userID)
+	if err != nil {
+		return webhookError(c, err, "fetch_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    webhook,
+	})
+}
+
+func (h *WebhookHandler) CreateWebhook(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req models.WebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	secret, webhook, err := h.webhookService.CreateWebhook(userID, &req)
+	if err != nil {
+		return webhookError(c, err, "create_failed")
+	}
+	h.audit(c, webhook, models.AuditWebhookCreate, nil, webhookAuditFields(webhook))
+
+	return c.Status(fiber.StatusCreated).JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"secret":  secret,
+			"webhook": webhook,
+		},
+		Message: "Webhook created. Copy the signing secret now, it will not be shown again",
+	})
+}
+
+func (h *WebhookHandler) UpdateWebhook(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	webhookID, ok := idParam(c, "id", "invalid_webhook_id", "Invalid webhook ID")
+	if !ok {
+		return nil
+	}
+
+	var req models.WebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	before, err := h.webhookService.GetWebhook(webhookID, userID)
+	if err != nil {
+		return webhookError(c, err, "update_failed")
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(webhookID, userID, &req)
+	if err != nil {
+		return webhookError(c, err, "update_failed")
+	}
+	h.audit(c, webhook, models.AuditWebhookUpdate, webhookAuditFields(before), webhookAuditFields(webhook))
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    webhook,
+		Message: "Webhook updated successfully",
+	})
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	webhookID, ok := idParam(c, "id", "invalid_webhook_id", "Invalid webhook ID")
+	if !ok {
+		return nil
+	}
+
+	webhook, err := h.webhookService.GetWebhook(webhookID, userID)
+	if err != nil {
+		return webhookError(c, err, "delete_failed")
+	}
+	if err := h.webhookService.DeleteWebhook(webhookID, userID); err != nil {
+		return webhookError(c, err, "delete_failed")
+	}
+	h.audit(c, webhook, models.AuditWebhookDelete, webhookAuditFields(webhook), nil)
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "Webhook deleted successfully",
+	})
+}
+
+// ListDeliveries returns a webhook's deliveries, newest first. Pass
+// status=dead to see the ones that ran out of attempts.
+func (h *WebhookHandler) ListDeliveries(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	webhookID, ok := idParam(c, "id", "invalid_webhook_id", "Invalid webhook ID")
+	if !ok {
+		return nil
+	}
+	limit, offset := pageParams(c)
+
+	deliveries, total, err := h.webhookService.ListDeliveries(webhookID, userID, c.Query("status"), limit, offset)
+	if err != nil {
+		return webhookError(c, err, "fetch_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data: fiber.Map{
+			"deliveries": deliveries,
+			"total":      total,
+			"limit":      limit,
+			"offset":     offset,
+		},
+	})
+}
+
+// Redeliver queues a dead or delivered delivery to be sent again.
+func (h *WebhookHandler) Redeliver(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	webhookID, ok := idParam(c, "id", "invalid_webhook_id", "Invalid webhook ID")
+	if !ok {
+		return nil
+	}
+	deliveryID, ok := idParam(c, "deliveryId", "invalid_delivery_id", "Invalid delivery ID")
+	if !ok {
+		return nil
+	}
+
+	delivery, err := h.webhookService.Redeliver(webhookID, deliveryID, userID, time.Now())
+	if err != nil {
+		return webhookError(c, err, "redeliver_failed")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(models.SuccessResponse{
+		Success: true,
+		Data:    delivery,
+		Message: "Delivery queued",
+	})
+}
+
+// RedeliverDead queues every dead delivery of a webhook to be sent again.
+func (h *WebhookHandler) RedeliverDead(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	webhookID, ok := idParam(c, "id", "invalid_webhook_id", "Invalid webhook ID")
+	if !ok {
+		return nil
+	}
+
+	queued, err := h.webhookService.RedeliverDead(webhookID, userID, time.Now())
+	if err != nil {
+		return webhookError(c, err, "redeliver_failed")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(models.SuccessResponse{
+		Success: true,
+		Data:    fiber.Map{"queued": queued},
+	})
+}
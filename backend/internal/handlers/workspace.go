@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WorkspaceHandler struct {
+	workspaceService *services.WorkspaceService
+	auditService     *services.AuditService
+}
+
+func NewWorkspaceHandler(workspaceService *services.WorkspaceService, auditService *services.AuditService) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaceService: workspaceService,
+		auditService:     auditService,
+	}
+}
+
+// audit records a change to a workspace in its audit log and the acting
+// user's.
+func (h *WorkspaceHandler) audit(c *fiber.Ctx, workspaceID uint, action, targetType string, targetID uint, changes map[string]models.AuditChange) {
+	h.auditService.Record(auditActor(c), &models.AuditEvent{
+		Action:      action,
+		TargetType:  targetType,
+		TargetID:    targetID,
+		WorkspaceID: &workspaceID,
+		Changes:     changes,
+	})
+}
+
+// workspaceError maps workspace service errors to a response.
+func workspaceError(c *fiber.Ctx, err error, code string) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, services.ErrWorkspaceNotFound), errors.Is(err, services.ErrMemberNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrInsufficientRole), errors.Is(err, services.ErrInvitationEmailMismatch):
+		status = fiber.StatusForbidden
+	case errors.Is(err, services.ErrLastOwner), errors.Is(err, services.ErrAlreadyMember):
+		status = fiber.StatusConflict
+	}
+	return c.Status(status).JSON(models.ErrorResponse{
+		Error:   code,
+		Message: err.Error(),
+	})
+}
+
+// idParam parses a numeric route parameter, writing a 400 response if it is
+// malformed.
+func idParam(c *fiber.Ctx, name, code, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Params(name), 10, 32)
+	if err != nil {
+		c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   code,
+			Message: message,
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *WorkspaceHandler) ListWorkspaces(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	workspaces, err := h.workspaceService.ListWorkspaces(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    workspaces,
+	})
+}
+
+func (h *WorkspaceHandler) CreateWorkspace(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req models.WorkspaceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	workspace, err := h.workspaceService.CreateWorkspace(userID, &req)
+	if err != nil {
+		return workspaceError(c, err, "workspace_creation_failed")
+	}
+	h.audit(c, workspace.ID, models.AuditWorkspaceCreate, models.AuditTargetWorkspace, workspace.ID, map[string]models.AuditChange{
+		"name": {To: workspace.Name},
+	})
+
+	return c.Status(fiber.StatusCreated).JSON(models.SuccessResponse{
+		Success: true,
+		Data:    workspace,
+		Message: "Workspace created successfully",
+	})
+}
+
+func (h *WorkspaceHandler) GetWorkspace(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	workspaceID, ok := idParam(c, "id", "invalid_workspace_id", "Invalid workspace ID")
+	if !ok {
+		return nil
+	}
+
+	workspace, err := h.workspaceService.GetWorkspace(workspaceID, userID)
+	if err != nil {
+		return workspaceError(c, err, "fetch_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    workspace,
+	})
+}
+
+func (h *WorkspaceHandler) UpdateWorkspace(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	workspaceID, ok := idParam(c, "id", "invalid_workspace_id", "Invalid workspace ID")
+	if !ok {
+		return nil
+	}
+
+	var req models.WorkspaceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	before, err := h.workspaceService.GetWorkspace(workspaceID, userID)
+	if err != nil {
+		return workspaceError(c, err, "update_failed")
+	}
+
+	workspace, err := h.workspaceService.UpdateWorkspace(workspaceID, userID, &req)
+	if err != nil {
+		return workspaceError(c, err, "update_failed")
+	}
+	if workspace.Name != before.Name {
+		h.audit(c, workspaceID, models.AuditWorkspaceUpdate, models.AuditTargetWorkspace, workspaceID, map[string]models.AuditChange{
+			"name": {From: before.Name, To: workspace.Name},
+		})
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    workspace,
+		Message: "Workspace updated successfully",
+	})
+}
+
+func (h *WorkspaceHandler) DeleteWorkspace(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	workspaceID, ok := idParam(c, "id", "invalid_workspace_id", "Invalid workspace ID")
+	if !ok {
+		return nil
+	}
+
+	if err := h.workspaceService.DeleteWorkspace(workspaceID, userID); err != nil {
+		return workspaceError(c, err, "delete_failed")
+	}
+	h.audit(c, workspaceID, models.AuditWorkspaceDelete, models.AuditTargetWorkspace, workspaceID, nil)
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "Workspace deleted successfully",
+	})
+}
+
+func (h *WorkspaceHandler) ListMembers(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	workspaceID, ok := idParam(c, "id", "invalid_workspace_id", "Invalid workspace ID")
+	if !ok {
+		return nil
+	}
+
+	members, err := h.workspaceService.ListMembers(workspaceID, userID)
+	if err != nil {
+		return workspaceError(c, err, "fetch_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    members,
+	})
+}
+
+func (h *WorkspaceHandler) UpdateMember(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	workspaceID, ok := idParam(c, "id", "invalid_workspace_id", "Invalid workspace ID")
+	if !ok {
+		return nil
+	}
+	memberID, ok := idParam(c, "userId", "invalid_user_id", "Invalid user ID")
+	if !ok {
+		return nil
+	}
+
+	var req models.UpdateMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	member, err := h.workspaceService.UpdateMemberRole(workspaceID, userID, memberID, req.Role)
+	if err != nil {
+		return workspaceError(c, err, "update_failed")
+	}
+	h.audit(c, workspaceID, models.AuditMemberUpdate, models.AuditTargetMember, memberID, map[string]models.AuditChange{
+		"role": {To: member.Role},
+	})
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    member,
+		Message: "Member updated successfully",
+	})
+}
+
+func (h *WorkspaceHandler) RemoveMember(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	workspaceID, ok := idParam(c, "id", "invalid_workspace_id", "Invalid workspace ID")
+	if !ok {
+		return nil
+	}
+	memberID, ok := idParam(c, "userId", "invalid_user_id", "Invalid user ID")
+	if !ok {
+		return nil
+	}
+
+	if err := h.workspaceService.RemoveMember(workspaceID, userID, memberID); err != nil {
+		return workspaceError(c, err, "remove_failed")
+	}
+	h.audit(c, workspaceID, models.AuditMemberRemove, models.AuditTargetMember, memberID, nil)
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "Member removed successfully",
+	})
+}
+
+func (h *WorkspaceHandler) Invite(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	workspaceID, ok := idParam(c, "id", "invalid_workspace_id", "Invalid workspace ID")
+	if !ok {
+		return nil
+	}
+
+	var req models.InviteMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	invitation, err := h.workspaceService.Invite(workspaceID, userID, &req)
+	if err != nil {
+		return workspaceError(c, err, "invitation_failed")
+	}
+	h.audit(c, workspaceID, models.AuditInvitationCreate, models.AuditTargetInvitation, invitation.ID, map[string]models.AuditChange{
+		"email": {To: invitation.Email},
+		"role":  {To: invitation.Role},
+	})
+
+	return c.Status(fiber.StatusCreated).JSON(models.SuccessResponse{
+		Success: true,
+		Data:    invitation,
+		Message: "Invitation sent",
+	})
+}
+
+func (h *WorkspaceHandler) ListInvitations(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	workspaceID, ok := idParam(c, "id", "invalid_workspace_id", "Invalid workspace ID")
+	if !ok {
+		return nil
+	}
+
+	invitations, err := h.workspaceService.ListInvitations(workspaceID, userID)
+	if err != nil {
+		return workspaceError(c, err, "fetch_failed")
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    invitations,
+	})
+}
+
+func (h *WorkspaceHandler) RevokeInvitation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	workspaceID, ok := idParam(c, "id", "invalid_workspace_id", "Invalid workspace ID")
+	if !ok {
+		return nil
+	}
+	invitationID, ok := idParam(c, "invitationId", "invalid_invitation_id", "Invalid invitation ID")
+	if !ok {
+		return nil
+	}
+
+	if err := h.workspaceService.RevokeInvitation(workspaceID, userID, invitationID); err != nil {
+		if errors.Is(err, services.ErrInvalidInvitation) {
+			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+				Error:   "revoke_failed",
+				Message: err.Error(),
+			})
+		}
+		return workspaceError(c, err, "revoke_failed")
+	}
+	h.audit(c, workspaceID, models.AuditInvitationRevoke, models.AuditTargetInvitation, invitationID, nil)
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Message: "Invitation revoked",
+	})
+}
+
+func (h *WorkspaceHandler) AcceptInvitation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req models.AcceptInvitationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+	}
+
+	workspace, err := h.workspaceService.AcceptInvitation(userID, req.Token)
+	if err != nil {
+		return workspaceError(c, err, "accept_failed")
+	}
+	h.audit(c, workspace.ID, models.AuditInvitationAccept, models.AuditTargetWorkspace, workspace.ID, map[string]models.AuditChange{
+		"role": {To: workspace.Role},
+	})
+
+	return c.JSON(models.SuccessResponse{
+		Success: true,
+		Data:    workspace,
+		Message: "You joined " + workspace.Name,
+	})
+}
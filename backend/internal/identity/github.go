@@ -0,0 +1,100 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+const githubAPIURL = "https://api.github.com"
+
+type githubUser struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+type GitHubProvider struct {
+	oauthConfig *oauth2.Config
+	apiURL      string
+	httpClient  *http.Client
+}
+
+func NewGitHubProvider(clientID, clientSecret, redirectURL string) *GitHubProvider {
+	return &GitHubProvider{
+		oauthConfig: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"read:user", "user:email"},
+			Endpoint:     github.Endpoint,
+		},
+		apiURL:     githubAPIURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *GitHubProvider) Name() string {
+	return "github"
+}
+
+func (p *GitHubProvider) AuthCodeURL(ctx context.Context, req *AuthRequest) (string, error) {
+	return p.oauthConfig.AuthCodeURL(req.State, oauth2.S256ChallengeOption(req.CodeVerifier)), nil
+}
+
+// Exchange resolves the GitHub account and its primary email. GitHub does not
+// return private emails on /user, so the email comes from /user/emails.
+func (p *GitHubProvider) Exchange(ctx context.Context, code string, req *AuthRequest) (*UserInfo, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.httpClient)
+	token, err := p.oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(req.CodeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+	client := p.oauthConfig.Client(ctx, token)
+
+	var user githubUser
+	if err := getJSON(ctx, client, p.apiURL+"/user", &user); err != nil {
+		return nil, err
+	}
+
+	var emails []githubEmail
+	if err := getJSON(ctx, client, p.apiURL+"/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	info := &UserInfo{
+		Provider: p.Name(),
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Name:     user.Name,
+		Picture:  user.AvatarURL,
+	}
+	if info.Name == "" {
+		info.Name = user.Login
+	}
+
+	for _, email := range emails {
+		if email.Primary {
+			info.Email = email.Email
+			info.EmailVerified = email.Verified
+			break
+		}
+	}
+	if info.Email == "" {
+		return nil, errors.New("github account has no primary email")
+	}
+
+	return info, nil
+}
@@ -0,0 +1,126 @@
+// Package identity implements the external identity providers users can
+// sign in with.
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"url-shortener-backend/internal/config"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrInvalidIDToken  = errors.New("invalid ID token")
+)
+
+// UserInfo is the identity a provider vouches for after a successful login.
+type UserInfo struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// AuthRequest carries the per-login secrets that bind the callback to the
+// browser that started the flow.
+type AuthRequest struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+// Provider is an OAuth 2.0 / OpenID Connect identity provider.
+type Provider interface {
+	Name() string
+	// AuthCodeURL returns the URL to send the user to for consent.
+	AuthCodeURL(ctx context.Context, req *AuthRequest) (string, error)
+	// Exchange redeems an authorization code and returns the verified user.
+	Exchange(ctx context.Context, code string, req *AuthRequest) (*UserInfo, error)
+}
+
+// Registry holds the providers enabled for this deployment.
+type Registry struct {
+	providers map[string]Provider
+}
+
+// NewRegistry enables every provider with credentials in the config. Google
+// is always registered so existing deployments keep working.
+func NewRegistry(cfg *config.Config) *Registry {
+	redirectURL := cfg.FrontendURL + "/auth/callback"
+
+	registry := &Registry{providers: make(map[string]Provider)}
+	registry.Register(NewGoogleProvider(cfg.GoogleClientID, cfg.GoogleClientSecret, redirectURL))
+
+	if cfg.GitHubClientID != "" {
+		registry.Register(NewGitHubProvider(cfg.GitHubClientID, cfg.GitHubClientSecret, redirectURL))
+	}
+
+	if cfg.OIDCIssuerURL != "" {
+		registry.Register(NewOIDCProvider(OIDCConfig{
+			Name:         cfg.OIDCProviderName,
+			IssuerURL:    cfg.OIDCIssuerURL,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  redirectURL,
+		}))
+	}
+
+	return registry
+}
+
+func (r *Registry) Register(provider Provider) {
+	r.providers[provider.Name()] = provider
+}
+
+func (r *Registry) Get(name string) (Provider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+// Names lists the enabled providers in alphabetical order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewAuthRequest generates fresh state, nonce and PKCE verifier values.
+func NewAuthRequest() (*AuthRequest, error) {
+	state, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthRequest{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	}, nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"url-shortener-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminChecker decides whether a user may use the moderation API.
+type AdminChecker interface {
+	IsAdmin(userID uint) bool
+}
+
+// RequireAdmin rejects requests from users who are not administrators. It
+// must run after AuthMiddleware. Impersonation sessions are always rejected
+// so an admin viewing another account cannot act with that account's rights.
+func RequireAdmin(checker AdminChecker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(uint)
+		if !ok || c.Locals("impersonator_id") != nil || !checker.IsAdmin(userID) {
+			return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+				Error:   "admin_required",
+				Message: "Administrator access required",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CSRFHeader carries the CSRF token on mutating requests.
+const CSRFHeader = "X-CSRF-Token"
+
+// CSRFProtection guards cookie-authenticated requests against cross-site
+// forgery. Tokens are stateless: a random nonce plus an HMAC binding it to the
+// session ID, so a token is only valid for the session it was issued to and a
+// cross-site page cannot read or mint one.
+type CSRFProtection struct {
+	sessions       *SessionManager
+	keys           [][]byte
+	allowedOrigins map[string]bool
+}
+
+func NewCSRFProtection(cfg *config.Config, sessions *SessionManager) *CSRFProtection {
+	keys := [][]byte{utils.DeriveKey(cfg.SessionSecret, "csrf")}
+	for _, secret := range cfg.SessionSecretPrevious {
+		keys = append(keys, utils.DeriveKey(secret, "csrf"))
+	}
+
+	allowedOrigins := make(map[string]bool)
+	if origin := originOf(cfg.FrontendURL); origin != "" {
+		allowedOrigins[origin] = true
+	}
+
+	return &CSRFProtection{
+		sessions:       sessions,
+		keys:           keys,
+		allowedOrigins: allowedOrigins,
+	}
+}
+
+// Token issues a CSRF token for the request's current session. Without a
+// session the token is bound to no session and must be fetched again after
+// signing in.
+func (p *CSRFProtection) Token(c *fiber.Ctx) (string, error) {
+	nonce := make([]byte, 18)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(nonce)
+	return encoded + "." + p.mac(p.keys[0], encoded, p.sessions.SessionID(c)), nil
+}
+
+func (p *CSRFProtection) mac(key []byte, nonce, sessionID string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(nonce + "|" + sessionID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (p *CSRFProtection) validToken(token, sessionID string) bool {
+	i := strings.IndexByte(token, '.')
+	if i <= 0 {
+		return false
+	}
+
+	nonce, signature := token[:i], token[i+1:]
+	for _, key := range p.keys {
+		if hmac.Equal([]byte(signature), []byte(p.mac(key, nonce, sessionID))) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware rejects unsafe requests that come from another origin, and
+// requires a valid token header when the request is authenticated by the
+// session cookie. Bearer-token requests are exempt because browsers never
+// attach them automatically.
+func (p *CSRFProtection) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+
+		// The auth middleware never falls back to the cookie when a bearer
+		// token is present, so the cookie cannot be what authenticates.
+		if _, ok := bearerToken(c); ok {
+			return c.Next()
+		}
+
+		if code, message := p.checkOrigin(c); code != "" {
+			return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+				Error:   code,
+				Message: message,
+			})
+		}
+
+		if c.Cookies("session_id") == "" {
+			return c.Next()
+		}
+
+		token := c.Get(CSRFHeader)
+		if token == "" {
+			return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+				Error:   "csrf_token_missing",
+				Message: "Missing " + CSRFHeader + " header",
+			})
+		}
+		if !p.validToken(token, p.sessions.SessionID(c)) {
+			return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+				Error:   "csrf_token_invalid",
+				Message: "CSRF token is invalid or belongs to another session",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// checkOrigin compares Origin, or Referer when Origin is absent, with the
+// frontend and the API's own origin. Requests carrying neither header are only
+// refused when they also carry a session cookie.
+func (p *CSRFProtection) checkOrigin(c *fiber.Ctx) (string, string) {
+	// A literal Origin such as "null" is kept so it fails the comparison.
+	origin := strings.ToLower(c.Get(fiber.HeaderOrigin))
+	if origin == "" {
+		origin = originOf(c.Get(fiber.HeaderReferer))
+	}
+
+	if origin == "" {
+		if c.Cookies("session_id") != "" {
+			return "csrf_origin_missing", "Request has no Origin or Referer header"
+		}
+		return "", ""
+	}
+
+	if p.allowedOrigins[origin] || origin == originOf(c.BaseURL()) {
+		return "", ""
+	}
+	return "csrf_origin_mismatch", "Request origin " + origin + " is not allowed"
+}
+
+// originOf reduces a URL to its scheme://host form, or "" if it has none.
+func originOf(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
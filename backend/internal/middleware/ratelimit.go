@@ -1,100 +1,214 @@
 package middleware
 
 import (
-	"sync"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync/atomic"
 	"time"
 	"url-shortener-backend/internal/config"
 	"url-shortener-backend/internal/models"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
 )
 
-type RateLimiter struct {
-	requests map[string][]time.Time
-	mu       sync.RWMutex
-	limit    int
-	window   time.Duration
+// Rate limit policy names. Each route group is limited by one of these.
+const (
+	RateLimitRedirect = "redirect"
+	RateLimitCreate   = "create"
+	RateLimitAuth     = "auth"
+	RateLimitAPI      = "api"
+	RateLimitReport   = "report"
+)
+
+// RateLimitKeyFunc identifies the client a request is counted against.
+type RateLimitKeyFunc func(c *fiber.Ctx) string
+
+// KeyByIP counts requests per client IP.
+func KeyByIP(c *fiber.Ctx) string {
+	return "ip:" + c.IP()
 }
 
-func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
-	rl := &RateLimiter{
-		requests: make(map[string][]time.Time),
-		limit:    limit,
-		window:   window,
+// KeyByUser counts requests per signed-in user, or per IP for anonymous
+// requests. It must run after the auth middleware.
+func KeyByUser(c *fiber.Ctx) string {
+	if userID, ok := c.Locals("user_id").(uint); ok {
+		return fmt.Sprintf("user:%d", userID)
 	}
-	
-	go rl.cleanup()
-	return rl
+	return KeyByIP(c)
 }
 
-func (rl *RateLimiter) Allow(key string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-	
-	now := time.Now()
-	cutoff := now.Add(-rl.window)
-	
-	if _, exists := rl.requests[key]; !exists {
-		rl.requests[key] = []time.Time{}
+// KeyByToken counts requests per API token, so each of a user's
+// integrations has its own budget, falling back to KeyByUser.
+func KeyByToken(c *fiber.Ctx) string {
+	if tokenID, ok := c.Locals("token_id").(uint); ok {
+		return fmt.Sprintf("token:%d", tokenID)
 	}
-	
-	var validRequests []time.Time
-	for _, req := range rl.requests[key] {
-		if req.After(cutoff) {
-			validRequests = append(validRequests, req)
-		}
+	return KeyByUser(c)
+}
+
+// RateLimitPolicy allows Limit requests per Window for each key, with bursts
+// of up to Limit.
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+	Key    RateLimitKeyFunc
+}
+
+// RateLimitResult is the outcome of counting one request.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed. It
+	// is zero when the request was allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore counts requests against token buckets. Take removes one
+// token from key's bucket, which holds up to limit tokens and refills at
+// limit per window.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error)
+	Close()
+}
+
+// rateLimitStoreTimeout bounds how long a request waits on a shared store
+// before the fail-open setting decides.
+const rateLimitStoreTimeout = 250 * time.Millisecond
+
+// NewRateLimitStore returns the store selected by RATE_LIMIT_STORE: "redis"
+// shares limits between replicas, anything else keeps them in process.
+func NewRateLimitStore(cfg *config.Config) (RateLimitStore, error) {
+	if cfg.RateLimitStore != "redis" {
+		return NewMemoryRateLimitStore(cfg.RateLimitMaxKeys), nil
 	}
-	
-	rl.requests[key] = validRequests
-	
-	if len(validRequests) >= rl.limit {
-		return false
+
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
 	}
-	
-	rl.requests[key] = append(rl.requests[key], now)
-	return true
+	return NewRedisRateLimitStore(redis.NewClient(opts)), nil
 }
 
-func (rl *RateLimiter) cleanup() {
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
-	
-	for range ticker.C {
-		rl.mu.Lock()
-		now := time.Now()
-		cutoff := now.Add(-rl.window)
-		
-		for key, requests := range rl.requests {
-			var validRequests []time.Time
-			for _, req := range requests {
-				if req.After(cutoff) {
-					validRequests = append(validRequests, req)
-				}
-			}
-			
-			if len(validRequests) == 0 {
-				delete(rl.requests, key)
-			} else {
-				rl.requests[key] = validRequests
-			}
+// RateLimits applies named policies using a shared store.
+type RateLimits struct {
+	store    RateLimitStore
+	failOpen bool
+	policies map[string]RateLimitPolicy
+	// lastStoreError throttles logging while the store is unreachable.
+	lastStoreError atomic.Int64
+}
+
+// DefaultRateLimitPolicies are the built-in policies. The api policy uses
+// RATE_LIMIT_REQUESTS per RATE_LIMIT_WINDOW; any policy can be overridden
+// with RATE_LIMIT_POLICIES.
+func DefaultRateLimitPolicies(cfg *config.Config) []RateLimitPolicy {
+	return []RateLimitPolicy{
+		{Name: RateLimitRedirect, Limit: 600, Window: time.Minute, Key: KeyByIP},
+		{Name: RateLimitCreate, Limit: 60, Window: time.Hour, Key: KeyByUser},
+		{Name: RateLimitAuth, Limit: 30, Window: 10 * time.Minute, Key: KeyByIP},
+		{Name: RateLimitReport, Limit: 10, Window: time.Hour, Key: KeyByIP},
+		{Name: RateLimitAPI, Limit: cfg.RateLimitRequests, Window: time.Duration(cfg.RateLimitWindow) * time.Second, Key: KeyByToken},
+	}
+}
+
+// NewRateLimits counts requests in store, or in process when store is nil.
+func NewRateLimits(cfg *config.Config, store RateLimitStore, policies ...RateLimitPolicy) *RateLimits {
+	if store == nil {
+		store = NewMemoryRateLimitStore(cfg.RateLimitMaxKeys)
+	}
+
+	rl := &RateLimits{
+		store:    store,
+		failOpen: cfg.RateLimitFailOpen,
+		policies: make(map[string]RateLimitPolicy, len(policies)),
+	}
+
+	for _, policy := range policies {
+		if override, ok := cfg.RateLimitPolicies[policy.Name]; ok {
+			policy.Limit = override.Limit
+			policy.Window = time.Duration(override.Window) * time.Second
+		}
+		if policy.Limit <= 0 || policy.Window <= 0 {
+			log.Printf("Warning: rate limit policy %q has no valid limit, allowing 1 request per second", policy.Name)
+			policy.Limit, policy.Window = 1, time.Second
 		}
-		rl.mu.Unlock()
+		if policy.Key == nil {
+			policy.Key = KeyByIP
+		}
+		rl.policies[policy.Name] = policy
 	}
+
+	return rl
 }
 
-func RateLimitMiddleware(cfg *config.Config) fiber.Handler {
-	limiter := NewRateLimiter(cfg.RateLimitRequests, time.Duration(cfg.RateLimitWindow)*time.Second)
-	
+// Handler limits requests with the named policy. It panics on an unknown
+// name so a typo fails at startup rather than leaving routes unlimited.
+func (rl *RateLimits) Handler(name string) fiber.Handler {
+	policy, ok := rl.policies[name]
+	if !ok {
+		panic("unknown rate limit policy: " + name)
+	}
+	policyHeader := fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Window.Seconds()))
+
 	return func(c *fiber.Ctx) error {
-		key := c.IP()
-		
-		if !limiter.Allow(key) {
+		ctx, cancel := context.WithTimeout(context.Background(), rateLimitStoreTimeout)
+		defer cancel()
+
+		result, err := rl.store.Take(ctx, name+":"+policy.Key(c), policy.Limit, policy.Window)
+		if err != nil {
+			rl.logStoreError(err)
+			if rl.failOpen {
+				return c.Next()
+			}
+			return c.Status(fiber.StatusServiceUnavailable).JSON(models.ErrorResponse{
+				Error:   "rate_limit_unavailable",
+				Message: "Service temporarily unavailable, please try again later",
+			})
+		}
+
+		c.Set("RateLimit-Policy", policyHeader)
+		c.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(max(1, ceilSeconds(result.RetryAfter))))
 			return c.Status(fiber.StatusTooManyRequests).JSON(models.ErrorResponse{
 				Error:   "rate_limit_exceeded",
 				Message: "Too many requests, please try again later",
 			})
 		}
-		
+
 		return c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// logStoreError logs store failures at most once a minute.
+func (rl *RateLimits) logStoreError(err error) {
+	now := time.Now().Unix()
+	last := rl.lastStoreError.Load()
+	if now-last >= 60 && rl.lastStoreError.CompareAndSwap(last, now) {
+		mode := "rejecting"
+		if rl.failOpen {
+			mode = "allowing"
+		}
+		log.Printf("Rate limit store unavailable, %s requests: %v", mode, err)
+	}
+}
+
+// Close releases the store
+func (rl *RateLimits) Close() {
+	rl.store.Close()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	rateLimitShards      = 64
+	defaultRateLimitKeys = 100000
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  float64
+	rate   float64 // tokens per second
+}
+
+// refill tops the bucket up for the time elapsed since it was last used.
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(b.limit, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// duration is how long it takes to refill the given number of tokens.
+func (b *bucket) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / b.rate * float64(time.Second))
+}
+
+type rateLimitShard struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// MemoryRateLimitStore keeps token buckets in process. Buckets are spread
+// over shards with their own locks, and the number of keys tracked is
+// bounded: when a shard is full an arbitrary bucket is dropped, which at
+// worst gives that client a fresh allowance. Each replica counts separately.
+type MemoryRateLimitStore struct {
+	shards   [rateLimitShards]rateLimitShard
+	maxShard int
+	done     chan struct{}
+}
+
+func NewMemoryRateLimitStore(maxKeys int) *MemoryRateLimitStore {
+	if maxKeys <= 0 {
+		maxKeys = defaultRateLimitKeys
+	}
+
+	store := &MemoryRateLimitStore{
+		maxShard: (maxKeys + rateLimitShards - 1) / rateLimitShards,
+		done:     make(chan struct{}),
+	}
+	for i := range store.shards {
+		store.shards[i].buckets = make(map[string]*bucket)
+	}
+
+	go store.cleanup()
+	return store
+}
+
+func (s *MemoryRateLimitStore) shard(key string) *rateLimitShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &s.shards[h.Sum32()%rateLimitShards]
+}
+
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	now := time.Now()
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	b, ok := shard.buckets[key]
+	if !ok {
+		if len(shard.buckets) >= s.maxShard {
+			for evict := range shard.buckets {
+				delete(shard.buckets, evict)
+				break
+			}
+		}
+		b = &bucket{
+			tokens: float64(limit),
+			last:   now,
+			limit:  float64(limit),
+			rate:   float64(limit) / window.Seconds(),
+		}
+		shard.buckets[key] = b
+	}
+	b.refill(now)
+
+	result := RateLimitResult{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = b.duration(1 - b.tokens)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = b.duration(b.limit - b.tokens)
+
+	return result, nil
+}
+
+// Len returns the number of clients currently tracked.
+func (s *MemoryRateLimitStore) Len() int {
+	n := 0
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		n += len(shard.buckets)
+		shard.mu.Unlock()
+	}
+	return n
+}
+
+// cleanup drops buckets that have refilled completely, since a new bucket
+// would behave identically.
+func (s *MemoryRateLimitStore) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			for i := range s.shards {
+				shard := &s.shards[i]
+				shard.mu.Lock()
+				for key, b := range shard.buckets {
+					b.refill(now)
+					if b.tokens >= b.limit {
+						delete(shard.buckets, key)
+					}
+				}
+				shard.mu.Unlock()
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Close stops the cleanup goroutine
+func (s *MemoryRateLimitStore) Close() {
+	close(s.done)
+}
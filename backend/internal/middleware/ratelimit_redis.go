@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// gcraScript implements the generic cell rate algorithm, which behaves like
+// a token bucket but needs only one value per key: the theoretical arrival
+// time (TAT) of the next request. Times are in microseconds from the Redis
+// server clock, so replicas with skewed clocks still agree.
+//
+// KEYS[1] is the bucket; ARGV[1] is the emission interval (window/limit) and
+// ARGV[2] the burst size. It returns {allowed, remaining, reset, retry_after}
+// with durations in microseconds.
+var gcraScript = redis.NewScript(`
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local tolerance = interval * burst
+
+local tat = tonumber(redis.call("GET", KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+
+local new_tat = tat + interval
+local allow_at = new_tat - tolerance
+if allow_at > now then
+	return {0, 0, tat - now, allow_at - now}
+end
+
+redis.call("SET", KEYS[1], new_tat, "PX", math.ceil((new_tat - now) / 1000))
+return {1, math.floor((tolerance - (new_tat - now)) / interval), new_tat - now, 0}
+`)
+
+// RedisRateLimitStore shares rate limits between replicas through Redis.
+type RedisRateLimitStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisRateLimitStore(client *redis.Client) *RedisRateLimitStore {
+	return &RedisRateLimitStore{
+		client: client,
+		prefix: "ratelimit:",
+	}
+}
+
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	interval := window.Microseconds() / int64(limit)
+	if interval < 1 {
+		interval = 1
+	}
+
+	values, err := gcraScript.Run(ctx, s.client, []string{s.prefix + key}, interval, limit).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	return RateLimitResult{
+		Allowed:    values[0] == 1,
+		Limit:      limit,
+		Remaining:  int(values[1]),
+		Reset:      time.Duration(values[2]) * time.Microsecond,
+		RetryAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
+
+// Close closes the Redis connection pool
+func (s *RedisRateLimitStore) Close() {
+	s.client.Close()
+}
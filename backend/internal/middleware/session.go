@@ -0,0 +1,407 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultSessionIdleTimeout = 24 * time.Hour
+	defaultSessionMaxLifetime = 30 * 24 * time.Hour
+	// sessionTouchResolution limits last-activity writes to one per session
+	// per minute.
+	sessionTouchResolution = time.Minute
+	// impersonationLifetime is how long an admin may view an account.
+	impersonationLifetime = time.Hour
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionData struct {
+	Key          string // SessionKey of the session ID, set by the store on reads
+	UserID       uint
+	UserEmail    string
+	IPAddress    string
+	UserAgent    string
+	CreatedAt    time.Time
+	LastActiveAt time.Time
+	ExpiresAt    time.Time
+
+	// ImpersonatorID is the admin viewing the account through this session,
+	// or 0. Impersonation sessions are read-only and do not slide.
+	ImpersonatorID uint
+}
+
+// SessionStore persists sessions by session ID. Get returns nil, nil for
+// unknown or expired sessions. Sessions are listed and revoked by their
+// SessionKey so raw session IDs never leave the store.
+type SessionStore interface {
+	Save(sessionID string, data *SessionData) error
+	Get(sessionID string) (*SessionData, error)
+	Delete(sessionID string) error
+	ListByUser(userID uint) ([]*SessionData, error)
+	DeleteByKey(userID uint, key string) (bool, error)
+	DeleteByUser(userID uint) (int64, error)
+	DeleteExpired() (int64, error)
+	Close()
+}
+
+// SessionManager issues session cookies and authenticates requests against
+// a SessionStore. Sessions slide forward on activity up to an absolute
+// maximum lifetime.
+type SessionManager struct {
+	store       SessionStore
+	config      *config.Config
+	tokens      TokenAuthenticator
+	idleTimeout time.Duration
+	maxLifetime time.Duration
+	// cookieKeys sign the session cookie. The first key signs new cookies;
+	// the rest are previous secrets still accepted during rotation.
+	cookieKeys [][]byte
+}
+
+func NewSessionManager(store SessionStore, config *config.Config) *SessionManager {
+	idleTimeout := time.Duration(config.SessionIdleTimeout) * time.Second
+	if idleTimeout <= 0 {
+		idleTimeout = defaultSessionIdleTimeout
+	}
+	maxLifetime := time.Duration(config.SessionMaxLifetime) * time.Second
+	if maxLifetime <= 0 {
+		maxLifetime = defaultSessionMaxLifetime
+	}
+	if idleTimeout > maxLifetime {
+		idleTimeout = maxLifetime
+	}
+
+	cookieKeys := [][]byte{utils.DeriveKey(config.SessionSecret, "session-cookie")}
+	for _, secret := range config.SessionSecretPrevious {
+		cookieKeys = append(cookieKeys, utils.DeriveKey(secret, "session-cookie"))
+	}
+
+	return &SessionManager{
+		store:       store,
+		config:      config,
+		idleTimeout: idleTimeout,
+		maxLifetime: maxLifetime,
+		cookieKeys:  cookieKeys,
+	}
+}
+
+// SetTokenAuthenticator enables "Authorization: Bearer" API token
+// authentication in the auth middlewares.
+func (m *SessionManager) SetTokenAuthenticator(tokens TokenAuthenticator) {
+	m.tokens = tokens
+}
+
+// Close shuts down the underlying store
+func (m *SessionManager) Close() {
+	m.store.Close()
+}
+
+func (m *SessionManager) generateSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secure session ID: %w", err)
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
+func (m *SessionManager) CreateSession(c *fiber.Ctx, userID uint, userEmail string) (string, error) {
+	return m.startSession(c, &SessionData{
+		UserID:    userID,
+		UserEmail: userEmail,
+	}, m.idleTimeout)
+}
+
+// CreateImpersonationSession signs the admin in as the user for a fixed,
+// short period. The session only allows reads and is listed among the user's
+// sessions, so they can see and revoke it.
+func (m *SessionManager) CreateImpersonationSession(c *fiber.Ctx, adminID, userID uint, userEmail string) (string, error) {
+	return m.startSession(c, &SessionData{
+		UserID:         userID,
+		UserEmail:      userEmail,
+		ImpersonatorID: adminID,
+	}, impersonationLifetime)
+}
+
+// startSession records the client, saves the session and sets its cookie.
+func (m *SessionManager) startSession(c *fiber.Ctx, data *SessionData, lifetime time.Duration) (string, error) {
+	sessionID, err := m.generateSessionID()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	if len(userAgent) > 500 {
+		userAgent = userAgent[:500]
+	}
+
+	now := time.Now()
+	data.IPAddress = c.IP()
+	data.UserAgent = userAgent
+	data.CreatedAt = now
+	data.LastActiveAt = now
+	data.ExpiresAt = now.Add(lifetime)
+	if err := m.store.Save(sessionID, data); err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	m.setCookie(c, sessionID, data.ExpiresAt)
+
+	return sessionID, nil
+}
+
+// CookieValue returns the signed cookie value for a session ID.
+func (m *SessionManager) CookieValue(sessionID string) string {
+	return utils.SignValue(m.cookieKeys[0], sessionID)
+}
+
+// SessionID returns the session ID from the request's session cookie, or ""
+// if the cookie is missing or its signature does not verify. A cookie signed
+// with a previous secret is re-signed with the current one.
+func (m *SessionManager) SessionID(c *fiber.Ctx) string {
+	// Fiber reuses the request buffer, so copy the value before it can be
+	// kept as a store key.
+	cookie := strings.Clone(c.Cookies("session_id"))
+	if cookie == "" {
+		return ""
+	}
+
+	if sessionID, ok := utils.VerifySignedValue(cookie, m.cookieKeys[0]); ok {
+		return sessionID
+	}
+
+	sessionID, ok := utils.VerifySignedValue(cookie, m.cookieKeys[1:]...)
+	if !ok {
+		return ""
+	}
+	if data := m.GetSession(sessionID); data != nil {
+		m.setCookie(c, sessionID, data.ExpiresAt)
+	}
+	return sessionID
+}
+
+func (m *SessionManager) setCookie(c *fiber.Ctx, sessionID string, expires time.Time) {
+	value := ""
+	if sessionID != "" {
+		value = m.CookieValue(sessionID)
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     "session_id",
+		Value:    value,
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   m.config.Environment == "production",
+		SameSite: "Lax",
+	})
+}
+
+// touch records activity on a session and slides its expiry forward, capped
+// at the absolute lifetime measured from creation.
+func (m *SessionManager) touch(c *fiber.Ctx, sessionID string, data *SessionData) {
+	now := time.Now()
+	if now.Sub(data.LastActiveAt) < sessionTouchResolution {
+		return
+	}
+
+	expiresAt := now.Add(m.idleTimeout)
+	if limit := data.CreatedAt.Add(m.maxLifetime); expiresAt.After(limit) {
+		expiresAt = limit
+	}
+	if data.ImpersonatorID != 0 {
+		expiresAt = data.ExpiresAt
+	}
+
+	data.LastActiveAt = now
+	data.ExpiresAt = expiresAt
+	if err := m.store.Save(sessionID, data); err != nil {
+		return
+	}
+
+	m.setCookie(c, sessionID, expiresAt)
+}
+
+// GetSession returns the session for sessionID, or nil if it is unknown,
+// expired or the store could not be read.
+func (m *SessionManager) GetSession(sessionID string) *SessionData {
+	data, err := m.store.Get(sessionID)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func (m *SessionManager) DestroySession(c *fiber.Ctx, sessionID string) {
+	m.store.Delete(sessionID)
+	m.ClearCookie(c)
+}
+
+// ClearCookie expires the session cookie on the client.
+func (m *SessionManager) ClearCookie(c *fiber.Ctx) {
+	m.setCookie(c, "", time.Now().Add(-time.Hour))
+}
+
+// ListSessions returns the user's active sessions, most recently used first.
+// currentSessionID marks the session making the request.
+func (m *SessionManager) ListSessions(userID uint, currentSessionID string) ([]models.SessionInfo, error) {
+	sessions, err := m.store.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	currentKey := ""
+	if currentSessionID != "" {
+		currentKey = SessionKey(currentSessionID)
+	}
+
+	infos := make([]models.SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		device, os, browser := utils.ParseUserAgent(session.UserAgent)
+		infos = append(infos, models.SessionInfo{
+			ID:           session.Key,
+			IPAddress:    session.IPAddress,
+			UserAgent:    session.UserAgent,
+			Device:       device,
+			Browser:      browser,
+			OS:           os,
+			CreatedAt:    session.CreatedAt,
+			LastActiveAt: session.LastActiveAt,
+			ExpiresAt:    session.ExpiresAt,
+			Current:      session.Key == currentKey,
+			Impersonated: session.ImpersonatorID != 0,
+		})
+	}
+
+	return infos, nil
+}
+
+// RevokeSession ends one of the user's sessions by its SessionKey.
+func (m *SessionManager) RevokeSession(userID uint, key string) error {
+	deleted, err := m.store.DeleteByKey(userID, key)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeUserSessions ends every session belonging to the user.
+func (m *SessionManager) RevokeUserSessions(userID uint) (int64, error) {
+	return m.store.DeleteByUser(userID)
+}
+
+func (m *SessionManager) AuthMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token, ok := bearerToken(c); ok {
+			if !authenticateBearer(c, m.tokens, token) {
+				return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+					Error:   "unauthorized",
+					Message: "Invalid or expired API token",
+				})
+			}
+			return c.Next()
+		}
+
+		if c.Cookies("session_id") == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+				Error:   "unauthorized",
+				Message: "No session found",
+			})
+		}
+
+		// Forged or garbled cookies are rejected without a store lookup.
+		sessionID := m.SessionID(c)
+		if sessionID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+				Error:   "unauthorized",
+				Message: "Invalid or expired session",
+			})
+		}
+
+		sessionData := m.GetSession(sessionID)
+		if sessionData == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+				Error:   "unauthorized",
+				Message: "Invalid or expired session",
+			})
+		}
+
+		if rejectImpersonatedWrite(c, sessionData) {
+			return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+				Error:   "impersonation_read_only",
+				Message: "Impersonation sessions are read-only",
+			})
+		}
+
+		m.touch(c, sessionID, sessionData)
+		setSessionLocals(c, sessionID, sessionData)
+
+		return c.Next()
+	}
+}
+
+func (m *SessionManager) OptionalAuthMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// A bad token is an error rather than a silent anonymous request.
+		if token, ok := bearerToken(c); ok {
+			if !authenticateBearer(c, m.tokens, token) {
+				return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+					Error:   "unauthorized",
+					Message: "Invalid or expired API token",
+				})
+			}
+			return c.Next()
+		}
+
+		if sessionID := m.SessionID(c); sessionID != "" {
+			if sessionData := m.GetSession(sessionID); sessionData != nil {
+				if rejectImpersonatedWrite(c, sessionData) {
+					return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+						Error:   "impersonation_read_only",
+						Message: "Impersonation sessions are read-only",
+					})
+				}
+				m.touch(c, sessionID, sessionData)
+				setSessionLocals(c, sessionID, sessionData)
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+func setSessionLocals(c *fiber.Ctx, sessionID string, data *SessionData) {
+	c.Locals("user_id", data.UserID)
+	c.Locals("user_email", data.UserEmail)
+	c.Locals("session_id", sessionID)
+	c.Locals("auth_method", AuthMethodSession)
+	if data.ImpersonatorID != 0 {
+		c.Locals("impersonator_id", data.ImpersonatorID)
+	}
+}
+
+// rejectImpersonatedWrite reports whether the request would change state
+// through an impersonation session.
+func rejectImpersonatedWrite(c *fiber.Ctx, data *SessionData) bool {
+	if data.ImpersonatorID == 0 {
+		return false
+	}
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return false
+	}
+	return true
+}
@@ -1,34 +1,23 @@
 package middleware
 
 import (
-	"crypto/rand"
-	"encoding/base64"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
-	"url-shortener-backend/internal/config"
-	"url-shortener-backend/internal/models"
-
-	"github.com/gofiber/fiber/v2"
 )
 
-type SessionData struct {
-	UserID    uint
-	UserEmail string
-	CreatedAt time.Time
-}
-
+// SimpleSessionStore is the in-memory SessionStore. Sessions are lost on
+// restart and are not shared between replicas.
 type SimpleSessionStore struct {
 	Sessions map[string]*SessionData // Made public for testing
 	mu       sync.RWMutex
-	config   *config.Config
 	done     chan struct{} // For graceful shutdown
 }
 
-func NewSimpleSessionStore(config *config.Config) *SimpleSessionStore {
+func NewSimpleSessionStore() *SimpleSessionStore {
 	store := &SimpleSessionStore{
 		Sessions: make(map[string]*SessionData),
-		config:   config,
 		done:     make(chan struct{}),
 	}
 	
@@ -45,15 +34,7 @@ func (s *SimpleSessionStore) cleanup() {
 	for {
 		select {
 		case <-ticker.C:
-			s.mu.Lock()
-			expired := 0
-			for sessionID, data := range s.Sessions {
-				if time.Since(data.CreatedAt) > 24*time.Hour {
-					delete(s.Sessions, sessionID)
-					expired++
-				}
-			}
-			s.mu.Unlock()
+			expired, _ := s.DeleteExpired()
 			// Log cleanup activity for monitoring
 			if expired > 0 {
 				fmt.Printf("Session cleanup: removed %d expired sessions\n", expired)
@@ -69,107 +50,96 @@ func (s *SimpleSessionStore) Close() {
 	close(s.done)
 }
 
-func (s *SimpleSessionStore) generateSessionID() (string, error) {
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
-		return "", fmt.Errorf("failed to generate secure session ID: %w", err)
-	}
-	return base64.URLEncoding.EncodeToString(b), nil
-}
-
-func (s *SimpleSessionStore) CreateSession(c *fiber.Ctx, userID uint, userEmail string) (string, error) {
-	sessionID, err := s.generateSessionID()
-	if err != nil {
-		return "", fmt.Errorf("failed to create session: %w", err)
-	}
-	
+func (s *SimpleSessionStore) Save(key string, data *SessionData) error {
 	s.mu.Lock()
-	s.Sessions[sessionID] = &SessionData{
-		UserID:    userID,
-		UserEmail: userEmail,
-		CreatedAt: time.Now(),
-	}
+	s.Sessions[key] = data
 	s.mu.Unlock()
 	
-	c.Cookie(&fiber.Cookie{
-		Name:     "session_id",
-		Value:    sessionID,
-		Expires:  time.Now().Add(24 * time.Hour),
-		HTTPOnly: true,
-		Secure:   s.config.Environment == "production",
-		SameSite: "Lax",
-	})
-	
-	return sessionID, nil
+	return nil
 }
 
-func (s *SimpleSessionStore) GetSession(sessionID string) *SessionData {
+func (s *SimpleSessionStore) Get(key string) (*SessionData, error) {
 	s.mu.Lock() // Use write lock to allow safe deletion
 	defer s.mu.Unlock()
 	
-	if data, exists := s.Sessions[sessionID]; exists {
-		if time.Since(data.CreatedAt) < 24*time.Hour {
-			return data
+	if data, exists := s.Sessions[key]; exists {
+		if time.Now().Before(data.ExpiresAt) {
+			copied := *data
+			copied.Key = SessionKey(key)
+			return &copied, nil
 		}
 		// Session expired, delete it safely
-		delete(s.Sessions, sessionID)
+		delete(s.Sessions, key)
 	}
-	return nil
+	return nil, nil
 }
 
-func (s *SimpleSessionStore) DestroySession(c *fiber.Ctx, sessionID string) {
+func (s *SimpleSessionStore) Delete(key string) error {
 	s.mu.Lock()
-	delete(s.Sessions, sessionID)
+	delete(s.Sessions, key)
 	s.mu.Unlock()
 	
-	c.Cookie(&fiber.Cookie{
-		Name:     "session_id",
-		Value:    "",
-		Expires:  time.Now().Add(-time.Hour),
-		HTTPOnly: true,
-		Secure:   s.config.Environment == "production",
-		SameSite: "Lax",
+	return nil
+}
+
+func (s *SimpleSessionStore) ListByUser(userID uint) ([]*SessionData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	now := time.Now()
+	var sessions []*SessionData
+	for key, data := range s.Sessions {
+		if data.UserID == userID && now.Before(data.ExpiresAt) {
+			copied := *data
+			copied.Key = SessionKey(key)
+			sessions = append(sessions, &copied)
+		}
+	}
+	
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastActiveAt.After(sessions[j].LastActiveAt)
 	})
+	return sessions, nil
 }
 
-func (s *SimpleSessionStore) AuthMiddleware() fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		sessionID := c.Cookies("session_id")
-		if sessionID == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
-				Error:   "unauthorized",
-				Message: "No session found",
-			})
+func (s *SimpleSessionStore) DeleteByKey(userID uint, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	for sessionID, data := range s.Sessions {
+		if data.UserID == userID && SessionKey(sessionID) == key {
+			delete(s.Sessions, sessionID)
+			return true, nil
 		}
-		
-		sessionData := s.GetSession(sessionID)
-		if sessionData == nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
-				Error:   "unauthorized",
-				Message: "Invalid or expired session",
-			})
+	}
+	return false, nil
+}
+
+func (s *SimpleSessionStore) DeleteByUser(userID uint) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	var deleted int64
+	for sessionID, data := range s.Sessions {
+		if data.UserID == userID {
+			delete(s.Sessions, sessionID)
+			deleted++
 		}
-		
-		c.Locals("user_id", sessionData.UserID)
-		c.Locals("user_email", sessionData.UserEmail)
-		c.Locals("session_id", sessionID)
-		
-		return c.Next()
 	}
+	return deleted, nil
 }
 
-func (s *SimpleSessionStore) OptionalAuthMiddleware() fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		sessionID := c.Cookies("session_id")
-		if sessionID != "" {
-			sessionData := s.GetSession(sessionID)
-			if sessionData != nil {
-				c.Locals("user_id", sessionData.UserID)
-				c.Locals("user_email", sessionData.UserEmail)
-				c.Locals("session_id", sessionID)
-			}
+func (s *SimpleSessionStore) DeleteExpired() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	var expired int64
+	now := time.Now()
+	for key, data := range s.Sessions {
+		if !now.Before(data.ExpiresAt) {
+			delete(s.Sessions, key)
+			expired++
 		}
-		
-		return c.Next()
 	}
-}
\ No newline at end of file
+	return expired, nil
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+	"url-shortener-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SQLSessionStore keeps sessions in the sessions table so they survive
+// restarts and are shared between replicas.
+type SQLSessionStore struct {
+	db   *gorm.DB
+	done chan struct{} // For graceful shutdown
+}
+
+func NewSQLSessionStore(db *gorm.DB, cleanupInterval time.Duration) *SQLSessionStore {
+	store := &SQLSessionStore{
+		db:   db,
+		done: make(chan struct{}),
+	}
+
+	if cleanupInterval > 0 {
+		go store.cleanup(cleanupInterval)
+	}
+
+	return store
+}
+
+func (s *SQLSessionStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			expired, err := s.DeleteExpired()
+			if err != nil {
+				fmt.Printf("Session cleanup failed: %v\n", err)
+			} else if expired > 0 {
+				fmt.Printf("Session cleanup: removed %d expired sessions\n", expired)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Close stops the cleanup routine
+func (s *SQLSessionStore) Close() {
+	close(s.done)
+}
+
+func (s *SQLSessionStore) Save(sessionID string, data *SessionData) error {
+	session := models.Session{
+		ID:           SessionKey(sessionID),
+		UserID:       data.UserID,
+		UserEmail:    data.UserEmail,
+		IPAddress:    data.IPAddress,
+		UserAgent:    data.UserAgent,
+		CreatedAt:    data.CreatedAt,
+		LastActiveAt: data.LastActiveAt,
+		ExpiresAt:    data.ExpiresAt,
+	}
+	if data.ImpersonatorID != 0 {
+		session.ImpersonatorID = &data.ImpersonatorID
+	}
+	if err := s.db.Save(&session).Error; err != nil {
+		return errors.New("failed to save session")
+	}
+
+	return nil
+}
+
+func (s *SQLSessionStore) Get(sessionID string) (*SessionData, error) {
+	var session models.Session
+	err := s.db.Where("id = ? AND expires_at > ?", SessionKey(sessionID), time.Now()).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errors.New("failed to load session")
+	}
+
+	return sessionDataFromModel(&session), nil
+}
+
+func (s *SQLSessionStore) Delete(sessionID string) error {
+	if err := s.db.Delete(&models.Session{}, "id = ?", SessionKey(sessionID)).Error; err != nil {
+		return errors.New("failed to delete session")
+	}
+
+	return nil
+}
+
+func (s *SQLSessionStore) ListByUser(userID uint) ([]*SessionData, error) {
+	var sessions []models.Session
+	err := s.db.Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("last_active_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, errors.New("failed to list sessions")
+	}
+
+	result := make([]*SessionData, 0, len(sessions))
+	for i := range sessions {
+		result = append(result, sessionDataFromModel(&sessions[i]))
+	}
+	return result, nil
+}
+
+func (s *SQLSessionStore) DeleteByKey(userID uint, key string) (bool, error) {
+	result := s.db.Where("id = ? AND user_id = ?", key, userID).Delete(&models.Session{})
+	if result.Error != nil {
+		return false, errors.New("failed to delete session")
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (s *SQLSessionStore) DeleteByUser(userID uint) (int64, error) {
+	result := s.db.Where("user_id = ?", userID).Delete(&models.Session{})
+	if result.Error != nil {
+		return 0, errors.New("failed to delete sessions")
+	}
+
+	return result.RowsAffected, nil
+}
+
+func (s *SQLSessionStore) DeleteExpired() (int64, error) {
+	result := s.db.Where("expires_at <= ?", time.Now()).Delete(&models.Session{})
+	if result.Error != nil {
+		return 0, errors.New("failed to delete expired sessions")
+	}
+
+	return result.RowsAffected, nil
+}
+
+func sessionDataFromModel(session *models.Session) *SessionData {
+	data := &SessionData{
+		Key:          session.ID,
+		UserID:       session.UserID,
+		UserEmail:    session.UserEmail,
+		IPAddress:    session.IPAddress,
+		UserAgent:    session.UserAgent,
+		CreatedAt:    session.CreatedAt,
+		LastActiveAt: session.LastActiveAt,
+		ExpiresAt:    session.ExpiresAt,
+	}
+	if session.ImpersonatorID != nil {
+		data.ImpersonatorID = *session.ImpersonatorID
+	}
+	return data
+}
+
+// SessionKey is the value stored in place of the raw session ID, and the
+// identifier sessions are listed and revoked by.
+func SessionKey(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])
+}
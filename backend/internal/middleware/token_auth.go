@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"strings"
+	"url-shortener-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	AuthMethodSession = "session"
+	AuthMethodToken   = "token"
+)
+
+// TokenAuthenticator resolves a personal API token sent as a bearer token.
+type TokenAuthenticator interface {
+	AuthenticateToken(token string) (*models.APIToken, error)
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header.
+func bearerToken(c *fiber.Ctx) (string, bool) {
+	header := c.Get(fiber.HeaderAuthorization)
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return "", false
+	}
+
+	token := strings.TrimSpace(header[7:])
+	return token, token != ""
+}
+
+// authenticateBearer validates a bearer token and populates the request
+// locals. It returns false if the token was rejected.
+func authenticateBearer(c *fiber.Ctx, tokens TokenAuthenticator, token string) bool {
+	if tokens == nil {
+		return false
+	}
+
+	apiToken, err := tokens.AuthenticateToken(token)
+	if err != nil {
+		return false
+	}
+
+	c.Locals("user_id", apiToken.UserID)
+	c.Locals("auth_method", AuthMethodToken)
+	c.Locals("token_id", apiToken.ID)
+	c.Locals("token_scopes", apiToken.Scopes)
+	return true
+}
+
+// RequireScope rejects token-authenticated requests whose token lacks any of
+// the given scopes. Session-authenticated requests carry every scope.
+func RequireScope(scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals("auth_method") != AuthMethodToken {
+			return c.Next()
+		}
+
+		granted, _ := c.Locals("token_scopes").([]string)
+		for _, scope := range scopes {
+			if !containsString(granted, scope) {
+				return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+					Error:   "insufficient_scope",
+					Message: "Token is missing the " + scope + " scope",
+				})
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+// SessionOnly rejects requests authenticated with an API token, for routes
+// such as token management that must only be reachable from a browser login.
+func SessionOnly() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals("auth_method") == AuthMethodToken {
+			return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+				Error:   "session_required",
+				Message: "This endpoint is not available to API tokens",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Abuse report categories.
+const (
+	ReportPhishing = "phishing"
+	ReportMalware  = "malware"
+	ReportSpam     = "spam"
+	ReportIllegal  = "illegal"
+	ReportOther    = "other"
+)
+
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusConfirmed = "confirmed"
+	ReportStatusDismissed = "dismissed"
+)
+
+// ReportCategories lists the categories a report may use.
+var ReportCategories = []string{ReportPhishing, ReportMalware, ReportSpam, ReportIllegal, ReportOther}
+
+// AbuseReport is a complaint about a link, made by anyone who has its
+// short code. Reports stay open until a moderator confirms or dismisses
+// them. ReporterIP holds a keyed hash of the reporter's address rather than
+// the address itself.
+type AbuseReport struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	URLID          uint       `json:"url_id" gorm:"not null;index:idx_abuse_report_url_status"`
+	Category       string     `json:"category" gorm:"not null;size:20"`
+	Details        string     `json:"details,omitempty" gorm:"size:1000"`
+	ReporterIP     string     `json:"reporter_ip" gorm:"size:45"`
+	ReporterUserID *uint      `json:"reporter_user_id,omitempty"`
+	Status         string     `json:"status" gorm:"not null;size:20;default:open;index:idx_abuse_report_url_status"`
+	ResolvedByID   *uint      `json:"resolved_by_id,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	Resolution     string     `json:"resolution,omitempty" gorm:"size:500"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"index"`
+}
+
+type AbuseReportRequest struct {
+	Category string `json:"category"`
+	Details  string `json:"details,omitempty"`
+}
+
+// ReportedURL is an entry in the moderation queue: a link with open
+// reports.
+type ReportedURL struct {
+	URLID         uint       `json:"url_id"`
+	ShortCode     string     `json:"short_code"`
+	OriginalURL   string     `json:"original_url"`
+	UserID        *uint      `json:"user_id,omitempty"`
+	QuarantinedAt *time.Time `json:"quarantined_at,omitempty"`
+	OpenReports   int64      `json:"open_reports"`
+	FirstReported time.Time  `json:"first_reported"`
+}
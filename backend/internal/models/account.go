@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+const (
+	ExportStatusPending = "pending"
+	ExportStatusRunning = "running"
+	ExportStatusReady   = "ready"
+	ExportStatusFailed  = "failed"
+)
+
+// DataExport is an archive of everything stored about a user, built in the
+// background and downloadable until it expires.
+type DataExport struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"-" gorm:"not null;index"`
+	Status      string     `json:"status" gorm:"not null;size:20;index"`
+	FilePath    string     `json:"-" gorm:"size:500"`
+	Size        int64      `json:"size,omitempty"`
+	Error       string     `json:"error,omitempty" gorm:"size:500"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// AccountDeletion describes a scheduled account deletion.
+type AccountDeletion struct {
+	ScheduledAt time.Time `json:"scheduled_at"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+const (
+	AdminActionBootstrap   = "admin.bootstrap"
+	AdminActionDisableUser = "user.disable"
+	AdminActionEnableUser  = "user.enable"
+	AdminActionDisableURL  = "url.disable"
+	AdminActionEnableURL   = "url.enable"
+	AdminActionImpersonate = "user.impersonate"
+	AdminActionChangePlan  = "user.plan"
+	AdminTargetUser        = "user"
+	AdminTargetURL         = "url"
+
+	AdminActionConfirmReports = "url.reports.confirm"
+	AdminActionDismissReports = "url.reports.dismiss"
+)
+
+// AdminAction records an administrator's action for accountability. Rows
+// are written in the same transaction as the change they describe.
+type AdminAction struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	AdminID    uint      `json:"admin_id" gorm:"not null;index"`
+	Action     string    `json:"action" gorm:"not null;size:50;index"`
+	TargetType string    `json:"target_type" gorm:"not null;size:20;index:idx_admin_action_target"`
+	TargetID   uint      `json:"target_id" gorm:"not null;index:idx_admin_action_target"`
+	Reason     string    `json:"reason,omitempty" gorm:"size:500"`
+	IPAddress  string    `json:"ip_address,omitempty" gorm:"size:45"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+type ModerationRequest struct {
+	Reason string `json:"reason"`
+}
+
+// PlatformStats summarises the whole platform for administrators.
+type PlatformStats struct {
+	Users           int64 `json:"users"`
+	DisabledUsers   int64 `json:"disabled_users"`
+	Admins          int64 `json:"admins"`
+	NewUsers7d      int64 `json:"new_users_7d"`
+	URLs            int64 `json:"urls"`
+	DisabledURLs    int64 `json:"disabled_urls"`
+	QuarantinedURLs int64 `json:"quarantined_urls"`
+	OpenReports     int64 `json:"open_reports"`
+	NewURLs7d       int64 `json:"new_urls_7d"`
+	Workspaces      int64 `json:"workspaces"`
+	TotalClicks     int64 `json:"total_clicks"`
+	Clicks24h       int64 `json:"clicks_24h"`
+}
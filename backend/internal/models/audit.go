@@ -0,0 +1,124 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Who made an audited change.
+const (
+	ActorUser      = "user"
+	ActorToken     = "token"
+	ActorAdmin     = "admin"
+	ActorAnonymous = "anonymous"
+)
+
+const (
+	AuditLinkCreate = "link.create"
+	AuditLinkUpdate = "link.update"
+	AuditLinkDelete = "link.delete"
+
+	AuditLinkRollback = "link.rollback"
+
+	AuditRegister         = "auth.register"
+	AuditLogin            = "auth.login"
+	AuditLoginFailed      = "auth.login_failed"
+	AuditLogout           = "auth.logout"
+	AuditVerifyEmail      = "auth.verify_email"
+	AuditPasswordReset    = "auth.password_reset"
+	AuditIdentityLink     = "identity.link"
+	AuditIdentityUnlink   = "identity.unlink"
+	AuditSessionRevoke    = "session.revoke"
+	AuditSessionRevokeAll = "session.revoke_all"
+	AuditTokenCreate      = "token.create"
+	AuditTokenRevoke      = "token.revoke"
+
+	AuditWorkspaceCreate  = "workspace.create"
+	AuditWorkspaceUpdate  = "workspace.update"
+	AuditWorkspaceDelete  = "workspace.delete"
+	AuditMemberUpdate     = "member.update"
+	AuditMemberRemove     = "member.remove"
+	AuditInvitationCreate = "invitation.create"
+	AuditInvitationRevoke = "invitation.revoke"
+	AuditInvitationAccept = "invitation.accept"
+	AuditWebhookCreate    = "webhook.create"
+	AuditWebhookUpdate    = "webhook.update"
+	AuditWebhookDelete    = "webhook.delete"
+
+	AuditAccountRetention    = "account.retention"
+	AuditAccountExport       = "account.export"
+	AuditAccountDelete       = "account.delete"
+	AuditAccountDeleteCancel = "account.delete_cancel"
+)
+
+const (
+	AuditTargetURL        = "url"
+	AuditTargetUser       = "user"
+	AuditTargetSession    = "session"
+	AuditTargetToken      = "token"
+	AuditTargetIdentity   = "identity"
+	AuditTargetWorkspace  = "workspace"
+	AuditTargetMember     = "member"
+	AuditTargetInvitation = "invitation"
+	AuditTargetWebhook    = "webhook"
+)
+
+var ErrAuditAppendOnly = errors.New("audit events cannot be changed or deleted")
+
+// AuditChange is a field's value before and after a change. From is nil
+// for creations and To for deletions.
+type AuditChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// AuditEvent records a change to a link, workspace or account, or an
+// authentication event. AccountID and WorkspaceID say whose audit log the
+// event appears in. Events are never updated or deleted.
+type AuditEvent struct {
+	ID          uint                   `json:"id" gorm:"primaryKey"`
+	ActorType   string                 `json:"actor_type" gorm:"not null;size:20"`
+	ActorID     *uint                  `json:"actor_id,omitempty" gorm:"index"`
+	TokenID     *uint                  `json:"token_id,omitempty"`
+	Action      string                 `json:"action" gorm:"not null;size:50;index"`
+	TargetType  string                 `json:"target_type" gorm:"not null;size:20;index:idx_audit_event_target"`
+	TargetID    uint                   `json:"target_id" gorm:"index:idx_audit_event_target"`
+	AccountID   *uint                  `json:"account_id,omitempty" gorm:"index:idx_audit_event_account"`
+	WorkspaceID *uint                  `json:"workspace_id,omitempty" gorm:"index:idx_audit_event_workspace"`
+	Changes     map[string]AuditChange `json:"changes,omitempty" gorm:"serializer:json"`
+	Reason      string                 `json:"reason,omitempty" gorm:"size:500"`
+	IPAddress   string                 `json:"ip_address,omitempty" gorm:"size:45"`
+	UserAgent   string                 `json:"user_agent,omitempty" gorm:"size:500"`
+	CreatedAt   time.Time              `json:"created_at" gorm:"index:idx_audit_event_account;index:idx_audit_event_workspace"`
+}
+
+func (e *AuditEvent) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+func (e *AuditEvent) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+// Actor identifies who is making a change, for the audit log.
+type Actor struct {
+	Type      string
+	UserID    *uint
+	TokenID   *uint
+	IPAddress string
+	UserAgent string
+}
+
+// AuditFilter narrows an audit log listing. Action matches a whole action
+// or, when it ends in a dot, every action with that prefix.
+type AuditFilter struct {
+	Action     string
+	ActorType  string
+	ActorID    uint
+	TargetType string
+	TargetID   uint
+	Since      *time.Time
+	Until      *time.Time
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Folder groups links within a workspace. Folders nest: ParentID is nil for
+// top-level folders, and names are unique among siblings. A link is in at
+// most one folder.
+type Folder struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint      `json:"workspace_id" gorm:"not null;index"`
+	ParentID    *uint     `json:"parent_id,omitempty" gorm:"index"`
+	Name        string    `json:"name" gorm:"not null;size:100"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type FolderRequest struct {
+	// WorkspaceID picks the workspace a new folder belongs to. It defaults
+	// to the creator's personal workspace and is ignored on update.
+	WorkspaceID *uint  `json:"workspace_id,omitempty"`
+	ParentID    *uint  `json:"parent_id"`
+	Name        string `json:"name"`
+}
+
+// MoveURLsRequest files a selection of links into a folder, or takes them
+// out of their folders when FolderID is nil.
+type MoveURLsRequest struct {
+	URLIDs   []uint `json:"url_ids"`
+	FolderID *uint  `json:"folder_id"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// UserIdentity links an account at an external identity provider to a user.
+// Logins resolve users by (Provider, Subject), never by email alone.
+type UserIdentity struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	User        *User      `json:"-" gorm:"foreignKey:UserID"`
+	Provider    string     `json:"provider" gorm:"not null;size:50;uniqueIndex:idx_identity_provider_subject"`
+	Subject     string     `json:"-" gorm:"not null;size:255;uniqueIndex:idx_identity_provider_subject"`
+	Email       string     `json:"email" gorm:"size:255"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
@@ -15,40 +15,114 @@ type User struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 	URLs      []URL          `json:"urls,omitempty" gorm:"foreignKey:UserID"`
+
+	// AnalyticsRetentionDays overrides the platform-wide raw click retention
+	// for links owned by this account. Nil means the default applies.
+	AnalyticsRetentionDays *int `json:"analytics_retention_days,omitempty"`
+
+	// DisabledAt is set when the account is disabled. Disabled accounts
+	// cannot sign in and their sessions and API tokens stop working.
+	DisabledAt     *time.Time `json:"disabled_at,omitempty" gorm:"index"`
+	DisabledReason string     `json:"disabled_reason,omitempty" gorm:"size:500"`
+
+	// IsAdmin grants access to the moderation API.
+	IsAdmin bool `json:"is_admin" gorm:"not null;default:false"`
+
+	// PlanCode is the subscription plan that sets the account's quotas.
+	PlanCode string `json:"plan" gorm:"not null;size:20;default:free;index"`
+
+	// DeletionScheduledAt is when a requested account deletion takes effect.
+	// The owner can cancel it until then.
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty" gorm:"index"`
+
+	// Local password sign-in. PasswordHash is empty for accounts that only
+	// use external identity providers.
+	PasswordHash        string     `json:"-" gorm:"size:255"`
+	EmailVerifiedAt     *time.Time `json:"email_verified_at,omitempty"`
+	FailedLoginAttempts int        `json:"-" gorm:"not null;default:0"`
+	LockedUntil         *time.Time `json:"-"`
 }
 
 type URL struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
 	OriginalURL string         `json:"original_url" gorm:"not null;type:text"`
 	ShortCode   string         `json:"short_code" gorm:"unique;not null;size:10"`
-	CustomAlias string         `json:"custom_alias,omitempty" gorm:"unique;size:50"`
+	CustomAlias string         `json:"custom_alias,omitempty" gorm:"size:50;uniqueIndex:idx_urls_custom_alias,where:custom_alias <> ''"`
 	UserID      *uint          `json:"user_id,omitempty" gorm:"index"`
 	User        *User          `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	WorkspaceID *uint          `json:"workspace_id,omitempty" gorm:"index"`
+	FolderID    *uint          `json:"folder_id,omitempty" gorm:"index"`
+	Tags        []Tag          `json:"tags,omitempty" gorm:"many2many:url_tags"`
 	Title       string         `json:"title,omitempty" gorm:"size:200"`
 	Description string         `json:"description,omitempty" gorm:"size:500"`
 	ExpiresAt   *time.Time     `json:"expires_at,omitempty"`
 	IsActive    bool           `json:"is_active" gorm:"default:true"`
+	Revision    int            `json:"revision" gorm:"not null;default:1"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 	Analytics   []Analytics    `json:"analytics,omitempty" gorm:"foreignKey:URLID"`
+
+	// DisabledAt is set when an administrator takes the link down. The link
+	// stops redirecting and its owners cannot re-enable it.
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason string     `json:"disabled_reason,omitempty" gorm:"size:500"`
+
+	// QuarantinedAt is set when enough abuse reports come in. Visitors see a
+	// warning page instead of being redirected until a moderator decides.
+	QuarantinedAt *time.Time `json:"quarantined_at,omitempty"`
+
+	// ClickCount and LastClickedAt summarize the link's tracked clicks so
+	// listings can show and sort by them without reading the analytics.
+	ClickCount    int64      `json:"click_count" gorm:"not null;default:0;index"`
+	LastClickedAt *time.Time `json:"last_clicked_at,omitempty" gorm:"index"`
+
+	// ExpiryNotifiedAt is set once link.expired has been sent for the
+	// current expiry, and cleared when the expiry changes.
+	ExpiryNotifiedAt *time.Time `json:"-"`
 }
 
+// URLSearchDocument is the SQL expression link search matches against. The
+// Postgres search indexes are built on it, so queries must use it verbatim.
+const URLSearchDocument = "lower(coalesce(original_url, '') || ' ' || short_code || ' ' || coalesce(custom_alias, '') || ' ' || coalesce(title, '') || ' ' || coalesce(description, ''))"
+
 type Analytics struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	URLID     uint           `json:"url_id" gorm:"not null;index"`
-	URL       URL            `json:"url" gorm:"foreignKey:URLID"`
-	IPAddress string         `json:"ip_address" gorm:"size:45"`
-	UserAgent string         `json:"user_agent" gorm:"size:500"`
-	Referrer  string         `json:"referrer" gorm:"size:500"`
-	Country   string         `json:"country,omitempty" gorm:"size:100"`
-	City      string         `json:"city,omitempty" gorm:"size:100"`
-	Device    string         `json:"device,omitempty" gorm:"size:100"`
-	OS        string         `json:"os,omitempty" gorm:"size:100"`
-	Browser   string         `json:"browser,omitempty" gorm:"size:100"`
-	ClickedAt time.Time      `json:"clicked_at"`
-	CreatedAt time.Time      `json:"created_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID        uint   `json:"id" gorm:"primaryKey;index:idx_analytics_url_clicked,priority:3"`
+	URLID     uint   `json:"url_id" gorm:"not null;index;index:idx_analytics_url_clicked,priority:1"`
+	URL       URL    `json:"url" gorm:"foreignKey:URLID"`
+	IPAddress string `json:"ip_address" gorm:"size:45"`
+	UserAgent string `json:"user_agent" gorm:"size:500"`
+	Referrer  string `json:"referrer" gorm:"size:500"`
+	// ReferrerDomain and TrafficSource are derived from Referrer at ingestion.
+	ReferrerDomain string         `json:"referrer_domain,omitempty" gorm:"size:255;index"`
+	TrafficSource  string         `json:"traffic_source,omitempty" gorm:"size:20;index"`
+	Country        string         `json:"country,omitempty" gorm:"size:100"`
+	City           string         `json:"city,omitempty" gorm:"size:100"`
+	Device         string         `json:"device,omitempty" gorm:"size:100"`
+	OS             string         `json:"os,omitempty" gorm:"size:100"`
+	Browser        string         `json:"browser,omitempty" gorm:"size:100"`
+	IsBot          bool           `json:"is_bot" gorm:"not null;default:false;index"`
+	// Revision is the link revision that was live when the click happened.
+	Revision       int            `json:"revision,omitempty" gorm:"not null;default:0"`
+	ClickedAt      time.Time      `json:"clicked_at" gorm:"index:idx_analytics_url_clicked,priority:2"`
+	CreatedAt      time.Time      `json:"created_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// AnalyticsDaily holds per-day click totals that survive the purge of raw
+// analytics events by the retention job.
+type AnalyticsDaily struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	URLID        uint      `json:"url_id" gorm:"not null;uniqueIndex:idx_analytics_daily_url_date"`
+	Date         time.Time `json:"date" gorm:"not null;uniqueIndex:idx_analytics_daily_url_date"`
+	Clicks       int64     `json:"clicks" gorm:"not null;default:0"`
+	UniqueClicks int64     `json:"unique_clicks" gorm:"not null;default:0"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (AnalyticsDaily) TableName() string {
+	return "analytics_daily"
 }
 
 type URLStats struct {
@@ -58,12 +132,141 @@ type URLStats struct {
 	LastClicked *time.Time `json:"last_clicked"`
 }
 
+// ClickEvent is the live notification published for every redirect.
+type ClickEvent struct {
+	URLID          uint      `json:"url_id"`
+	Device         string    `json:"device,omitempty"`
+	Country        string    `json:"country,omitempty"`
+	ReferrerDomain string    `json:"referrer_domain,omitempty"`
+	TrafficSource  string    `json:"traffic_source,omitempty"`
+	IsBot          bool      `json:"is_bot"`
+	ClickedAt      time.Time `json:"clicked_at"`
+}
+
+type ReferrerStat struct {
+	Domain string `json:"domain"`
+	Source string `json:"source"`
+	Clicks int64  `json:"clicks"`
+}
+
+type TrafficSourceStat struct {
+	Source string `json:"source"`
+	Clicks int64  `json:"clicks"`
+}
+
+// ClickEventFilter narrows and pages the raw click event listing. Cursor is
+// the opaque next_cursor returned by the previous page.
+type ClickEventFilter struct {
+	From           *time.Time
+	To             *time.Time
+	Device         string
+	OS             string
+	Browser        string
+	Country        string
+	ReferrerDomain string
+	IsBot          *bool
+	Revision       int
+	Fields         []string
+	Limit          int
+	Cursor         string
+}
+
+type PeriodTotals struct {
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	TotalClicks  int64     `json:"total_clicks"`
+	UniqueClicks int64     `json:"unique_clicks"`
+}
+
+type LinkClickStat struct {
+	URLID     uint   `json:"url_id"`
+	ShortCode string `json:"short_code"`
+	Title     string `json:"title,omitempty"`
+	Clicks    int64  `json:"clicks"`
+}
+
+type DeviceStat struct {
+	Device string `json:"device"`
+	Clicks int64  `json:"clicks"`
+}
+
+type DailyClicks struct {
+	Date         string `json:"date"`
+	Clicks       int64  `json:"clicks"`
+	UniqueClicks int64  `json:"unique_clicks"`
+}
+
+// AnalyticsOverview summarizes every link of an account over a date range,
+// compared with the preceding period of equal length. Change values are
+// percentages and are nil when the previous period had no clicks.
+type AnalyticsOverview struct {
+	PeriodTotals
+	Previous           PeriodTotals    `json:"previous"`
+	TotalClicksChange  *float64        `json:"total_clicks_change"`
+	UniqueClicksChange *float64        `json:"unique_clicks_change"`
+	TopLinks           []LinkClickStat `json:"top_links"`
+	TopReferrers       []ReferrerStat  `json:"top_referrers"`
+	Devices            []DeviceStat    `json:"devices"`
+	DailyTrend         []DailyClicks   `json:"daily_trend"`
+}
+
 type CreateURLRequest struct {
 	OriginalURL string `json:"original_url" validate:"required,url"`
 	CustomAlias string `json:"custom_alias,omitempty" validate:"omitempty,min=3,max=50,alphanum"`
 	Title       string `json:"title,omitempty" validate:"omitempty,max=200"`
 	Description string `json:"description,omitempty" validate:"omitempty,max=500"`
 	ExpiresAt   string `json:"expires_at,omitempty"`
+
+	// WorkspaceID picks the workspace a new link belongs to. It defaults to
+	// the creator's personal workspace.
+	WorkspaceID *uint `json:"workspace_id,omitempty"`
+
+	// FolderID and TagIDs file and tag a new link. They must belong to the
+	// link's workspace.
+	FolderID *uint  `json:"folder_id,omitempty"`
+	TagIDs   []uint `json:"tag_ids,omitempty"`
+}
+
+// URLFilter narrows a link listing. Tagged links must carry every listed
+// tag. FolderID matches links filed directly in the folder, or anywhere
+// beneath it with IncludeSubfolders; Unfiled matches links in no folder.
+type URLFilter struct {
+	WorkspaceID       *uint
+	TagIDs            []uint
+	FolderID          *uint
+	IncludeSubfolders bool
+	Unfiled           bool
+
+	// Query searches the link's destination, short code, alias, title and
+	// description.
+	Query string
+
+	// Active matches links that are enabled and not taken down, Expired
+	// links past their expiry and HasClicks links with tracked clicks.
+	Active    *bool
+	Expired   *bool
+	HasClicks *bool
+
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+
+	// Sort is one of the URLSort values, newest or most first unless
+	// Ascending is set. Searches without a sort are ranked by relevance on
+	// Postgres.
+	Sort      string
+	Ascending bool
+}
+
+const (
+	URLSortCreated     = "created"
+	URLSortUpdated     = "updated"
+	URLSortClicks      = "clicks"
+	URLSortLastClicked = "last_clicked"
+)
+
+type UpdateRetentionRequest struct {
+	// Days of raw click history to keep; nil resets to the platform default.
+	Days *int `json:"days"`
 }
 
 
@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+const (
+	PlanFree     = "free"
+	PlanPro      = "pro"
+	PlanBusiness = "business"
+)
+
+// Usage metrics counted per account and calendar month.
+const (
+	MetricLinksCreated  = "links_created"
+	MetricCustomAliases = "custom_aliases"
+	MetricClicksTracked = "clicks_tracked"
+)
+
+// Plan is a subscription tier. Limits of zero mean unlimited, except
+// BrandedDomains where zero means none are allowed.
+type Plan struct {
+	ID                     uint      `json:"-" gorm:"primaryKey"`
+	Code                   string    `json:"code" gorm:"uniqueIndex;not null;size:20"`
+	Name                   string    `json:"name" gorm:"not null;size:100"`
+	MonthlyLinks           int64     `json:"monthly_links" gorm:"not null;default:0"`
+	MonthlyCustomAliases   int64     `json:"monthly_custom_aliases" gorm:"not null;default:0"`
+	MonthlyClicks          int64     `json:"monthly_clicks" gorm:"not null;default:0"`
+	BrandedDomains         int64     `json:"branded_domains" gorm:"not null;default:0"`
+	AnalyticsRetentionDays int       `json:"analytics_retention_days" gorm:"not null;default:0"`
+	CreatedAt              time.Time `json:"-"`
+	UpdatedAt              time.Time `json:"-"`
+}
+
+// Limit returns the monthly limit for a metric, zero meaning unlimited.
+func (p *Plan) Limit(metric string) int64 {
+	switch metric {
+	case MetricLinksCreated:
+		return p.MonthlyLinks
+	case MetricCustomAliases:
+		return p.MonthlyCustomAliases
+	case MetricClicksTracked:
+		return p.MonthlyClicks
+	}
+	return 0
+}
+
+// DefaultPlans are created at startup when missing. Existing rows are left
+// alone so limits can be changed in the database.
+func DefaultPlans() []Plan {
+	return []Plan{
+		{Code: PlanFree, Name: "Free", MonthlyLinks: 100, MonthlyCustomAliases: 10, MonthlyClicks: 10000, BrandedDomains: 0, AnalyticsRetentionDays: 90},
+		{Code: PlanPro, Name: "Pro", MonthlyLinks: 5000, MonthlyCustomAliases: 1000, MonthlyClicks: 250000, BrandedDomains: 1, AnalyticsRetentionDays: 365},
+		{Code: PlanBusiness, Name: "Business", MonthlyLinks: 0, MonthlyCustomAliases: 0, MonthlyClicks: 0, BrandedDomains: 10, AnalyticsRetentionDays: 0},
+	}
+}
+
+// UsageCounter totals one metric for an account over a calendar month
+// (Period is "2006-01", UTC).
+type UsageCounter struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	UserID    uint      `json:"-" gorm:"not null;uniqueIndex:idx_usage_counter_key"`
+	Period    string    `json:"period" gorm:"not null;size:7;uniqueIndex:idx_usage_counter_key"`
+	Metric    string    `json:"metric" gorm:"not null;size:30;uniqueIndex:idx_usage_counter_key"`
+	Count     int64     `json:"count" gorm:"not null;default:0"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UsageEvent is an outbox row for billing. It is written in the same
+// transaction as the metered change and deleted once the billing sink has
+// accepted it.
+type UsageEvent struct {
+	ID         uint      `gorm:"primaryKey"`
+	UserID     uint      `gorm:"not null;index"`
+	PlanCode   string    `gorm:"not null;size:20"`
+	Metric     string    `gorm:"not null;size:30"`
+	Quantity   int64     `gorm:"not null"`
+	OccurredAt time.Time `gorm:"not null;index"`
+}
+
+// MetricUsage is how much of one monthly allowance has been used. Limit is
+// nil when the plan has no limit.
+type MetricUsage struct {
+	Used  int64  `json:"used"`
+	Limit *int64 `json:"limit"`
+}
+
+// AccountUsage reports an account's plan and its usage this month.
+type AccountUsage struct {
+	Plan           Plan        `json:"plan"`
+	Period         string      `json:"period"`
+	PeriodStart    time.Time   `json:"period_start"`
+	PeriodEnd      time.Time   `json:"period_end"`
+	LinksCreated   MetricUsage `json:"links_created"`
+	CustomAliases  MetricUsage `json:"custom_aliases"`
+	ClicksTracked  MetricUsage `json:"clicks_tracked"`
+	BrandedDomains MetricUsage `json:"branded_domains"`
+}
+
+type UpdatePlanRequest struct {
+	Plan string `json:"plan"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// URLRevision is a numbered snapshot of a link's destination and settings.
+// Revision 1 is the link as created; every later change, including a
+// rollback, adds the next number. Clicks counts the visits made while the
+// revision was live.
+type URLRevision struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	URLID        uint       `json:"url_id" gorm:"not null;uniqueIndex:idx_url_revision_number"`
+	Number       int        `json:"number" gorm:"not null;uniqueIndex:idx_url_revision_number"`
+	OriginalURL  string     `json:"original_url" gorm:"not null;type:text"`
+	Title        string     `json:"title,omitempty" gorm:"size:200"`
+	Description  string     `json:"description,omitempty" gorm:"size:500"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	RestoredFrom *int       `json:"restored_from,omitempty"`
+	CreatedByID  *uint      `json:"created_by_id,omitempty"`
+	Clicks       int64      `json:"clicks" gorm:"not null;default:0"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// URLRevisionDiff lists the fields that differ between two revisions of a
+// link.
+type URLRevisionDiff struct {
+	URLID   uint                   `json:"url_id"`
+	From    int                    `json:"from"`
+	To      int                    `json:"to"`
+	Changes map[string]AuditChange `json:"changes"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Session is a persisted login session. ID holds the SHA-256 hash of the
+// session cookie value so a database leak does not expose live sessions.
+type Session struct {
+	ID           string    `gorm:"primaryKey;size:64"`
+	UserID       uint      `gorm:"not null;index"`
+	UserEmail    string    `gorm:"size:255"`
+	IPAddress    string    `gorm:"size:45"`
+	UserAgent    string    `gorm:"size:500"`
+	CreatedAt    time.Time `gorm:"not null"`
+	LastActiveAt time.Time `gorm:"not null"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+
+	// ImpersonatorID is the admin viewing the account read-only through
+	// this session.
+	ImpersonatorID *uint `gorm:"index"`
+}
+
+// SessionInfo describes an active session to its owner. ID is the hashed
+// session key, never the cookie value.
+type SessionInfo struct {
+	ID           string    `json:"id"`
+	IPAddress    string    `json:"ip_address"`
+	UserAgent    string    `json:"user_agent"`
+	Device       string    `json:"device"`
+	Browser      string    `json:"browser"`
+	OS           string    `json:"os"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastActiveAt time.Time `json:"last_active_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Current      bool      `json:"current"`
+	Impersonated bool      `json:"impersonated,omitempty"`
+}
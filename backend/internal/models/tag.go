@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Tag labels links within a workspace. Names are unique per workspace,
+// ignoring case, and a link can carry any number of tags.
+type Tag struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint      `json:"workspace_id" gorm:"not null;uniqueIndex:idx_tag_workspace_name"`
+	Name        string    `json:"name" gorm:"not null;size:50;uniqueIndex:idx_tag_workspace_name"`
+	Color       string    `json:"color,omitempty" gorm:"size:7"`
+	LinkCount   *int64    `json:"link_count,omitempty" gorm:"->;-:migration"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// URLTag attaches a tag to a link. It is the join table behind URL.Tags.
+type URLTag struct {
+	URLID uint `gorm:"primaryKey"`
+	TagID uint `gorm:"primaryKey;index"`
+}
+
+func (URLTag) TableName() string {
+	return "url_tags"
+}
+
+type TagRequest struct {
+	// WorkspaceID picks the workspace a new tag belongs to. It defaults to
+	// the creator's personal workspace and is ignored on update.
+	WorkspaceID *uint  `json:"workspace_id,omitempty"`
+	Name        string `json:"name"`
+	Color       string `json:"color,omitempty"`
+}
+
+// BulkTagRequest adds tags to, or removes them from, a selection of links.
+type BulkTagRequest struct {
+	URLIDs []uint `json:"url_ids"`
+	TagIDs []uint `json:"tag_ids"`
+}
+
+// TagStats aggregates the clicks on every link carrying a tag over a period.
+type TagStats struct {
+	PeriodTotals
+	TagID    uint            `json:"tag_id"`
+	Links    int64           `json:"links"`
+	TopLinks []LinkClickStat `json:"top_links"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+const (
+	ScopeLinksRead     = "links:read"
+	ScopeLinksWrite    = "links:write"
+	ScopeAnalyticsRead = "analytics:read"
+)
+
+var TokenScopes = []string{ScopeLinksRead, ScopeLinksWrite, ScopeAnalyticsRead}
+
+// APIToken is a personal access token for programmatic access. Only the
+// SHA-256 hash of the token is stored; Prefix lets users recognise it.
+type APIToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	User       *User      `json:"-" gorm:"foreignKey:UserID"`
+	Name       string     `json:"name" gorm:"not null;size:100"`
+	Prefix     string     `json:"prefix" gorm:"not null;size:16"`
+	TokenHash  string     `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	Scopes     []string   `json:"scopes" gorm:"not null;serializer:json"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateAPITokenRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt string   `json:"expires_at,omitempty"`
+}
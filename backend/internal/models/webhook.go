@@ -0,0 +1,114 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Events a webhook can subscribe to.
+const (
+	WebhookLinkCreated   = "link.created"
+	WebhookLinkUpdated   = "link.updated"
+	WebhookLinkDeleted   = "link.deleted"
+	WebhookLinkExpired   = "link.expired"
+	WebhookClickRecorded = "click.recorded"
+)
+
+var WebhookEvents = []string{WebhookLinkCreated, WebhookLinkUpdated, WebhookLinkDeleted, WebhookLinkExpired, WebhookClickRecorded}
+
+// Delivery states. Pending deliveries are retried until they succeed or
+// run out of attempts, when they become dead until redelivered.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Webhook is an endpoint that receives a workspace's link and click events.
+// Deliveries are signed with Secret, which is only shown when the webhook is
+// created. ClickSampleRate is the share of clicks sent as click.recorded.
+type Webhook struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	WorkspaceID     uint      `json:"workspace_id" gorm:"not null;index"`
+	CreatedByID     *uint     `json:"created_by_id,omitempty"`
+	URL             string    `json:"url" gorm:"not null;type:text"`
+	Description     string    `json:"description,omitempty" gorm:"size:200"`
+	Events          []string  `json:"events" gorm:"not null;serializer:json"`
+	ClickSampleRate float64   `json:"click_sample_rate" gorm:"not null;default:1"`
+	Active          bool      `json:"active" gorm:"not null;default:true"`
+	Secret          string    `json:"-" gorm:"not null;size:100"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event queued for one webhook. Payload is the exact
+// body sent, so retries carry the same event.
+type WebhookDelivery struct {
+	ID             uint            `json:"id" gorm:"primaryKey"`
+	WebhookID      uint            `json:"webhook_id" gorm:"not null;index"`
+	EventID        string          `json:"event_id" gorm:"not null;size:40;index"`
+	Event          string          `json:"event" gorm:"not null;size:30"`
+	Payload        json.RawMessage `json:"payload" gorm:"not null;type:text;serializer:json"`
+	Status         string          `json:"status" gorm:"not null;size:20;index:idx_webhook_deliveries_due,priority:1"`
+	Attempts       int             `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" gorm:"not null;index:idx_webhook_deliveries_due,priority:2"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty" gorm:"size:500"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// WebhookRequest creates or updates a webhook. WorkspaceID is only read on
+// creation and defaults to the personal workspace. ClickSampleRate defaults
+// to sending every click.
+type WebhookRequest struct {
+	WorkspaceID     *uint    `json:"workspace_id,omitempty"`
+	URL             string   `json:"url"`
+	Description     string   `json:"description,omitempty"`
+	Events          []string `json:"events"`
+	ClickSampleRate *float64 `json:"click_sample_rate,omitempty"`
+	Active          *bool    `json:"active,omitempty"`
+}
+
+// WebhookEvent is the body of every delivery.
+type WebhookEvent struct {
+	ID          string      `json:"id"`
+	Type        string      `json:"type"`
+	CreatedAt   time.Time   `json:"created_at"`
+	WorkspaceID uint        `json:"workspace_id"`
+	Data        interface{} `json:"data"`
+}
+
+// WebhookLinkData is the data of the link events. Changes is only set for
+// link.updated.
+type WebhookLinkData struct {
+	Link    *URL                   `json:"link"`
+	Changes map[string]AuditChange `json:"changes,omitempty"`
+}
+
+// WebhookClickData is the data of click.recorded. It leaves out the
+// visitor's IP address and user agent.
+type WebhookClickData struct {
+	LinkID         uint      `json:"link_id"`
+	ShortCode      string    `json:"short_code"`
+	Revision       int       `json:"revision"`
+	ClickedAt      time.Time `json:"clicked_at"`
+	ReferrerDomain string    `json:"referrer_domain,omitempty"`
+	TrafficSource  string    `json:"traffic_source,omitempty"`
+	Country        string    `json:"country,omitempty"`
+	City           string    `json:"city,omitempty"`
+	Device         string    `json:"device,omitempty"`
+	OS             string    `json:"os,omitempty"`
+	Browser        string    `json:"browser,omitempty"`
+	IsBot          bool      `json:"is_bot"`
+}
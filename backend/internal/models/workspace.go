@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// WorkspaceRoles lists member roles from most to least privileged.
+var WorkspaceRoles = []string{RoleOwner, RoleAdmin, RoleEditor, RoleViewer}
+
+// RoleAtLeast reports whether role grants at least the privileges of min.
+func RoleAtLeast(role, min string) bool {
+	for _, r := range WorkspaceRoles {
+		if r == role {
+			return true
+		}
+		if r == min {
+			return false
+		}
+	}
+	return false
+}
+
+// RolesAtLeast returns every role that grants at least the privileges of min.
+func RolesAtLeast(min string) []string {
+	for i, r := range WorkspaceRoles {
+		if r == min {
+			return WorkspaceRoles[:i+1]
+		}
+	}
+	return nil
+}
+
+// Workspace owns links and is shared by its members. Every user has one
+// personal workspace, identified by PersonalUserID, that cannot be deleted.
+type Workspace struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	Name           string         `json:"name" gorm:"not null;size:100"`
+	PersonalUserID *uint          `json:"personal_user_id,omitempty" gorm:"uniqueIndex"`
+	Role           string         `json:"role,omitempty" gorm:"-"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type WorkspaceMember struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint      `json:"workspace_id" gorm:"not null;uniqueIndex:idx_workspace_member"`
+	UserID      uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_workspace_member;index"`
+	User        *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Role        string    `json:"role" gorm:"not null;size:20"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WorkspaceInvitation invites an email address to join a workspace. Only the
+// SHA-256 hash of the emailed token is stored.
+type WorkspaceInvitation struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint       `json:"workspace_id" gorm:"not null;index"`
+	Email       string     `json:"email" gorm:"not null;size:255"`
+	Role        string     `json:"role" gorm:"not null;size:20"`
+	TokenHash   string     `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	InvitedByID uint       `json:"invited_by_id" gorm:"not null"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type WorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+type InviteMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token"`
+}
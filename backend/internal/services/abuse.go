@@ -0,0 +1,335 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/mail"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidReportCategory = errors.New("category must be one of phishing, malware, spam, illegal or other")
+	ErrNoOpenReports         = errors.New("link has no open reports")
+)
+
+// AbuseService takes abuse reports about links, quarantines links that
+// collect enough of them, and lets moderators resolve the reports.
+type AbuseService struct {
+	db          *gorm.DB
+	config      *config.Config
+	mailer      mail.Mailer
+	reporterKey []byte
+}
+
+func NewAbuseService(cfg *config.Config, mailer mail.Mailer) *AbuseService {
+	return &AbuseService{
+		db:          database.GetDB(),
+		config:      cfg,
+		mailer:      mailer,
+		reporterKey: utils.DeriveKey(cfg.SessionSecret, "abuse-reporter"),
+	}
+}
+
+// reporterHash stands in for a reporter's address: reports from one address
+// share it, but the address cannot be read back from it.
+func (s *AbuseService) reporterHash(ip string) string {
+	mac := hmac.New(sha256.New, s.reporterKey)
+	mac.Write([]byte(ip))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:24])
+}
+
+func isReportCategory(category string) bool {
+	for _, c := range models.ReportCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Report records a report against an active link. A reporter with an open
+// report on the link is not counted twice. Once open reports from
+// AbuseReportThreshold distinct addresses have come in, the link is
+// quarantined and its owner told.
+func (s *AbuseService) Report(shortCode string, req *models.AbuseReportRequest, reporterIP string, reporterUserID *uint) error {
+	category := strings.ToLower(strings.TrimSpace(req.Category))
+	if !isReportCategory(category) {
+		return ErrInvalidReportCategory
+	}
+	details := strings.TrimSpace(req.Details)
+	if len(details) > 1000 {
+		details = details[:1000]
+	}
+
+	var url models.URL
+	if err := s.db.Where("(short_code = ? OR custom_alias = ?) AND is_active = ?", shortCode, shortCode, true).First(&url).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrURLNotFound
+		}
+		return errors.New("database error")
+	}
+
+	reporter := s.reporterHash(reporterIP)
+	quarantined := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.AbuseReport{}).
+			Where("url_id = ? AND status = ? AND reporter_ip = ?", url.ID, models.ReportStatusOpen, reporter).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return nil
+		}
+
+		report := models.AbuseReport{
+			URLID:          url.ID,
+			Category:       category,
+			Details:        details,
+			ReporterIP:     reporter,
+			ReporterUserID: reporterUserID,
+			Status:         models.ReportStatusOpen,
+		}
+		if err := tx.Create(&report).Error; err != nil {
+			return err
+		}
+
+		if url.QuarantinedAt != nil || s.config.AbuseReportThreshold <= 0 {
+			return nil
+		}
+		var reporters int64
+		if err := tx.Model(&models.AbuseReport{}).
+			Where("url_id = ? AND status = ?", url.ID, models.ReportStatusOpen).
+			Distinct("reporter_ip").Count(&reporters).Error; err != nil {
+			return err
+		}
+		if reporters < int64(s.config.AbuseReportThreshold) {
+			return nil
+		}
+
+		result := tx.Model(&models.URL{}).Where("id = ? AND quarantined_at IS NULL", url.ID).Update("quarantined_at", time.Now())
+		quarantined = result.RowsAffected > 0
+		return result.Error
+	})
+	if err != nil {
+		return errors.New("failed to record report")
+	}
+
+	if quarantined {
+		s.notifyOwner(&url, "Your link has been quarantined", fmt.Sprintf(
+			"Your short link /%s has been reported several times, so visitors now see a warning before continuing to %s.\n\n"+
+				"A moderator will review the reports. You do not need to do anything unless we contact you.",
+			url.ShortCode, url.OriginalURL,
+		))
+	}
+	return nil
+}
+
+// Queue lists links with open reports, most reported first.
+func (s *AbuseService) Queue(limit, offset int) ([]models.ReportedURL, int64, error) {
+	open := s.db.Model(&models.AbuseReport{}).Where("status = ?", models.ReportStatusOpen)
+
+	var total int64
+	if err := open.Session(&gorm.Session{}).Distinct("url_id").Count(&total).Error; err != nil {
+		return nil, 0, errors.New("failed to count reported links")
+	}
+
+	var rows []struct {
+		URLID         uint
+		OpenReports   int64
+		FirstReportID uint
+	}
+	if err := open.Session(&gorm.Session{}).
+		Select("url_id, COUNT(*) AS open_reports, MIN(id) AS first_report_id").
+		Group("url_id").Order("open_reports DESC, first_report_id").
+		Limit(limit).Offset(offset).Scan(&rows).Error; err != nil {
+		return nil, 0, errors.New("failed to load reported links")
+	}
+
+	urlIDs := make([]uint, len(rows))
+	reportIDs := make([]uint, len(rows))
+	for i, row := range rows {
+		urlIDs[i] = row.URLID
+		reportIDs[i] = row.FirstReportID
+	}
+
+	var urls []models.URL
+	var reports []models.AbuseReport
+	if err := s.db.Unscoped().Where("id IN ?", urlIDs).Find(&urls).Error; err != nil {
+		return nil, 0, errors.New("failed to load reported links")
+	}
+	if err := s.db.Select("id", "created_at").Where("id IN ?", reportIDs).Find(&reports).Error; err != nil {
+		return nil, 0, errors.New("failed to load reported links")
+	}
+	urlsByID := make(map[uint]models.URL, len(urls))
+	for _, url := range urls {
+		urlsByID[url.ID] = url
+	}
+	reportedAt := make(map[uint]time.Time, len(reports))
+	for _, report := range reports {
+		reportedAt[report.ID] = report.CreatedAt
+	}
+
+	queue := make([]models.ReportedURL, 0, len(rows))
+	for _, row := range rows {
+		url := urlsByID[row.URLID]
+		queue = append(queue, models.ReportedURL{
+			URLID:         row.URLID,
+			ShortCode:     url.ShortCode,
+			OriginalURL:   url.OriginalURL,
+			UserID:        url.UserID,
+			QuarantinedAt: url.QuarantinedAt,
+			OpenReports:   row.OpenReports,
+			FirstReported: reportedAt[row.FirstReportID],
+		})
+	}
+
+	return queue, total, nil
+}
+
+// ListReports returns the reports about a link, newest first, optionally
+// with one status.
+func (s *AbuseService) ListReports(urlID uint, status string, limit, offset int) ([]models.AbuseReport, int64, error) {
+	query := s.db.Model(&models.AbuseReport{}).Where("url_id = ?", urlID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("failed to count reports")
+	}
+
+	var reports []models.AbuseReport
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&reports).Error; err != nil {
+		return nil, 0, errors.New("failed to load reports")
+	}
+
+	return reports, total, nil
+}
+
+// resolve closes a link's open reports and applies the decision to the
+// link, recording the moderator's action in the same transaction.
+func (s *AbuseService) resolve(adminID, urlID uint, status, reason, ip string, urlUpdates map[string]interface{}) (*models.URL, error) {
+	var url models.URL
+	if err := s.db.Unscoped().Where("id = ?", urlID).First(&url).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrURLNotFound
+		}
+		return nil, errors.New("database error")
+	}
+
+	action := models.AdminActionConfirmReports
+	if status == models.ReportStatusDismissed {
+		action = models.AdminActionDismissReports
+	}
+
+	now := time.Now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.AbuseReport{}).
+			Where("url_id = ? AND status = ?", urlID, models.ReportStatusOpen).
+			Updates(map[string]interface{}{
+				"status":         status,
+				"resolved_by_id": adminID,
+				"resolved_at":    now,
+				"resolution":     reason,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNoOpenReports
+		}
+
+		if err := tx.Unscoped().Model(&models.URL{}).Where("id = ?", urlID).Updates(urlUpdates).Error; err != nil {
+			return err
+		}
+
+		return recordAdminAction(tx, &models.AdminAction{
+			AdminID:    adminID,
+			Action:     action,
+			TargetType: models.AdminTargetURL,
+			TargetID:   urlID,
+			Reason:     reason,
+			IPAddress:  ip,
+		})
+	})
+	if errors.Is(err, ErrNoOpenReports) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New("failed to resolve reports")
+	}
+
+	return &url, nil
+}
+
+// Confirm upholds a link's open reports and takes the link down.
+func (s *AbuseService) Confirm(adminID, urlID uint, reason, ip string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return ErrReasonRequired
+	}
+
+	url, err := s.resolve(adminID, urlID, models.ReportStatusConfirmed, reason, ip, map[string]interface{}{
+		"is_active":       false,
+		"disabled_at":     time.Now(),
+		"disabled_reason": reason,
+		"quarantined_at":  nil,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.notifyOwner(url, "Your link has been taken down", fmt.Sprintf(
+		"Your short link /%s, which pointed to %s, has been disabled after a review of abuse reports.\n\nReason: %s",
+		url.ShortCode, url.OriginalURL, reason,
+	))
+	return nil
+}
+
+// Dismiss rejects a link's open reports and lifts any quarantine.
+func (s *AbuseService) Dismiss(adminID, urlID uint, reason, ip string) error {
+	url, err := s.resolve(adminID, urlID, models.ReportStatusDismissed, strings.TrimSpace(reason), ip, map[string]interface{}{
+		"quarantined_at": nil,
+	})
+	if err != nil {
+		return err
+	}
+
+	if url.QuarantinedAt != nil {
+		s.notifyOwner(url, "Your link is no longer quarantined", fmt.Sprintf(
+			"A moderator reviewed the reports about your short link /%s and found nothing wrong. "+
+				"Visitors are sent straight to %s again.",
+			url.ShortCode, url.OriginalURL,
+		))
+	}
+	return nil
+}
+
+// notifyOwner emails the account that created the link.
+func (s *AbuseService) notifyOwner(url *models.URL, subject, body string) {
+	if url.UserID == nil {
+		return
+	}
+
+	var owner models.User
+	if err := s.db.Select("id", "email").Where("id = ?", *url.UserID).First(&owner).Error; err != nil {
+		return
+	}
+
+	if err := s.mailer.Send(mail.Message{To: owner.Email, Subject: subject, Body: body}); err != nil {
+		log.Printf("Failed to send %q to %s: %v", subject, owner.Email, err)
+	}
+}
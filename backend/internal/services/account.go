@@ -0,0 +1,687 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// exportLifetime is how long a finished export can be downloaded.
+	exportLifetime = 7 * 24 * time.Hour
+	// accountSweepInterval is how often interrupted exports are retried,
+	// expired archives removed and due account deletions carried out.
+	accountSweepInterval = 10 * time.Minute
+)
+
+var (
+	ErrExportNotFound       = errors.New("no export found")
+	ErrExportNotReady       = errors.New("export is not ready yet")
+	ErrDeletionNotScheduled = errors.New("account deletion is not scheduled")
+	ErrOwnsSharedWorkspace  = errors.New("transfer ownership of your shared workspaces before deleting your account")
+)
+
+// AccountSessions lists and ends a user's login sessions.
+type AccountSessions interface {
+	SessionRevoker
+	ListSessions(userID uint, currentSessionID string) ([]models.SessionInfo, error)
+}
+
+// AccountService exports a user's personal data and deletes accounts once
+// their grace period has passed. Exports run on a background worker.
+type AccountService struct {
+	db       *gorm.DB
+	config   *config.Config
+	sessions AccountSessions
+	jobs     chan uint
+	done     chan struct{}
+}
+
+func NewAccountService(cfg *config.Config) *AccountService {
+	return &AccountService{
+		db:     database.GetDB(),
+		config: cfg,
+		jobs:   make(chan uint, 64),
+		done:   make(chan struct{}),
+	}
+}
+
+// SetSessions lets exports include sessions and deletion end them.
+func (s *AccountService) SetSessions(sessions AccountSessions) {
+	s.sessions = sessions
+}
+
+// Start runs export jobs as they are requested, and periodically sweeps up
+// exports left pending, expired archives and accounts due for deletion.
+func (s *AccountService) Start() {
+	// Exports interrupted by a restart are run again.
+	s.db.Model(&models.DataExport{}).Where("status = ?", models.ExportStatusRunning).
+		Update("status", models.ExportStatusPending)
+
+	go func() {
+		ticker := time.NewTicker(accountSweepInterval)
+		defer ticker.Stop()
+
+		s.sweep(time.Now())
+		for {
+			select {
+			case exportID := <-s.jobs:
+				if err := s.RunExport(exportID); err != nil {
+					log.Printf("Data export %d: %v", exportID, err)
+				}
+			case <-ticker.C:
+				s.sweep(time.Now())
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the background worker
+func (s *AccountService) Close() {
+	close(s.done)
+}
+
+func (s *AccountService) sweep(now time.Time) {
+	if _, err := s.RunPendingExports(); err != nil {
+		log.Printf("Data exports: %v", err)
+	}
+	if _, err := s.PurgeExpiredExports(now); err != nil {
+		log.Printf("Data exports: %v", err)
+	}
+	deleted, err := s.PurgeDeletedAccounts(now)
+	if err != nil {
+		log.Printf("Account deletion: %v", err)
+	}
+	if deleted > 0 {
+		log.Printf("Account deletion: deleted %d accounts", deleted)
+	}
+}
+
+// LatestExport returns the user's most recent export.
+func (s *AccountService) LatestExport(userID uint) (*models.DataExport, error) {
+	var export models.DataExport
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").First(&export).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExportNotFound
+		}
+		return nil, errors.New("database error")
+	}
+	return &export, nil
+}
+
+// RequestExport queues a new export, or returns the one already in progress.
+func (s *AccountService) RequestExport(userID uint) (*models.DataExport, error) {
+	var export models.DataExport
+	err := s.db.Where("user_id = ? AND status IN ?", userID, []string{models.ExportStatusPending, models.ExportStatusRunning}).
+		First(&export).Error
+	if err == nil {
+		return &export, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("database error")
+	}
+
+	export = models.DataExport{UserID: userID, Status: models.ExportStatusPending}
+	if err := s.db.Create(&export).Error; err != nil {
+		return nil, errors.New("failed to create export")
+	}
+
+	// A full queue is fine: the next sweep picks the export up.
+	select {
+	case s.jobs <- export.ID:
+	default:
+	}
+
+	return &export, nil
+}
+
+// ReadyExport returns the user's latest export if it can be downloaded.
+func (s *AccountService) ReadyExport(userID uint) (*models.DataExport, error) {
+	export, err := s.LatestExport(userID)
+	if err != nil {
+		return nil, err
+	}
+	if export.Status != models.ExportStatusReady {
+		return nil, ErrExportNotReady
+	}
+	if export.ExpiresAt != nil && export.ExpiresAt.Before(time.Now()) {
+		return nil, ErrExportNotFound
+	}
+	return export, nil
+}
+
+// RunPendingExports runs every queued export and returns how many ran.
+func (s *AccountService) RunPendingExports() (int, error) {
+	var ids []uint
+	if err := s.db.Model(&models.DataExport{}).Where("status = ?", models.ExportStatusPending).
+		Order("id").Pluck("id", &ids).Error; err != nil {
+		return 0, errors.New("failed to load pending exports")
+	}
+
+	for i, id := range ids {
+		if err := s.RunExport(id); err != nil {
+			return i, err
+		}
+	}
+	return len(ids), nil
+}
+
+// RunExport builds the archive for a pending export. An export another
+// worker has already claimed is skipped.
+func (s *AccountService) RunExport(exportID uint) error {
+	claim := s.db.Model(&models.DataExport{}).
+		Where("id = ? AND status = ?", exportID, models.ExportStatusPending).
+		Update("status", models.ExportStatusRunning)
+	if claim.Error != nil {
+		return errors.New("failed to claim export")
+	}
+	if claim.RowsAffected == 0 {
+		return nil
+	}
+
+	var export models.DataExport
+	if err := s.db.First(&export, exportID).Error; err != nil {
+		return errors.New("failed to load export")
+	}
+
+	path := filepath.Join(s.config.ExportDir, fmt.Sprintf("export-%d-%d.zip", export.UserID, export.ID))
+	size, err := s.writeArchive(export.UserID, path)
+	if err != nil {
+		s.db.Model(&export).Updates(map[string]interface{}{
+			"status": models.ExportStatusFailed,
+			"error":  err.Error(),
+		})
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(exportLifetime)
+	if err := s.db.Model(&export).Updates(map[string]interface{}{
+		"status":       models.ExportStatusReady,
+		"file_path":    path,
+		"size":         size,
+		"completed_at": now,
+		"expires_at":   expiresAt,
+	}).Error; err != nil {
+		os.Remove(path)
+		return errors.New("failed to update export")
+	}
+
+	// Only the newest archive is kept.
+	var older []models.DataExport
+	s.db.Where("user_id = ? AND id <> ? AND status = ?", export.UserID, export.ID, models.ExportStatusReady).Find(&older)
+	s.removeExports(older)
+
+	return nil
+}
+
+// exportedClick is a click event as it appears in an export.
+type exportedClick struct {
+	URLID          uint      `json:"url_id"`
+	IPAddress      string    `json:"ip_address"`
+	UserAgent      string    `json:"user_agent"`
+	Referrer       string    `json:"referrer"`
+	ReferrerDomain string    `json:"referrer_domain,omitempty"`
+	TrafficSource  string    `json:"traffic_source,omitempty"`
+	Country        string    `json:"country,omitempty"`
+	City           string    `json:"city,omitempty"`
+	Device         string    `json:"device,omitempty"`
+	OS             string    `json:"os,omitempty"`
+	Browser        string    `json:"browser,omitempty"`
+	IsBot          bool      `json:"is_bot"`
+	ClickedAt      time.Time `json:"clicked_at"`
+}
+
+// exportedMembership is a workspace membership as it appears in an export.
+type exportedMembership struct {
+	WorkspaceID uint      `json:"workspace_id"`
+	Name        string    `json:"name"`
+	Role        string    `json:"role"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+// writeArchive writes the user's data to a zip file at path and returns its
+// size. Click events are written as JSON lines so large histories are
+// streamed rather than loaded at once.
+func (s *AccountService) writeArchive(userID uint, path string) (int64, error) {
+	var user models.User
+	if err := s.db.Unscoped().First(&user, userID).Error; err != nil {
+		return 0, errors.New("user not found")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return 0, errors.New("failed to create export directory")
+	}
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, errors.New("failed to create export file")
+	}
+	defer os.Remove(tmp)
+
+	archive := zip.NewWriter(file)
+	if err := s.writeEntries(archive, &user); err != nil {
+		file.Close()
+		return 0, err
+	}
+	if err := archive.Close(); err != nil {
+		file.Close()
+		return 0, errors.New("failed to write export")
+	}
+	if err := file.Close(); err != nil {
+		return 0, errors.New("failed to write export")
+	}
+
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return 0, errors.New("failed to write export")
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, errors.New("failed to write export")
+	}
+	return info.Size(), nil
+}
+
+func (s *AccountService) writeEntries(archive *zip.Writer, user *models.User) error {
+	writeJSON := func(name string, value interface{}) error {
+		w, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+
+	var identities []models.UserIdentity
+	var tokens []models.APIToken
+	var links []models.URL
+	var daily []models.AnalyticsDaily
+	var revisions []models.URLRevision
+	var usage []models.UsageCounter
+	var audit []models.AuditEvent
+	var memberships []exportedMembership
+	linkIDs := s.db.Unscoped().Model(&models.URL{}).Select("id").Where("user_id = ?", user.ID)
+
+	queries := []error{
+		s.db.Where("user_id = ?", user.ID).Find(&identities).Error,
+		s.db.Where("user_id = ?", user.ID).Find(&tokens).Error,
+		s.db.Unscoped().Preload("Tags").Where("user_id = ?", user.ID).Order("id").Find(&links).Error,
+		s.db.Where("url_id IN (?)", linkIDs).Order("url_id, date").Find(&daily).Error,
+		s.db.Where("url_id IN (?)", linkIDs).Order("url_id, number").Find(&revisions).Error,
+		s.db.Where("user_id = ?", user.ID).Order("period, metric").Find(&usage).Error,
+		s.db.Where("account_id = ?", user.ID).Order("id").Find(&audit).Error,
+		s.db.Table("workspace_members").
+			Select("workspaces.id AS workspace_id, workspaces.name, workspace_members.role, workspace_members.created_at AS joined_at").
+			Joins("JOIN workspaces ON workspaces.id = workspace_members.workspace_id").
+			Where("workspace_members.user_id = ?", user.ID).Scan(&memberships).Error,
+	}
+	for _, err := range queries {
+		if err != nil {
+			return errors.New("failed to load account data")
+		}
+	}
+
+	sessions := []models.SessionInfo{}
+	if s.sessions != nil {
+		list, err := s.sessions.ListSessions(user.ID, "")
+		if err != nil {
+			return errors.New("failed to load sessions")
+		}
+		sessions = list
+	}
+
+	entries := []struct {
+		name  string
+		value interface{}
+	}{
+		{"profile.json", user},
+		{"identities.json", identities},
+		{"sessions.json", sessions},
+		{"api_tokens.json", tokens},
+		{"workspaces.json", memberships},
+		{"links.json", links},
+		{"analytics_daily.json", daily},
+		{"link_revisions.json", revisions},
+		{"usage.json", usage},
+		{"audit.json", audit},
+	}
+	for _, entry := range entries {
+		if err := writeJSON(entry.name, entry.value); err != nil {
+			return errors.New("failed to write export")
+		}
+	}
+
+	w, err := archive.Create("clicks.jsonl")
+	if err != nil {
+		return errors.New("failed to write export")
+	}
+	encoder := json.NewEncoder(w)
+	var batch []models.Analytics
+	result := s.db.Where("url_id IN (?)", linkIDs).Order("id").FindInBatches(&batch, 1000, func(tx *gorm.DB, _ int) error {
+		for _, event := range batch {
+			if err := encoder.Encode(exportedClick{
+				URLID:          event.URLID,
+				IPAddress:      event.IPAddress,
+				UserAgent:      event.UserAgent,
+				Referrer:       event.Referrer,
+				ReferrerDomain: event.ReferrerDomain,
+				TrafficSource:  event.TrafficSource,
+				Country:        event.Country,
+				City:           event.City,
+				Device:         event.Device,
+				OS:             event.OS,
+				Browser:        event.Browser,
+				IsBot:          event.IsBot,
+				ClickedAt:      event.ClickedAt,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return errors.New("failed to write clicks")
+	}
+
+	return nil
+}
+
+// PurgeExpiredExports removes archives past their download window and
+// failed exports older than that window.
+func (s *AccountService) PurgeExpiredExports(now time.Time) (int, error) {
+	var expired []models.DataExport
+	if err := s.db.Where("expires_at < ? OR (status = ? AND created_at < ?)",
+		now, models.ExportStatusFailed, now.Add(-exportLifetime)).Find(&expired).Error; err != nil {
+		return 0, errors.New("failed to load expired exports")
+	}
+
+	s.removeExports(expired)
+	return len(expired), nil
+}
+
+func (s *AccountService) removeExports(exports []models.DataExport) {
+	for _, export := range exports {
+		if export.FilePath != "" {
+			os.Remove(export.FilePath)
+		}
+		s.db.Delete(&export)
+	}
+}
+
+// ScheduleDeletion marks the account for deletion once the grace period has
+// passed. Accounts that are the last owner of a shared workspace must hand
+// it over first so its other members keep their links.
+func (s *AccountService) ScheduleDeletion(userID uint) (*models.AccountDeletion, error) {
+	var blocking int64
+	err := s.db.Model(&models.WorkspaceMember{}).
+		Joins("JOIN workspaces ON workspaces.id = workspace_members.workspace_id").
+		Where("workspace_members.user_id = ? AND workspace_members.role = ? AND workspaces.personal_user_id IS NULL", userID, models.RoleOwner).
+		Where("NOT EXISTS (SELECT 1 FROM workspace_members o WHERE o.workspace_id = workspace_members.workspace_id AND o.user_id <> ? AND o.role = ?)", userID, models.RoleOwner).
+		Where("EXISTS (SELECT 1 FROM workspace_members o WHERE o.workspace_id = workspace_members.workspace_id AND o.user_id <> ?)", userID).
+		Count(&blocking).Error
+	if err != nil {
+		return nil, errors.New("database error")
+	}
+	if blocking > 0 {
+		return nil, ErrOwnsSharedWorkspace
+	}
+
+	scheduledAt := time.Now().AddDate(0, 0, s.config.AccountDeletionGraceDays)
+	result := s.db.Model(&models.User{}).Where("id = ?", userID).Update("deletion_scheduled_at", scheduledAt)
+	if result.Error != nil {
+		return nil, errors.New("failed to schedule deletion")
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	return &models.AccountDeletion{ScheduledAt: scheduledAt}, nil
+}
+
+func (s *AccountService) CancelDeletion(userID uint) error {
+	result := s.db.Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", userID).
+		Update("deletion_scheduled_at", nil)
+	if result.Error != nil {
+		return errors.New("failed to cancel deletion")
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeletionNotScheduled
+	}
+	return nil
+}
+
+// PurgeDeletedAccounts deletes every account whose deletion date has passed
+// and returns how many were removed.
+func (s *AccountService) PurgeDeletedAccounts(now time.Time) (int, error) {
+	var ids []uint
+	if err := s.db.Unscoped().Model(&models.User{}).
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", now).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, errors.New("failed to load accounts due for deletion")
+	}
+
+	for i, id := range ids {
+		if err := s.deleteAccount(id); err != nil {
+			return i, err
+		}
+	}
+	return len(ids), nil
+}
+
+// deleteAccount removes a user and everything that identifies them.
+// Workspaces they were alone in are deleted with their links and click
+// history; links they made in shared workspaces stay with the workspace but
+// lose their creator, and their click events are rolled up into daily totals
+// so no visitor history tied to the user is left. Rows are removed outright
+// rather than soft-deleted.
+func (s *AccountService) deleteAccount(userID uint) error {
+	if s.sessions != nil {
+		if _, err := s.sessions.RevokeUserSessions(userID); err != nil {
+			return errors.New("failed to revoke sessions")
+		}
+	}
+
+	var user models.User
+	if err := s.db.Unscoped().First(&user, userID).Error; err != nil {
+		return errors.New("user not found")
+	}
+	var exports []models.DataExport
+	s.db.Where("user_id = ?", userID).Find(&exports)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var memberships []models.WorkspaceMember
+		if err := tx.Where("user_id = ?", userID).Find(&memberships).Error; err != nil {
+			return err
+		}
+		for _, membership := range memberships {
+			if err := leaveWorkspace(tx, membership); err != nil {
+				return err
+			}
+		}
+
+		orphaned := tx.Unscoped().Model(&models.URL{}).Select("id").Where("user_id = ? AND workspace_id IS NULL", userID)
+		if err := purgeURLs(tx, orphaned); err != nil {
+			return err
+		}
+		var shared []uint
+		if err := tx.Unscoped().Model(&models.URL{}).Where("user_id = ?", userID).Pluck("id", &shared).Error; err != nil {
+			return err
+		}
+		if err := rollupURLs(tx, shared); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.URL{}).Where("user_id = ?", userID).Update("user_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Webhook{}).Where("created_by_id = ?", userID).Update("created_by_id", nil).Error; err != nil {
+			return err
+		}
+		if err := anonymizeAuditTrail(tx, &user); err != nil {
+			return err
+		}
+
+		deletes := []struct {
+			model interface{}
+			where string
+			args  []interface{}
+		}{
+			{&models.WorkspaceInvitation{}, "invited_by_id = ? OR LOWER(email) = LOWER(?)", []interface{}{userID, user.Email}},
+			{&models.APIToken{}, "user_id = ?", []interface{}{userID}},
+			{&models.UserIdentity{}, "user_id = ?", []interface{}{userID}},
+			{&models.Session{}, "user_id = ?", []interface{}{userID}},
+			{&models.DataExport{}, "user_id = ?", []interface{}{userID}},
+			{&models.UsageCounter{}, "user_id = ?", []interface{}{userID}},
+		}
+		for _, d := range deletes {
+			if err := tx.Unscoped().Where(d.where, d.args...).Delete(d.model).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Unscoped().Delete(&user).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete account %d", userID)
+	}
+
+	for _, export := range exports {
+		if export.FilePath != "" {
+			os.Remove(export.FilePath)
+		}
+	}
+
+	return nil
+}
+
+// anonymizeAuditTrail strips a deleted user from the records kept after
+// them: audit events they made or that were in their account's log, their
+// administrator actions, invitations sent to their address and the abuse
+// reports they filed. The rows stay so workspace and moderation history
+// still add up. UpdateColumns skips the audit log's append-only hooks.
+func anonymizeAuditTrail(tx *gorm.DB, user *models.User) error {
+	if err := tx.Model(&models.AuditEvent{}).Where("actor_id = ? OR account_id = ?", user.ID, user.ID).
+		UpdateColumns(map[string]interface{}{"ip_address": "", "user_agent": ""}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.AuditEvent{}).Where("actor_id = ?", user.ID).
+		UpdateColumns(map[string]interface{}{"actor_id": nil, "token_id": nil}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.AuditEvent{}).Where("account_id = ?", user.ID).
+		UpdateColumn("account_id", nil).Error; err != nil {
+		return err
+	}
+
+	// Invitations record the invited address among their changes.
+	var invitations []models.AuditEvent
+	if err := tx.Where(`action = ? AND LOWER(changes) LIKE ? ESCAPE '\'`, models.AuditInvitationCreate, "%"+likePrefix(strings.ToLower(user.Email))).
+		Find(&invitations).Error; err != nil {
+		return err
+	}
+	for _, event := range invitations {
+		email, _ := event.Changes["email"].To.(string)
+		if !strings.EqualFold(email, user.Email) {
+			continue
+		}
+		if err := tx.Model(&models.AuditEvent{}).Where("id = ?", event.ID).UpdateColumn("changes", nil).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&models.AdminAction{}).Where("admin_id = ?", user.ID).
+		UpdateColumns(map[string]interface{}{"admin_id": 0, "ip_address": ""}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.AbuseReport{}).Where("reporter_user_id = ?", user.ID).
+		UpdateColumns(map[string]interface{}{"reporter_user_id": nil, "reporter_ip": ""}).Error
+}
+
+// leaveWorkspace removes a departing user's membership. A workspace left
+// empty is deleted; one left without an owner passes to its longest-standing
+// member.
+func leaveWorkspace(tx *gorm.DB, membership models.WorkspaceMember) error {
+	var others []models.WorkspaceMember
+	if err := tx.Where("workspace_id = ? AND user_id <> ?", membership.WorkspaceID, membership.UserID).
+		Order("created_at, id").Find(&others).Error; err != nil {
+		return err
+	}
+
+	if len(others) == 0 {
+		links := tx.Unscoped().Model(&models.URL{}).Select("id").Where("workspace_id = ?", membership.WorkspaceID)
+		if err := purgeURLs(tx, links); err != nil {
+			return err
+		}
+		if err := deleteWorkspaceWebhooks(tx, membership.WorkspaceID); err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.WorkspaceInvitation{}, &models.Tag{}, &models.Folder{}} {
+			if err := tx.Where("workspace_id = ?", membership.WorkspaceID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("workspace_id = ?", membership.WorkspaceID).Delete(&models.WorkspaceMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id = ?", membership.WorkspaceID).Delete(&models.Workspace{}).Error
+	}
+
+	if membership.Role == models.RoleOwner {
+		hasOwner := false
+		for _, other := range others {
+			if other.Role == models.RoleOwner {
+				hasOwner = true
+				break
+			}
+		}
+		if !hasOwner {
+			if err := tx.Model(&others[0]).Update("role", models.RoleOwner).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Delete(&membership).Error
+}
+
+// purgeURLs permanently deletes the selected links with their click history.
+func purgeURLs(tx *gorm.DB, urlIDs *gorm.DB) error {
+	var ids []uint
+	if err := urlIDs.Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := tx.Unscoped().Where("url_id IN ?", ids).Delete(&models.Analytics{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("url_id IN ?", ids).Delete(&models.AnalyticsDaily{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("url_id IN ?", ids).Delete(&models.AbuseReport{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("url_id IN ?", ids).Delete(&models.URLRevision{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("url_id IN ?", ids).Delete(&models.URLTag{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("id IN ?", ids).Delete(&models.URL{}).Error
+}
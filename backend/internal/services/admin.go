@@ -0,0 +1,440 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserNotFound           = errors.New("user not found")
+	ErrReasonRequired         = errors.New("a reason is required")
+	ErrCannotModerateSelf     = errors.New("administrators cannot disable their own account")
+	ErrCannotImpersonateAdmin = errors.New("administrators cannot be impersonated")
+)
+
+// AdminService backs the moderation API. Every change an administrator
+// makes is recorded as an AdminAction in the same transaction.
+type AdminService struct {
+	db       *gorm.DB
+	config   *config.Config
+	sessions SessionRevoker
+}
+
+func NewAdminService(cfg *config.Config) *AdminService {
+	return &AdminService{
+		db:     database.GetDB(),
+		config: cfg,
+	}
+}
+
+// SetSessionRevoker lets disabling a user end their sessions.
+func (s *AdminService) SetSessionRevoker(sessions SessionRevoker) {
+	s.sessions = sessions
+}
+
+func (s *AdminService) isBootstrapEmail(email string) bool {
+	email = strings.ToLower(email)
+	for _, admin := range s.config.AdminEmails {
+		if admin == email {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin reports whether the user may use the moderation API. Users listed
+// in ADMIN_EMAILS are promoted the first time they are checked, provided
+// their address is verified.
+func (s *AdminService) IsAdmin(userID uint) bool {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return false
+	}
+	if user.DisabledAt != nil {
+		return false
+	}
+	if user.IsAdmin {
+		return true
+	}
+
+	if user.EmailVerifiedAt == nil || !s.isBootstrapEmail(user.Email) {
+		return false
+	}
+	return s.promote(&user) == nil
+}
+
+// BootstrapAdmins promotes the existing verified accounts listed in
+// ADMIN_EMAILS. It is safe to run on every start.
+func (s *AdminService) BootstrapAdmins() (int, error) {
+	if len(s.config.AdminEmails) == 0 {
+		return 0, nil
+	}
+
+	var users []models.User
+	if err := s.db.Where("email IN ? AND is_admin = ? AND email_verified_at IS NOT NULL", s.config.AdminEmails, false).
+		Find(&users).Error; err != nil {
+		return 0, errors.New("failed to load users")
+	}
+
+	for i := range users {
+		if err := s.promote(&users[i]); err != nil {
+			return i, err
+		}
+	}
+
+	return len(users), nil
+}
+
+func (s *AdminService) promote(user *models.User) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("is_admin", true).Error; err != nil {
+			return err
+		}
+		return recordAdminAction(tx, &models.AdminAction{
+			AdminID:    user.ID,
+			Action:     models.AdminActionBootstrap,
+			TargetType: models.AdminTargetUser,
+			TargetID:   user.ID,
+			Reason:     "listed in ADMIN_EMAILS",
+		})
+	})
+	if err != nil {
+		return errors.New("failed to promote administrator")
+	}
+
+	user.IsAdmin = true
+	return nil
+}
+
+// likePattern turns a search term into a case-insensitive LIKE pattern that
+// matches the term literally.
+func likePattern(q string) string {
+	q = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(strings.TrimSpace(q)))
+	return "%" + q + "%"
+}
+
+// SearchUsers finds accounts by email or name.
+func (s *AdminService) SearchUsers(q string, limit, offset int) ([]models.User, int64, error) {
+	query := s.db.Model(&models.User{})
+	if strings.TrimSpace(q) != "" {
+		pattern := likePattern(q)
+		query = query.Where(`LOWER(email) LIKE ? ESCAPE '\' OR LOWER(name) LIKE ? ESCAPE '\'`, pattern, pattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("failed to count users")
+	}
+
+	var users []models.User
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		return nil, 0, errors.New("failed to fetch users")
+	}
+
+	return users, total, nil
+}
+
+func (s *AdminService) GetUser(userID uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, errors.New("database error")
+	}
+	return &user, nil
+}
+
+// SearchURLs finds links across all accounts by short code, alias,
+// destination or title.
+func (s *AdminService) SearchURLs(q string, limit, offset int) ([]models.URL, int64, error) {
+	query := s.db.Model(&models.URL{})
+	if strings.TrimSpace(q) != "" {
+		pattern := likePattern(q)
+		query = query.Where(`LOWER(short_code) LIKE ? ESCAPE '\' OR LOWER(custom_alias) LIKE ? ESCAPE '\' OR `+
+			`LOWER(original_url) LIKE ? ESCAPE '\' OR LOWER(title) LIKE ? ESCAPE '\'`, pattern, pattern, pattern, pattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("failed to count URLs")
+	}
+
+	var urls []models.URL
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&urls).Error; err != nil {
+		return nil, 0, errors.New("failed to fetch URLs")
+	}
+
+	return urls, total, nil
+}
+
+// audited runs change and records the action in the same transaction.
+func (s *AdminService) audited(action *models.AdminAction, change func(tx *gorm.DB) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := change(tx); err != nil {
+			return err
+		}
+		return recordAdminAction(tx, action)
+	})
+}
+
+// DisableUser disables an account and ends its sessions.
+func (s *AdminService) DisableUser(adminID, userID uint, reason, ip string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return ErrReasonRequired
+	}
+	if adminID == userID {
+		return ErrCannotModerateSelf
+	}
+	if _, err := s.GetUser(userID); err != nil {
+		return err
+	}
+
+	action := &models.AdminAction{
+		AdminID:    adminID,
+		Action:     models.AdminActionDisableUser,
+		TargetType: models.AdminTargetUser,
+		TargetID:   userID,
+		Reason:     reason,
+		IPAddress:  ip,
+	}
+	err := s.audited(action, func(tx *gorm.DB) error {
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"disabled_at":     time.Now(),
+			"disabled_reason": reason,
+		}).Error
+	})
+	if err != nil {
+		return errors.New("failed to disable user")
+	}
+
+	if s.sessions != nil {
+		if _, err := s.sessions.RevokeUserSessions(userID); err != nil {
+			return errors.New("failed to revoke sessions")
+		}
+	}
+
+	return nil
+}
+
+func (s *AdminService) EnableUser(adminID, userID uint, ip string) error {
+	if _, err := s.GetUser(userID); err != nil {
+		return err
+	}
+
+	action := &models.AdminAction{
+		AdminID:    adminID,
+		Action:     models.AdminActionEnableUser,
+		TargetType: models.AdminTargetUser,
+		TargetID:   userID,
+		IPAddress:  ip,
+	}
+	err := s.audited(action, func(tx *gorm.DB) error {
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"disabled_at":     nil,
+			"disabled_reason": "",
+		}).Error
+	})
+	if err != nil {
+		return errors.New("failed to enable user")
+	}
+
+	return nil
+}
+
+// SetUserPlan moves an account to another plan. The new limits apply to
+// usage already counted this month.
+func (s *AdminService) SetUserPlan(adminID, userID uint, code, ip string) error {
+	code = strings.TrimSpace(code)
+	plans, err := allPlans(s.db)
+	if err != nil {
+		return err
+	}
+	known := false
+	for _, plan := range plans {
+		known = known || plan.Code == code
+	}
+	if !known {
+		return ErrUnknownPlan
+	}
+	if _, err := s.GetUser(userID); err != nil {
+		return err
+	}
+
+	action := &models.AdminAction{
+		AdminID:    adminID,
+		Action:     models.AdminActionChangePlan,
+		TargetType: models.AdminTargetUser,
+		TargetID:   userID,
+		Reason:     "plan: " + code,
+		IPAddress:  ip,
+	}
+	err = s.audited(action, func(tx *gorm.DB) error {
+		return tx.Model(&models.User{}).Where("id = ?", userID).Update("plan_code", code).Error
+	})
+	if err != nil {
+		return errors.New("failed to change plan")
+	}
+
+	return nil
+}
+
+// DisableURL takes a link down so it no longer redirects.
+func (s *AdminService) DisableURL(adminID, urlID uint, reason, ip string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return ErrReasonRequired
+	}
+
+	return s.setURLDisabled(urlID, map[string]interface{}{
+		"is_active":       false,
+		"disabled_at":     time.Now(),
+		"disabled_reason": reason,
+	}, &models.AdminAction{
+		AdminID:    adminID,
+		Action:     models.AdminActionDisableURL,
+		TargetType: models.AdminTargetURL,
+		TargetID:   urlID,
+		Reason:     reason,
+		IPAddress:  ip,
+	})
+}
+
+func (s *AdminService) EnableURL(adminID, urlID uint, ip string) error {
+	return s.setURLDisabled(urlID, map[string]interface{}{
+		"is_active":       true,
+		"disabled_at":     nil,
+		"disabled_reason": "",
+	}, &models.AdminAction{
+		AdminID:    adminID,
+		Action:     models.AdminActionEnableURL,
+		TargetType: models.AdminTargetURL,
+		TargetID:   urlID,
+		IPAddress:  ip,
+	})
+}
+
+func (s *AdminService) setURLDisabled(urlID uint, updates map[string]interface{}, action *models.AdminAction) error {
+	err := s.audited(action, func(tx *gorm.DB) error {
+		result := tx.Model(&models.URL{}).Where("id = ?", urlID).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrURLNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, ErrURLNotFound) {
+		return err
+	}
+	if err != nil {
+		return errors.New("failed to update URL")
+	}
+
+	return nil
+}
+
+// StartImpersonation checks that the admin may view the account and records
+// that they are doing so. The caller creates the read-only session.
+func (s *AdminService) StartImpersonation(adminID, userID uint, reason, ip string) (*models.User, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsAdmin {
+		return nil, ErrCannotImpersonateAdmin
+	}
+
+	action := &models.AdminAction{
+		AdminID:    adminID,
+		Action:     models.AdminActionImpersonate,
+		TargetType: models.AdminTargetUser,
+		TargetID:   userID,
+		Reason:     reason,
+		IPAddress:  ip,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return recordAdminAction(tx, action)
+	})
+	if err != nil {
+		return nil, errors.New("failed to record impersonation")
+	}
+
+	return user, nil
+}
+
+func (s *AdminService) Stats() (*models.PlatformStats, error) {
+	stats := &models.PlatformStats{}
+	weekAgo := time.Now().AddDate(0, 0, -7)
+	dayAgo := time.Now().Add(-24 * time.Hour)
+
+	counts := []struct {
+		dest  *int64
+		query *gorm.DB
+	}{
+		{&stats.Users, s.db.Model(&models.User{})},
+		{&stats.DisabledUsers, s.db.Model(&models.User{}).Where("disabled_at IS NOT NULL")},
+		{&stats.Admins, s.db.Model(&models.User{}).Where("is_admin = ?", true)},
+		{&stats.NewUsers7d, s.db.Model(&models.User{}).Where("created_at >= ?", weekAgo)},
+		{&stats.URLs, s.db.Model(&models.URL{})},
+		{&stats.DisabledURLs, s.db.Model(&models.URL{}).Where("disabled_at IS NOT NULL")},
+		{&stats.QuarantinedURLs, s.db.Model(&models.URL{}).Where("quarantined_at IS NOT NULL")},
+		{&stats.OpenReports, s.db.Model(&models.AbuseReport{}).Where("status = ?", models.ReportStatusOpen)},
+		{&stats.NewURLs7d, s.db.Model(&models.URL{}).Where("created_at >= ?", weekAgo)},
+		{&stats.Workspaces, s.db.Model(&models.Workspace{})},
+		{&stats.Clicks24h, s.db.Model(&models.Analytics{}).Where("clicked_at >= ?", dayAgo)},
+	}
+	for _, count := range counts {
+		if err := count.query.Count(count.dest).Error; err != nil {
+			return nil, errors.New("failed to compute stats")
+		}
+	}
+
+	var raw, rolledUp int64
+	if err := s.db.Model(&models.Analytics{}).Count(&raw).Error; err != nil {
+		return nil, errors.New("failed to compute stats")
+	}
+	if err := s.db.Model(&models.AnalyticsDaily{}).Select("COALESCE(SUM(clicks), 0)").Scan(&rolledUp).Error; err != nil {
+		return nil, errors.New("failed to compute stats")
+	}
+	stats.TotalClicks = raw + rolledUp
+
+	return stats, nil
+}
+
+// ListActions returns the admin audit trail, newest first, optionally for a
+// single target.
+func (s *AdminService) ListActions(targetType string, targetID uint, limit, offset int) ([]models.AdminAction, int64, error) {
+	query := s.db.Model(&models.AdminAction{})
+	if targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID != 0 {
+		query = query.Where("target_id = ?", targetID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("failed to count actions")
+	}
+
+	var actions []models.AdminAction
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&actions).Error; err != nil {
+		return nil, 0, errors.New("failed to fetch actions")
+	}
+
+	return actions, total, nil
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	overviewTopLimit     = 10
+	maxOverviewRangeDays = 366
+)
+
+// AnalyticsService aggregates click data across all links of an account.
+// Totals combine raw events with the daily rollups left behind by the
+// retention job, so purged periods still count.
+type AnalyticsService struct {
+	db *gorm.DB
+}
+
+func NewAnalyticsService() *AnalyticsService {
+	return &AnalyticsService{
+		db: database.GetDB(),
+	}
+}
+
+// userURLIDs is the subquery selecting the links an overview covers: those
+// in every workspace the user belongs to.
+func (s *AnalyticsService) userURLIDs(userID uint) *gorm.DB {
+	return s.db.Model(&models.URL{}).Select("id").Where("workspace_id IN (?)", memberWorkspaceIDs(s.db, userID, models.RoleViewer))
+}
+
+// checkRange normalizes a reporting period to UTC and checks its bounds.
+func checkRange(from, to time.Time) (time.Time, time.Time, error) {
+	from, to = from.UTC(), to.UTC()
+	if !from.Before(to) {
+		return from, to, errors.New("from must be before to")
+	}
+	if to.Sub(from) > maxOverviewRangeDays*24*time.Hour {
+		return from, to, errors.New("date range cannot exceed 366 days")
+	}
+	return from, to, nil
+}
+
+func (s *AnalyticsService) GetOverview(userID uint, from, to time.Time) (*models.AnalyticsOverview, error) {
+	from, to, err := checkRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	urlIDs := s.userURLIDs(userID)
+	overview := &models.AnalyticsOverview{}
+
+	current, err := s.periodTotals(urlIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+	overview.PeriodTotals = *current
+
+	previous, err := s.periodTotals(urlIDs, from.Add(-to.Sub(from)), from)
+	if err != nil {
+		return nil, err
+	}
+	overview.Previous = *previous
+	overview.TotalClicksChange = percentChange(previous.TotalClicks, current.TotalClicks)
+	overview.UniqueClicksChange = percentChange(previous.UniqueClicks, current.UniqueClicks)
+
+	if overview.TopLinks, err = s.topLinks(urlIDs, from, to); err != nil {
+		return nil, err
+	}
+	if overview.TopReferrers, err = s.topReferrers(urlIDs, from, to); err != nil {
+		return nil, err
+	}
+	if overview.Devices, err = s.deviceMix(urlIDs, from, to); err != nil {
+		return nil, err
+	}
+	if overview.DailyTrend, err = s.dailyTrend(urlIDs, from, to); err != nil {
+		return nil, err
+	}
+
+	return overview, nil
+}
+
+// GetTagStats aggregates the clicks on every live link carrying a tag.
+func (s *AnalyticsService) GetTagStats(tagID, userID uint, from, to time.Time) (*models.TagStats, error) {
+	from, to, err := checkRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := getMemberTag(s.db, tagID, userID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	tagged := "id IN (SELECT url_id FROM url_tags WHERE tag_id = ?)"
+	urlIDs := s.db.Model(&models.URL{}).Select("id").Where(tagged, tagID)
+	stats := &models.TagStats{TagID: tagID}
+	if err := s.db.Model(&models.URL{}).Where(tagged, tagID).Count(&stats.Links).Error; err != nil {
+		return nil, errors.New("failed to count tagged links")
+	}
+
+	totals, err := s.periodTotals(urlIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+	stats.PeriodTotals = *totals
+	if stats.TopLinks, err = s.topLinks(urlIDs, from, to); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (s *AnalyticsService) periodTotals(urlIDs *gorm.DB, from, to time.Time) (*models.PeriodTotals, error) {
+	totals := &models.PeriodTotals{From: from, To: to}
+
+	query := `
+		SELECT COALESCE(SUM(t.clicks), 0) as total_clicks, COALESCE(SUM(t.unique_clicks), 0) as unique_clicks
+		FROM (
+			SELECT COUNT(a.id) as clicks, COUNT(DISTINCT a.ip_address) as unique_clicks
+			FROM analytics a
+			WHERE a.url_id IN (?) AND a.clicked_at >= ? AND a.clicked_at < ? AND a.deleted_at IS NULL
+			UNION ALL
+			SELECT SUM(d.clicks), SUM(d.unique_clicks)
+			FROM analytics_daily d
+			WHERE d.url_id IN (?) AND d.date >= ? AND d.date < ?
+		) t
+	`
+
+	if err := s.db.Raw(query, urlIDs, from, to, urlIDs, from, to).Scan(totals).Error; err != nil {
+		return nil, errors.New("failed to fetch click totals")
+	}
+
+	totals.From, totals.To = from, to
+	return totals, nil
+}
+
+func (s *AnalyticsService) topLinks(urlIDs *gorm.DB, from, to time.Time) ([]models.LinkClickStat, error) {
+	stats := []models.LinkClickStat{}
+
+	query := `
+		SELECT u.id as url_id, u.short_code, u.title, SUM(t.clicks) as clicks
+		FROM (
+			SELECT a.url_id, COUNT(a.id) as clicks
+			FROM analytics a
+			WHERE a.url_id IN (?) AND a.clicked_at >= ? AND a.clicked_at < ? AND a.deleted_at IS NULL
+			GROUP BY a.url_id
+			UNION ALL
+			SELECT d.url_id, SUM(d.clicks)
+			FROM analytics_daily d
+			WHERE d.url_id IN (?) AND d.date >= ? AND d.date < ?
+			GROUP BY d.url_id
+		) t
+		JOIN urls u ON u.id = t.url_id
+		GROUP BY u.id, u.short_code, u.title
+		ORDER BY clicks DESC
+		LIMIT ?
+	`
+
+	if err := s.db.Raw(query, urlIDs, from, to, urlIDs, from, to, overviewTopLimit).Scan(&stats).Error; err != nil {
+		return nil, errors.New("failed to fetch top links")
+	}
+
+	return stats, nil
+}
+
+func (s *AnalyticsService) topReferrers(urlIDs *gorm.DB, from, to time.Time) ([]models.ReferrerStat, error) {
+	stats := []models.ReferrerStat{}
+
+	query := `
+		SELECT a.referrer_domain as domain, a.traffic_source as source, COUNT(a.id) as clicks
+		FROM analytics a
+		WHERE a.url_id IN (?) AND a.clicked_at >= ? AND a.clicked_at < ? AND a.deleted_at IS NULL
+			AND a.referrer_domain <> ''
+		GROUP BY a.referrer_domain, a.traffic_source
+		ORDER BY clicks DESC
+		LIMIT ?
+	`
+
+	if err := s.db.Raw(query, urlIDs, from, to, overviewTopLimit).Scan(&stats).Error; err != nil {
+		return nil, errors.New("failed to fetch top referrers")
+	}
+
+	return stats, nil
+}
+
+func (s *AnalyticsService) deviceMix(urlIDs *gorm.DB, from, to time.Time) ([]models.DeviceStat, error) {
+	stats := []models.DeviceStat{}
+
+	query := `
+		SELECT COALESCE(NULLIF(a.device, ''), 'unknown') as device, COUNT(a.id) as clicks
+		FROM analytics a
+		WHERE a.url_id IN (?) AND a.clicked_at >= ? AND a.clicked_at < ? AND a.deleted_at IS NULL
+		GROUP BY device
+		ORDER BY clicks DESC
+	`
+
+	if err := s.db.Raw(query, urlIDs, from, to).Scan(&stats).Error; err != nil {
+		return nil, errors.New("failed to fetch device mix")
+	}
+
+	return stats, nil
+}
+
+// dailyTrend returns one entry per UTC day in [from, to), including days
+// without clicks.
+func (s *AnalyticsService) dailyTrend(urlIDs *gorm.DB, from, to time.Time) ([]models.DailyClicks, error) {
+	var rows []models.DailyClicks
+
+	clickedDay, rolledUpDay := s.utcDay("a.clicked_at"), s.utcDay("d.date")
+	query := `
+		SELECT t.date, SUM(t.clicks) as clicks, SUM(t.unique_clicks) as unique_clicks
+		FROM (
+			SELECT ` + clickedDay + ` as date, COUNT(a.id) as clicks, COUNT(DISTINCT a.ip_address) as unique_clicks
+			FROM analytics a
+			WHERE a.url_id IN (?) AND a.clicked_at >= ? AND a.clicked_at < ? AND a.deleted_at IS NULL
+			GROUP BY ` + clickedDay + `
+			UNION ALL
+			SELECT ` + rolledUpDay + ` as date, SUM(d.clicks), SUM(d.unique_clicks)
+			FROM analytics_daily d
+			WHERE d.url_id IN (?) AND d.date >= ? AND d.date < ?
+			GROUP BY ` + rolledUpDay + `
+		) t
+		GROUP BY t.date
+	`
+
+	if err := s.db.Raw(query, urlIDs, from, to, urlIDs, from, to).Scan(&rows).Error; err != nil {
+		return nil, errors.New("failed to fetch daily trend")
+	}
+
+	byDate := make(map[string]models.DailyClicks, len(rows))
+	for _, row := range rows {
+		byDate[row.Date] = row
+	}
+
+	trend := []models.DailyClicks{}
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	for day := start; day.Before(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		if row, ok := byDate[date]; ok {
+			trend = append(trend, row)
+		} else {
+			trend = append(trend, models.DailyClicks{Date: date})
+		}
+	}
+
+	return trend, nil
+}
+
+// utcDay returns the UTC calendar day of a timestamp column as YYYY-MM-DD.
+// Postgres would otherwise take the day in the session's time zone; SQLite
+// converts stored offsets to UTC itself.
+func (s *AnalyticsService) utcDay(column string) string {
+	if s.db.Dialector.Name() == "postgres" {
+		column += " AT TIME ZONE 'UTC'"
+	}
+	return "CAST(DATE(" + column + ") AS TEXT)"
+}
+
+func percentChange(previous, current int64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+
+	change := math.Round(float64(current-previous)/float64(previous)*1000) / 10
+	return &change
+}
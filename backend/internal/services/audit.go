@@ -0,0 +1,191 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditService records changes and authentication events, and lists them
+// for the accounts and workspaces they belong to.
+type AuditService struct {
+	db *gorm.DB
+}
+
+func NewAuditService() *AuditService {
+	return &AuditService{
+		db: database.GetDB(),
+	}
+}
+
+// recordAudit writes an event made by actor. Pass the transaction making
+// the change so the event is only kept if the change is. The event belongs
+// to the actor's account unless it already names one.
+func recordAudit(db *gorm.DB, actor models.Actor, event *models.AuditEvent) error {
+	event.ActorType = actor.Type
+	if event.ActorType == "" {
+		event.ActorType = models.ActorAnonymous
+		if actor.UserID != nil {
+			event.ActorType = models.ActorUser
+		}
+	}
+	event.ActorID = actor.UserID
+	event.TokenID = actor.TokenID
+	event.IPAddress = actor.IPAddress
+	event.UserAgent = actor.UserAgent
+	if event.AccountID == nil {
+		event.AccountID = actor.UserID
+	}
+	return db.Create(event).Error
+}
+
+// recordAdminAction writes an administrator's action to the admin trail and
+// to the audit log of the account, or the link's account and workspace,
+// that it affected.
+func recordAdminAction(tx *gorm.DB, action *models.AdminAction) error {
+	if err := tx.Create(action).Error; err != nil {
+		return err
+	}
+
+	event := &models.AuditEvent{
+		Action:     action.Action,
+		TargetType: action.TargetType,
+		TargetID:   action.TargetID,
+		Reason:     action.Reason,
+	}
+	switch action.TargetType {
+	case models.AdminTargetUser:
+		event.AccountID = &action.TargetID
+	case models.AdminTargetURL:
+		var url models.URL
+		if err := tx.Unscoped().Select("id", "user_id", "workspace_id").Where("id = ?", action.TargetID).First(&url).Error; err != nil {
+			return err
+		}
+		event.AccountID = url.UserID
+		event.WorkspaceID = url.WorkspaceID
+	}
+
+	adminID := action.AdminID
+	return recordAudit(tx, models.Actor{
+		Type:      models.ActorAdmin,
+		UserID:    &adminID,
+		IPAddress: action.IPAddress,
+	}, event)
+}
+
+// Record writes an event for a change that has already been made, or for
+// an event with no change behind it such as a sign-in. A failure is logged
+// rather than returned, since the action itself has succeeded.
+func (s *AuditService) Record(actor models.Actor, event *models.AuditEvent) {
+	if err := recordAudit(s.db, actor, event); err != nil {
+		log.Printf("Failed to record audit event %s: %v", event.Action, err)
+	}
+}
+
+// ListAccountEvents returns the events in a user's audit log, newest first.
+func (s *AuditService) ListAccountEvents(userID uint, filter *models.AuditFilter, limit, offset int) ([]models.AuditEvent, int64, error) {
+	return s.list(s.db.Where("account_id = ?", userID), filter, limit, offset)
+}
+
+// ListWorkspaceEvents returns the events in a workspace's audit log, newest
+// first. Only workspace admins may read it.
+func (s *AuditService) ListWorkspaceEvents(workspaceID, userID uint, filter *models.AuditFilter, limit, offset int) ([]models.AuditEvent, int64, error) {
+	if _, err := requireRole(s.db, workspaceID, userID, models.RoleAdmin); err != nil {
+		return nil, 0, err
+	}
+	return s.list(s.db.Where("workspace_id = ?", workspaceID), filter, limit, offset)
+}
+
+func (s *AuditService) list(query *gorm.DB, filter *models.AuditFilter, limit, offset int) ([]models.AuditEvent, int64, error) {
+	query = query.Model(&models.AuditEvent{})
+	if filter.Action != "" {
+		if strings.HasSuffix(filter.Action, ".") {
+			query = query.Where(`action LIKE ? ESCAPE '\'`, likePrefix(filter.Action))
+		} else {
+			query = query.Where("action = ?", filter.Action)
+		}
+	}
+	if filter.ActorType != "" {
+		query = query.Where("actor_type = ?", filter.ActorType)
+	}
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("failed to count audit events")
+	}
+
+	var events []models.AuditEvent
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&events).Error; err != nil {
+		return nil, 0, errors.New("failed to fetch audit events")
+	}
+
+	return events, total, nil
+}
+
+// likePrefix turns a prefix into a LIKE pattern that matches it literally.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
+
+// linkAuditFields returns the fields of a link that the audit log tracks,
+// leaving out empty ones.
+func linkAuditFields(url *models.URL) map[string]interface{} {
+	fields := map[string]interface{}{
+		"original_url": url.OriginalURL,
+		"short_code":   url.ShortCode,
+		"custom_alias": url.CustomAlias,
+		"title":        url.Title,
+		"description":  url.Description,
+		"is_active":    url.IsActive,
+	}
+	for name, value := range fields {
+		if value == "" {
+			delete(fields, name)
+		}
+	}
+	if url.ExpiresAt != nil {
+		fields["expires_at"] = url.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	if url.WorkspaceID != nil {
+		fields["workspace_id"] = *url.WorkspaceID
+	}
+	return fields
+}
+
+// auditDiff lists the fields whose values differ between before and after.
+// A field missing from one side is recorded as nil.
+func auditDiff(before, after map[string]interface{}) map[string]models.AuditChange {
+	changes := make(map[string]models.AuditChange)
+	for name, from := range before {
+		if to, ok := after[name]; !ok || !reflect.DeepEqual(from, to) {
+			changes[name] = models.AuditChange{From: from, To: after[name]}
+		}
+	}
+	for name, to := range after {
+		if _, ok := before[name]; !ok {
+			changes[name] = models.AuditChange{To: to}
+		}
+	}
+	return changes
+}
@@ -2,14 +2,30 @@ package services
 
 import (
 	"errors"
+	"time"
 	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/identity"
 	"url-shortener-backend/internal/models"
 
 	"gorm.io/gorm"
 )
 
+var (
+	ErrAccountDisabled  = errors.New("account is disabled")
+	ErrEmailNotVerified = errors.New("email address is not verified by the identity provider")
+	ErrIdentityInUse    = errors.New("identity is already linked to another account")
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrLastIdentity     = errors.New("cannot unlink the only sign-in method")
+)
+
+// SessionRevoker ends every login session of a user.
+type SessionRevoker interface {
+	RevokeUserSessions(userID uint) (int64, error)
+}
+
 type AuthService struct {
-	db *gorm.DB
+	db       *gorm.DB
+	sessions SessionRevoker
 }
 
 func NewAuthService() *AuthService {
@@ -18,6 +34,10 @@ func NewAuthService() *AuthService {
 	}
 }
 
+// SetSessionRevoker lets DisableUser log the user out everywhere.
+func (s *AuthService) SetSessionRevoker(sessions SessionRevoker) {
+	s.sessions = sessions
+}
 
 func (s *AuthService) GetUserByID(userID uint) (*models.User, error) {
 	var user models.User
@@ -31,34 +51,180 @@ func (s *AuthService) GetUserByID(userID uint) (*models.User, error) {
 	return &user, nil
 }
 
-func (s *AuthService) LoginOrRegisterOAuth(email, name, picture string) (*models.User, error) {
+// LoginWithIdentity resolves the user behind a provider identity. Known
+// identities log straight in. A new identity is linked to an existing account
+// only when the provider has verified the matching email; otherwise the user
+// must link it explicitly from their account. New accounts likewise require
+// a verified email.
+func (s *AuthService) LoginWithIdentity(info *identity.UserInfo) (*models.User, error) {
+	if info.Provider == "" || info.Subject == "" {
+		return nil, errors.New("identity is missing provider or subject")
+	}
+
+	var linked models.UserIdentity
+	err := s.db.Preload("User").
+		Where("provider = ? AND subject = ?", info.Provider, info.Subject).
+		First(&linked).Error
+	if err == nil {
+		user := linked.User
+		if user == nil {
+			return nil, errors.New("user not found")
+		}
+		if user.DisabledAt != nil {
+			return nil, ErrAccountDisabled
+		}
+
+		now := time.Now()
+		s.db.Model(&linked).Updates(map[string]interface{}{"last_login_at": now, "email": info.Email})
+		if info.Picture != "" && user.Picture != info.Picture {
+			s.db.Model(user).Update("picture", info.Picture)
+		}
+		return user, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("database error")
+	}
+
+	if !info.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
 	var user models.User
-	
-	// Try to find existing user
-	err := s.db.Where("email = ?", email).First(&user).Error
+	err = s.db.Where("email = ?", info.Email).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("database error")
+	}
+
+	if err == nil && user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if user.ID == 0 {
+			user = models.User{
+				Email:           info.Email,
+				Name:            info.Name,
+				Picture:         info.Picture,
+				EmailVerifiedAt: &now,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return errors.New("failed to create user")
+			}
+		} else if user.EmailVerifiedAt == nil {
+			// Whoever set a password on this account never proved they own
+			// the email, so the password must not survive the real owner
+			// signing in.
+			updates := map[string]interface{}{"email_verified_at": now, "password_hash": ""}
+			if err := tx.Model(&user).Updates(updates).Error; err != nil {
+				return errors.New("database error")
+			}
+		}
+
+		return tx.Create(newIdentity(user.ID, info)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// LinkIdentity attaches a provider identity to a signed-in user.
+func (s *AuthService) LinkIdentity(userID uint, info *identity.UserInfo) (*models.UserIdentity, error) {
+	var existing models.UserIdentity
+	err := s.db.Where("provider = ? AND subject = ?", info.Provider, info.Subject).First(&existing).Error
 	if err == nil {
-		// User exists, update profile picture if provided
-		if picture != "" && user.Picture != picture {
-			user.Picture = picture
-			s.db.Save(&user)
+		if existing.UserID != userID {
+			return nil, ErrIdentityInUse
 		}
-		return &user, nil
+		return &existing, nil
 	}
-	
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, errors.New("database error")
 	}
-	
-	// User doesn't exist, create new user
-	user = models.User{
-		Email:   email,
-		Name:    name,
-		Picture: picture,
+
+	linked := newIdentity(userID, info)
+	if err := s.db.Create(linked).Error; err != nil {
+		return nil, errors.New("failed to link identity")
 	}
-	
-	if err := s.db.Create(&user).Error; err != nil {
-		return nil, errors.New("failed to create user")
+
+	return linked, nil
+}
+
+func (s *AuthService) ListIdentities(userID uint) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error; err != nil {
+		return nil, errors.New("failed to fetch identities")
 	}
-	
-	return &user, nil
-}
\ No newline at end of file
+
+	return identities, nil
+}
+
+// UnlinkIdentity removes an identity unless it is the user's only way to
+// sign in.
+func (s *AuthService) UnlinkIdentity(userID, identityID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.UserIdentity{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return errors.New("database error")
+		}
+
+		var linked models.UserIdentity
+		if err := tx.Where("id = ? AND user_id = ?", identityID, userID).First(&linked).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrIdentityNotFound
+			}
+			return errors.New("database error")
+		}
+
+		var user models.User
+		if err := tx.Select("password_hash").Where("id = ?", userID).First(&user).Error; err != nil {
+			return errors.New("database error")
+		}
+
+		if count <= 1 && user.PasswordHash == "" {
+			return ErrLastIdentity
+		}
+
+		if err := tx.Delete(&linked).Error; err != nil {
+			return errors.New("failed to unlink identity")
+		}
+		return nil
+	})
+}
+
+func newIdentity(userID uint, info *identity.UserInfo) *models.UserIdentity {
+	now := time.Now()
+	return &models.UserIdentity{
+		UserID:      userID,
+		Provider:    info.Provider,
+		Subject:     info.Subject,
+		Email:       info.Email,
+		LastLoginAt: &now,
+	}
+}
+
+// DisableUser blocks the account from signing in and ends all its sessions.
+func (s *AuthService) DisableUser(userID uint) error {
+	result := s.db.Model(&models.User{}).
+		Where("id = ? AND disabled_at IS NULL", userID).
+		Update("disabled_at", time.Now())
+	if result.Error != nil {
+		return errors.New("failed to disable user")
+	}
+
+	if result.RowsAffected == 0 {
+		if _, err := s.GetUserByID(userID); err != nil {
+			return err
+		}
+	}
+
+	if s.sessions != nil {
+		if _, err := s.sessions.RevokeUserSessions(userID); err != nil {
+			return errors.New("failed to revoke sessions")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener-backend/internal/models"
+)
+
+const (
+	defaultClickEventLimit = 100
+	maxClickEventLimit     = 1000
+)
+
+// clickEventFields maps the selectable field names of the click event API to
+// their column and value.
+var clickEventFields = map[string]struct {
+	column string
+	value  func(a *models.Analytics) interface{}
+}{
+	"id":              {"id", func(a *models.Analytics) interface{} { return a.ID }},
+	"clicked_at":      {"clicked_at", func(a *models.Analytics) interface{} { return a.ClickedAt }},
+	"ip_address":      {"ip_address", func(a *models.Analytics) interface{} { return a.IPAddress }},
+	"user_agent":      {"user_agent", func(a *models.Analytics) interface{} { return a.UserAgent }},
+	"referrer":        {"referrer", func(a *models.Analytics) interface{} { return a.Referrer }},
+	"referrer_domain": {"referrer_domain", func(a *models.Analytics) interface{} { return a.ReferrerDomain }},
+	"traffic_source":  {"traffic_source", func(a *models.Analytics) interface{} { return a.TrafficSource }},
+	"country":         {"country", func(a *models.Analytics) interface{} { return a.Country }},
+	"city":            {"city", func(a *models.Analytics) interface{} { return a.City }},
+	"device":          {"device", func(a *models.Analytics) interface{} { return a.Device }},
+	"os":              {"os", func(a *models.Analytics) interface{} { return a.OS }},
+	"browser":         {"browser", func(a *models.Analytics) interface{} { return a.Browser }},
+	"is_bot":          {"is_bot", func(a *models.Analytics) interface{} { return a.IsBot }},
+	"revision":        {"revision", func(a *models.Analytics) interface{} { return a.Revision }},
+}
+
+var defaultClickEventFields = []string{
+	"id", "clicked_at", "referrer_domain", "traffic_source", "country", "city", "device", "os", "browser", "is_bot", "revision",
+}
+
+// ListClickEvents returns one page of raw click events for a link, newest
+// first, using keyset pagination on (clicked_at, id). The returned cursor is
+// empty on the last page.
+func (s *URLService) ListClickEvents(urlID uint, userID uint, filter *models.ClickEventFilter) ([]map[string]interface{}, string, error) {
+	if _, err := s.GetMemberURL(urlID, userID, models.RoleViewer); err != nil {
+		return nil, "", err
+	}
+
+	fields := filter.Fields
+	if len(fields) == 0 {
+		fields = defaultClickEventFields
+	}
+
+	// id and clicked_at are always loaded so the next cursor can be built.
+	columns := []string{"id", "clicked_at"}
+	for _, field := range fields {
+		spec, ok := clickEventFields[field]
+		if !ok {
+			return nil, "", fmt.Errorf("unknown field %q", field)
+		}
+		if spec.column != "id" && spec.column != "clicked_at" {
+			columns = append(columns, spec.column)
+		}
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultClickEventLimit
+	}
+	if limit > maxClickEventLimit {
+		limit = maxClickEventLimit
+	}
+
+	query := s.db.Model(&models.Analytics{}).Select(columns).Where("url_id = ?", urlID)
+
+	if filter.From != nil {
+		query = query.Where("clicked_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("clicked_at < ?", *filter.To)
+	}
+	if filter.Device != "" {
+		query = query.Where("device = ?", filter.Device)
+	}
+	if filter.OS != "" {
+		query = query.Where("os = ?", filter.OS)
+	}
+	if filter.Browser != "" {
+		query = query.Where("browser = ?", filter.Browser)
+	}
+	if filter.Country != "" {
+		query = query.Where("country = ?", filter.Country)
+	}
+	if filter.ReferrerDomain != "" {
+		query = query.Where("referrer_domain = ?", strings.ToLower(filter.ReferrerDomain))
+	}
+	if filter.IsBot != nil {
+		query = query.Where("is_bot = ?", *filter.IsBot)
+	}
+	if filter.Revision != 0 {
+		query = query.Where("revision = ?", filter.Revision)
+	}
+
+	if filter.Cursor != "" {
+		clickedAt, id, err := decodeClickCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query = query.Where("(clicked_at < ? OR (clicked_at = ? AND id < ?))", clickedAt, clickedAt, id)
+	}
+
+	var events []models.Analytics
+	if err := query.Order("clicked_at DESC, id DESC").Limit(limit + 1).Find(&events).Error; err != nil {
+		return nil, "", errors.New("failed to fetch click events")
+	}
+
+	var nextCursor string
+	if len(events) > limit {
+		events = events[:limit]
+		last := events[len(events)-1]
+		nextCursor = encodeClickCursor(last.ClickedAt, last.ID)
+	}
+
+	page := make([]map[string]interface{}, len(events))
+	for i := range events {
+		row := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			row[field] = clickEventFields[field].value(&events[i])
+		}
+		page[i] = row
+	}
+
+	return page, nextCursor, nil
+}
+
+func encodeClickCursor(clickedAt time.Time, id uint) string {
+	raw := strconv.FormatInt(clickedAt.UnixNano(), 10) + ":" + strconv.FormatUint(uint64(id), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeClickCursor(cursor string) (time.Time, uint, error) {
+	invalid := errors.New("invalid cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, invalid
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, invalid
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, invalid
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return time.Time{}, 0, invalid
+	}
+
+	return time.Unix(0, nanos).UTC(), uint(id), nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"sync"
+	"sync/atomic"
+	"url-shortener-backend/internal/models"
+)
+
+const defaultClickHubBuffer = 64
+
+// ClickSubscription receives the live click events of one link. Events that
+// arrive while the buffer is full are dropped and counted rather than
+// blocking the redirect path.
+type ClickSubscription struct {
+	URLID   uint
+	Events  <-chan models.ClickEvent
+	events  chan models.ClickEvent
+	dropped atomic.Int64
+}
+
+// TakeDropped returns the number of events dropped since the last call.
+func (s *ClickSubscription) TakeDropped() int64 {
+	return s.dropped.Swap(0)
+}
+
+// ClickHub is an in-process pub/sub hub fanning click events out to the
+// subscribers of each link.
+type ClickHub struct {
+	mu          sync.RWMutex
+	subscribers map[uint]map[*ClickSubscription]struct{}
+	bufferSize  int
+	closed      bool
+}
+
+func NewClickHub(bufferSize int) *ClickHub {
+	if bufferSize <= 0 {
+		bufferSize = defaultClickHubBuffer
+	}
+
+	return &ClickHub{
+		subscribers: make(map[uint]map[*ClickSubscription]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+func (h *ClickHub) Subscribe(urlID uint) *ClickSubscription {
+	events := make(chan models.ClickEvent, h.bufferSize)
+	sub := &ClickSubscription{URLID: urlID, Events: events, events: events}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(events)
+		return sub
+	}
+
+	if h.subscribers[urlID] == nil {
+		h.subscribers[urlID] = make(map[*ClickSubscription]struct{})
+	}
+	h.subscribers[urlID][sub] = struct{}{}
+
+	return sub
+}
+
+func (h *ClickHub) Unsubscribe(sub *ClickSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs, ok := h.subscribers[sub.URLID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscribers, sub.URLID)
+	}
+	close(sub.events)
+}
+
+// Publish delivers an event to every subscriber of its link without blocking.
+func (h *ClickHub) Publish(event models.ClickEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers[event.URLID] {
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Close ends every subscription so open streams can finish.
+func (h *ClickHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for urlID, subs := range h.subscribers {
+		for sub := range subs {
+			close(sub.events)
+		}
+		delete(h.subscribers, urlID)
+	}
+}
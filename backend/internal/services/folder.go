@@ -0,0 +1,296 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFolderNotFound = errors.New("folder not found")
+	ErrFolderExists   = errors.New("a folder with this name already exists here")
+	ErrInvalidFolder  = errors.New("folder name must be 1 to 100 characters")
+	ErrFolderCycle    = errors.New("a folder cannot be moved into itself or one of its subfolders")
+)
+
+type FolderService struct {
+	db    *gorm.DB
+	actor models.Actor
+}
+
+func NewFolderService() *FolderService {
+	return &FolderService{
+		db: database.GetDB(),
+	}
+}
+
+// WithActor returns a copy of the service that attributes its changes to
+// links to actor in the audit log. Without one, changes are attributed to
+// the user making them.
+func (s *FolderService) WithActor(actor models.Actor) *FolderService {
+	service := *s
+	service.actor = actor
+	return &service
+}
+
+func (s *FolderService) actorFor(userID uint) models.Actor {
+	if s.actor.Type != "" {
+		return s.actor
+	}
+	return models.Actor{UserID: &userID}
+}
+
+// moveLinks files the links in folderID, nil for none, and records the
+// change for each link that moved.
+func moveLinks(tx *gorm.DB, actor models.Actor, links *gorm.DB, folderID *uint) (int64, error) {
+	var urls []models.URL
+	if err := links.Select("id", "folder_id").Find(&urls).Error; err != nil {
+		return 0, err
+	}
+	if len(urls) == 0 {
+		return 0, nil
+	}
+
+	urlIDs := make([]uint, len(urls))
+	changes := make(map[uint]map[string]models.AuditChange)
+	for i, url := range urls {
+		urlIDs[i] = url.ID
+		if !sameFolder(url.FolderID, folderID) {
+			changes[url.ID] = map[string]models.AuditChange{"folder_id": {From: url.FolderID, To: folderID}}
+		}
+	}
+
+	result := tx.Unscoped().Model(&models.URL{}).Where("id IN ?", urlIDs).Update("folder_id", folderID)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, recordLinkChanges(tx, actor, changes)
+}
+
+func sameFolder(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// getMemberFolder loads a folder the user can see. Members whose role is
+// below minRole get ErrInsufficientRole rather than ErrFolderNotFound.
+func getMemberFolder(db *gorm.DB, folderID, userID uint, minRole string) (*models.Folder, error) {
+	var folder models.Folder
+	if err := db.Where("id = ? AND workspace_id IN (?)", folderID, memberWorkspaceIDs(db, userID, models.RoleViewer)).First(&folder).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFolderNotFound
+		}
+		return nil, errors.New("database error")
+	}
+
+	if _, err := requireRole(db, folder.WorkspaceID, userID, minRole); err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// folderSubtree returns the IDs of a folder and every folder beneath it.
+func folderSubtree(db *gorm.DB, root *models.Folder) ([]uint, error) {
+	var folders []models.Folder
+	if err := db.Select("id", "parent_id").Where("workspace_id = ?", root.WorkspaceID).Find(&folders).Error; err != nil {
+		return nil, errors.New("database error")
+	}
+
+	children := make(map[uint][]uint)
+	for _, folder := range folders {
+		if folder.ParentID != nil {
+			children[*folder.ParentID] = append(children[*folder.ParentID], folder.ID)
+		}
+	}
+
+	ids := []uint{root.ID}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids, nil
+}
+
+// checkParent checks that parentID, if set, is a folder in the workspace
+// that is not folderID or beneath it.
+func (s *FolderService) checkParent(workspaceID uint, parentID *uint, folderID uint) error {
+	if parentID == nil {
+		return nil
+	}
+
+	// Walk up from the new parent; reaching the folder itself means the
+	// move would create a cycle.
+	for id := *parentID; ; {
+		if id == folderID {
+			return ErrFolderCycle
+		}
+		var parent models.Folder
+		if err := s.db.Select("id", "parent_id").Where("id = ? AND workspace_id = ?", id, workspaceID).First(&parent).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFolderNotFound
+			}
+			return errors.New("database error")
+		}
+		if parent.ParentID == nil {
+			return nil
+		}
+		id = *parent.ParentID
+	}
+}
+
+// checkFolderName rejects a name already used, in any case, by a sibling.
+func (s *FolderService) checkFolderName(workspaceID uint, parentID *uint, name string, exceptID uint) error {
+	query := s.db.Model(&models.Folder{}).
+		Where("workspace_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", workspaceID, name, exceptID)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return errors.New("database error")
+	}
+	if count > 0 {
+		return ErrFolderExists
+	}
+	return nil
+}
+
+func folderName(req *models.FolderRequest) (string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return "", ErrInvalidFolder
+	}
+	return name, nil
+}
+
+// ListFolders returns the folders in every workspace the user belongs to,
+// or in just one, as a flat list ordered by name. ParentID links them into
+// a tree.
+func (s *FolderService) ListFolders(userID uint, workspaceID *uint) ([]models.Folder, error) {
+	query := s.db.Where("workspace_id IN (?)", memberWorkspaceIDs(s.db, userID, models.RoleViewer))
+	if workspaceID != nil {
+		query = query.Where("workspace_id = ?", *workspaceID)
+	}
+
+	folders := []models.Folder{}
+	if err := query.Order("name, id").Find(&folders).Error; err != nil {
+		return nil, errors.New("failed to fetch folders")
+	}
+	return folders, nil
+}
+
+func (s *FolderService) CreateFolder(userID uint, req *models.FolderRequest) (*models.Folder, error) {
+	name, err := folderName(req)
+	if err != nil {
+		return nil, err
+	}
+	workspaceID, err := editableWorkspace(s.db, userID, req.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkParent(workspaceID, req.ParentID, 0); err != nil {
+		return nil, err
+	}
+	if err := s.checkFolderName(workspaceID, req.ParentID, name, 0); err != nil {
+		return nil, err
+	}
+
+	folder := &models.Folder{WorkspaceID: workspaceID, ParentID: req.ParentID, Name: name}
+	if err := s.db.Create(folder).Error; err != nil {
+		return nil, errors.New("failed to create folder")
+	}
+	return folder, nil
+}
+
+// UpdateFolder renames a folder and moves it under ParentID, or to the top
+// level when ParentID is nil. Its links and subfolders move with it.
+func (s *FolderService) UpdateFolder(folderID, userID uint, req *models.FolderRequest) (*models.Folder, error) {
+	name, err := folderName(req)
+	if err != nil {
+		return nil, err
+	}
+	folder, err := getMemberFolder(s.db, folderID, userID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkParent(folder.WorkspaceID, req.ParentID, folder.ID); err != nil {
+		return nil, err
+	}
+	if err := s.checkFolderName(folder.WorkspaceID, req.ParentID, name, folder.ID); err != nil {
+		return nil, err
+	}
+
+	folder.Name, folder.ParentID = name, req.ParentID
+	if err := s.db.Model(folder).Updates(map[string]interface{}{"name": name, "parent_id": req.ParentID}).Error; err != nil {
+		return nil, errors.New("failed to update folder")
+	}
+	return folder, nil
+}
+
+// DeleteFolder deletes a folder and its subfolders. The links filed in them
+// are kept but no longer in any folder.
+func (s *FolderService) DeleteFolder(folderID, userID uint) error {
+	folder, err := getMemberFolder(s.db, folderID, userID, models.RoleEditor)
+	if err != nil {
+		return err
+	}
+	ids, err := folderSubtree(s.db, folder)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		filed := tx.Unscoped().Model(&models.URL{}).Where("folder_id IN ?", ids)
+		if _, err := moveLinks(tx, s.actorFor(userID), filed, nil); err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&models.Folder{}).Error
+	})
+	if err != nil {
+		return errors.New("failed to delete folder")
+	}
+	return nil
+}
+
+// MoveURLs files a selection of links into a folder, or takes them out of
+// their folders when the request names none, and returns how many moved.
+// The folder must be in the links' workspace.
+func (s *FolderService) MoveURLs(userID uint, req *models.MoveURLsRequest) (int64, error) {
+	workspaces, err := editableURLs(s.db, req.URLIDs, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var folder *models.Folder
+	if req.FolderID != nil {
+		if folder, err = getMemberFolder(s.db, *req.FolderID, userID, models.RoleViewer); err != nil {
+			return 0, err
+		}
+	}
+
+	urlIDs := make([]uint, 0, len(workspaces))
+	for urlID, workspaceID := range workspaces {
+		if folder != nil && folder.WorkspaceID != workspaceID {
+			return 0, ErrWorkspaceMismatch
+		}
+		urlIDs = append(urlIDs, urlID)
+	}
+
+	var moved int64
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		moved, err = moveLinks(tx, s.actorFor(userID), tx.Model(&models.URL{}).Where("id IN ?", urlIDs), req.FolderID)
+		return err
+	})
+	if err != nil {
+		return 0, errors.New("failed to move links")
+	}
+	return moved, nil
+}
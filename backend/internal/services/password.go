@@ -0,0 +1,406 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	netmail "net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/mail"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/utils"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	bcryptCost        = 12
+	minPasswordLength = 10
+	// bcrypt ignores everything after 72 bytes.
+	maxPasswordBytes = 72
+
+	verifyTokenTTL = 24 * time.Hour
+	resetTokenTTL  = time.Hour
+
+	// lockoutThreshold consecutive failures lock the account for
+	// lockoutDuration.
+	lockoutThreshold = 5
+	lockoutDuration  = 15 * time.Minute
+
+	tokenPurposeVerify = "verify"
+	tokenPurposeReset  = "reset"
+)
+
+var (
+	ErrEmailTaken         = errors.New("an account with this email already exists")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidEmailToken  = errors.New("link is invalid or has expired")
+)
+
+var commonPasswords = map[string]bool{
+	"password123": true, "password1234": true, "1234567890": true, "qwertyuiop": true,
+	"iloveyou123": true, "letmein1234": true, "welcome123": true, "admin12345": true,
+	"passw0rd123": true, "abc1234567": true, "qwerty1234": true, "1q2w3e4r5t": true,
+}
+
+// dummyHash is compared against when the account does not exist, so the
+// response time does not reveal which emails are registered.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcryptCost)
+
+// PasswordAuthService handles local email and password accounts.
+type PasswordAuthService struct {
+	db       *gorm.DB
+	config   *config.Config
+	mailer   mail.Mailer
+	sessions SessionRevoker
+
+	// tokenKeys sign emailed links; the first signs, all of them verify.
+	tokenKeys [][]byte
+}
+
+func NewPasswordAuthService(cfg *config.Config, mailer mail.Mailer) *PasswordAuthService {
+	tokenKeys := [][]byte{utils.DeriveKey(cfg.SessionSecret, "email-token")}
+	for _, secret := range cfg.SessionSecretPrevious {
+		tokenKeys = append(tokenKeys, utils.DeriveKey(secret, "email-token"))
+	}
+
+	return &PasswordAuthService{
+		db:        database.GetDB(),
+		config:    cfg,
+		mailer:    mailer,
+		tokenKeys: tokenKeys,
+	}
+}
+
+// SetSessionRevoker lets a password reset log the user out everywhere.
+func (s *PasswordAuthService) SetSessionRevoker(sessions SessionRevoker) {
+	s.sessions = sessions
+}
+
+func (s *PasswordAuthService) Register(req *models.RegisterRequest) (*models.User, error) {
+	email, err := normalizeEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidatePassword(req.Password, email); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = email[:strings.IndexByte(email, '@')]
+	}
+	if len(name) > 100 {
+		return nil, errors.New("name must be at most 100 characters")
+	}
+
+	var count int64
+	s.db.Unscoped().Model(&models.User{}).Where("email = ?", email).Count(&count)
+	if count > 0 {
+		return nil, ErrEmailTaken
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcryptCost)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+
+	user := models.User{
+		Email:        email,
+		Name:         name,
+		PasswordHash: string(hash),
+	}
+	if err := s.db.Create(&user).Error; err != nil {
+		return nil, errors.New("failed to create user")
+	}
+
+	s.sendVerification(&user)
+
+	return &user, nil
+}
+
+// ResendVerification sends a new verification link if the email belongs to
+// an unverified password account. It reports nothing about the account.
+func (s *PasswordAuthService) ResendVerification(email string) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return
+	}
+
+	var user models.User
+	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return
+	}
+	if user.EmailVerifiedAt == nil && user.PasswordHash != "" && user.DisabledAt == nil {
+		s.sendVerification(&user)
+	}
+}
+
+func (s *PasswordAuthService) VerifyEmail(token string) (*models.User, error) {
+	user, err := s.userForToken(token, tokenPurposeVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		if err := s.db.Model(user).Update("email_verified_at", now).Error; err != nil {
+			return nil, errors.New("failed to verify email")
+		}
+		user.EmailVerifiedAt = &now
+	}
+
+	return user, nil
+}
+
+// Login checks a password against the account, enforcing per-account
+// lockout. Per-IP throttling is left to the auth rate limit policy.
+func (s *PasswordAuthService) Login(email, password, clientIP string) (*models.User, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	var user models.User
+	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, errors.New("database error")
+	}
+
+	if user.PasswordHash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	// A locked account looks and takes as long as a wrong password, so
+	// the lockout does not reveal that the account exists.
+	if user.LockedUntil != nil && user.LockedUntil.After(now) {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, s.recordFailure(&user, clientIP, now)
+	}
+
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+	if user.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		s.db.Model(&user).Updates(map[string]interface{}{
+			"failed_login_attempts": 0,
+			"locked_until":          nil,
+		})
+	}
+
+	return &user, nil
+}
+
+// RequestPasswordReset emails a reset link if the account exists. It reports
+// nothing about the account.
+func (s *PasswordAuthService) RequestPasswordReset(email string) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return
+	}
+
+	var user models.User
+	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil || user.DisabledAt != nil {
+		return
+	}
+
+	token := s.signToken(&user, tokenPurposeReset, resetTokenTTL)
+	s.send(user.Email, "Reset your password", fmt.Sprintf(
+		"Someone asked to reset the password for your account.\n\n"+
+			"Choose a new password here within the next hour:\n%s/reset-password?token=%s\n\n"+
+			"If this wasn't you, you can ignore this email.",
+		s.config.FrontendURL, token,
+	))
+}
+
+// ResetPassword sets a new password from a reset link. The link stops
+// working once used, and every existing session is ended.
+func (s *PasswordAuthService) ResetPassword(token, password string) (*models.User, error) {
+	user, err := s.userForToken(token, tokenPurposeReset)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidatePassword(password, user.Email); err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+
+	updates := map[string]interface{}{
+		"password_hash":         string(hash),
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+	}
+	// Receiving the link proves control of the mailbox.
+	if user.EmailVerifiedAt == nil {
+		updates["email_verified_at"] = time.Now()
+	}
+	if err := s.db.Model(user).Updates(updates).Error; err != nil {
+		return nil, errors.New("failed to reset password")
+	}
+
+	if s.sessions != nil {
+		s.sessions.RevokeUserSessions(user.ID)
+	}
+
+	return user, nil
+}
+
+// ValidatePassword enforces the password policy.
+func ValidatePassword(password, email string) error {
+	if len([]rune(password)) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("password must be at most %d bytes", maxPasswordBytes)
+	}
+
+	var hasLetter, hasOther bool
+	for _, r := range password {
+		if unicode.IsLetter(r) {
+			hasLetter = true
+		} else if !unicode.IsSpace(r) {
+			hasOther = true
+		}
+	}
+	if !hasLetter || !hasOther {
+		return errors.New("password must contain letters and at least one number or symbol")
+	}
+
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		return errors.New("password is too common")
+	}
+	if at := strings.IndexByte(email, '@'); at >= 4 && strings.Contains(lower, strings.ToLower(email[:at])) {
+		return errors.New("password must not contain your email address")
+	}
+
+	return nil
+}
+
+// recordFailure counts a wrong password against the account, locking it
+// after too many, and adds the attempt to the account's audit log.
+func (s *PasswordAuthService) recordFailure(user *models.User, clientIP string, now time.Time) error {
+	attempts := user.FailedLoginAttempts + 1
+	updates := map[string]interface{}{"failed_login_attempts": gorm.Expr("failed_login_attempts + 1")}
+	if attempts >= lockoutThreshold {
+		updates = map[string]interface{}{
+			"failed_login_attempts": 0,
+			"locked_until":          now.Add(lockoutDuration),
+		}
+	}
+	s.db.Model(user).Updates(updates)
+	if err := recordAudit(s.db, models.Actor{IPAddress: clientIP}, &models.AuditEvent{
+		Action:     models.AuditLoginFailed,
+		TargetType: models.AuditTargetUser,
+		TargetID:   user.ID,
+		AccountID:  &user.ID,
+	}); err != nil {
+		log.Printf("Failed to record failed login for user %d: %v", user.ID, err)
+	}
+
+	return ErrInvalidCredentials
+}
+
+func (s *PasswordAuthService) sendVerification(user *models.User) {
+	token := s.signToken(user, tokenPurposeVerify, verifyTokenTTL)
+	s.send(user.Email, "Verify your email address", fmt.Sprintf(
+		"Welcome, %s!\n\nConfirm your email address to finish setting up your account:\n%s/verify-email?token=%s\n\n"+
+			"The link expires in 24 hours.",
+		user.Name, s.config.FrontendURL, token,
+	))
+}
+
+func (s *PasswordAuthService) send(to, subject, body string) {
+	if err := s.mailer.Send(mail.Message{To: to, Subject: subject, Body: body}); err != nil {
+		log.Printf("Failed to send %q to %s: %v", subject, to, err)
+	}
+}
+
+// signToken creates an expiring link token. The fingerprint ties it to the
+// account state it was issued for, so a reset link dies once the password
+// changes.
+func (s *PasswordAuthService) signToken(user *models.User, purpose string, ttl time.Duration) string {
+	payload := strings.Join([]string{
+		purpose,
+		strconv.FormatUint(uint64(user.ID), 10),
+		strconv.FormatInt(time.Now().Add(ttl).Unix(), 10),
+		tokenFingerprint(user, purpose),
+	}, ".")
+	return utils.SignValue(s.tokenKeys[0], payload)
+}
+
+func (s *PasswordAuthService) userForToken(token, purpose string) (*models.User, error) {
+	payload, ok := utils.VerifySignedValue(token, s.tokenKeys...)
+	if !ok {
+		return nil, ErrInvalidEmailToken
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 4 || parts[0] != purpose {
+		return nil, ErrInvalidEmailToken
+	}
+
+	userID, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, ErrInvalidEmailToken
+	}
+	expiresAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return nil, ErrInvalidEmailToken
+	}
+
+	var user models.User
+	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, ErrInvalidEmailToken
+	}
+	if user.DisabledAt != nil || tokenFingerprint(&user, purpose) != parts[3] {
+		return nil, ErrInvalidEmailToken
+	}
+
+	return &user, nil
+}
+
+func tokenFingerprint(user *models.User, purpose string) string {
+	state := user.Email
+	if purpose == tokenPurposeReset {
+		state += "|" + user.PasswordHash
+	}
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:8])
+}
+
+func normalizeEmail(raw string) (string, error) {
+	email := strings.ToLower(strings.TrimSpace(raw))
+	addr, err := netmail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 255 {
+		return "", errors.New("invalid email address")
+	}
+	return email, nil
+}
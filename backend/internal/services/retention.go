@@ -0,0 +1,305 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const maxRetentionDays = 3650
+
+// RetentionService purges raw click events once they fall outside the
+// retention window of the account owning the link. Purged events are rolled
+// up into analytics_daily first so aggregate totals are preserved.
+type RetentionService struct {
+	db     *gorm.DB
+	config *config.Config
+	done   chan struct{}
+}
+
+func NewRetentionService(cfg *config.Config) *RetentionService {
+	return &RetentionService{
+		db:     database.GetDB(),
+		config: cfg,
+		done:   make(chan struct{}),
+	}
+}
+
+// Start runs the purge job on the configured interval until Close is called.
+func (s *RetentionService) Start() {
+	interval := time.Duration(s.config.RetentionInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				purged, err := s.PurgeExpired(time.Now())
+				if err != nil {
+					log.Printf("Analytics retention: %v", err)
+				}
+				if purged > 0 {
+					log.Printf("Analytics retention: purged %d raw click events", purged)
+				}
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the purge job
+func (s *RetentionService) Close() {
+	close(s.done)
+}
+
+// PurgeExpired deletes raw analytics older than each account's retention
+// window, in batches of RetentionBatchSize, and returns the number removed.
+func (s *RetentionService) PurgeExpired(now time.Time) (int64, error) {
+	var total int64
+
+	var overrides []int
+	if err := s.db.Model(&models.User{}).Unscoped().
+		Where("analytics_retention_days IS NOT NULL").
+		Distinct().Pluck("analytics_retention_days", &overrides).Error; err != nil {
+		return 0, errors.New("failed to load retention overrides")
+	}
+
+	for _, days := range overrides {
+		if days <= 0 {
+			continue
+		}
+		urlIDs := s.db.Unscoped().Model(&models.URL{}).Select("urls.id").
+			Joins("JOIN users ON users.id = urls.user_id").
+			Where("users.analytics_retention_days = ?", days)
+
+		purged, err := s.purge(urlIDs, now.AddDate(0, 0, -days))
+		total += purged
+		if err != nil {
+			return total, err
+		}
+	}
+
+	if s.config.AnalyticsRetentionDays > 0 {
+		urlIDs := s.db.Unscoped().Model(&models.URL{}).Select("urls.id").
+			Joins("LEFT JOIN users ON users.id = urls.user_id").
+			Where("users.analytics_retention_days IS NULL")
+
+		purged, err := s.purge(urlIDs, now.AddDate(0, 0, -s.config.AnalyticsRetentionDays))
+		total += purged
+		if err != nil {
+			return total, err
+		}
+	}
+
+	// Plans cap retention whatever the account or platform setting. Accounts
+	// on an unknown plan get the free plan's limits.
+	plans, err := allPlans(s.db)
+	if err != nil {
+		return total, err
+	}
+	codes := make([]string, len(plans))
+	for i, plan := range plans {
+		codes[i] = plan.Code
+	}
+	for _, plan := range plans {
+		if plan.AnalyticsRetentionDays <= 0 {
+			continue
+		}
+		urlIDs := s.db.Unscoped().Model(&models.URL{}).Select("urls.id").
+			Joins("JOIN users ON users.id = urls.user_id")
+		if plan.Code == models.PlanFree {
+			urlIDs = urlIDs.Where("users.plan_code = ? OR users.plan_code NOT IN ?", plan.Code, codes)
+		} else {
+			urlIDs = urlIDs.Where("users.plan_code = ?", plan.Code)
+		}
+
+		purged, err := s.purge(urlIDs, now.AddDate(0, 0, -plan.AnalyticsRetentionDays))
+		total += purged
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+func (s *RetentionService) purge(urlIDs *gorm.DB, cutoff time.Time) (int64, error) {
+	batchSize := s.config.RetentionBatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	var total int64
+	for {
+		var batch []models.Analytics
+		if err := s.db.Unscoped().Select("id", "url_id", "ip_address", "clicked_at").
+			Where("url_id IN (?) AND clicked_at < ?", urlIDs, cutoff).
+			Order("id").Limit(batchSize).Find(&batch).Error; err != nil {
+			return total, errors.New("failed to load expired analytics")
+		}
+
+		if len(batch) == 0 {
+			return total, nil
+		}
+
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := rollupDaily(tx, batch); err != nil {
+				return err
+			}
+
+			ids := make([]uint, len(batch))
+			for i, event := range batch {
+				ids[i] = event.ID
+			}
+			return tx.Unscoped().Where("id IN ?", ids).Delete(&models.Analytics{}).Error
+		})
+		if err != nil {
+			return total, errors.New("failed to purge expired analytics")
+		}
+
+		total += int64(len(batch))
+		if len(batch) < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// rollupURLs rolls every raw click event of the links in urlIDs up into
+// analytics_daily and deletes the events, leaving only the daily totals.
+func rollupURLs(tx *gorm.DB, urlIDs []uint) error {
+	if len(urlIDs) == 0 {
+		return nil
+	}
+
+	for {
+		var batch []models.Analytics
+		if err := tx.Unscoped().Select("id", "url_id", "ip_address", "clicked_at").
+			Where("url_id IN ?", urlIDs).
+			Order("id").Limit(1000).Find(&batch).Error; err != nil {
+			return err
+		}
+
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := rollupDaily(tx, batch); err != nil {
+			return err
+		}
+
+		ids := make([]uint, len(batch))
+		for i, event := range batch {
+			ids[i] = event.ID
+		}
+		if err := tx.Unscoped().Where("id IN ?", ids).Delete(&models.Analytics{}).Error; err != nil {
+			return err
+		}
+	}
+}
+
+// rollupDaily adds a batch of raw events to the per-day aggregates. Unique
+// clicks are counted per batch, so a visitor whose events straddle two
+// batches on the same day is counted twice.
+func rollupDaily(tx *gorm.DB, batch []models.Analytics) error {
+	type dayKey struct {
+		urlID uint
+		date  time.Time
+	}
+
+	rows := make(map[dayKey]*models.AnalyticsDaily)
+	visitors := make(map[dayKey]map[string]struct{})
+	for _, event := range batch {
+		clicked := event.ClickedAt.UTC()
+		key := dayKey{event.URLID, time.Date(clicked.Year(), clicked.Month(), clicked.Day(), 0, 0, 0, 0, time.UTC)}
+
+		row, ok := rows[key]
+		if !ok {
+			row = &models.AnalyticsDaily{URLID: key.urlID, Date: key.date}
+			rows[key] = row
+			visitors[key] = make(map[string]struct{})
+		}
+
+		row.Clicks++
+		if _, seen := visitors[key][event.IPAddress]; !seen {
+			visitors[key][event.IPAddress] = struct{}{}
+			row.UniqueClicks++
+		}
+	}
+
+	daily := make([]models.AnalyticsDaily, 0, len(rows))
+	for _, row := range rows {
+		daily = append(daily, *row)
+	}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "url_id"}, {Name: "date"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"clicks":        gorm.Expr("analytics_daily.clicks + excluded.clicks"),
+			"unique_clicks": gorm.Expr("analytics_daily.unique_clicks + excluded.unique_clicks"),
+			"updated_at":    time.Now(),
+		}),
+	}).Create(&daily).Error
+}
+
+// GetUserRetention returns the effective retention for an account, capped
+// by its plan, and whether it comes from a per-account override.
+func (s *RetentionService) GetUserRetention(userID uint) (int, bool, error) {
+	var user models.User
+	if err := s.db.Select("id", "analytics_retention_days", "plan_code").Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, false, errors.New("user not found")
+		}
+		return 0, false, errors.New("database error")
+	}
+
+	plan, err := findPlan(s.db, user.PlanCode)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if user.AnalyticsRetentionDays != nil {
+		return retentionCap(plan, *user.AnalyticsRetentionDays), true, nil
+	}
+	return retentionCap(plan, s.config.AnalyticsRetentionDays), false, nil
+}
+
+// SetUserRetention stores a per-account override; nil clears it. The
+// override cannot exceed what the account's plan allows.
+func (s *RetentionService) SetUserRetention(userID uint, days *int) error {
+	if days != nil && (*days < 1 || *days > maxRetentionDays) {
+		return errors.New("retention must be between 1 and 3650 days")
+	}
+
+	if days != nil {
+		plan, err := userPlan(s.db, userID)
+		if err != nil {
+			return err
+		}
+		if limit := retentionCap(plan, *days); limit < *days {
+			return fmt.Errorf("%w (at most %d days on the %s plan)", ErrRetentionLimit, limit, plan.Name)
+		}
+	}
+
+	result := s.db.Model(&models.User{}).Where("id = ?", userID).Update("analytics_retention_days", days)
+	if result.Error != nil {
+		return errors.New("failed to update retention")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("user not found")
+	}
+
+	return nil
+}
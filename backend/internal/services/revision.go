@@ -0,0 +1,211 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"time"
+	"url-shortener-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRevisionNotFound  = errors.New("revision not found")
+	ErrRevisionIsCurrent = errors.New("link already matches this revision")
+)
+
+// newRevision snapshots a link's destination and settings as its current
+// revision number.
+func newRevision(url *models.URL, createdByID *uint) *models.URLRevision {
+	return &models.URLRevision{
+		URLID:       url.ID,
+		Number:      url.Revision,
+		OriginalURL: url.OriginalURL,
+		Title:       url.Title,
+		Description: url.Description,
+		ExpiresAt:   url.ExpiresAt,
+		CreatedByID: createdByID,
+	}
+}
+
+// revisionFields returns the fields a revision records, leaving out empty
+// ones, for comparing revisions with auditDiff.
+func revisionFields(rev *models.URLRevision) map[string]interface{} {
+	fields := map[string]interface{}{"original_url": rev.OriginalURL}
+	if rev.Title != "" {
+		fields["title"] = rev.Title
+	}
+	if rev.Description != "" {
+		fields["description"] = rev.Description
+	}
+	if rev.ExpiresAt != nil {
+		fields["expires_at"] = rev.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	return fields
+}
+
+// ListRevisions returns a link's revisions, newest first.
+func (s *URLService) ListRevisions(urlID uint, userID uint, limit, offset int) ([]models.URLRevision, int64, error) {
+	if _, err := s.GetMemberURL(urlID, userID, models.RoleViewer); err != nil {
+		return nil, 0, err
+	}
+
+	query := s.db.Model(&models.URLRevision{}).Where("url_id = ?", urlID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("failed to count revisions")
+	}
+
+	var revisions []models.URLRevision
+	if err := query.Order("number DESC").Limit(limit).Offset(offset).Find(&revisions).Error; err != nil {
+		return nil, 0, errors.New("failed to fetch revisions")
+	}
+
+	return revisions, total, nil
+}
+
+// DiffRevisions lists the fields that differ between two revisions of a
+// link. To defaults to the live revision and from to the one before it.
+func (s *URLService) DiffRevisions(urlID uint, userID uint, from, to int) (*models.URLRevisionDiff, error) {
+	url, err := s.GetMemberURL(urlID, userID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	if to == 0 {
+		to = url.Revision
+	}
+	if from == 0 {
+		from = to - 1
+	}
+
+	before, err := s.getRevision(urlID, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.getRevision(urlID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.URLRevisionDiff{
+		URLID:   urlID,
+		From:    from,
+		To:      to,
+		Changes: auditDiff(revisionFields(before), revisionFields(after)),
+	}, nil
+}
+
+// RollbackURL restores a link's destination and settings from an earlier
+// revision. The restored state becomes a new revision rather than replacing
+// the history after it.
+func (s *URLService) RollbackURL(urlID uint, userID uint, number int) (*models.URL, error) {
+	url, err := s.getEditableURL(urlID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := s.getRevision(urlID, number)
+	if err != nil {
+		return nil, err
+	}
+	if len(auditDiff(revisionFields(newRevision(url, nil)), revisionFields(target))) == 0 {
+		return nil, ErrRevisionIsCurrent
+	}
+
+	before := linkAuditFields(url)
+	url.OriginalURL = target.OriginalURL
+	url.Title = target.Title
+	url.Description = target.Description
+	url.ExpiresAt = target.ExpiresAt
+	url.Revision++
+	changes := auditDiff(before, linkAuditFields(url))
+
+	revision := newRevision(url, &userID)
+	revision.RestoredFrom = &number
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := saveLinkEdit(tx, url, changes); err != nil {
+			return err
+		}
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, s.actorFor(&userID), &models.AuditEvent{
+			Action:      models.AuditLinkRollback,
+			TargetType:  models.AuditTargetURL,
+			TargetID:    url.ID,
+			WorkspaceID: url.WorkspaceID,
+			Changes:     changes,
+			Reason:      "restored revision " + strconv.Itoa(number),
+		}); err != nil {
+			return err
+		}
+		return enqueueWebhooks(tx, url.WorkspaceID, models.WebhookLinkUpdated, &models.WebhookLinkData{Link: url, Changes: changes}, url.UpdatedAt)
+	})
+	if err != nil {
+		return nil, errors.New("failed to roll back URL")
+	}
+
+	return url, nil
+}
+
+func (s *URLService) getRevision(urlID uint, number int) (*models.URLRevision, error) {
+	var revision models.URLRevision
+	if err := s.db.Where("url_id = ? AND number = ?", urlID, number).First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, errors.New("database error")
+	}
+	return &revision, nil
+}
+
+// BackfillRevisions records links created before revisions were kept as
+// their first revision, and attributes their existing clicks to it.
+func (s *URLService) BackfillRevisions() (int64, error) {
+	var total int64
+	for {
+		var batch []models.URL
+		if err := s.db.Unscoped().
+			Where("NOT EXISTS (SELECT 1 FROM url_revisions WHERE url_revisions.url_id = urls.id)").
+			Order("id").Limit(500).Find(&batch).Error; err != nil {
+			return total, errors.New("failed to load URLs")
+		}
+
+		if len(batch) == 0 {
+			return total, nil
+		}
+
+		for i := range batch {
+			url := &batch[i]
+			err := s.db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Unscoped().Model(&models.Analytics{}).
+					Where("url_id = ? AND revision = 0", url.ID).
+					Update("revision", url.Revision).Error; err != nil {
+					return err
+				}
+
+				var raw, rolledUp int64
+				if err := tx.Model(&models.Analytics{}).Where("url_id = ?", url.ID).Count(&raw).Error; err != nil {
+					return err
+				}
+				if err := tx.Model(&models.AnalyticsDaily{}).Where("url_id = ?", url.ID).
+					Select("COALESCE(SUM(clicks), 0)").Scan(&rolledUp).Error; err != nil {
+					return err
+				}
+
+				revision := newRevision(url, url.UserID)
+				revision.Clicks = raw + rolledUp
+				revision.CreatedAt = url.CreatedAt
+				return tx.Create(revision).Error
+			})
+			if err != nil {
+				return total, errors.New("failed to backfill revisions")
+			}
+		}
+
+		total += int64(len(batch))
+	}
+}
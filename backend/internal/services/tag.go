@@ -0,0 +1,465 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxBulkLinks caps how many links one bulk operation may change.
+const maxBulkLinks = 1000
+
+var (
+	ErrTagNotFound       = errors.New("tag not found")
+	ErrTagExists         = errors.New("a tag with this name already exists in the workspace")
+	ErrInvalidTag        = errors.New("tag name must be 1 to 50 characters")
+	ErrInvalidTagColor   = errors.New("tag color must be a hex color such as #1a2b3c")
+	ErrWorkspaceMismatch = errors.New("tags and folders can only be used on links in their own workspace")
+	ErrInvalidSelection  = errors.New("select between 1 and 1000 links")
+)
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// tagLinkCountSQL counts the live links carrying each tag, for selecting
+// alongside tags.*.
+const tagLinkCountSQL = `(SELECT COUNT(*) FROM url_tags JOIN urls ON urls.id = url_tags.url_id
+	WHERE url_tags.tag_id = tags.id AND urls.deleted_at IS NULL) AS link_count`
+
+type TagService struct {
+	db    *gorm.DB
+	actor models.Actor
+}
+
+func NewTagService() *TagService {
+	return &TagService{
+		db: database.GetDB(),
+	}
+}
+
+// WithActor returns a copy of the service that attributes its changes to
+// links to actor in the audit log. Without one, changes are attributed to
+// the user making them.
+func (s *TagService) WithActor(actor models.Actor) *TagService {
+	service := *s
+	service.actor = actor
+	return &service
+}
+
+func (s *TagService) actorFor(userID uint) models.Actor {
+	if s.actor.Type != "" {
+		return s.actor
+	}
+	return models.Actor{UserID: &userID}
+}
+
+// getMemberTag loads a tag the user can see. Members whose role is below
+// minRole get ErrInsufficientRole rather than ErrTagNotFound.
+func getMemberTag(db *gorm.DB, tagID, userID uint, minRole string) (*models.Tag, error) {
+	var tag models.Tag
+	if err := db.Where("id = ? AND workspace_id IN (?)", tagID, memberWorkspaceIDs(db, userID, models.RoleViewer)).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTagNotFound
+		}
+		return nil, errors.New("database error")
+	}
+
+	if _, err := requireRole(db, tag.WorkspaceID, userID, minRole); err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// loadTags loads the listed tags, all of which must be in workspaces the
+// user belongs to.
+func loadTags(db *gorm.DB, tagIDs []uint, userID uint) ([]models.Tag, error) {
+	tagIDs = uniqueIDs(tagIDs)
+	if len(tagIDs) == 0 {
+		return nil, ErrTagNotFound
+	}
+
+	var tags []models.Tag
+	if err := db.Where("id IN ? AND workspace_id IN (?)", tagIDs, memberWorkspaceIDs(db, userID, models.RoleViewer)).
+		Order("name").Find(&tags).Error; err != nil {
+		return nil, errors.New("database error")
+	}
+	if len(tags) != len(tagIDs) {
+		return nil, ErrTagNotFound
+	}
+	return tags, nil
+}
+
+// attachTags tags every listed link, skipping pairs that already exist. It
+// returns how many tags were newly attached.
+func attachTags(tx *gorm.DB, urlIDs []uint, tags []models.Tag) (int64, error) {
+	if len(urlIDs) == 0 || len(tags) == 0 {
+		return 0, nil
+	}
+
+	rows := make([]models.URLTag, 0, len(urlIDs)*len(tags))
+	for _, urlID := range urlIDs {
+		for _, tag := range tags {
+			rows = append(rows, models.URLTag{URLID: urlID, TagID: tag.ID})
+		}
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&rows, 500)
+	return result.RowsAffected, result.Error
+}
+
+// linkTagNames returns the names of the tags on each of the links, sorted,
+// for auditing changes to them.
+func linkTagNames(tx *gorm.DB, urlIDs []uint) (map[uint][]string, error) {
+	var rows []struct {
+		URLID uint
+		Name  string
+	}
+	if err := tx.Table("url_tags").Select("url_tags.url_id, tags.name").
+		Joins("JOIN tags ON tags.id = url_tags.tag_id").
+		Where("url_tags.url_id IN ?", urlIDs).Order("tags.name").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	names := make(map[uint][]string)
+	for _, row := range rows {
+		names[row.URLID] = append(names[row.URLID], row.Name)
+	}
+	return names, nil
+}
+
+// tagChanges lists, for each link whose tags differ between before and
+// after, the change to its tags.
+func tagChanges(urlIDs []uint, before, after map[uint][]string) map[uint]map[string]models.AuditChange {
+	changes := make(map[uint]map[string]models.AuditChange)
+	for _, urlID := range urlIDs {
+		from, to := before[urlID], after[urlID]
+		if !reflect.DeepEqual(from, to) {
+			changes[urlID] = map[string]models.AuditChange{"tags": {From: from, To: to}}
+		}
+	}
+	return changes
+}
+
+// recordLinkChanges records a link.update event and queues a link.updated
+// webhook for each link a tag or folder operation changed. Pass the
+// operation's transaction so they are only kept if the change is.
+func recordLinkChanges(tx *gorm.DB, actor models.Actor, changes map[uint]map[string]models.AuditChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	urlIDs := make([]uint, 0, len(changes))
+	for urlID := range changes {
+		urlIDs = append(urlIDs, urlID)
+	}
+
+	var urls []models.URL
+	if err := tx.Where("id IN ?", urlIDs).Order("id").Find(&urls).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range urls {
+		url := &urls[i]
+		if err := recordAudit(tx, actor, &models.AuditEvent{
+			Action:      models.AuditLinkUpdate,
+			TargetType:  models.AuditTargetURL,
+			TargetID:    url.ID,
+			WorkspaceID: url.WorkspaceID,
+			Changes:     changes[url.ID],
+		}); err != nil {
+			return err
+		}
+		if err := enqueueWebhooks(tx, url.WorkspaceID, models.WebhookLinkUpdated, &models.WebhookLinkData{Link: url, Changes: changes[url.ID]}, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// editableURLs checks that the user may edit every selected link, and
+// returns the workspace each belongs to.
+func editableURLs(db *gorm.DB, urlIDs []uint, userID uint) (map[uint]uint, error) {
+	urlIDs = uniqueIDs(urlIDs)
+	if len(urlIDs) == 0 || len(urlIDs) > maxBulkLinks {
+		return nil, ErrInvalidSelection
+	}
+
+	var urls []models.URL
+	if err := db.Select("id", "workspace_id").
+		Where("id IN ? AND workspace_id IN (?)", urlIDs, memberWorkspaceIDs(db, userID, models.RoleViewer)).
+		Find(&urls).Error; err != nil {
+		return nil, errors.New("database error")
+	}
+	if len(urls) != len(urlIDs) {
+		return nil, ErrURLNotFound
+	}
+
+	workspaces := make(map[uint]uint, len(urls))
+	checked := make(map[uint]bool)
+	for _, url := range urls {
+		workspaceID := *url.WorkspaceID
+		if !checked[workspaceID] {
+			if _, err := requireRole(db, workspaceID, userID, models.RoleEditor); err != nil {
+				return nil, err
+			}
+			checked[workspaceID] = true
+		}
+		workspaces[url.ID] = workspaceID
+	}
+	return workspaces, nil
+}
+
+// newLinkOrganization checks the folder and tags requested for a new link
+// in workspaceID, which is nil for anonymous links.
+func newLinkOrganization(db *gorm.DB, workspaceID *uint, req *models.CreateURLRequest) (*uint, []models.Tag, error) {
+	if req.FolderID != nil {
+		var count int64
+		if workspaceID != nil {
+			if err := db.Model(&models.Folder{}).Where("id = ? AND workspace_id = ?", *req.FolderID, *workspaceID).Count(&count).Error; err != nil {
+				return nil, nil, errors.New("database error")
+			}
+		}
+		if count == 0 {
+			return nil, nil, ErrFolderNotFound
+		}
+	}
+
+	var tags []models.Tag
+	if tagIDs := uniqueIDs(req.TagIDs); len(tagIDs) > 0 {
+		if workspaceID != nil {
+			if err := db.Where("id IN ? AND workspace_id = ?", tagIDs, *workspaceID).Order("name").Find(&tags).Error; err != nil {
+				return nil, nil, errors.New("database error")
+			}
+		}
+		if len(tags) != len(tagIDs) {
+			return nil, nil, ErrTagNotFound
+		}
+	}
+
+	return req.FolderID, tags, nil
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func normalizeTagRequest(req *models.TagRequest) (string, string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > 50 {
+		return "", "", ErrInvalidTag
+	}
+	color := strings.ToLower(strings.TrimSpace(req.Color))
+	if color != "" && !tagColorPattern.MatchString(color) {
+		return "", "", ErrInvalidTagColor
+	}
+	return name, color, nil
+}
+
+// checkTagName rejects a name already used, in any case, by another tag in
+// the workspace.
+func (s *TagService) checkTagName(workspaceID uint, name string, exceptID uint) error {
+	var count int64
+	if err := s.db.Model(&models.Tag{}).
+		Where("workspace_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", workspaceID, name, exceptID).
+		Count(&count).Error; err != nil {
+		return errors.New("database error")
+	}
+	if count > 0 {
+		return ErrTagExists
+	}
+	return nil
+}
+
+// ListTags returns the tags in every workspace the user belongs to, or in
+// just one, with the number of links carrying each.
+func (s *TagService) ListTags(userID uint, workspaceID *uint) ([]models.Tag, error) {
+	query := s.db.Model(&models.Tag{}).Select("tags.*, "+tagLinkCountSQL).
+		Where("workspace_id IN (?)", memberWorkspaceIDs(s.db, userID, models.RoleViewer))
+	if workspaceID != nil {
+		query = query.Where("workspace_id = ?", *workspaceID)
+	}
+
+	tags := []models.Tag{}
+	if err := query.Order("name").Find(&tags).Error; err != nil {
+		return nil, errors.New("failed to fetch tags")
+	}
+	return tags, nil
+}
+
+func (s *TagService) CreateTag(userID uint, req *models.TagRequest) (*models.Tag, error) {
+	name, color, err := normalizeTagRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	workspaceID, err := editableWorkspace(s.db, userID, req.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTagName(workspaceID, name, 0); err != nil {
+		return nil, err
+	}
+
+	tag := &models.Tag{WorkspaceID: workspaceID, Name: name, Color: color}
+	if err := s.db.Create(tag).Error; err != nil {
+		// Another request may have taken the name concurrently.
+		if s.checkTagName(workspaceID, name, 0) == ErrTagExists {
+			return nil, ErrTagExists
+		}
+		return nil, errors.New("failed to create tag")
+	}
+	return tag, nil
+}
+
+func (s *TagService) UpdateTag(tagID, userID uint, req *models.TagRequest) (*models.Tag, error) {
+	name, color, err := normalizeTagRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := getMemberTag(s.db, tagID, userID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTagName(tag.WorkspaceID, name, tag.ID); err != nil {
+		return nil, err
+	}
+
+	tag.Name, tag.Color = name, color
+	if err := s.db.Model(tag).Updates(map[string]interface{}{"name": name, "color": color}).Error; err != nil {
+		return nil, errors.New("failed to update tag")
+	}
+	return tag, nil
+}
+
+// DeleteTag deletes a tag and removes it from every link.
+func (s *TagService) DeleteTag(tagID, userID uint) error {
+	tag, err := getMemberTag(s.db, tagID, userID, models.RoleEditor)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var urlIDs []uint
+		if err := tx.Model(&models.URLTag{}).Where("tag_id = ?", tag.ID).Pluck("url_id", &urlIDs).Error; err != nil {
+			return err
+		}
+		before, err := linkTagNames(tx, urlIDs)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("tag_id = ?", tag.ID).Delete(&models.URLTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(tag).Error; err != nil {
+			return err
+		}
+		after, err := linkTagNames(tx, urlIDs)
+		if err != nil {
+			return err
+		}
+		return recordLinkChanges(tx, s.actorFor(userID), tagChanges(urlIDs, before, after))
+	})
+	if err != nil {
+		return errors.New("failed to delete tag")
+	}
+	return nil
+}
+
+// TagURLs adds tags to a selection of links and returns how many tags were
+// newly attached. Every link and tag must be in the same workspace, and the
+// change is made to all of them or none.
+func (s *TagService) TagURLs(userID uint, req *models.BulkTagRequest) (int64, error) {
+	urlIDs, tags, err := s.bulkSelection(userID, req)
+	if err != nil {
+		return 0, err
+	}
+
+	var attached int64
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		before, err := linkTagNames(tx, urlIDs)
+		if err != nil {
+			return err
+		}
+		if attached, err = attachTags(tx, urlIDs, tags); err != nil {
+			return err
+		}
+		after, err := linkTagNames(tx, urlIDs)
+		if err != nil {
+			return err
+		}
+		return recordLinkChanges(tx, s.actorFor(userID), tagChanges(urlIDs, before, after))
+	})
+	if err != nil {
+		return 0, errors.New("failed to tag links")
+	}
+	return attached, nil
+}
+
+// UntagURLs removes tags from a selection of links and returns how many
+// tags were detached.
+func (s *TagService) UntagURLs(userID uint, req *models.BulkTagRequest) (int64, error) {
+	urlIDs, tags, err := s.bulkSelection(userID, req)
+	if err != nil {
+		return 0, err
+	}
+
+	tagIDs := make([]uint, len(tags))
+	for i, tag := range tags {
+		tagIDs[i] = tag.ID
+	}
+	var detached int64
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		before, err := linkTagNames(tx, urlIDs)
+		if err != nil {
+			return err
+		}
+		result := tx.Where("url_id IN ? AND tag_id IN ?", urlIDs, tagIDs).Delete(&models.URLTag{})
+		if result.Error != nil {
+			return result.Error
+		}
+		detached = result.RowsAffected
+		after, err := linkTagNames(tx, urlIDs)
+		if err != nil {
+			return err
+		}
+		return recordLinkChanges(tx, s.actorFor(userID), tagChanges(urlIDs, before, after))
+	})
+	if err != nil {
+		return 0, errors.New("failed to untag links")
+	}
+	return detached, nil
+}
+
+func (s *TagService) bulkSelection(userID uint, req *models.BulkTagRequest) ([]uint, []models.Tag, error) {
+	workspaces, err := editableURLs(s.db, req.URLIDs, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	tags, err := loadTags(s.db, req.TagIDs, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	urlIDs := make([]uint, 0, len(workspaces))
+	for urlID, workspaceID := range workspaces {
+		for _, tag := range tags {
+			if tag.WorkspaceID != workspaceID {
+				return nil, nil, ErrWorkspaceMismatch
+			}
+		}
+		urlIDs = append(urlIDs, urlID)
+	}
+	return urlIDs, tags, nil
+}
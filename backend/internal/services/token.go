@@ -0,0 +1,164 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	apiTokenPrefix = "usk_"
+	// lastUsedResolution limits last_used_at writes to one per token per minute.
+	lastUsedResolution = time.Minute
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+type TokenService struct {
+	db *gorm.DB
+}
+
+func NewTokenService() *TokenService {
+	return &TokenService{
+		db: database.GetDB(),
+	}
+}
+
+// CreateToken mints a token and returns its plaintext value, which is not
+// stored and cannot be retrieved again.
+func (s *TokenService) CreateToken(userID uint, req *models.CreateAPITokenRequest) (string, *models.APIToken, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return "", nil, errors.New("token name must be between 1 and 100 characters")
+	}
+
+	if len(req.Scopes) == 0 {
+		return "", nil, errors.New("at least one scope is required")
+	}
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !isTokenScope(scope) {
+			return "", nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		scopes = append(scopes, scope)
+	}
+
+	token := &models.APIToken{
+		UserID: userID,
+		Name:   name,
+		Scopes: scopes,
+	}
+
+	if req.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			return "", nil, errors.New("expires_at must be an RFC3339 timestamp")
+		}
+		if !expiresAt.After(time.Now()) {
+			return "", nil, errors.New("expires_at must be in the future")
+		}
+		token.ExpiresAt = &expiresAt
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, errors.New("failed to generate token")
+	}
+	plaintext := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	token.Prefix = plaintext[:len(apiTokenPrefix)+8]
+	token.TokenHash = hashToken(plaintext)
+
+	if err := s.db.Create(token).Error; err != nil {
+		return "", nil, errors.New("failed to create token")
+	}
+
+	return plaintext, token, nil
+}
+
+func (s *TokenService) ListTokens(userID uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return nil, errors.New("failed to fetch tokens")
+	}
+
+	return tokens, nil
+}
+
+func (s *TokenService) RevokeToken(tokenID uint, userID uint) error {
+	result := s.db.Model(&models.APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return errors.New("failed to revoke token")
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("token not found")
+	}
+
+	return nil
+}
+
+// AuthenticateToken resolves a bearer token to its record, rejecting revoked
+// and expired tokens, and records when it was last used.
+func (s *TokenService) AuthenticateToken(plaintext string) (*models.APIToken, error) {
+	if !strings.HasPrefix(plaintext, apiTokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+
+	var token models.APIToken
+	if err := s.db.Where("token_hash = ?", hashToken(plaintext)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, errors.New("database error")
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil || (token.ExpiresAt != nil && token.ExpiresAt.Before(now)) {
+		return nil, ErrInvalidToken
+	}
+
+	var user models.User
+	if err := s.db.Select("id", "disabled_at").First(&user, token.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, errors.New("database error")
+	}
+	if user.DisabledAt != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > lastUsedResolution {
+		if err := s.db.Model(&token).UpdateColumn("last_used_at", now).Error; err != nil {
+			log.Printf("API token %d: failed to record last use: %v", token.ID, err)
+		}
+	}
+
+	return &token, nil
+}
+
+func isTokenScope(scope string) bool {
+	for _, s := range models.TokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func hashToken(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
@@ -10,8 +10,15 @@ import (
 	"gorm.io/gorm"
 )
 
+var ErrURLNotFound = errors.New("URL not found")
+
+// memberWorkspacesSQL selects the workspaces a user belongs to, for raw
+// queries. It takes the user ID as its only argument.
+const memberWorkspacesSQL = "SELECT workspace_id FROM workspace_members WHERE user_id = ?"
+
 type URLService struct {
-	db *gorm.DB
+	db    *gorm.DB
+	actor models.Actor
 }
 
 func NewURLService() *URLService {
@@ -20,6 +27,22 @@ func NewURLService() *URLService {
 	}
 }
 
+// WithActor returns a copy of the service that attributes its changes to
+// actor in the audit log. Without one, changes are attributed to the user
+// making them.
+func (s *URLService) WithActor(actor models.Actor) *URLService {
+	service := *s
+	service.actor = actor
+	return &service
+}
+
+func (s *URLService) actorFor(userID *uint) models.Actor {
+	if s.actor.Type != "" {
+		return s.actor
+	}
+	return models.Actor{UserID: userID}
+}
+
 func (s *URLService) CreateURL(req *models.CreateURLRequest, userID *uint) (*models.URL, error) {
 	if !utils.IsValidURL(req.OriginalURL) {
 		return nil, errors.New("invalid URL format")
@@ -27,6 +50,23 @@ func (s *URLService) CreateURL(req *models.CreateURLRequest, userID *uint) (*mod
 	
 	normalizedURL := utils.NormalizeURL(req.OriginalURL)
 	
+	// Signed-in users create links in a workspace where they can edit,
+	// their personal one unless another is chosen.
+	var workspaceID *uint
+	if userID != nil {
+		id, err := editableWorkspace(s.db, *userID, req.WorkspaceID)
+		if err != nil {
+			return nil, err
+		}
+		workspaceID = &id
+	}
+	
+	// Folders and tags must come from the new link's workspace.
+	folderID, tags, err := newLinkOrganization(s.db, workspaceID, req)
+	if err != nil {
+		return nil, err
+	}
+	
 	var shortCode string
 	if req.CustomAlias != "" {
 		if !utils.IsValidCustomAlias(req.CustomAlias) {
@@ -48,9 +88,12 @@ func (s *URLService) CreateURL(req *models.CreateURLRequest, userID *uint) (*mod
 		ShortCode:   shortCode,
 		CustomAlias: req.CustomAlias,
 		UserID:      userID,
+		WorkspaceID: workspaceID,
+		FolderID:    folderID,
 		Title:       req.Title,
 		Description: req.Description,
 		IsActive:    true,
+		Revision:    1,
 	}
 	
 	if req.ExpiresAt != "" {
@@ -59,13 +102,61 @@ func (s *URLService) CreateURL(req *models.CreateURLRequest, userID *uint) (*mod
 		}
 	}
 	
-	if err := s.db.Create(url).Error; err != nil {
+	// Links count towards the creator's plan; anonymous links are only
+	// rate limited.
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if userID != nil {
+			if err := s.meterCreate(tx, *userID, url); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(url).Error; err != nil {
+			return err
+		}
+		if _, err := attachTags(tx, []uint{url.ID}, tags); err != nil {
+			return err
+		}
+		if err := tx.Create(newRevision(url, userID)).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, s.actorFor(userID), &models.AuditEvent{
+			Action:      models.AuditLinkCreate,
+			TargetType:  models.AuditTargetURL,
+			TargetID:    url.ID,
+			WorkspaceID: url.WorkspaceID,
+			Changes:     auditDiff(nil, linkAuditFields(url)),
+		}); err != nil {
+			return err
+		}
+		return enqueueWebhooks(tx, url.WorkspaceID, models.WebhookLinkCreated, &models.WebhookLinkData{Link: url}, url.CreatedAt)
+	})
+	if errors.Is(err, ErrQuotaExceeded) || errors.Is(err, ErrUserNotFound) {
+		return nil, err
+	}
+	if err != nil {
 		return nil, errors.New("failed to create URL")
 	}
+	url.Tags = tags
 	
 	return url, nil
 }
 
+func (s *URLService) meterCreate(tx *gorm.DB, userID uint, url *models.URL) error {
+	plan, err := userPlan(tx, userID)
+	if err != nil {
+		return err
+	}
+	
+	now := time.Now()
+	if err := meterUsage(tx, plan, userID, models.MetricLinksCreated, 1, now); err != nil {
+		return err
+	}
+	if url.CustomAlias != "" {
+		return meterUsage(tx, plan, userID, models.MetricCustomAliases, 1, now)
+	}
+	return nil
+}
+
 func (s *URLService) GetURLByShortCode(shortCode string) (*models.URL, error) {
 	var url models.URL
 	query := s.db.Where("(short_code = ? OR custom_alias = ?) AND is_active = ?", shortCode, shortCode, true)
@@ -84,31 +175,102 @@ func (s *URLService) GetURLByShortCode(shortCode string) (*models.URL, error) {
 	return &url, nil
 }
 
-func (s *URLService) GetUserURLs(userID uint, limit, offset int) ([]models.URL, int64, error) {
+// GetMemberURL loads a link only if the user holds at least minRole in the
+// workspace that owns it.
+func (s *URLService) GetMemberURL(urlID uint, userID uint, minRole string) (*models.URL, error) {
+	var url models.URL
+	if err := s.db.Where("id = ? AND workspace_id IN (?)", urlID, memberWorkspaceIDs(s.db, userID, minRole)).First(&url).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrURLNotFound
+		}
+		return nil, errors.New("database error")
+	}
+
+	return &url, nil
+}
+
+// GetUserURLs lists the links in the user's workspaces that match the
+// filter, newest first unless it sets another order.
+func (s *URLService) GetUserURLs(userID uint, filter *models.URLFilter, limit, offset int) ([]models.URL, int64, error) {
 	var urls []models.URL
 	var total int64
 	
-	query := s.db.Where("user_id = ?", userID).Order("created_at DESC")
+	if _, ok := urlSortColumns[filter.Sort]; filter.Sort != "" && !ok {
+		return nil, 0, ErrInvalidSort
+	}
+	
+	query := s.db.Where("workspace_id IN (?)", memberWorkspaceIDs(s.db, userID, models.RoleViewer))
+	if filter.WorkspaceID != nil {
+		query = query.Where("workspace_id = ?", *filter.WorkspaceID)
+	}
+	for _, tagID := range filter.TagIDs {
+		query = query.Where("id IN (SELECT url_id FROM url_tags WHERE tag_id = ?)", tagID)
+	}
+	if filter.Unfiled {
+		query = query.Where("folder_id IS NULL")
+	} else if filter.FolderID != nil {
+		folderIDs := []uint{*filter.FolderID}
+		if filter.IncludeSubfolders {
+			folder, err := getMemberFolder(s.db, *filter.FolderID, userID, models.RoleViewer)
+			if err != nil {
+				return nil, 0, err
+			}
+			if folderIDs, err = folderSubtree(s.db, folder); err != nil {
+				return nil, 0, err
+			}
+		}
+		query = query.Where("folder_id IN ?", folderIDs)
+	}
+	query = s.filterURLs(query, filter, time.Now())
 	
 	if err := query.Model(&models.URL{}).Count(&total).Error; err != nil {
 		return nil, 0, errors.New("failed to count URLs")
 	}
+	query = s.sortURLs(query, filter)
 	
-	if err := query.Limit(limit).Offset(offset).Find(&urls).Error; err != nil {
+	if err := query.Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("name") }).
+		Limit(limit).Offset(offset).Find(&urls).Error; err != nil {
 		return nil, 0, errors.New("failed to fetch URLs")
 	}
 	
 	return urls, total, nil
 }
 
+// getEditableURL loads a link the user may change. Members who can only
+// view it get ErrInsufficientRole rather than ErrURLNotFound.
+func (s *URLService) getEditableURL(urlID uint, userID uint) (*models.URL, error) {
+	url, err := s.GetMemberURL(urlID, userID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	
+	if _, err := requireRole(s.db, *url.WorkspaceID, userID, models.RoleEditor); err != nil {
+		return nil, err
+	}
+	
+	return url, nil
+}
+
+// saveLinkEdit writes the columns an edit or rollback changes. Clicks may
+// have been counted, the link disabled or quarantined, and link.expired sent
+// since the link was loaded, so no other column is written. A new expiry
+// gets its own link.expired.
+func saveLinkEdit(tx *gorm.DB, url *models.URL, changes map[string]models.AuditChange) error {
+	columns := []string{"original_url", "title", "description", "expires_at", "revision", "updated_at"}
+	if _, ok := changes["expires_at"]; ok {
+		url.ExpiryNotifiedAt = nil
+		columns = append(columns, "expiry_notified_at")
+	}
+	return tx.Model(url).Select(columns).Updates(url).Error
+}
+
 func (s *URLService) UpdateURL(urlID uint, userID uint, req *models.CreateURLRequest) (*models.URL, error) {
-	var url models.URL
-	if err := s.db.Where("id = ? AND user_id = ?", urlID, userID).First(&url).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("URL not found")
-		}
-		return nil, errors.New("database error")
+	url, err := s.getEditableURL(urlID, userID)
+	if err != nil {
+		return nil, err
 	}
+	before := linkAuditFields(url)
+	beforeRevision := revisionFields(newRevision(url, nil))
 	
 	if req.OriginalURL != "" {
 		if !utils.IsValidURL(req.OriginalURL) {
@@ -131,21 +293,68 @@ func (s *URLService) UpdateURL(urlID uint, userID uint, req *models.CreateURLReq
 		}
 	}
 	
-	if err := s.db.Save(&url).Error; err != nil {
+	changes := auditDiff(before, linkAuditFields(url))
+	
+	// Only changes to what a revision records start a new one.
+	var revision *models.URLRevision
+	if len(auditDiff(beforeRevision, revisionFields(newRevision(url, nil)))) > 0 {
+		url.Revision++
+		revision = newRevision(url, &userID)
+	}
+	
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := saveLinkEdit(tx, url, changes); err != nil {
+			return err
+		}
+		if revision != nil {
+			if err := tx.Create(revision).Error; err != nil {
+				return err
+			}
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		if err := recordAudit(tx, s.actorFor(&userID), &models.AuditEvent{
+			Action:      models.AuditLinkUpdate,
+			TargetType:  models.AuditTargetURL,
+			TargetID:    url.ID,
+			WorkspaceID: url.WorkspaceID,
+			Changes:     changes,
+		}); err != nil {
+			return err
+		}
+		return enqueueWebhooks(tx, url.WorkspaceID, models.WebhookLinkUpdated, &models.WebhookLinkData{Link: url, Changes: changes}, url.UpdatedAt)
+	})
+	if err != nil {
 		return nil, errors.New("failed to update URL")
 	}
 	
-	return &url, nil
+	return url, nil
 }
 
 func (s *URLService) DeleteURL(urlID uint, userID uint) error {
-	result := s.db.Where("id = ? AND user_id = ?", urlID, userID).Delete(&models.URL{})
-	if result.Error != nil {
-		return errors.New("failed to delete URL")
+	url, err := s.getEditableURL(urlID, userID)
+	if err != nil {
+		return err
 	}
 	
-	if result.RowsAffected == 0 {
-		return errors.New("URL not found")
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(url).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, s.actorFor(&userID), &models.AuditEvent{
+			Action:      models.AuditLinkDelete,
+			TargetType:  models.AuditTargetURL,
+			TargetID:    url.ID,
+			WorkspaceID: url.WorkspaceID,
+			Changes:     auditDiff(linkAuditFields(url), nil),
+		}); err != nil {
+			return err
+		}
+		return enqueueWebhooks(tx, url.WorkspaceID, models.WebhookLinkDeleted, &models.WebhookLinkData{Link: url}, time.Now())
+	})
+	if err != nil {
+		return errors.New("failed to delete URL")
 	}
 	
 	return nil
@@ -155,7 +364,60 @@ func (s *URLService) RecordClick(urlID uint, analytics *models.Analytics) error
 	analytics.URLID = urlID
 	analytics.ClickedAt = time.Now()
 	
-	if err := s.db.Create(analytics).Error; err != nil {
+	var url models.URL
+	if err := s.db.Unscoped().Select("id", "user_id", "workspace_id", "short_code", "revision").Where("id = ?", urlID).First(&url).Error; err != nil {
+		return errors.New("failed to record click")
+	}
+	if analytics.Revision == 0 {
+		analytics.Revision = url.Revision
+	}
+	
+	// Clicks beyond the owner's monthly allowance still redirect but are
+	// not tracked.
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if url.UserID != nil {
+			plan, err := userPlan(tx, *url.UserID)
+			if err != nil {
+				return err
+			}
+			if err := meterUsage(tx, plan, *url.UserID, models.MetricClicksTracked, 1, analytics.ClickedAt); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(analytics).Error; err != nil {
+			return err
+		}
+		// Counting a click is not an edit, so updated_at is left alone.
+		if err := tx.Unscoped().Model(&models.URL{}).Where("id = ?", urlID).UpdateColumns(map[string]interface{}{
+			"click_count":     gorm.Expr("click_count + 1"),
+			"last_clicked_at": analytics.ClickedAt,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.URLRevision{}).
+			Where("url_id = ? AND number = ?", urlID, analytics.Revision).
+			Update("clicks", gorm.Expr("clicks + 1")).Error; err != nil {
+			return err
+		}
+		return enqueueWebhooks(tx, url.WorkspaceID, models.WebhookClickRecorded, &models.WebhookClickData{
+			LinkID:         urlID,
+			ShortCode:      url.ShortCode,
+			Revision:       analytics.Revision,
+			ClickedAt:      analytics.ClickedAt,
+			ReferrerDomain: analytics.ReferrerDomain,
+			TrafficSource:  analytics.TrafficSource,
+			Country:        analytics.Country,
+			City:           analytics.City,
+			Device:         analytics.Device,
+			OS:             analytics.OS,
+			Browser:        analytics.Browser,
+			IsBot:          analytics.IsBot,
+		}, analytics.ClickedAt)
+	})
+	if errors.Is(err, ErrQuotaExceeded) {
+		return err
+	}
+	if err != nil {
 		return errors.New("failed to record click")
 	}
 	
@@ -168,7 +430,7 @@ func (s *URLService) GetURLAnalytics(urlID uint, userID uint) ([]models.Analytic
 	query := `
 		SELECT a.* FROM analytics a
 		JOIN urls u ON a.url_id = u.id
-		WHERE u.id = ? AND u.user_id = ?
+		WHERE u.id = ? AND u.workspace_id IN (`+memberWorkspacesSQL+`)
 		ORDER BY a.clicked_at DESC
 		LIMIT 1000
 	`
@@ -186,12 +448,12 @@ func (s *URLService) GetURLStats(urlID uint, userID uint) (*models.URLStats, err
 	query := `
 		SELECT 
 			u.id as url_id,
-			COUNT(a.id) as total_clicks,
-			COUNT(DISTINCT a.ip_address) as unique_clicks,
+			COUNT(a.id) + COALESCE((SELECT SUM(d.clicks) FROM analytics_daily d WHERE d.url_id = u.id), 0) as total_clicks,
+			COUNT(DISTINCT a.ip_address) + COALESCE((SELECT SUM(d.unique_clicks) FROM analytics_daily d WHERE d.url_id = u.id), 0) as unique_clicks,
 			MAX(a.clicked_at) as last_clicked
 		FROM urls u
 		LEFT JOIN analytics a ON u.id = a.url_id
-		WHERE u.id = ? AND u.user_id = ?
+		WHERE u.id = ? AND u.workspace_id IN (`+memberWorkspacesSQL+`)
 		GROUP BY u.id
 	`
 	
@@ -202,6 +464,119 @@ func (s *URLService) GetURLStats(urlID uint, userID uint) (*models.URLStats, err
 	return &stats, nil
 }
 
+func (s *URLService) GetTopReferrers(urlID uint, userID uint, limit int) ([]models.ReferrerStat, error) {
+	var stats []models.ReferrerStat
+	
+	query := `
+		SELECT a.referrer_domain as domain, a.traffic_source as source, COUNT(a.id) as clicks
+		FROM analytics a
+		JOIN urls u ON a.url_id = u.id
+		WHERE u.id = ? AND u.workspace_id IN (`+memberWorkspacesSQL+`) AND a.referrer_domain <> '' AND a.deleted_at IS NULL
+		GROUP BY a.referrer_domain, a.traffic_source
+		ORDER BY clicks DESC
+		LIMIT ?
+	`
+	
+	if err := s.db.Raw(query, urlID, userID, limit).Scan(&stats).Error; err != nil {
+		return nil, errors.New("failed to fetch referrers")
+	}
+	
+	return stats, nil
+}
+
+func (s *URLService) GetTrafficSources(urlID uint, userID uint) ([]models.TrafficSourceStat, error) {
+	var stats []models.TrafficSourceStat
+	
+	query := `
+		SELECT COALESCE(NULLIF(a.traffic_source, ''), 'unknown') as source, COUNT(a.id) as clicks
+		FROM analytics a
+		JOIN urls u ON a.url_id = u.id
+		WHERE u.id = ? AND u.workspace_id IN (`+memberWorkspacesSQL+`) AND a.deleted_at IS NULL
+		GROUP BY source
+		ORDER BY clicks DESC
+	`
+	
+	if err := s.db.Raw(query, urlID, userID).Scan(&stats).Error; err != nil {
+		return nil, errors.New("failed to fetch traffic sources")
+	}
+	
+	return stats, nil
+}
+
+// BackfillReferrerSources classifies click events that were recorded before
+// referrers were normalized at ingestion.
+func (s *URLService) BackfillReferrerSources(internalHosts ...string) (int64, error) {
+	var total int64
+	for {
+		var batch []models.Analytics
+		if err := s.db.Unscoped().Select("id", "referrer").
+			Where("COALESCE(referrer, '') <> '' AND COALESCE(traffic_source, '') = ''").
+			Order("id").Limit(500).Find(&batch).Error; err != nil {
+			return total, errors.New("failed to load analytics")
+		}
+		
+		if len(batch) == 0 {
+			return total, nil
+		}
+		
+		for _, event := range batch {
+			domain, source := utils.ClassifyReferrer(event.Referrer, internalHosts...)
+			if err := s.db.Unscoped().Model(&models.Analytics{}).Where("id = ?", event.ID).
+				Updates(map[string]interface{}{"referrer_domain": domain, "traffic_source": source}).Error; err != nil {
+				return total, errors.New("failed to update analytics")
+			}
+		}
+		
+		total += int64(len(batch))
+	}
+}
+
+// BackfillClickCounts fills in the click summary of links clicked before it
+// was kept, from their raw events and daily rollups.
+func (s *URLService) BackfillClickCounts() (int64, error) {
+	var total int64
+	for {
+		var ids []uint
+		if err := s.db.Unscoped().Model(&models.URL{}).
+			Where("last_clicked_at IS NULL AND (EXISTS (SELECT 1 FROM analytics WHERE analytics.url_id = urls.id) OR EXISTS (SELECT 1 FROM analytics_daily WHERE analytics_daily.url_id = urls.id))").
+			Order("id").Limit(500).Pluck("id", &ids).Error; err != nil {
+			return total, errors.New("failed to load URLs")
+		}
+		
+		if len(ids) == 0 {
+			return total, nil
+		}
+		
+		for _, id := range ids {
+			var clicks, rolledUp int64
+			var latest models.Analytics
+			var latestDay models.AnalyticsDaily
+			if err := s.db.Model(&models.Analytics{}).Where("url_id = ?", id).Count(&clicks).Error; err != nil {
+				return total, errors.New("failed to count clicks")
+			}
+			if err := s.db.Model(&models.AnalyticsDaily{}).Where("url_id = ?", id).
+				Select("COALESCE(SUM(clicks), 0)").Scan(&rolledUp).Error; err != nil {
+				return total, errors.New("failed to count clicks")
+			}
+			s.db.Unscoped().Where("url_id = ?", id).Order("clicked_at DESC").Limit(1).Find(&latest)
+			s.db.Where("url_id = ?", id).Order("date DESC").Limit(1).Find(&latestDay)
+			
+			lastClicked := latest.ClickedAt
+			if lastClicked.IsZero() || latestDay.Date.After(lastClicked) {
+				lastClicked = latestDay.Date
+			}
+			if err := s.db.Unscoped().Model(&models.URL{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+				"click_count":     clicks + rolledUp,
+				"last_clicked_at": lastClicked,
+			}).Error; err != nil {
+				return total, errors.New("failed to update URL")
+			}
+		}
+		
+		total += int64(len(ids))
+	}
+}
+
 func (s *URLService) generateUniqueShortCode() string {
 	for {
 		code := utils.GenerateShortCode(6)
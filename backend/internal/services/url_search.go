@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"url-shortener-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidSort = errors.New("sort must be one of created, updated, clicks, last_clicked")
+
+// urlSortColumns maps the listing's sort options to their columns.
+var urlSortColumns = map[string]string{
+	models.URLSortCreated:     "created_at",
+	models.URLSortUpdated:     "updated_at",
+	models.URLSortClicks:      "click_count",
+	models.URLSortLastClicked: "last_clicked_at",
+}
+
+func (s *URLService) usesPostgres() bool {
+	return s.db.Dialector.Name() == "postgres"
+}
+
+// filterURLs narrows a link query by the filter's search and attributes.
+func (s *URLService) filterURLs(query *gorm.DB, filter *models.URLFilter, now time.Time) *gorm.DB {
+	if q := strings.ToLower(strings.TrimSpace(filter.Query)); q != "" {
+		// Postgres matches whole words through the full-text index and
+		// fragments through the trigram index; elsewhere only fragments.
+		contains := "%" + likePrefix(q)
+		if s.usesPostgres() {
+			query = query.Where("(to_tsvector('simple', "+models.URLSearchDocument+") @@ plainto_tsquery('simple', ?) OR "+
+				models.URLSearchDocument+` LIKE ? ESCAPE '\')`, q, contains)
+		} else {
+			query = query.Where(models.URLSearchDocument+` LIKE ? ESCAPE '\'`, contains)
+		}
+	}
+
+	if filter.Active != nil {
+		if *filter.Active {
+			query = query.Where("is_active = ? AND disabled_at IS NULL", true)
+		} else {
+			query = query.Where("(is_active = ? OR disabled_at IS NOT NULL)", false)
+		}
+	}
+	if filter.Expired != nil {
+		if *filter.Expired {
+			query = query.Where("expires_at IS NOT NULL AND expires_at <= ?", now)
+		} else {
+			query = query.Where("(expires_at IS NULL OR expires_at > ?)", now)
+		}
+	}
+	if filter.HasClicks != nil {
+		if *filter.HasClicks {
+			query = query.Where("click_count > 0")
+		} else {
+			query = query.Where("click_count = 0")
+		}
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+
+	return query
+}
+
+// sortURLs orders a link query. Links never clicked sort last by
+// last_clicked in either direction, and ties fall back to the newest link.
+func (s *URLService) sortURLs(query *gorm.DB, filter *models.URLFilter) *gorm.DB {
+	direction := " DESC"
+	if filter.Ascending {
+		direction = " ASC"
+	}
+
+	q := strings.ToLower(strings.TrimSpace(filter.Query))
+	switch {
+	case filter.Sort == "" && q != "" && s.usesPostgres():
+		query = query.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "ts_rank(to_tsvector('simple', " + models.URLSearchDocument + "), plainto_tsquery('simple', ?)) DESC",
+			Vars: []interface{}{q},
+		}})
+	case filter.Sort == models.URLSortLastClicked:
+		query = query.Order("last_clicked_at IS NULL").Order("last_clicked_at" + direction)
+	case filter.Sort != "":
+		query = query.Order(urlSortColumns[filter.Sort] + direction)
+	}
+
+	return query.Order("created_at DESC").Order("id DESC")
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"url-shortener-backend/internal/billing"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// usageExportBatch is the most usage events handed to the sink at once.
+const usageExportBatch = 500
+
+var (
+	ErrQuotaExceeded  = errors.New("your plan's monthly limit has been reached")
+	ErrUnknownPlan    = errors.New("unknown plan")
+	ErrRetentionLimit = errors.New("your plan does not keep analytics that long")
+)
+
+// metricNames describe metrics in quota errors.
+var metricNames = map[string]string{
+	models.MetricLinksCreated:  "links",
+	models.MetricCustomAliases: "custom aliases",
+	models.MetricClicksTracked: "tracked clicks",
+}
+
+// usagePeriod is the calendar month, in UTC, that usage at t counts towards.
+func usagePeriod(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// findPlan loads a plan by code. Plans missing from the database fall back
+// to the built-in defaults, and unknown codes to the free plan.
+func findPlan(db *gorm.DB, code string) (*models.Plan, error) {
+	var plan models.Plan
+	err := db.Where("code = ?", code).First(&plan).Error
+	if err == nil {
+		return &plan, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("failed to load plan")
+	}
+
+	for _, plan := range models.DefaultPlans() {
+		if plan.Code == code {
+			return &plan, nil
+		}
+	}
+	return findPlan(db, models.PlanFree)
+}
+
+// allPlans lists every plan, including defaults not yet in the database.
+func allPlans(db *gorm.DB) ([]models.Plan, error) {
+	var plans []models.Plan
+	if err := db.Order("id").Find(&plans).Error; err != nil {
+		return nil, errors.New("failed to load plans")
+	}
+
+	for _, plan := range models.DefaultPlans() {
+		found := false
+		for _, existing := range plans {
+			found = found || existing.Code == plan.Code
+		}
+		if !found {
+			plans = append(plans, plan)
+		}
+	}
+	return plans, nil
+}
+
+// userPlan loads the plan an account is subscribed to.
+func userPlan(db *gorm.DB, userID uint) (*models.Plan, error) {
+	var user models.User
+	if err := db.Unscoped().Select("id", "plan_code").Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, errors.New("database error")
+	}
+	return findPlan(db, user.PlanCode)
+}
+
+// meterUsage adds quantity to the account's counter for this month and
+// queues a billing event. It returns ErrQuotaExceeded, leaving the caller
+// to roll back the transaction, when the count passes the plan's limit.
+func meterUsage(tx *gorm.DB, plan *models.Plan, userID uint, metric string, quantity int64, now time.Time) error {
+	counter := models.UsageCounter{
+		UserID:    userID,
+		Period:    usagePeriod(now),
+		Metric:    metric,
+		Count:     quantity,
+		UpdatedAt: now,
+	}
+	err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "period"}, {Name: "metric"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count":      gorm.Expr("usage_counters.count + excluded.count"),
+			"updated_at": now,
+		}),
+	}).Create(&counter).Error
+	if err != nil {
+		return err
+	}
+
+	if limit := plan.Limit(metric); limit > 0 {
+		var count int64
+		if err := tx.Model(&models.UsageCounter{}).
+			Where("user_id = ? AND period = ? AND metric = ?", userID, counter.Period, metric).
+			Pluck("count", &count).Error; err != nil {
+			return err
+		}
+		if count > limit {
+			return fmt.Errorf("%w (%d %s per month)", ErrQuotaExceeded, limit, metricNames[metric])
+		}
+	}
+
+	return tx.Create(&models.UsageEvent{
+		UserID:     userID,
+		PlanCode:   plan.Code,
+		Metric:     metric,
+		Quantity:   quantity,
+		OccurredAt: now,
+	}).Error
+}
+
+// retentionCap limits a retention period to what the plan allows. Zero
+// means raw events are kept forever.
+func retentionCap(plan *models.Plan, days int) int {
+	if plan.AnalyticsRetentionDays > 0 && (days <= 0 || days > plan.AnalyticsRetentionDays) {
+		return plan.AnalyticsRetentionDays
+	}
+	return days
+}
+
+// UsageService reports plan usage and exports metered usage to billing.
+type UsageService struct {
+	db     *gorm.DB
+	config *config.Config
+	sink   billing.Sink
+	done   chan struct{}
+}
+
+func NewUsageService(cfg *config.Config, sink billing.Sink) *UsageService {
+	return &UsageService{
+		db:     database.GetDB(),
+		config: cfg,
+		sink:   sink,
+		done:   make(chan struct{}),
+	}
+}
+
+// SeedPlans creates the default plans that are missing and returns how many
+// were added.
+func (s *UsageService) SeedPlans() (int, error) {
+	created := 0
+	for _, plan := range models.DefaultPlans() {
+		result := s.db.Where("code = ?", plan.Code).FirstOrCreate(&plan)
+		if result.Error != nil {
+			return created, errors.New("failed to seed plans")
+		}
+		created += int(result.RowsAffected)
+	}
+	return created, nil
+}
+
+// GetUsage reports the account's plan and what it has used this month.
+func (s *UsageService) GetUsage(userID uint, now time.Time) (*models.AccountUsage, error) {
+	plan, err := userPlan(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	period := usagePeriod(now)
+	var counters []models.UsageCounter
+	if err := s.db.Where("user_id = ? AND period = ?", userID, period).Find(&counters).Error; err != nil {
+		return nil, errors.New("failed to load usage")
+	}
+	used := make(map[string]int64, len(counters))
+	for _, counter := range counters {
+		used[counter.Metric] = counter.Count
+	}
+
+	metric := func(name string) models.MetricUsage {
+		usage := models.MetricUsage{Used: used[name]}
+		if limit := plan.Limit(name); limit > 0 {
+			usage.Limit = &limit
+		}
+		return usage
+	}
+
+	start := now.UTC()
+	start = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	domains := plan.BrandedDomains
+
+	return &models.AccountUsage{
+		Plan:          *plan,
+		Period:        period,
+		PeriodStart:   start,
+		PeriodEnd:     start.AddDate(0, 1, 0),
+		LinksCreated:  metric(models.MetricLinksCreated),
+		CustomAliases: metric(models.MetricCustomAliases),
+		ClicksTracked: metric(models.MetricClicksTracked),
+		// Branded domains are not supported yet, so none are in use.
+		BrandedDomains: models.MetricUsage{Limit: &domains},
+	}, nil
+}
+
+// Start exports usage to the billing sink on the configured interval until
+// Close is called.
+func (s *UsageService) Start() {
+	interval := time.Duration(s.config.BillingExportInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := s.ExportUsage(context.Background()); err != nil {
+					log.Printf("Usage export: %v", err)
+				}
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the export job
+func (s *UsageService) Close() {
+	close(s.done)
+}
+
+// ExportUsage sends queued usage events to the billing sink, oldest first,
+// and removes those it accepted. It stops at the first batch the sink
+// rejects, which is retried on the next run.
+func (s *UsageService) ExportUsage(ctx context.Context) (int, error) {
+	exported := 0
+	for {
+		var batch []models.UsageEvent
+		if err := s.db.Order("id").Limit(usageExportBatch).Find(&batch).Error; err != nil {
+			return exported, errors.New("failed to load usage events")
+		}
+		if len(batch) == 0 {
+			return exported, nil
+		}
+
+		events := make([]billing.Event, len(batch))
+		ids := make([]uint, len(batch))
+		for i, row := range batch {
+			events[i] = billing.Event{
+				ID:         row.ID,
+				UserID:     row.UserID,
+				Plan:       row.PlanCode,
+				Metric:     row.Metric,
+				Quantity:   row.Quantity,
+				OccurredAt: row.OccurredAt,
+			}
+			ids[i] = row.ID
+		}
+
+		if err := s.sink.Export(ctx, events); err != nil {
+			return exported, fmt.Errorf("billing sink rejected usage: %w", err)
+		}
+		if err := s.db.Where("id IN ?", ids).Delete(&models.UsageEvent{}).Error; err != nil {
+			return exported, errors.New("failed to remove exported usage events")
+		}
+
+		exported += len(batch)
+		if len(batch) < usageExportBatch {
+			return exported, nil
+		}
+	}
+}
@@ -0,0 +1,727 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	mathrand "math/rand"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	webhookSecretPrefix = "whsec_"
+	// webhookBatch is the most deliveries attempted, or expired links
+	// queued, per query.
+	webhookBatch = 100
+	// Failed deliveries are retried after webhookRetryBase, doubling each
+	// time up to webhookRetryMax.
+	webhookRetryBase = 30 * time.Second
+	webhookRetryMax  = 6 * time.Hour
+	// webhookWorkers is how many webhooks are sent to at once.
+	webhookWorkers = 8
+)
+
+var (
+	ErrWebhookNotFound   = errors.New("webhook not found")
+	ErrDeliveryNotFound  = errors.New("delivery not found")
+	ErrDeliveryPending   = errors.New("delivery is still being attempted")
+	ErrInvalidWebhookURL = errors.New("webhook URL must be an absolute http or https URL")
+	ErrPrivateWebhookURL = errors.New("webhook URL must not point at a private or local address")
+	ErrInvalidEvents     = errors.New("events must list one or more of " + strings.Join(models.WebhookEvents, ", "))
+	ErrInvalidSampleRate = errors.New("click_sample_rate must be greater than 0 and at most 1")
+)
+
+// WebhookService manages the webhooks of workspaces and delivers their
+// events. Events are written to an outbox in the same transaction as the
+// change behind them, and a background job sends them, retrying failures
+// with exponential backoff until they are delivered or dead.
+type WebhookService struct {
+	db     *gorm.DB
+	config *config.Config
+	client *http.Client
+	done   chan struct{}
+}
+
+func NewWebhookService(cfg *config.Config) *WebhookService {
+	return &WebhookService{
+		db:     database.GetDB(),
+		config: cfg,
+		client: webhookClient(cfg),
+		done:   make(chan struct{}),
+	}
+}
+
+// webhookClient sends deliveries without following redirects. Unless
+// private targets are allowed, it refuses to connect to private and local
+// addresses, whatever the endpoint's host name resolves to.
+func webhookClient(cfg *config.Config) *http.Client {
+	timeout := time.Duration(cfg.WebhookTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if !cfg.WebhookAllowPrivateTargets {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return ErrPrivateWebhookURL
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: timeout},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// nonPublicNets are ranges that are not reachable on the public internet
+// but that the net.IP predicates do not cover.
+var nonPublicNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8",      // "this network"
+		"100.64.0.0/10",  // carrier-grade NAT
+		"192.0.0.0/24",   // IETF protocol assignments
+		"198.18.0.0/15",  // benchmarking
+		"240.0.0.0/4",    // reserved, including broadcast
+		"64:ff9b::/96",   // NAT64, which embeds an IPv4 address
+		"64:ff9b:1::/48", // local-use NAT64
+	} {
+		_, ipNet, _ := net.ParseCIDR(cidr)
+		nets = append(nets, ipNet)
+	}
+	return nets
+}()
+
+func isPrivateIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, ipNet := range nonPublicNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// signWebhook signs a delivery body and its timestamp. Receivers recompute
+// it to check that the delivery came from us and was not replayed later.
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff is how long to wait after a delivery's nth failed attempt.
+func webhookBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	if attempts > 16 {
+		return webhookRetryMax
+	}
+	if delay := webhookRetryBase << (attempts - 1); delay < webhookRetryMax {
+		return delay
+	}
+	return webhookRetryMax
+}
+
+// enqueueWebhooks queues an event for every active webhook in the workspace
+// that subscribes to it, sampling clicks at each webhook's rate. Pass the
+// transaction making the change so the event is only sent if the change is
+// kept. Links without a workspace have no webhooks.
+func enqueueWebhooks(tx *gorm.DB, workspaceID *uint, event string, data interface{}, now time.Time) error {
+	if workspaceID == nil {
+		return nil
+	}
+
+	var webhooks []models.Webhook
+	if err := tx.Where("workspace_id = ? AND active = ?", *workspaceID, true).Find(&webhooks).Error; err != nil {
+		return err
+	}
+
+	var deliveries []models.WebhookDelivery
+	var payload []byte
+	var eventID string
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event) {
+			continue
+		}
+		if event == models.WebhookClickRecorded && mathrand.Float64() >= webhook.ClickSampleRate {
+			continue
+		}
+
+		if payload == nil {
+			b := make([]byte, 12)
+			if _, err := rand.Read(b); err != nil {
+				return err
+			}
+			eventID = "evt_" + hex.EncodeToString(b)
+
+			var err error
+			payload, err = json.Marshal(models.WebhookEvent{
+				ID:          eventID,
+				Type:        event,
+				CreatedAt:   now.UTC(),
+				WorkspaceID: *workspaceID,
+				Data:        data,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       eventID,
+			Event:         event,
+			Payload:       payload,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return tx.Create(&deliveries).Error
+}
+
+// deleteWorkspaceWebhooks deletes a workspace's webhooks and their
+// deliveries.
+func deleteWorkspaceWebhooks(tx *gorm.DB, workspaceID uint) error {
+	webhookIDs := tx.Model(&models.Webhook{}).Select("id").Where("workspace_id = ?", workspaceID)
+	if err := tx.Where("webhook_id IN (?)", webhookIDs).Delete(&models.WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("workspace_id = ?", workspaceID).Delete(&models.Webhook{}).Error
+}
+
+// getAdminWebhook loads a webhook the user can see. Only workspace admins
+// manage webhooks; other members get ErrInsufficientRole.
+func getAdminWebhook(db *gorm.DB, webhookID, userID uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := db.Where("id = ? AND workspace_id IN (?)", webhookID, memberWorkspaceIDs(db, userID, models.RoleViewer)).First(&webhook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, errors.New("database error")
+	}
+
+	if _, err := requireRole(db, webhook.WorkspaceID, userID, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// checkWebhookRequest validates a request and applies it to webhook.
+func (s *WebhookService) checkWebhookRequest(req *models.WebhookRequest, webhook *models.Webhook) error {
+	endpoint, err := neturl.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Hostname() == "" || len(endpoint.String()) > 2048 {
+		return ErrInvalidWebhookURL
+	}
+	// Host names are checked again when connecting, after resolution.
+	if !s.config.WebhookAllowPrivateTargets {
+		host := strings.ToLower(endpoint.Hostname())
+		if ip := net.ParseIP(host); (ip != nil && isPrivateIP(ip)) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			return ErrPrivateWebhookURL
+		}
+	}
+
+	if len(req.Events) == 0 {
+		return ErrInvalidEvents
+	}
+	events := make([]string, 0, len(req.Events))
+	seen := make(map[string]bool)
+	for _, event := range req.Events {
+		valid := false
+		for _, known := range models.WebhookEvents {
+			valid = valid || event == known
+		}
+		if !valid {
+			return ErrInvalidEvents
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+
+	rate := 1.0
+	if req.ClickSampleRate != nil {
+		rate = *req.ClickSampleRate
+		if !(rate > 0 && rate <= 1) {
+			return ErrInvalidSampleRate
+		}
+	}
+
+	description := strings.TrimSpace(req.Description)
+	if len(description) > 200 {
+		return errors.New("description must be at most 200 characters")
+	}
+
+	webhook.URL = endpoint.String()
+	webhook.Description = description
+	webhook.Events = events
+	webhook.ClickSampleRate = rate
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	return nil
+}
+
+// ListWebhooks returns the webhooks of the workspaces the user administers,
+// or of just one.
+func (s *WebhookService) ListWebhooks(userID uint, workspaceID *uint) ([]models.Webhook, error) {
+	query := s.db.Where("workspace_id IN (?)", memberWorkspaceIDs(s.db, userID, models.RoleAdmin))
+	if workspaceID != nil {
+		query = query.Where("workspace_id = ?", *workspaceID)
+	}
+
+	webhooks := []models.Webhook{}
+	if err := query.Order("created_at DESC, id DESC").Find(&webhooks).Error; err != nil {
+		return nil, errors.New("failed to fetch webhooks")
+	}
+	return webhooks, nil
+}
+
+func (s *WebhookService) GetWebhook(webhookID, userID uint) (*models.Webhook, error) {
+	return getAdminWebhook(s.db, webhookID, userID)
+}
+
+// CreateWebhook registers an endpoint in a workspace the user administers,
+// their personal one unless another is chosen. It returns the signing
+// secret, which is not shown again.
+func (s *WebhookService) CreateWebhook(userID uint, req *models.WebhookRequest) (string, *models.Webhook, error) {
+	var workspaceID uint
+	if req.WorkspaceID == nil {
+		id, err := personalWorkspaceID(s.db, userID)
+		if err != nil {
+			return "", nil, err
+		}
+		workspaceID = id
+	} else {
+		if _, err := requireRole(s.db, *req.WorkspaceID, userID, models.RoleAdmin); err != nil {
+			return "", nil, err
+		}
+		workspaceID = *req.WorkspaceID
+	}
+
+	webhook := &models.Webhook{WorkspaceID: workspaceID, CreatedByID: &userID, Active: true}
+	if err := s.checkWebhookRequest(req, webhook); err != nil {
+		return "", nil, err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, errors.New("failed to generate webhook secret")
+	}
+	webhook.Secret = webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	// Create writes the zero value of Active as the column default, so an
+	// inactive webhook is switched off separately.
+	active := webhook.Active
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(webhook).Error; err != nil {
+			return err
+		}
+		if active {
+			return nil
+		}
+		webhook.Active = false
+		return tx.Model(webhook).Update("active", false).Error
+	})
+	if err != nil {
+		return "", nil, errors.New("failed to create webhook")
+	}
+	return webhook.Secret, webhook, nil
+}
+
+// UpdateWebhook replaces a webhook's endpoint, description, events and
+// sample rate, and pauses or resumes it when Active is set. Deliveries
+// queued while a webhook is paused are sent when it resumes.
+func (s *WebhookService) UpdateWebhook(webhookID, userID uint, req *models.WebhookRequest) (*models.Webhook, error) {
+	webhook, err := getAdminWebhook(s.db, webhookID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWebhookRequest(req, webhook); err != nil {
+		return nil, err
+	}
+
+	// Updating from the struct applies the events column's JSON serializer,
+	// which a map update would skip.
+	if err := s.db.Model(webhook).Select("url", "description", "events", "click_sample_rate", "active").
+		Updates(webhook).Error; err != nil {
+		return nil, errors.New("failed to update webhook")
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook deletes a webhook and its deliveries, including any not
+// yet sent.
+func (s *WebhookService) DeleteWebhook(webhookID, userID uint) error {
+	webhook, err := getAdminWebhook(s.db, webhookID, userID)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(webhook).Error
+	})
+	if err != nil {
+		return errors.New("failed to delete webhook")
+	}
+	return nil
+}
+
+// ListDeliveries returns a webhook's deliveries, newest first, optionally
+// only those in one state.
+func (s *WebhookService) ListDeliveries(webhookID, userID uint, status string, limit, offset int) ([]models.WebhookDelivery, int64, error) {
+	webhook, err := getAdminWebhook(s.db, webhookID, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := s.db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhook.ID)
+	switch status {
+	case "":
+	case models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+		query = query.Where("status = ?", status)
+	default:
+		return nil, 0, errors.New("status must be one of pending, delivered, dead")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("failed to count deliveries")
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&deliveries).Error; err != nil {
+		return nil, 0, errors.New("failed to fetch deliveries")
+	}
+	return deliveries, total, nil
+}
+
+// Redeliver queues a dead or delivered delivery to be sent again with a
+// fresh set of attempts. The payload, and so the event ID, is unchanged.
+func (s *WebhookService) Redeliver(webhookID, deliveryID, userID uint, now time.Time) (*models.WebhookDelivery, error) {
+	webhook, err := getAdminWebhook(s.db, webhookID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var delivery models.WebhookDelivery
+	if err := s.db.Where("id = ? AND webhook_id = ?", deliveryID, webhook.ID).First(&delivery).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, errors.New("database error")
+	}
+	if delivery.Status == models.DeliveryPending {
+		return nil, ErrDeliveryPending
+	}
+
+	result := s.db.Model(&delivery).Where("status <> ?", models.DeliveryPending).Updates(map[string]interface{}{
+		"status":          models.DeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": now,
+		"last_error":      "",
+	})
+	if result.Error != nil {
+		return nil, errors.New("failed to redeliver")
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrDeliveryPending
+	}
+	delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastError = models.DeliveryPending, 0, now, ""
+	return &delivery, nil
+}
+
+// RedeliverDead queues every dead delivery of a webhook to be sent again
+// and returns how many there were.
+func (s *WebhookService) RedeliverDead(webhookID, userID uint, now time.Time) (int64, error) {
+	webhook, err := getAdminWebhook(s.db, webhookID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	result := s.db.Model(&models.WebhookDelivery{}).
+		Where("webhook_id = ? AND status = ?", webhook.ID, models.DeliveryDead).
+		Updates(map[string]interface{}{
+			"status":          models.DeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+			"last_error":      "",
+		})
+	if result.Error != nil {
+		return 0, errors.New("failed to redeliver")
+	}
+	return result.RowsAffected, nil
+}
+
+// Start runs the delivery job on the configured interval until Close is
+// called.
+func (s *WebhookService) Start() {
+	interval := time.Duration(s.config.WebhookInterval) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := s.QueueExpiredLinks(time.Now()); err != nil {
+					log.Printf("Webhooks: %v", err)
+				}
+				if _, err := s.DeliverDue(context.Background(), time.Now()); err != nil {
+					log.Printf("Webhooks: %v", err)
+				}
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the delivery job
+func (s *WebhookService) Close() {
+	close(s.done)
+}
+
+// QueueExpiredLinks queues link.expired for every link that has passed its
+// expiry since the event was last sent for it, and returns how many links
+// it found.
+func (s *WebhookService) QueueExpiredLinks(now time.Time) (int, error) {
+	total := 0
+	for {
+		var urls []models.URL
+		if err := s.db.Where("expires_at IS NOT NULL AND expires_at <= ? AND expiry_notified_at IS NULL", now).
+			Order("id").Limit(webhookBatch).Find(&urls).Error; err != nil {
+			return total, errors.New("failed to load expired links")
+		}
+		if len(urls) == 0 {
+			return total, nil
+		}
+
+		for i := range urls {
+			url := &urls[i]
+			err := s.db.Transaction(func(tx *gorm.DB) error {
+				// Another instance may have queued the event already.
+				result := tx.Model(&models.URL{}).Where("id = ? AND expiry_notified_at IS NULL", url.ID).
+					UpdateColumn("expiry_notified_at", now)
+				if result.Error != nil || result.RowsAffected == 0 {
+					return result.Error
+				}
+				return enqueueWebhooks(tx, url.WorkspaceID, models.WebhookLinkExpired, &models.WebhookLinkData{Link: url}, now)
+			})
+			if err != nil {
+				return total, errors.New("failed to queue link.expired")
+			}
+		}
+		total += len(urls)
+	}
+}
+
+// DeliverDue attempts the deliveries of active webhooks that are due at
+// now, and returns how many succeeded. Webhooks are sent to concurrently,
+// each in the order its events happened. A failed delivery holds back the
+// webhook's later events until its retry, and no attempt starts later than
+// one timeout into the run, so a slow endpoint cannot hold up the others.
+// Each attempt is claimed before it is made, so several instances can run
+// the job at once.
+func (s *WebhookService) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	// Attempts are timed from when they are made, not when the run started.
+	started := time.Now()
+	clock := func() time.Time { return now.Add(time.Since(started)) }
+
+	// A delivery waits while an earlier one to the same webhook is backing
+	// off or claimed, so a failed event is never overtaken by later ones.
+	var due []models.WebhookDelivery
+	if err := s.db.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Where("webhook_id IN (?)", s.db.Model(&models.Webhook{}).Select("id").Where("active = ?", true)).
+		Where("NOT EXISTS (SELECT 1 FROM webhook_deliveries earlier WHERE earlier.webhook_id = webhook_deliveries.webhook_id AND earlier.status = ? AND earlier.id < webhook_deliveries.id AND earlier.next_attempt_at > ?)", models.DeliveryPending, now).
+		Order("id").Limit(webhookBatch).Find(&due).Error; err != nil {
+		return 0, errors.New("failed to load due deliveries")
+	}
+	if len(due) == 0 {
+		return 0, nil
+	}
+
+	webhookIDs := make([]uint, 0, len(due))
+	byWebhook := make(map[uint][]*models.WebhookDelivery)
+	for i := range due {
+		webhookIDs = append(webhookIDs, due[i].WebhookID)
+		byWebhook[due[i].WebhookID] = append(byWebhook[due[i].WebhookID], &due[i])
+	}
+	webhookIDs = uniqueIDs(webhookIDs)
+	var webhooks []models.Webhook
+	if err := s.db.Where("id IN ?", webhookIDs).Find(&webhooks).Error; err != nil {
+		return 0, errors.New("failed to load webhooks")
+	}
+
+	deadline := now.Add(s.client.Timeout)
+	var (
+		mu        sync.Mutex
+		delivered int
+		firstErr  error
+		wg        sync.WaitGroup
+	)
+	workers := make(chan struct{}, webhookWorkers)
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		wg.Add(1)
+		workers <- struct{}{}
+		go func() {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			for _, delivery := range byWebhook[webhook.ID] {
+				if ctx.Err() != nil || clock().After(deadline) {
+					return
+				}
+				ok, err := s.attempt(ctx, webhook, delivery, clock)
+
+				mu.Lock()
+				if ok {
+					delivered++
+				}
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				if !ok {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return delivered, firstErr
+}
+
+// attempt claims a delivery, sends it and records the outcome. It reports
+// whether the delivery succeeded; the error is only set when the outcome
+// could not be recorded.
+func (s *WebhookService) attempt(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery, clock func() time.Time) (bool, error) {
+	maxAttempts := s.config.WebhookMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 8
+	}
+	// A claimed attempt that never reports back, because the instance
+	// stopped, is retried once its lease runs out.
+	lease := 2 * s.client.Timeout
+
+	claimedAt := clock()
+	claim := s.db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND attempts = ?", delivery.ID, models.DeliveryPending, delivery.Attempts).
+		Updates(map[string]interface{}{
+			"attempts":        delivery.Attempts + 1,
+			"last_attempt_at": claimedAt,
+			"next_attempt_at": claimedAt.Add(lease),
+		})
+	if claim.Error != nil {
+		return false, errors.New("failed to claim delivery")
+	}
+	if claim.RowsAffected == 0 {
+		// Another instance has it; later events for this webhook wait so
+		// they are not sent ahead of it.
+		return false, nil
+	}
+	delivery.Attempts++
+
+	status, err := s.send(ctx, webhook, delivery, claimedAt)
+	finishedAt := clock()
+	updates := map[string]interface{}{"response_status": status, "last_error": ""}
+	if err != nil {
+		message := err.Error()
+		if len(message) > 500 {
+			message = message[:500]
+		}
+		updates["last_error"] = message
+	}
+	switch {
+	case err == nil:
+		updates["status"] = models.DeliveryDelivered
+		updates["delivered_at"] = finishedAt
+	case delivery.Attempts >= maxAttempts:
+		updates["status"] = models.DeliveryDead
+	default:
+		updates["next_attempt_at"] = finishedAt.Add(webhookBackoff(delivery.Attempts))
+	}
+	if err := s.db.Model(delivery).Updates(updates).Error; err != nil {
+		return false, errors.New("failed to record delivery attempt")
+	}
+	return err == nil, nil
+}
+
+// send posts a delivery to its webhook and returns the response status.
+// Anything but a 2xx response is a failure.
+func (s *WebhookService) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "url-shortener-webhooks/1.0")
+	req.Header.Set("X-Webhook-ID", delivery.EventID)
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", signWebhook(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
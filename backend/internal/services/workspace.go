@@ -0,0 +1,560 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/mail"
+	"url-shortener-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+var (
+	ErrWorkspaceNotFound       = errors.New("workspace not found")
+	ErrInsufficientRole        = errors.New("your role in this workspace does not allow this")
+	ErrInvalidRole             = errors.New("role must be one of owner, admin, editor, viewer")
+	ErrMemberNotFound          = errors.New("member not found")
+	ErrAlreadyMember           = errors.New("user is already a member of this workspace")
+	ErrLastOwner               = errors.New("a workspace must keep at least one owner")
+	ErrPersonalWorkspace       = errors.New("personal workspaces cannot be deleted")
+	ErrInvalidInvitation       = errors.New("invitation is invalid or has expired")
+	ErrInvitationEmailMismatch = errors.New("invitation was sent to a different email address")
+)
+
+// WorkspaceService manages workspaces, their members and invitations. Links
+// belong to a workspace, and a member's role decides what they may do:
+// viewers read, editors manage links, admins manage members, and owners
+// manage the workspace itself.
+type WorkspaceService struct {
+	db     *gorm.DB
+	config *config.Config
+	mailer mail.Mailer
+}
+
+func NewWorkspaceService(cfg *config.Config, mailer mail.Mailer) *WorkspaceService {
+	return &WorkspaceService{
+		db:     database.GetDB(),
+		config: cfg,
+		mailer: mailer,
+	}
+}
+
+// memberWorkspaceIDs is the subquery selecting the workspaces in which the
+// user holds at least the given role.
+func memberWorkspaceIDs(db *gorm.DB, userID uint, minRole string) *gorm.DB {
+	return db.Model(&models.WorkspaceMember{}).Select("workspace_id").
+		Where("user_id = ? AND role IN ?", userID, models.RolesAtLeast(minRole))
+}
+
+// memberRole returns the user's role in a workspace. Non-members get
+// ErrWorkspaceNotFound so the workspace's existence is not revealed.
+func memberRole(db *gorm.DB, workspaceID, userID uint) (string, error) {
+	var member models.WorkspaceMember
+	err := db.Joins("JOIN workspaces ON workspaces.id = workspace_members.workspace_id AND workspaces.deleted_at IS NULL").
+		Where("workspace_members.workspace_id = ? AND workspace_members.user_id = ?", workspaceID, userID).
+		First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrWorkspaceNotFound
+		}
+		return "", errors.New("database error")
+	}
+	return member.Role, nil
+}
+
+func requireRole(db *gorm.DB, workspaceID, userID uint, minRole string) (string, error) {
+	role, err := memberRole(db, workspaceID, userID)
+	if err != nil {
+		return "", err
+	}
+	if !models.RoleAtLeast(role, minRole) {
+		return role, ErrInsufficientRole
+	}
+	return role, nil
+}
+
+// personalWorkspaceID returns the user's personal workspace, creating it on
+// first use.
+func personalWorkspaceID(db *gorm.DB, userID uint) (uint, error) {
+	var workspace models.Workspace
+	err := db.Unscoped().Where("personal_user_id = ?", userID).First(&workspace).Error
+	if err == nil {
+		return workspace.ID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, errors.New("database error")
+	}
+
+	var user models.User
+	if err := db.Unscoped().Select("id", "name").Where("id = ?", userID).First(&user).Error; err != nil {
+		return 0, errors.New("user not found")
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		workspace = models.Workspace{Name: personalWorkspaceName(user.Name), PersonalUserID: &userID}
+		if err := tx.Create(&workspace).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: userID, Role: models.RoleOwner}).Error
+	})
+	if err != nil {
+		// Another request may have created it concurrently.
+		if db.Unscoped().Where("personal_user_id = ?", userID).First(&workspace).Error == nil {
+			return workspace.ID, nil
+		}
+		return 0, errors.New("failed to create personal workspace")
+	}
+
+	return workspace.ID, nil
+}
+
+// editableWorkspace returns the workspace something new is created in: the
+// requested one if the user may edit there, or else their personal one.
+func editableWorkspace(db *gorm.DB, userID uint, workspaceID *uint) (uint, error) {
+	if workspaceID == nil {
+		return personalWorkspaceID(db, userID)
+	}
+	if _, err := requireRole(db, *workspaceID, userID, models.RoleEditor); err != nil {
+		return 0, err
+	}
+	return *workspaceID, nil
+}
+
+func personalWorkspaceName(userName string) string {
+	name := strings.TrimSpace(userName)
+	if name == "" {
+		return "Personal"
+	}
+	name += "'s workspace"
+	if len(name) > 100 {
+		return "Personal"
+	}
+	return name
+}
+
+func isWorkspaceRole(role string) bool {
+	for _, r := range models.WorkspaceRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// MigratePersonalWorkspaces gives every user a personal workspace and moves
+// links that predate workspaces into their creator's personal workspace. It
+// is safe to run on every start.
+func (s *WorkspaceService) MigratePersonalWorkspaces() (int64, error) {
+	var userIDs []uint
+	if err := s.db.Unscoped().Model(&models.User{}).
+		Where("id NOT IN (?)", s.db.Unscoped().Model(&models.Workspace{}).Select("personal_user_id").Where("personal_user_id IS NOT NULL")).
+		Pluck("id", &userIDs).Error; err != nil {
+		return 0, errors.New("failed to load users")
+	}
+
+	for _, userID := range userIDs {
+		if _, err := personalWorkspaceID(s.db, userID); err != nil {
+			return 0, err
+		}
+	}
+
+	result := s.db.Unscoped().Model(&models.URL{}).
+		Where("workspace_id IS NULL AND user_id IS NOT NULL").
+		Update("workspace_id", gorm.Expr("(SELECT w.id FROM workspaces w WHERE w.personal_user_id = urls.user_id)"))
+	if result.Error != nil {
+		return 0, errors.New("failed to move links into workspaces")
+	}
+
+	return result.RowsAffected, nil
+}
+
+// ListWorkspaces returns the workspaces the user belongs to, with the user's
+// role in each.
+func (s *WorkspaceService) ListWorkspaces(userID uint) ([]models.Workspace, error) {
+	if _, err := personalWorkspaceID(s.db, userID); err != nil {
+		return nil, err
+	}
+
+	var members []models.WorkspaceMember
+	if err := s.db.Where("user_id = ?", userID).Find(&members).Error; err != nil {
+		return nil, errors.New("failed to fetch workspaces")
+	}
+
+	roles := make(map[uint]string, len(members))
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		roles[m.WorkspaceID] = m.Role
+		ids = append(ids, m.WorkspaceID)
+	}
+
+	var workspaces []models.Workspace
+	if err := s.db.Where("id IN ?", ids).Order("personal_user_id IS NULL, name").Find(&workspaces).Error; err != nil {
+		return nil, errors.New("failed to fetch workspaces")
+	}
+	for i := range workspaces {
+		workspaces[i].Role = roles[workspaces[i].ID]
+	}
+
+	return workspaces, nil
+}
+
+func (s *WorkspaceService) CreateWorkspace(userID uint, req *models.WorkspaceRequest) (*models.Workspace, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, errors.New("workspace name must be between 1 and 100 characters")
+	}
+
+	workspace := models.Workspace{Name: name, Role: models.RoleOwner}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&workspace).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: userID, Role: models.RoleOwner}).Error
+	})
+	if err != nil {
+		return nil, errors.New("failed to create workspace")
+	}
+
+	return &workspace, nil
+}
+
+func (s *WorkspaceService) GetWorkspace(workspaceID, userID uint) (*models.Workspace, error) {
+	role, err := memberRole(s.db, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var workspace models.Workspace
+	if err := s.db.Where("id = ?", workspaceID).First(&workspace).Error; err != nil {
+		return nil, ErrWorkspaceNotFound
+	}
+	workspace.Role = role
+
+	return &workspace, nil
+}
+
+func (s *WorkspaceService) UpdateWorkspace(workspaceID, userID uint, req *models.WorkspaceRequest) (*models.Workspace, error) {
+	if _, err := requireRole(s.db, workspaceID, userID, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, errors.New("workspace name must be between 1 and 100 characters")
+	}
+
+	if err := s.db.Model(&models.Workspace{}).Where("id = ?", workspaceID).Update("name", name).Error; err != nil {
+		return nil, errors.New("failed to update workspace")
+	}
+
+	return s.GetWorkspace(workspaceID, userID)
+}
+
+// DeleteWorkspace deletes a shared workspace together with its links,
+// members, webhooks and pending invitations.
+func (s *WorkspaceService) DeleteWorkspace(workspaceID, userID uint) error {
+	if _, err := requireRole(s.db, workspaceID, userID, models.RoleOwner); err != nil {
+		return err
+	}
+
+	var workspace models.Workspace
+	if err := s.db.Where("id = ?", workspaceID).First(&workspace).Error; err != nil {
+		return ErrWorkspaceNotFound
+	}
+	if workspace.PersonalUserID != nil {
+		return ErrPersonalWorkspace
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("workspace_id = ?", workspaceID).Delete(&models.URL{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workspace_id = ?", workspaceID).Delete(&models.WorkspaceInvitation{}).Error; err != nil {
+			return err
+		}
+		if err := deleteWorkspaceWebhooks(tx, workspaceID); err != nil {
+			return err
+		}
+		if err := tx.Where("workspace_id = ?", workspaceID).Delete(&models.WorkspaceMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&workspace).Error
+	})
+	if err != nil {
+		return errors.New("failed to delete workspace")
+	}
+
+	return nil
+}
+
+func (s *WorkspaceService) ListMembers(workspaceID, userID uint) ([]models.WorkspaceMember, error) {
+	if _, err := memberRole(s.db, workspaceID, userID); err != nil {
+		return nil, err
+	}
+
+	var members []models.WorkspaceMember
+	if err := s.db.Preload("User").Where("workspace_id = ?", workspaceID).Order("created_at").Find(&members).Error; err != nil {
+		return nil, errors.New("failed to fetch members")
+	}
+
+	return members, nil
+}
+
+// UpdateMemberRole changes a member's role. Admins manage non-owners; only
+// owners can grant or take away ownership, and the last owner stays.
+func (s *WorkspaceService) UpdateMemberRole(workspaceID, actorID, memberUserID uint, role string) (*models.WorkspaceMember, error) {
+	if !isWorkspaceRole(role) {
+		return nil, ErrInvalidRole
+	}
+
+	actorRole, err := requireRole(s.db, workspaceID, actorID, models.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	var member models.WorkspaceMember
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("workspace_id = ? AND user_id = ?", workspaceID, memberUserID).First(&member).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrMemberNotFound
+			}
+			return err
+		}
+
+		if (member.Role == models.RoleOwner || role == models.RoleOwner) && actorRole != models.RoleOwner {
+			return ErrInsufficientRole
+		}
+		if member.Role == models.RoleOwner && role != models.RoleOwner {
+			if err := ensureAnotherOwner(tx, workspaceID, memberUserID); err != nil {
+				return err
+			}
+		}
+
+		member.Role = role
+		return tx.Model(&member).Update("role", role).Error
+	})
+	if err != nil {
+		return nil, workspaceTxError(err, "failed to update member")
+	}
+
+	return &member, nil
+}
+
+// RemoveMember removes a member. Any member may leave; removing someone else
+// takes an admin, or an owner when the member is an owner.
+func (s *WorkspaceService) RemoveMember(workspaceID, actorID, memberUserID uint) error {
+	actorRole, err := memberRole(s.db, workspaceID, actorID)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var member models.WorkspaceMember
+		if err := tx.Where("workspace_id = ? AND user_id = ?", workspaceID, memberUserID).First(&member).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrMemberNotFound
+			}
+			return err
+		}
+
+		if actorID != memberUserID {
+			if !models.RoleAtLeast(actorRole, models.RoleAdmin) {
+				return ErrInsufficientRole
+			}
+			if member.Role == models.RoleOwner && actorRole != models.RoleOwner {
+				return ErrInsufficientRole
+			}
+		}
+		if member.Role == models.RoleOwner {
+			if err := ensureAnotherOwner(tx, workspaceID, memberUserID); err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&member).Error
+	})
+
+	return workspaceTxError(err, "failed to remove member")
+}
+
+func ensureAnotherOwner(tx *gorm.DB, workspaceID, userID uint) error {
+	var owners int64
+	if err := tx.Model(&models.WorkspaceMember{}).
+		Where("workspace_id = ? AND role = ? AND user_id <> ?", workspaceID, models.RoleOwner, userID).
+		Count(&owners).Error; err != nil {
+		return err
+	}
+	if owners == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// workspaceTxError passes through this service's errors and hides database
+// errors behind a generic message.
+func workspaceTxError(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	for _, known := range []error{ErrMemberNotFound, ErrInsufficientRole, ErrLastOwner, ErrAlreadyMember, ErrInvalidInvitation, ErrInvitationEmailMismatch} {
+		if errors.Is(err, known) {
+			return err
+		}
+	}
+	return errors.New(message)
+}
+
+// Invite emails an invitation link to join the workspace with the given role.
+func (s *WorkspaceService) Invite(workspaceID, actorID uint, req *models.InviteMemberRequest) (*models.WorkspaceInvitation, error) {
+	actorRole, err := requireRole(s.db, workspaceID, actorID, models.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	email, err := normalizeEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	role := req.Role
+	if role == "" {
+		role = models.RoleEditor
+	}
+	if !isWorkspaceRole(role) {
+		return nil, ErrInvalidRole
+	}
+	if role == models.RoleOwner && actorRole != models.RoleOwner {
+		return nil, ErrInsufficientRole
+	}
+
+	var existing int64
+	s.db.Model(&models.WorkspaceMember{}).
+		Joins("JOIN users ON users.id = workspace_members.user_id").
+		Where("workspace_members.workspace_id = ? AND users.email = ?", workspaceID, email).
+		Count(&existing)
+	if existing > 0 {
+		return nil, ErrAlreadyMember
+	}
+
+	var workspace models.Workspace
+	if err := s.db.Where("id = ?", workspaceID).First(&workspace).Error; err != nil {
+		return nil, ErrWorkspaceNotFound
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.New("failed to generate invitation")
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	invitation := models.WorkspaceInvitation{
+		WorkspaceID: workspaceID,
+		Email:       email,
+		Role:        role,
+		TokenHash:   hashToken(token),
+		InvitedByID: actorID,
+		ExpiresAt:   time.Now().Add(invitationTTL),
+	}
+	if err := s.db.Create(&invitation).Error; err != nil {
+		return nil, errors.New("failed to create invitation")
+	}
+
+	subject := fmt.Sprintf("You're invited to %s", workspace.Name)
+	body := fmt.Sprintf(
+		"You have been invited to join the workspace %q as %s.\n\n"+
+			"Sign in with this email address and accept the invitation here within 7 days:\n%s/invitations/accept?token=%s",
+		workspace.Name, role, s.config.FrontendURL, token,
+	)
+	if err := s.mailer.Send(mail.Message{To: email, Subject: subject, Body: body}); err != nil {
+		log.Printf("Failed to send %q to %s: %v", subject, email, err)
+	}
+
+	return &invitation, nil
+}
+
+// ListInvitations returns the workspace's pending invitations.
+func (s *WorkspaceService) ListInvitations(workspaceID, actorID uint) ([]models.WorkspaceInvitation, error) {
+	if _, err := requireRole(s.db, workspaceID, actorID, models.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	var invitations []models.WorkspaceInvitation
+	if err := s.db.Where("workspace_id = ? AND accepted_at IS NULL AND expires_at > ?", workspaceID, time.Now()).
+		Order("created_at DESC").Find(&invitations).Error; err != nil {
+		return nil, errors.New("failed to fetch invitations")
+	}
+
+	return invitations, nil
+}
+
+func (s *WorkspaceService) RevokeInvitation(workspaceID, actorID, invitationID uint) error {
+	if _, err := requireRole(s.db, workspaceID, actorID, models.RoleAdmin); err != nil {
+		return err
+	}
+
+	result := s.db.Where("id = ? AND workspace_id = ? AND accepted_at IS NULL", invitationID, workspaceID).
+		Delete(&models.WorkspaceInvitation{})
+	if result.Error != nil {
+		return errors.New("failed to revoke invitation")
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidInvitation
+	}
+
+	return nil
+}
+
+// AcceptInvitation adds the user to the invited workspace. The invitation
+// only works for the account whose email it was sent to.
+func (s *WorkspaceService) AcceptInvitation(userID uint, token string) (*models.Workspace, error) {
+	var user models.User
+	if err := s.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	var workspaceID uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var invitation models.WorkspaceInvitation
+		if err := tx.Where("token_hash = ? AND accepted_at IS NULL AND expires_at > ?", hashToken(token), time.Now()).
+			First(&invitation).Error; err != nil {
+			return ErrInvalidInvitation
+		}
+		if !strings.EqualFold(invitation.Email, user.Email) {
+			return ErrInvitationEmailMismatch
+		}
+
+		var count int64
+		if err := tx.Model(&models.Workspace{}).Where("id = ?", invitation.WorkspaceID).Count(&count).Error; err != nil || count == 0 {
+			return ErrInvalidInvitation
+		}
+
+		now := time.Now()
+		if err := tx.Model(&invitation).Update("accepted_at", now).Error; err != nil {
+			return err
+		}
+
+		tx.Model(&models.WorkspaceMember{}).Where("workspace_id = ? AND user_id = ?", invitation.WorkspaceID, userID).Count(&count)
+		if count == 0 {
+			member := models.WorkspaceMember{WorkspaceID: invitation.WorkspaceID, UserID: userID, Role: invitation.Role}
+			if err := tx.Create(&member).Error; err != nil {
+				return err
+			}
+		}
+
+		workspaceID = invitation.WorkspaceID
+		return nil
+	})
+	if err != nil {
+		return nil, workspaceTxError(err, "failed to accept invitation")
+	}
+
+	return s.GetWorkspace(workspaceID, userID)
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"net"
+	"strings"
+)
+
+// AnonymizeIP truncates an address before it is stored: IPv4 addresses are
+// reduced to their /24 network and IPv6 addresses to their /48 prefix.
+// Values that do not parse as an IP are dropped entirely.
+func AnonymizeIP(rawIP string) string {
+	ip := net.ParseIP(strings.TrimSpace(rawIP))
+	if ip == nil {
+		return ""
+	}
+
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// TrackingOptOut reports whether the visitor asked not to be tracked through
+// the DNT or Sec-GPC request headers.
+func TrackingOptOut(dnt, gpc string) bool {
+	return strings.TrimSpace(dnt) == "1" || strings.TrimSpace(gpc) == "1"
+}
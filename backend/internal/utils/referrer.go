@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+const (
+	SourceDirect   = "direct"
+	SourceSearch   = "search"
+	SourceSocial   = "social"
+	SourceEmail    = "email"
+	SourceInternal = "internal"
+	SourceOther    = "other"
+)
+
+// referrerSources maps referring domains and mobile app package names to a
+// traffic source. Subdomains match their parent entry, and keys ending in
+// "." match that name directly under a public suffix (google. covers
+// google.co.uk but not google.example.com).
+var referrerSources = map[string]string{
+	// Search engines
+	"google.":          SourceSearch,
+	"bing.com":         SourceSearch,
+	"yahoo.":           SourceSearch,
+	"duckduckgo.com":   SourceSearch,
+	"baidu.com":        SourceSearch,
+	"yandex.":          SourceSearch,
+	"ecosia.org":       SourceSearch,
+	"search.brave.com": SourceSearch,
+	"startpage.com":    SourceSearch,
+
+	// Social networks and messaging
+	"facebook.com":         SourceSocial,
+	"fb.me":                SourceSocial,
+	"instagram.com":        SourceSocial,
+	"twitter.com":          SourceSocial,
+	"x.com":                SourceSocial,
+	"t.co":                 SourceSocial,
+	"linkedin.com":         SourceSocial,
+	"lnkd.in":              SourceSocial,
+	"reddit.com":           SourceSocial,
+	"pinterest.com":        SourceSocial,
+	"tiktok.com":           SourceSocial,
+	"youtube.com":          SourceSocial,
+	"threads.net":          SourceSocial,
+	"bsky.app":             SourceSocial,
+	"mastodon.social":      SourceSocial,
+	"news.ycombinator.com": SourceSocial,
+	"web.whatsapp.com":     SourceSocial,
+	"web.telegram.org":     SourceSocial,
+	"discord.com":          SourceSocial,
+	"slack.com":            SourceSocial,
+
+	// Webmail
+	"mail.google.com":       SourceEmail,
+	"outlook.live.com":      SourceEmail,
+	"outlook.office.com":    SourceEmail,
+	"outlook.office365.com": SourceEmail,
+	"mail.yahoo.com":        SourceEmail,
+	"mail.proton.me":        SourceEmail,
+	"mail.zoho.com":         SourceEmail,
+	"icloud.com":            SourceEmail,
+
+	// Android and iOS apps (android-app:// and ios-app:// referrers)
+	"com.google.android.googlequicksearchbox": SourceSearch,
+	"com.google.android.gm":                   SourceEmail,
+	"com.microsoft.office.outlook":            SourceEmail,
+	"com.yahoo.mobile.client.android.mail":    SourceEmail,
+	"ch.protonmail.android":                   SourceEmail,
+	"com.facebook.katana":                     SourceSocial,
+	"com.facebook.orca":                       SourceSocial,
+	"com.instagram.android":                   SourceSocial,
+	"com.twitter.android":                     SourceSocial,
+	"com.linkedin.android":                    SourceSocial,
+	"com.reddit.frontpage":                    SourceSocial,
+	"com.pinterest":                           SourceSocial,
+	"com.zhiliaoapp.musically":                SourceSocial,
+	"com.whatsapp":                            SourceSocial,
+	"org.telegram.messenger":                  SourceSocial,
+	"com.Slack":                               SourceSocial,
+	"com.discord":                             SourceSocial,
+}
+
+// countrySecondLevels are the second-level labels that countries register
+// names under, as in co.uk or com.au.
+var countrySecondLevels = map[string]bool{
+	"co": true, "com": true, "net": true, "org": true, "ac": true,
+	"gov": true, "edu": true, "or": true, "ne": true, "gob": true,
+}
+
+// isPublicSuffix reports whether labels form a suffix under which names are
+// registered: a top-level domain, or a country's second level such as
+// co.uk.
+func isPublicSuffix(labels []string) bool {
+	switch len(labels) {
+	case 1:
+		return labels[0] != ""
+	case 2:
+		return countrySecondLevels[labels[0]] && len(labels[1]) == 2
+	}
+	return false
+}
+
+// NormalizeReferrer reduces a raw Referer header to the referring domain,
+// lower-cased and without "www." or "m." prefixes. For android-app:// and
+// ios-app:// referrers the app package name is returned.
+func NormalizeReferrer(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "android-app", "ios-app":
+		// Package names are case sensitive, and iOS referrers carry the
+		// numeric App Store id before the bundle name.
+		if strings.ToLower(parsed.Scheme) == "ios-app" {
+			if parts := strings.Split(strings.Trim(parsed.Path, "/"), "/"); len(parts) > 0 && parts[0] != "" {
+				return parts[0]
+			}
+		}
+		return parsed.Host
+	case "http", "https":
+	default:
+		return ""
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	for _, prefix := range []string{"www.", "m.", "mobile."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+
+	return host
+}
+
+// ClassifyReferrer normalizes a raw Referer header and assigns it a traffic
+// source. Referrers from any of internalHosts are classified as internal.
+func ClassifyReferrer(raw string, internalHosts ...string) (domain, source string) {
+	if strings.TrimSpace(raw) == "" {
+		return "", SourceDirect
+	}
+
+	domain = NormalizeReferrer(raw)
+	if domain == "" {
+		return "", SourceOther
+	}
+
+	scheme := strings.ToLower(strings.SplitN(strings.TrimSpace(raw), ":", 2)[0])
+	if scheme == "android-app" || scheme == "ios-app" {
+		if source, ok := referrerSources[domain]; ok {
+			return domain, source
+		}
+		return domain, SourceOther
+	}
+
+	for _, host := range internalHosts {
+		if host != "" && NormalizeReferrer("https://"+host) == domain {
+			return domain, SourceInternal
+		}
+	}
+
+	labels := strings.Split(domain, ".")
+	for i := range labels {
+		suffix := strings.Join(labels[i:], ".")
+		if source, ok := referrerSources[suffix]; ok {
+			return domain, source
+		}
+		if isPublicSuffix(labels[i+1:]) {
+			if source, ok := referrerSources[labels[i]+"."]; ok {
+				return domain, source
+			}
+		}
+	}
+
+	return domain, SourceOther
+}
@@ -127,4 +127,26 @@ func ParseUserAgent(userAgent string) (device, os, browser string) {
 	}
 	
 	return
-}
\ No newline at end of file
+}
+
+var botUserAgentMarkers = []string{
+	"bot", "crawler", "spider", "slurp", "crawl", "preview", "facebookexternalhit",
+	"headlesschrome", "phantomjs", "curl/", "wget/", "python-requests", "go-http-client",
+	"httpclient", "okhttp", "java/", "libwww", "scrapy", "monitor", "pingdom",
+}
+
+// IsBotUserAgent reports whether a User-Agent looks like an automated client
+// such as a crawler, link unfurler or scripted HTTP library.
+func IsBotUserAgent(userAgent string) bool {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return true
+	}
+
+	for _, marker := range botUserAgentMarkers {
+		if strings.Contains(ua, marker) {
+			return true
+		}
+	}
+	return false
+}
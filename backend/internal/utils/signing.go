@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// DeriveKey derives a purpose-specific key from a master secret, so one
+// secret can key several independent signatures.
+func DeriveKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// SignValue appends an HMAC-SHA256 signature to value.
+func SignValue(key []byte, value string) string {
+	return value + "." + base64.RawURLEncoding.EncodeToString(computeMAC(key, value))
+}
+
+// VerifySignedValue checks a value produced by SignValue against any of the
+// given keys and returns the original value.
+func VerifySignedValue(signed string, keys ...[]byte) (string, bool) {
+	i := strings.LastIndexByte(signed, '.')
+	if i < 0 {
+		return "", false
+	}
+
+	value := signed[:i]
+	signature, err := base64.RawURLEncoding.DecodeString(signed[i+1:])
+	if err != nil {
+		return "", false
+	}
+
+	for _, key := range keys {
+		if hmac.Equal(signature, computeMAC(key, value)) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func computeMAC(key []byte, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,282 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/mail"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+	"url-shortener-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// proceedLink finds the "Continue anyway" link on a quarantine warning.
+var proceedLink = regexp.MustCompile(`href="/sus123(\?proceed=[^"]+)"`)
+
+type AbuseTestSuite struct {
+	suite.Suite
+	app          *fiber.App
+	db           *gorm.DB
+	mailer       *mockMailer
+	mailSeen     int
+	sessionStore *middleware.SimpleSessionStore
+	sessions     *middleware.SessionManager
+	adminSession string
+	owner        models.User
+	url          models.URL
+}
+
+func (suite *AbuseTestSuite) SetupSuite() {
+	cfg := &config.Config{
+		SessionSecret:        "test-session-secret",
+		Environment:          "test",
+		FrontendURL:          "http://localhost:3000",
+		AbuseReportThreshold: 2,
+	}
+
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+
+	suite.mailer = &mockMailer{}
+	suite.sessionStore = middleware.NewSimpleSessionStore()
+	suite.sessions = middleware.NewSessionManager(suite.sessionStore, cfg)
+	adminService := services.NewAdminService(cfg)
+	abuseHandler := handlers.NewAbuseHandler(services.NewAbuseService(cfg, suite.mailer))
+	urlHandler := handlers.NewURLHandler(services.NewURLService(), services.NewClickHub(0), cfg)
+
+	// Reporters are told apart by address, taken from X-Forwarded-For here.
+	suite.app = fiber.New(fiber.Config{ProxyHeader: fiber.HeaderXForwardedFor})
+	admin := suite.app.Group("/admin", suite.sessions.AuthMiddleware(), middleware.RequireAdmin(adminService))
+	admin.Get("/reports", abuseHandler.Queue)
+	admin.Get("/urls/:id/reports", abuseHandler.ListReports)
+	admin.Post("/urls/:id/reports/confirm", abuseHandler.ConfirmReports)
+	admin.Post("/urls/:id/reports/dismiss", abuseHandler.DismissReports)
+
+	suite.app.Get("/:shortCode", urlHandler.RedirectURL)
+	suite.app.Post("/:shortCode/report", suite.sessions.OptionalAuthMiddleware(), abuseHandler.Report)
+}
+
+func (suite *AbuseTestSuite) SetupTest() {
+	suite.mailSeen = len(suite.mailer.Sent())
+	now := time.Now()
+	admin := models.User{Name: "Admin", Email: "admin@example.com", EmailVerifiedAt: &now, IsAdmin: true}
+	suite.Require().NoError(suite.db.Create(&admin).Error)
+	suite.adminSession = "session-admin"
+	suite.sessionStore.Sessions[suite.adminSession] = &middleware.SessionData{
+		UserID:    admin.ID,
+		UserEmail: admin.Email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+
+	suite.owner = models.User{Name: "Owner", Email: "owner@example.com"}
+	suite.Require().NoError(suite.db.Create(&suite.owner).Error)
+	suite.url = models.URL{OriginalURL: "https://example.com/login", ShortCode: "sus123", UserID: &suite.owner.ID, IsActive: true}
+	suite.Require().NoError(suite.db.Create(&suite.url).Error)
+}
+
+func (suite *AbuseTestSuite) TearDownTest() {
+	for _, table := range []string{"abuse_reports", "admin_actions", "urls", "users"} {
+		suite.db.Exec("DELETE FROM " + table)
+	}
+}
+
+func (suite *AbuseTestSuite) report(ip string, req interface{}) *http.Response {
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest(http.MethodPost, "/"+suite.url.ShortCode+"/report", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(fiber.HeaderXForwardedFor, ip)
+	resp, err := suite.app.Test(httpReq)
+	suite.Require().NoError(err)
+	return resp
+}
+
+func (suite *AbuseTestSuite) adminRequest(method, path string, body interface{}) (*http.Response, map[string]interface{}) {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", "session_id="+suite.sessions.CookieValue(suite.adminSession))
+	resp, err := suite.app.Test(req)
+	suite.Require().NoError(err)
+
+	var decoded map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp, decoded
+}
+
+func (suite *AbuseTestSuite) visit(query string) *http.Response {
+	resp, err := suite.app.Test(httptest.NewRequest(http.MethodGet, "/"+suite.url.ShortCode+query, nil))
+	suite.Require().NoError(err)
+	return resp
+}
+
+// quarantine files reports from enough addresses to reach the threshold.
+func (suite *AbuseTestSuite) quarantine() {
+	for _, ip := range []string{"198.51.100.1", "198.51.100.2"} {
+		suite.Require().Equal(http.StatusAccepted, suite.report(ip, models.AbuseReportRequest{Category: models.ReportPhishing}).StatusCode)
+	}
+}
+
+// mailsTo returns the messages sent to email during the current test.
+func (suite *AbuseTestSuite) mailsTo(email string) []mail.Message {
+	var sent []mail.Message
+	for _, msg := range suite.mailer.Sent()[suite.mailSeen:] {
+		if msg.To == email {
+			sent = append(sent, msg)
+		}
+	}
+	return sent
+}
+
+func (suite *AbuseTestSuite) TestReportValidation() {
+	resp := suite.report("198.51.100.1", models.AbuseReportRequest{Category: "boring"})
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	req := httptest.NewRequest(http.MethodPost, "/"+suite.url.ShortCode+"/report", strings.NewReader("category=phishing"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := suite.app.Test(req)
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	req = httptest.NewRequest(http.MethodPost, "/missing/report", strings.NewReader(`{"category":"spam"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = suite.app.Test(req)
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *AbuseTestSuite) TestQuarantineAfterThreshold() {
+	// Repeated reports from one address count once.
+	for i := 0; i < 3; i++ {
+		suite.Equal(http.StatusAccepted, suite.report("198.51.100.1", models.AbuseReportRequest{Category: models.ReportPhishing}).StatusCode)
+	}
+	var reports int64
+	suite.db.Model(&models.AbuseReport{}).Count(&reports)
+	suite.Equal(int64(1), reports)
+	var report models.AbuseReport
+	suite.Require().NoError(suite.db.First(&report).Error)
+	suite.NotEmpty(report.ReporterIP)
+	suite.NotContains(report.ReporterIP, "198.51.100", "reporter addresses are not stored")
+	suite.Equal(http.StatusFound, suite.visit("").StatusCode)
+
+	suite.Equal(http.StatusAccepted, suite.report("198.51.100.2", models.AbuseReportRequest{Category: models.ReportMalware, Details: "asks for my bank password"}).StatusCode)
+
+	resp := suite.visit("")
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.Contains(resp.Header.Get("Content-Type"), "text/html")
+	page, _ := io.ReadAll(resp.Body)
+	suite.Contains(string(page), "https://example.com/login")
+	proceed := proceedLink.FindStringSubmatch(string(page))
+	suite.Require().NotNil(proceed)
+
+	resp = suite.visit(html.UnescapeString(proceed[1]))
+	suite.Equal(http.StatusFound, resp.StatusCode)
+	suite.Equal("https://example.com/login", resp.Header.Get("Location"))
+
+	// The way past the warning cannot be guessed, reused for another link or
+	// shared for long.
+	key := utils.DeriveKey("test-session-secret", "quarantine-proceed")
+	stale := utils.SignValue(key, fmt.Sprintf("sus123:%d", time.Now().Add(-time.Hour).Unix()))
+	other := utils.SignValue(key, fmt.Sprintf("other:%d", time.Now().Unix()))
+	for _, token := range []string{"1", "forged.token", stale, other} {
+		suite.Equal(http.StatusOK, suite.visit("?proceed="+url.QueryEscape(token)).StatusCode, token)
+	}
+
+	sent := suite.mailsTo(suite.owner.Email)
+	suite.Require().Len(sent, 1)
+	suite.Contains(sent[0].Subject, "quarantined")
+}
+
+func (suite *AbuseTestSuite) TestConfirmDisablesLink() {
+	suite.quarantine()
+
+	resp, _ := suite.adminRequest(http.MethodPost, fmt.Sprintf("/admin/urls/%d/reports/confirm", suite.url.ID), models.ModerationRequest{})
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = suite.adminRequest(http.MethodPost, fmt.Sprintf("/admin/urls/%d/reports/confirm", suite.url.ID), models.ModerationRequest{Reason: "Credential phishing"})
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	var url models.URL
+	suite.db.First(&url, suite.url.ID)
+	suite.False(url.IsActive)
+	suite.NotNil(url.DisabledAt)
+	suite.Nil(url.QuarantinedAt)
+	suite.Equal(http.StatusNotFound, suite.visit("?proceed=1").StatusCode)
+
+	var open int64
+	suite.db.Model(&models.AbuseReport{}).Where("status = ?", models.ReportStatusOpen).Count(&open)
+	suite.Zero(open)
+
+	var action models.AdminAction
+	suite.Require().NoError(suite.db.Where("action = ?", models.AdminActionConfirmReports).First(&action).Error)
+	suite.Equal(suite.url.ID, action.TargetID)
+
+	sent := suite.mailsTo(suite.owner.Email)
+	suite.Require().Len(sent, 2)
+	suite.Contains(sent[1].Body, "Credential phishing")
+
+	resp, body := suite.adminRequest(http.MethodPost, fmt.Sprintf("/admin/urls/%d/reports/confirm", suite.url.ID), models.ModerationRequest{Reason: "again"})
+	suite.Equal(http.StatusConflict, resp.StatusCode)
+	suite.Equal("no_open_reports", body["error"])
+}
+
+func (suite *AbuseTestSuite) TestQueueAndDismiss() {
+	suite.quarantine()
+
+	resp, body := suite.adminRequest(http.MethodGet, "/admin/reports", nil)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	data := body["data"].(map[string]interface{})
+	suite.Equal(float64(1), data["total"])
+	links := data["links"].([]interface{})
+	suite.Require().Len(links, 1)
+	entry := links[0].(map[string]interface{})
+	suite.Equal("sus123", entry["short_code"])
+	suite.Equal(float64(2), entry["open_reports"])
+	suite.NotNil(entry["quarantined_at"])
+
+	resp, body = suite.adminRequest(http.MethodGet, fmt.Sprintf("/admin/urls/%d/reports?status=open", suite.url.ID), nil)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	suite.Len(body["data"].(map[string]interface{})["reports"], 2)
+
+	resp, _ = suite.adminRequest(http.MethodPost, fmt.Sprintf("/admin/urls/%d/reports/dismiss", suite.url.ID), models.ModerationRequest{Reason: "Legitimate bank login page"})
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	suite.Equal(http.StatusFound, suite.visit("").StatusCode)
+	sent := suite.mailsTo(suite.owner.Email)
+	suite.Require().Len(sent, 2)
+	suite.Contains(sent[1].Subject, "no longer quarantined")
+
+	resp, body = suite.adminRequest(http.MethodGet, "/admin/reports", nil)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal(float64(0), body["data"].(map[string]interface{})["total"])
+}
+
+func TestAbuseTestSuite(t *testing.T) {
+	suite.Run(t, new(AbuseTestSuite))
+}
@@ -0,0 +1,298 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type AccountTestSuite struct {
+	apiSuite
+	urlService     *services.URLService
+	accountService *services.AccountService
+}
+
+func (suite *AccountTestSuite) SetupSuite() {
+	suite.cfg = &config.Config{
+		SessionSecret:            "test-session-secret",
+		Environment:              "test",
+		FrontendURL:              "http://localhost:3000",
+		ExportDir:                suite.T().TempDir(),
+		AccountDeletionGraceDays: 14,
+	}
+
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	// The export worker runs on its own goroutine; every connection to
+	// ":memory:" would otherwise open a separate, empty database.
+	sqlDB, err := suite.db.DB()
+	suite.Require().NoError(err)
+	sqlDB.SetMaxOpenConns(1)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+
+	suite.sessionStore = middleware.NewSimpleSessionStore()
+	suite.sessions = middleware.NewSessionManager(suite.sessionStore, suite.cfg)
+	suite.urlService = services.NewURLService()
+	suite.accountService = services.NewAccountService(suite.cfg)
+	suite.accountService.SetSessions(suite.sessions)
+	accountHandler := handlers.NewAccountHandler(services.NewRetentionService(suite.cfg), suite.accountService, services.NewAuditService())
+
+	suite.app = fiber.New()
+	me := suite.app.Group("/me", suite.sessions.AuthMiddleware())
+	me.Delete("/", accountHandler.DeleteAccount)
+	me.Post("/deletion/cancel", accountHandler.CancelDeletion)
+	me.Get("/export", accountHandler.GetExport)
+	me.Post("/export", accountHandler.RequestExport)
+	me.Get("/export/download", accountHandler.DownloadExport)
+}
+
+func (suite *AccountTestSuite) TearDownTest() {
+	for _, table := range []string{"abuse_reports", "admin_actions", "audit_events", "data_exports", "analytics", "analytics_daily", "workspace_members", "workspaces", "urls", "api_tokens", "users"} {
+		suite.db.Exec("DELETE FROM " + table)
+	}
+}
+
+func (suite *AccountTestSuite) decode(resp *http.Response) map[string]interface{} {
+	var body map[string]interface{}
+	suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&body))
+	return body
+}
+
+func (suite *AccountTestSuite) createLink(userID uint, workspaceID *uint, alias string) *models.URL {
+	url, err := suite.urlService.CreateURL(&models.CreateURLRequest{
+		OriginalURL: "https://example.com/" + alias,
+		CustomAlias: alias,
+		WorkspaceID: workspaceID,
+	}, &userID)
+	suite.Require().NoError(err)
+	return url
+}
+
+// sharedWorkspace creates a workspace with the given members and roles.
+func (suite *AccountTestSuite) sharedWorkspace(roles map[uint]string) uint {
+	workspace := models.Workspace{Name: "Team"}
+	suite.Require().NoError(suite.db.Create(&workspace).Error)
+	for userID, role := range roles {
+		suite.Require().NoError(suite.db.Create(&models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: userID, Role: role}).Error)
+	}
+	return workspace.ID
+}
+
+func (suite *AccountTestSuite) TestExport() {
+	user, sessionID := suite.signIn("export@example.com")
+	url := suite.createLink(user.ID, nil, "exported")
+	for i := 0; i < 3; i++ {
+		suite.Require().NoError(suite.urlService.RecordClick(url.ID, &models.Analytics{IPAddress: "203.0.113.0"}))
+	}
+
+	resp := suite.send(http.MethodGet, "/me/export", sessionID, nil)
+	suite.Require().Equal(http.StatusAccepted, resp.StatusCode)
+	suite.Equal(models.ExportStatusPending, suite.decode(resp)["data"].(map[string]interface{})["status"])
+
+	resp = suite.send(http.MethodGet, "/me/export/download", sessionID, nil)
+	suite.Equal(http.StatusConflict, resp.StatusCode)
+
+	ran, err := suite.accountService.RunPendingExports()
+	suite.Require().NoError(err)
+	suite.Equal(1, ran)
+
+	resp = suite.send(http.MethodGet, "/me/export", sessionID, nil)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal(models.ExportStatusReady, suite.decode(resp)["data"].(map[string]interface{})["status"])
+
+	resp = suite.send(http.MethodGet, "/me/export/download", sessionID, nil)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	data, err := io.ReadAll(resp.Body)
+	suite.Require().NoError(err)
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	suite.Require().NoError(err)
+	files := make(map[string]string)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		suite.Require().NoError(err)
+		content, err := io.ReadAll(r)
+		suite.Require().NoError(err)
+		files[f.Name] = string(content)
+	}
+
+	for _, name := range []string{"profile.json", "identities.json", "sessions.json", "api_tokens.json", "workspaces.json", "links.json", "analytics_daily.json"} {
+		suite.Contains(files, name)
+	}
+	suite.Contains(files["profile.json"], "export@example.com")
+	suite.Contains(files["links.json"], "https://example.com/exported")
+	suite.Contains(files["sessions.json"], "last_active_at")
+	suite.Len(strings.Split(strings.TrimSpace(files["clicks.jsonl"]), "\n"), 3)
+}
+
+func (suite *AccountTestSuite) TestExportRunsInBackground() {
+	service := services.NewAccountService(suite.cfg)
+	service.Start()
+	defer service.Close()
+
+	user, _ := suite.signIn("async@example.com")
+	export, err := service.RequestExport(user.ID)
+	suite.Require().NoError(err)
+
+	suite.Eventually(func() bool {
+		ready, err := service.ReadyExport(user.ID)
+		return err == nil && ready.ID == export.ID
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func (suite *AccountTestSuite) TestScheduleAndCancelDeletion() {
+	user, sessionID := suite.signIn("undecided@example.com")
+
+	resp := suite.send(http.MethodPost, "/me/deletion/cancel", sessionID, nil)
+	suite.Equal(http.StatusNotFound, resp.StatusCode)
+
+	resp = suite.send(http.MethodDelete, "/me/", sessionID, nil)
+	suite.Require().Equal(http.StatusAccepted, resp.StatusCode)
+
+	var stored models.User
+	suite.db.First(&stored, user.ID)
+	suite.Require().NotNil(stored.DeletionScheduledAt)
+	suite.WithinDuration(time.Now().AddDate(0, 0, 14), *stored.DeletionScheduledAt, time.Minute)
+
+	deleted, err := suite.accountService.PurgeDeletedAccounts(time.Now())
+	suite.Require().NoError(err)
+	suite.Equal(0, deleted, "nothing is deleted during the grace period")
+
+	resp = suite.send(http.MethodPost, "/me/deletion/cancel", sessionID, nil)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+
+	deleted, err = suite.accountService.PurgeDeletedAccounts(time.Now().AddDate(0, 1, 0))
+	suite.Require().NoError(err)
+	suite.Equal(0, deleted)
+}
+
+func (suite *AccountTestSuite) TestDeletionPurgesData() {
+	user, sessionID := suite.signIn("leaving@example.com")
+	teammate, _ := suite.signIn("staying@example.com")
+	personal := suite.createLink(user.ID, nil, "personal")
+	suite.Require().NoError(suite.urlService.RecordClick(personal.ID, &models.Analytics{IPAddress: "203.0.113.0"}))
+	suite.Require().NoError(suite.db.Delete(personal).Error)
+
+	teamID := suite.sharedWorkspace(map[uint]string{user.ID: models.RoleOwner, teammate.ID: models.RoleOwner})
+	shared := suite.createLink(user.ID, &teamID, "shared")
+	suite.Require().NoError(suite.urlService.RecordClick(shared.ID, &models.Analytics{IPAddress: "203.0.113.0", UserAgent: "Visitor/1.0"}))
+
+	soloID := suite.sharedWorkspace(map[uint]string{user.ID: models.RoleOwner})
+	solo := suite.createLink(user.ID, &soloID, "solo")
+
+	suite.Require().NoError(suite.db.Create(&models.APIToken{UserID: user.ID, Name: "cli", Prefix: "usk_1", TokenHash: "hash", Scopes: []string{models.ScopeLinksRead}}).Error)
+
+	// Audit records that outlive the account.
+	const ip, userAgent = "198.51.100.4", "Leaving/1.0"
+	suite.Require().NoError(suite.db.Create(&models.AuditEvent{
+		ActorType: models.ActorUser, ActorID: &user.ID, Action: models.AuditLinkUpdate, TargetType: models.AuditTargetURL,
+		TargetID: shared.ID, WorkspaceID: &teamID, IPAddress: ip, UserAgent: userAgent,
+	}).Error)
+	suite.Require().NoError(suite.db.Create(&models.AuditEvent{
+		ActorType: models.ActorAnonymous, Action: models.AuditLoginFailed, TargetType: models.AuditTargetUser,
+		TargetID: user.ID, AccountID: &user.ID, IPAddress: ip, UserAgent: userAgent,
+	}).Error)
+	suite.Require().NoError(suite.db.Create(&models.AuditEvent{
+		ActorType: models.ActorUser, ActorID: &teammate.ID, Action: models.AuditInvitationCreate, TargetType: models.AuditTargetInvitation,
+		TargetID: 1, WorkspaceID: &teamID, Changes: map[string]models.AuditChange{"email": {To: "Leaving@example.com"}},
+	}).Error)
+	suite.Require().NoError(suite.db.Create(&models.AdminAction{
+		AdminID: user.ID, Action: models.AdminActionDisableURL, TargetType: models.AdminTargetURL, TargetID: shared.ID, IPAddress: ip,
+	}).Error)
+	suite.Require().NoError(suite.db.Create(&models.AbuseReport{
+		URLID: shared.ID, Category: models.ReportSpam, ReporterIP: ip, ReporterUserID: &user.ID, Status: models.ReportStatusOpen,
+	}).Error)
+
+	resp := suite.send(http.MethodDelete, "/me/", sessionID, nil)
+	suite.Require().Equal(http.StatusAccepted, resp.StatusCode)
+
+	deleted, err := suite.accountService.PurgeDeletedAccounts(time.Now().AddDate(0, 0, 15))
+	suite.Require().NoError(err)
+	suite.Equal(1, deleted)
+
+	var count int64
+	suite.db.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).Count(&count)
+	suite.Zero(count, "the user row is removed, not soft-deleted")
+	suite.db.Unscoped().Model(&models.URL{}).Where("id IN ?", []uint{personal.ID, solo.ID}).Count(&count)
+	suite.Zero(count, "links in workspaces the user was alone in are removed")
+	suite.db.Unscoped().Model(&models.Analytics{}).Where("url_id = ?", personal.ID).Count(&count)
+	suite.Zero(count)
+	suite.db.Unscoped().Model(&models.Workspace{}).Where("id = ? OR personal_user_id = ?", soloID, user.ID).Count(&count)
+	suite.Zero(count)
+	suite.db.Model(&models.APIToken{}).Where("user_id = ?", user.ID).Count(&count)
+	suite.Zero(count)
+	suite.Empty(suite.sessionStore.Sessions["session-leaving@example.com"])
+
+	var kept models.URL
+	suite.Require().NoError(suite.db.First(&kept, shared.ID).Error)
+	suite.Nil(kept.UserID, "links in shared workspaces stay but lose their creator")
+	suite.True(kept.IsActive)
+	suite.db.Unscoped().Model(&models.Analytics{}).Where("url_id = ?", shared.ID).Count(&count)
+	suite.Zero(count, "click events of links in shared workspaces are rolled up")
+	var daily []models.AnalyticsDaily
+	suite.Require().NoError(suite.db.Where("url_id = ?", shared.ID).Find(&daily).Error)
+	suite.Require().Len(daily, 1)
+	suite.Equal(int64(1), daily[0].Clicks)
+	suite.db.Model(&models.WorkspaceMember{}).Where("workspace_id = ?", teamID).Count(&count)
+	suite.Equal(int64(1), count)
+
+	// Audit records stay, with nothing left that identifies the user.
+	suite.db.Model(&models.AuditEvent{}).Where("action IN ?", []string{models.AuditLinkUpdate, models.AuditLoginFailed, models.AuditInvitationCreate}).Count(&count)
+	suite.Equal(int64(3), count)
+	suite.db.Model(&models.AuditEvent{}).Where("actor_id = ? OR account_id = ? OR ip_address = ? OR user_agent = ?", user.ID, user.ID, ip, userAgent).Count(&count)
+	suite.Zero(count)
+	suite.db.Model(&models.AuditEvent{}).Where("LOWER(changes) LIKE ?", "%leaving@example.com%").Count(&count)
+	suite.Zero(count)
+	suite.db.Model(&models.AdminAction{}).Where("admin_id = ? OR ip_address = ?", user.ID, ip).Count(&count)
+	suite.Zero(count)
+	suite.db.Model(&models.AbuseReport{}).Where("reporter_user_id = ? OR reporter_ip = ?", user.ID, ip).Count(&count)
+	suite.Zero(count)
+	suite.db.Model(&models.AbuseReport{}).Count(&count)
+	suite.Equal(int64(1), count)
+}
+
+func (suite *AccountTestSuite) TestLastOwnerMustHandOver() {
+	user, sessionID := suite.signIn("owner@example.com")
+	member, _ := suite.signIn("member@example.com")
+	teamID := suite.sharedWorkspace(map[uint]string{user.ID: models.RoleOwner, member.ID: models.RoleEditor})
+
+	resp := suite.send(http.MethodDelete, "/me/", sessionID, nil)
+	suite.Equal(http.StatusConflict, resp.StatusCode)
+
+	// A member who joins during the grace period inherits the workspace.
+	suite.db.Model(&models.WorkspaceMember{}).Where("user_id = ?", member.ID).Update("role", models.RoleOwner)
+	resp = suite.send(http.MethodDelete, "/me/", sessionID, nil)
+	suite.Require().Equal(http.StatusAccepted, resp.StatusCode)
+	suite.db.Model(&models.WorkspaceMember{}).Where("user_id = ?", member.ID).Update("role", models.RoleEditor)
+
+	_, err := suite.accountService.PurgeDeletedAccounts(time.Now().AddDate(0, 0, 15))
+	suite.Require().NoError(err)
+
+	var membership models.WorkspaceMember
+	suite.Require().NoError(suite.db.Where("workspace_id = ? AND user_id = ?", teamID, member.ID).First(&membership).Error)
+	suite.Equal(models.RoleOwner, membership.Role)
+}
+
+func TestAccountTestSuite(t *testing.T) {
+	suite.Run(t, new(AccountTestSuite))
+}
@@ -0,0 +1,259 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type AdminTestSuite struct {
+	apiSuite
+	adminService *services.AdminService
+}
+
+func (suite *AdminTestSuite) SetupSuite() {
+	cfg := &config.Config{
+		SessionSecret: "test-session-secret",
+		Environment:   "test",
+		FrontendURL:   "http://localhost:3000",
+		AdminEmails:   []string{"root@example.com"},
+	}
+
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+
+	suite.sessionStore = middleware.NewSimpleSessionStore()
+	suite.sessions = middleware.NewSessionManager(suite.sessionStore, cfg)
+	suite.adminService = services.NewAdminService(cfg)
+	suite.adminService.SetSessionRevoker(suite.sessions)
+	adminHandler := handlers.NewAdminHandler(suite.adminService, suite.sessions)
+	urlHandler := handlers.NewURLHandler(services.NewURLService(), services.NewClickHub(0), cfg)
+
+	suite.app = fiber.New()
+	urls := suite.app.Group("/urls", suite.sessions.AuthMiddleware())
+	urls.Post("/", urlHandler.CreateURL)
+	urls.Get("/", urlHandler.GetUserURLs)
+
+	admin := suite.app.Group("/admin", suite.sessions.AuthMiddleware(), middleware.RequireAdmin(suite.adminService))
+	admin.Get("/stats", adminHandler.Stats)
+	admin.Get("/users", adminHandler.SearchUsers)
+	admin.Post("/users/:id/disable", adminHandler.DisableUser)
+	admin.Post("/users/:id/impersonate", adminHandler.Impersonate)
+	admin.Get("/urls", adminHandler.SearchURLs)
+	admin.Post("/urls/:id/disable", adminHandler.DisableURL)
+	admin.Get("/audit", adminHandler.ListActions)
+
+	suite.app.Get("/:shortCode", urlHandler.RedirectURL)
+}
+
+func (suite *AdminTestSuite) TearDownTest() {
+	for _, table := range []string{"admin_actions", "workspace_members", "workspaces", "urls", "users"} {
+		suite.db.Exec("DELETE FROM " + table)
+	}
+}
+
+// signInAdmin creates a verified administrator with a session and returns
+// both.
+func (suite *AdminTestSuite) signInAdmin(email string) (models.User, string) {
+	now := time.Now()
+	return suite.signInAs(models.User{Name: "User", Email: email, EmailVerifiedAt: &now, IsAdmin: true})
+}
+
+func (suite *AdminTestSuite) createURL(sessionID, alias string) uint {
+	resp, body := suite.requestMap(http.MethodPost, "/urls/", sessionID,
+		models.CreateURLRequest{OriginalURL: "https://example.com/" + alias, CustomAlias: alias})
+	suite.Require().Equal(http.StatusCreated, resp.StatusCode)
+	return uint(body["data"].(map[string]interface{})["id"].(float64))
+}
+
+func (suite *AdminTestSuite) TestNonAdminRejected() {
+	_, sessionID := suite.signIn("user@example.com")
+
+	resp, body := suite.requestMap(http.MethodGet, "/admin/stats", sessionID, nil)
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+	suite.Equal("admin_required", body["error"])
+}
+
+func (suite *AdminTestSuite) TestBootstrapFromConfig() {
+	unverified := models.User{Name: "Root", Email: "root@example.com"}
+	suite.Require().NoError(suite.db.Create(&unverified).Error)
+
+	promoted, err := suite.adminService.BootstrapAdmins()
+	suite.Require().NoError(err)
+	suite.Equal(0, promoted, "unverified addresses must not be promoted")
+
+	now := time.Now()
+	suite.db.Model(&unverified).Update("email_verified_at", &now)
+	promoted, err = suite.adminService.BootstrapAdmins()
+	suite.Require().NoError(err)
+	suite.Equal(1, promoted)
+
+	var user models.User
+	suite.db.First(&user, unverified.ID)
+	suite.True(user.IsAdmin)
+
+	var actions int64
+	suite.db.Model(&models.AdminAction{}).Where("action = ?", models.AdminActionBootstrap).Count(&actions)
+	suite.Equal(int64(1), actions)
+}
+
+func (suite *AdminTestSuite) TestBootstrapOnFirstRequest() {
+	_, sessionID := suite.signIn("root@example.com")
+
+	resp, _ := suite.requestMap(http.MethodGet, "/admin/stats", sessionID, nil)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func (suite *AdminTestSuite) TestSearchAcrossAccounts() {
+	_, adminSession := suite.signInAdmin("admin@example.com")
+	_, aliceSession := suite.signIn("alice@example.com")
+	_, bobSession := suite.signIn("bob@example.com")
+	suite.createURL(aliceSession, "alice-link")
+	suite.createURL(bobSession, "bob-link")
+
+	resp, body := suite.requestMap(http.MethodGet, "/admin/users?q=ALICE", adminSession, nil)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal(float64(1), body["data"].(map[string]interface{})["total"])
+
+	resp, body = suite.requestMap(http.MethodGet, "/admin/urls?q=link", adminSession, nil)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal(float64(2), body["data"].(map[string]interface{})["total"])
+
+	resp, body = suite.requestMap(http.MethodGet, "/admin/urls?q=%25", adminSession, nil)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal(float64(0), body["data"].(map[string]interface{})["total"], "wildcards are matched literally")
+}
+
+func (suite *AdminTestSuite) TestDisableURLIsAudited() {
+	admin, adminSession := suite.signInAdmin("admin@example.com")
+	_, userSession := suite.signIn("user@example.com")
+	urlID := suite.createURL(userSession, "phish")
+
+	path := fmt.Sprintf("/admin/urls/%d/disable", urlID)
+	resp, _ := suite.requestMap(http.MethodPost, path, adminSession, models.ModerationRequest{})
+	suite.Equal(http.StatusBadRequest, resp.StatusCode, "a reason is required")
+
+	resp, _ = suite.requestMap(http.MethodPost, path, adminSession, models.ModerationRequest{Reason: "phishing"})
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	resp, _ = suite.requestMap(http.MethodGet, "/phish", "", nil)
+	suite.Equal(http.StatusNotFound, resp.StatusCode)
+
+	var url models.URL
+	suite.db.First(&url, urlID)
+	suite.False(url.IsActive)
+	suite.Equal("phishing", url.DisabledReason)
+
+	var action models.AdminAction
+	suite.Require().NoError(suite.db.Where("action = ?", models.AdminActionDisableURL).First(&action).Error)
+	suite.Equal(admin.ID, action.AdminID)
+	suite.Equal(urlID, action.TargetID)
+	suite.Equal("phishing", action.Reason)
+}
+
+func (suite *AdminTestSuite) TestDisableUserEndsSessions() {
+	_, adminSession := suite.signInAdmin("admin@example.com")
+	user, userSession := suite.signIn("user@example.com")
+
+	resp, _ := suite.requestMap(http.MethodPost, fmt.Sprintf("/admin/users/%d/disable", user.ID),
+		adminSession, models.ModerationRequest{Reason: "spam"})
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	resp, _ = suite.requestMap(http.MethodGet, "/urls/", userSession, nil)
+	suite.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	var stored models.User
+	suite.db.First(&stored, user.ID)
+	suite.NotNil(stored.DisabledAt)
+	suite.Equal("spam", stored.DisabledReason)
+}
+
+func (suite *AdminTestSuite) TestCannotDisableSelf() {
+	admin, adminSession := suite.signInAdmin("admin@example.com")
+
+	resp, _ := suite.requestMap(http.MethodPost, fmt.Sprintf("/admin/users/%d/disable", admin.ID),
+		adminSession, models.ModerationRequest{Reason: "oops"})
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+}
+
+func (suite *AdminTestSuite) TestStats() {
+	_, adminSession := suite.signInAdmin("admin@example.com")
+	_, userSession := suite.signIn("user@example.com")
+	suite.createURL(userSession, "first")
+
+	resp, body := suite.requestMap(http.MethodGet, "/admin/stats", adminSession, nil)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	stats := body["data"].(map[string]interface{})
+	suite.Equal(float64(2), stats["users"])
+	suite.Equal(float64(1), stats["admins"])
+	suite.Equal(float64(1), stats["urls"])
+}
+
+func (suite *AdminTestSuite) TestImpersonationIsReadOnly() {
+	_, adminSession := suite.signInAdmin("admin@example.com")
+	user, userSession := suite.signIn("user@example.com")
+	suite.createURL(userSession, "mine")
+
+	resp, _ := suite.requestMap(http.MethodPost, fmt.Sprintf("/admin/users/%d/impersonate", user.ID),
+		adminSession, models.ModerationRequest{Reason: "support ticket 42"})
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	var cookie string
+	for _, c := range resp.Cookies() {
+		if c.Name == "session_id" {
+			cookie = c.Value
+		}
+	}
+	suite.Require().NotEmpty(cookie)
+
+	// The admin's own session is gone, not merely replaced in the browser.
+	suite.Nil(suite.sessions.GetSession(adminSession))
+	resp, _ = suite.requestMap(http.MethodGet, "/admin/stats", adminSession, nil)
+	suite.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	resp, body := suite.requestMap(http.MethodGet, "/urls/", "", nil, "Cookie", "session_id="+cookie)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal(float64(1), body["data"].(map[string]interface{})["total"])
+
+	resp, body = suite.requestMap(http.MethodPost, "/urls/", "",
+		models.CreateURLRequest{OriginalURL: "https://example.com", CustomAlias: "sneaky"}, "Cookie", "session_id="+cookie)
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+	suite.Equal("impersonation_read_only", body["error"])
+
+	resp, _ = suite.requestMap(http.MethodGet, "/admin/stats", "", nil, "Cookie", "session_id="+cookie)
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+
+	var action models.AdminAction
+	suite.Require().NoError(suite.db.Where("action = ?", models.AdminActionImpersonate).First(&action).Error)
+	suite.Equal("support ticket 42", action.Reason)
+}
+
+func (suite *AdminTestSuite) TestCannotImpersonateAdmin() {
+	_, adminSession := suite.signInAdmin("admin@example.com")
+	other, _ := suite.signInAdmin("other@example.com")
+
+	resp, _ := suite.requestMap(http.MethodPost, fmt.Sprintf("/admin/users/%d/impersonate", other.ID),
+		adminSession, models.ModerationRequest{Reason: "curious"})
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+}
+
+func TestAdminTestSuite(t *testing.T) {
+	suite.Run(t, new(AdminTestSuite))
+}
@@ -0,0 +1,375 @@
+package tests
+
+import (
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+	"url-shortener-backend/internal/utils"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type AnalyticsTestSuite struct {
+	suite.Suite
+	db               *gorm.DB
+	config           *config.Config
+	urlService       *services.URLService
+	retentionService *services.RetentionService
+}
+
+func (suite *AnalyticsTestSuite) SetupSuite() {
+	suite.config = &config.Config{
+		Environment:            "test",
+		AnalyticsRetentionDays: 90,
+		AnonymizeIPs:           true,
+		RetentionBatchSize:     2,
+	}
+
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+
+	suite.urlService = services.NewURLService()
+	suite.retentionService = services.NewRetentionService(suite.config)
+}
+
+func (suite *AnalyticsTestSuite) TearDownTest() {
+	suite.db.Exec("DELETE FROM analytics")
+	suite.db.Exec("DELETE FROM analytics_daily")
+	suite.db.Exec("DELETE FROM urls")
+	suite.db.Exec("DELETE FROM users")
+	suite.db.Exec("DELETE FROM workspace_members")
+	suite.db.Exec("DELETE FROM workspaces")
+}
+
+func (suite *AnalyticsTestSuite) createUserURL(email string, retentionDays *int) (models.User, models.URL) {
+	user := models.User{Name: "Test User", Email: email, AnalyticsRetentionDays: retentionDays}
+	suite.Require().NoError(suite.db.Create(&user).Error)
+
+	code := utils.GenerateShortCode(6)
+	url := models.URL{OriginalURL: "https://example.com/", ShortCode: code, CustomAlias: code, UserID: &user.ID, IsActive: true}
+	suite.Require().NoError(suite.db.Create(&url).Error)
+
+	// Links inserted directly start outside any workspace, like links that
+	// predate workspaces; the migration moves them into the personal one.
+	_, err := services.NewWorkspaceService(suite.config, nil).MigratePersonalWorkspaces()
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.db.First(&url, url.ID).Error)
+
+	return user, url
+}
+
+func (suite *AnalyticsTestSuite) recordClicks(urlID uint, ip string, clickedAt time.Time, n int) {
+	for i := 0; i < n; i++ {
+		suite.Require().NoError(suite.db.Create(&models.Analytics{URLID: urlID, IPAddress: ip, ClickedAt: clickedAt}).Error)
+	}
+}
+
+func (suite *AnalyticsTestSuite) TestAnonymizeIP() {
+	suite.Equal("203.0.113.0", utils.AnonymizeIP("203.0.113.77"))
+	suite.Equal("2001:db8:85a3::", utils.AnonymizeIP("2001:db8:85a3:8d3:1319:8a2e:370:7348"))
+	suite.Equal("", utils.AnonymizeIP("not-an-ip"))
+}
+
+func (suite *AnalyticsTestSuite) TestTrackingOptOut() {
+	suite.True(utils.TrackingOptOut("1", ""))
+	suite.True(utils.TrackingOptOut("", "1"))
+	suite.False(utils.TrackingOptOut("0", ""))
+}
+
+func (suite *AnalyticsTestSuite) TestClassifyReferrer() {
+	cases := []struct {
+		raw, domain, source string
+	}{
+		{"", "", utils.SourceDirect},
+		{"https://www.google.co.uk/search?q=short+links", "google.co.uk", utils.SourceSearch},
+		{"https://news.google.com/articles/abc", "news.google.com", utils.SourceSearch},
+		{"https://www.google.com.au/search?q=x", "google.com.au", utils.SourceSearch},
+		{"https://google.example.com/search", "google.example.com", utils.SourceOther},
+		{"https://yahoo.evil.co.uk/", "yahoo.evil.co.uk", utils.SourceOther},
+		{"https://mail.google.com/mail/u/0/", "mail.google.com", utils.SourceEmail},
+		{"https://l.facebook.com/l.php?u=x", "l.facebook.com", utils.SourceSocial},
+		{"https://m.facebook.com/story.php", "facebook.com", utils.SourceSocial},
+		{"android-app://com.google.android.gm", "com.google.android.gm", utils.SourceEmail},
+		{"android-app://com.google.android.apps.docs", "com.google.android.apps.docs", utils.SourceOther},
+		{"https://localhost:3000/dashboard", "localhost", utils.SourceInternal},
+		{"https://blog.example.org/post", "blog.example.org", utils.SourceOther},
+	}
+
+	for _, tc := range cases {
+		domain, source := utils.ClassifyReferrer(tc.raw, "localhost:3000")
+		suite.Equal(tc.domain, domain, tc.raw)
+		suite.Equal(tc.source, source, tc.raw)
+	}
+}
+
+func (suite *AnalyticsTestSuite) TestReferrerBreakdown() {
+	user, url := suite.createUserURL("referrers@example.com", nil)
+	for _, referrer := range []string{
+		"https://www.google.com/search?q=a",
+		"https://www.google.com/search?q=b",
+		"https://twitter.com/someone/status/1",
+		"",
+	} {
+		domain, source := utils.ClassifyReferrer(referrer)
+		suite.Require().NoError(suite.db.Create(&models.Analytics{
+			URLID: url.ID, Referrer: referrer, ReferrerDomain: domain, TrafficSource: source, ClickedAt: time.Now(),
+		}).Error)
+	}
+
+	referrers, err := suite.urlService.GetTopReferrers(url.ID, user.ID, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(referrers, 2)
+	suite.Equal("google.com", referrers[0].Domain)
+	suite.Equal(int64(2), referrers[0].Clicks)
+
+	sources, err := suite.urlService.GetTrafficSources(url.ID, user.ID)
+	suite.Require().NoError(err)
+	suite.Len(sources, 3)
+	suite.Equal(utils.SourceSearch, sources[0].Source)
+}
+
+func (suite *AnalyticsTestSuite) TestBackfillClassifiesLegacyReferrers() {
+	_, url := suite.createUserURL("backfill@example.com", nil)
+
+	// Rows recorded before the columns existed hold NULL, not ''.
+	now := time.Now()
+	suite.Require().NoError(suite.db.Exec(
+		"INSERT INTO analytics (url_id, referrer, traffic_source, referrer_domain, clicked_at, created_at) VALUES (?, ?, NULL, NULL, ?, ?), (?, NULL, NULL, NULL, ?, ?)",
+		url.ID, "https://www.google.com/search?q=a", now, now, url.ID, now, now,
+	).Error)
+
+	backfilled, err := suite.urlService.BackfillReferrerSources()
+	suite.Require().NoError(err)
+	suite.Equal(int64(1), backfilled)
+
+	var event models.Analytics
+	suite.Require().NoError(suite.db.Where("referrer = ?", "https://www.google.com/search?q=a").First(&event).Error)
+	suite.Equal("google.com", event.ReferrerDomain)
+	suite.Equal(utils.SourceSearch, event.TrafficSource)
+}
+
+func (suite *AnalyticsTestSuite) TestListClickEventsPaginates() {
+	user, url := suite.createUserURL("events@example.com", nil)
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		suite.Require().NoError(suite.db.Create(&models.Analytics{
+			URLID: url.ID, Device: "mobile", IsBot: i == 4, ClickedAt: base.Add(time.Duration(i) * time.Minute),
+		}).Error)
+	}
+
+	humans := false
+	filter := &models.ClickEventFilter{Limit: 2, IsBot: &humans, Fields: []string{"id", "device"}}
+
+	var seen []interface{}
+	for page := 0; page < 5; page++ {
+		events, next, err := suite.urlService.ListClickEvents(url.ID, user.ID, filter)
+		suite.Require().NoError(err)
+		for _, event := range events {
+			suite.Len(event, 2)
+			seen = append(seen, event["id"])
+		}
+		if next == "" {
+			break
+		}
+		filter.Cursor = next
+	}
+
+	suite.Len(seen, 4)
+
+	_, _, err := suite.urlService.ListClickEvents(url.ID, user.ID+1, &models.ClickEventFilter{})
+	suite.ErrorIs(err, services.ErrURLNotFound)
+
+	_, _, err = suite.urlService.ListClickEvents(url.ID, user.ID, &models.ClickEventFilter{Fields: []string{"password"}})
+	suite.Error(err)
+}
+
+func (suite *AnalyticsTestSuite) TestClickEventCursorIgnoresLocalZone() {
+	local := time.Local
+	time.Local = time.FixedZone("UTC+5", 5*60*60)
+	defer func() { time.Local = local }()
+
+	user, url := suite.createUserURL("cursor@example.com", nil)
+	base := time.Now().UTC().Add(-time.Hour)
+	var ids []interface{}
+	for i := 0; i < 3; i++ {
+		event := models.Analytics{URLID: url.ID, ClickedAt: base.Add(time.Duration(i) * time.Minute)}
+		suite.Require().NoError(suite.db.Create(&event).Error)
+		ids = append([]interface{}{event.ID}, ids...)
+	}
+
+	filter := &models.ClickEventFilter{Limit: 1, Fields: []string{"id"}}
+	var seen []interface{}
+	for page := 0; page < 5; page++ {
+		events, next, err := suite.urlService.ListClickEvents(url.ID, user.ID, filter)
+		suite.Require().NoError(err)
+		for _, event := range events {
+			seen = append(seen, event["id"])
+		}
+		if next == "" {
+			break
+		}
+		filter.Cursor = next
+	}
+
+	suite.Equal(ids, seen)
+}
+
+func (suite *AnalyticsTestSuite) TestClickHubDropsWhenSubscriberFallsBehind() {
+	hub := services.NewClickHub(2)
+	slow := hub.Subscribe(1)
+	other := hub.Subscribe(2)
+
+	for i := 0; i < 5; i++ {
+		hub.Publish(models.ClickEvent{URLID: 1, Device: "mobile"})
+	}
+
+	suite.Len(slow.Events, 2)
+	suite.Equal(int64(3), slow.TakeDropped())
+	suite.Equal(int64(0), slow.TakeDropped())
+	suite.Len(other.Events, 0)
+
+	hub.Unsubscribe(slow)
+	_, open := <-slow.Events
+	suite.True(open, "buffered events are still delivered after unsubscribe")
+
+	hub.Close()
+	_, open = <-other.Events
+	suite.False(open)
+}
+
+func (suite *AnalyticsTestSuite) TestOverviewAggregatesAcrossLinks() {
+	user, first := suite.createUserURL("overview@example.com", nil)
+	second := models.URL{OriginalURL: "https://example.org/", ShortCode: "second", CustomAlias: "second", UserID: &user.ID, WorkspaceID: first.WorkspaceID, IsActive: true}
+	suite.Require().NoError(suite.db.Create(&second).Error)
+	_, foreign := suite.createUserURL("someone-else@example.com", nil)
+
+	to := time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -10)
+
+	suite.recordClicks(first.ID, "198.51.100.0", from.Add(26*time.Hour), 3)
+	suite.recordClicks(second.ID, "203.0.113.0", from.Add(50*time.Hour), 1)
+	suite.recordClicks(foreign.ID, "203.0.113.0", from.Add(50*time.Hour), 5)
+	suite.recordClicks(first.ID, "198.51.100.0", from.AddDate(0, 0, -5), 2)
+	suite.Require().NoError(suite.db.Create(&models.AnalyticsDaily{
+		URLID: second.ID, Date: from.AddDate(0, 0, 3), Clicks: 4, UniqueClicks: 2,
+	}).Error)
+
+	analyticsService := services.NewAnalyticsService()
+	overview, err := analyticsService.GetOverview(user.ID, from, to)
+	suite.Require().NoError(err)
+
+	suite.Equal(int64(8), overview.TotalClicks)
+	suite.Equal(int64(2), overview.Previous.TotalClicks)
+	suite.Require().NotNil(overview.TotalClicksChange)
+	suite.Equal(300.0, *overview.TotalClicksChange)
+
+	suite.Require().Len(overview.TopLinks, 2)
+	suite.Equal(second.ID, overview.TopLinks[0].URLID)
+	suite.Equal(int64(5), overview.TopLinks[0].Clicks)
+
+	suite.Require().Len(overview.DailyTrend, 10)
+	suite.Equal("2026-03-02", overview.DailyTrend[1].Date)
+	suite.Equal(int64(3), overview.DailyTrend[1].Clicks)
+	suite.Equal(int64(4), overview.DailyTrend[3].Clicks)
+
+	_, err = analyticsService.GetOverview(user.ID, to, from)
+	suite.Error(err)
+}
+
+func (suite *AnalyticsTestSuite) TestOverviewTrendUsesUTCDays() {
+	user, url := suite.createUserURL("utc-days@example.com", nil)
+
+	to := time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -3)
+
+	// 22:00 UTC on the 9th is already the 10th in UTC+5, where it is stored.
+	zone := time.FixedZone("UTC+5", 5*60*60)
+	suite.recordClicks(url.ID, "198.51.100.0", time.Date(2026, 3, 9, 22, 0, 0, 0, time.UTC).In(zone), 1)
+	suite.Require().NoError(suite.db.Create(&models.AnalyticsDaily{
+		URLID: url.ID, Date: time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), Clicks: 2, UniqueClicks: 1,
+	}).Error)
+
+	overview, err := services.NewAnalyticsService().GetOverview(user.ID, from, to)
+	suite.Require().NoError(err)
+
+	suite.Require().Len(overview.DailyTrend, 3)
+	suite.Equal("2026-03-09", overview.DailyTrend[1].Date)
+	suite.Equal(int64(3), overview.DailyTrend[1].Clicks)
+	suite.Zero(overview.DailyTrend[2].Clicks)
+}
+
+func (suite *AnalyticsTestSuite) TestPurgeRollsUpExpiredEvents() {
+	_, url := suite.createUserURL("default@example.com", nil)
+	now := time.Now().UTC()
+
+	suite.recordClicks(url.ID, "198.51.100.0", now.AddDate(0, 0, -120), 3)
+	suite.recordClicks(url.ID, "203.0.113.0", now.AddDate(0, 0, -120), 2)
+	suite.recordClicks(url.ID, "203.0.113.0", now.AddDate(0, 0, -1), 1)
+
+	purged, err := suite.retentionService.PurgeExpired(now)
+	suite.Require().NoError(err)
+	suite.Equal(int64(5), purged)
+
+	var remaining int64
+	suite.db.Unscoped().Model(&models.Analytics{}).Count(&remaining)
+	suite.Equal(int64(1), remaining)
+
+	var daily []models.AnalyticsDaily
+	suite.db.Where("url_id = ?", url.ID).Find(&daily)
+	suite.Require().Len(daily, 1)
+	suite.Equal(int64(5), daily[0].Clicks)
+	// Uniques are counted per purge batch, so they can only over-count.
+	suite.GreaterOrEqual(daily[0].UniqueClicks, int64(2))
+}
+
+func (suite *AnalyticsTestSuite) TestPurgeHonoursAccountOverride() {
+	days := 7
+	_, short := suite.createUserURL("short@example.com", &days)
+	_, standard := suite.createUserURL("standard@example.com", nil)
+	now := time.Now().UTC()
+
+	suite.recordClicks(short.ID, "198.51.100.0", now.AddDate(0, 0, -10), 1)
+	suite.recordClicks(standard.ID, "198.51.100.0", now.AddDate(0, 0, -10), 1)
+
+	purged, err := suite.retentionService.PurgeExpired(now)
+	suite.Require().NoError(err)
+	suite.Equal(int64(1), purged)
+
+	var remaining []models.Analytics
+	suite.db.Find(&remaining)
+	suite.Require().Len(remaining, 1)
+	suite.Equal(standard.ID, remaining[0].URLID)
+}
+
+func (suite *AnalyticsTestSuite) TestSetUserRetentionValidatesRange() {
+	user, _ := suite.createUserURL("range@example.com", nil)
+
+	invalid := 0
+	suite.Error(suite.retentionService.SetUserRetention(user.ID, &invalid))
+
+	valid := 30
+	suite.Require().NoError(suite.retentionService.SetUserRetention(user.ID, &valid))
+	days, overridden, err := suite.retentionService.GetUserRetention(user.ID)
+	suite.Require().NoError(err)
+	suite.True(overridden)
+	suite.Equal(30, days)
+
+	suite.Require().NoError(suite.retentionService.SetUserRetention(user.ID, nil))
+	days, overridden, err = suite.retentionService.GetUserRetention(user.ID)
+	suite.Require().NoError(err)
+	suite.False(overridden)
+	suite.Equal(90, days)
+}
+
+func TestAnalyticsTestSuite(t *testing.T) {
+	suite.Run(t, new(AnalyticsTestSuite))
+}
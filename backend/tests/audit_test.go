@@ -0,0 +1,245 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type AuditTestSuite struct {
+	apiSuite
+	tokenService     *services.TokenService
+	workspaceService *services.WorkspaceService
+	adminService     *services.AdminService
+	passwordService  *services.PasswordAuthService
+}
+
+func (suite *AuditTestSuite) SetupSuite() {
+	suite.cfg = &config.Config{
+		SessionSecret: "test-session-secret",
+		Environment:   "test",
+		FrontendURL:   "http://localhost:3000",
+	}
+
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+
+	mailer := &mockMailer{}
+	suite.tokenService = services.NewTokenService()
+	suite.workspaceService = services.NewWorkspaceService(suite.cfg, mailer)
+	suite.adminService = services.NewAdminService(suite.cfg)
+	suite.passwordService = services.NewPasswordAuthService(suite.cfg, mailer)
+	suite.sessionStore = middleware.NewSimpleSessionStore()
+	suite.sessions = middleware.NewSessionManager(suite.sessionStore, suite.cfg)
+	suite.sessions.SetTokenAuthenticator(suite.tokenService)
+
+	auditService := services.NewAuditService()
+	urlHandler := handlers.NewURLHandler(services.NewURLService(), services.NewClickHub(0), suite.cfg)
+	workspaceHandler := handlers.NewWorkspaceHandler(suite.workspaceService, auditService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+
+	suite.app = fiber.New(fiber.Config{ProxyHeader: fiber.HeaderXForwardedFor})
+	suite.headers = []string{fiber.HeaderXForwardedFor, "203.0.113.7", fiber.HeaderUserAgent, "audit-test/1.0"}
+	suite.app.Get("/me/audit", suite.sessions.AuthMiddleware(), middleware.SessionOnly(), auditHandler.ListAccountEvents)
+	suite.app.Put("/workspaces/:id", suite.sessions.AuthMiddleware(), middleware.SessionOnly(), workspaceHandler.UpdateWorkspace)
+	suite.app.Get("/workspaces/:id/audit", suite.sessions.AuthMiddleware(), middleware.SessionOnly(), auditHandler.ListWorkspaceEvents)
+	suite.app.Post("/urls", suite.sessions.OptionalAuthMiddleware(), middleware.RequireScope(models.ScopeLinksWrite), urlHandler.CreateURL)
+	suite.app.Put("/urls/:id", suite.sessions.AuthMiddleware(), middleware.RequireScope(models.ScopeLinksWrite), urlHandler.UpdateURL)
+	suite.app.Delete("/urls/:id", suite.sessions.AuthMiddleware(), middleware.RequireScope(models.ScopeLinksWrite), urlHandler.DeleteURL)
+}
+
+func (suite *AuditTestSuite) TearDownTest() {
+	for _, table := range []string{"audit_events", "admin_actions", "api_tokens", "usage_counters", "usage_events", "plans", "workspace_members", "workspaces", "urls", "users"} {
+		suite.db.Exec("DELETE FROM " + table)
+	}
+}
+
+func (suite *AuditTestSuite) events(path, sessionID string) []models.AuditEvent {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Cookie", "session_id="+suite.sessions.CookieValue(sessionID))
+	resp, err := suite.app.Test(req)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Data struct {
+			Events []models.AuditEvent `json:"events"`
+			Total  int64               `json:"total"`
+		} `json:"data"`
+	}
+	suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&body))
+	suite.Require().Equal(int64(len(body.Data.Events)), body.Data.Total)
+	return body.Data.Events
+}
+
+func (suite *AuditTestSuite) TestLinkChangesAreAudited() {
+	user, sessionID := suite.signIn("links@example.com")
+
+	resp, body := suite.requestMap(http.MethodPost, "/urls", sessionID, models.CreateURLRequest{OriginalURL: "https://example.com/old", Title: "Docs"})
+	suite.Require().Equal(http.StatusCreated, resp.StatusCode)
+	urlID := uint(body["data"].(map[string]interface{})["id"].(float64))
+
+	resp, _ = suite.requestMap(http.MethodPut, fmt.Sprintf("/urls/%d", urlID), sessionID, models.CreateURLRequest{OriginalURL: "https://example.com/new"})
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	// Saving without changing anything adds no event.
+	resp, _ = suite.requestMap(http.MethodPut, fmt.Sprintf("/urls/%d", urlID), sessionID, models.CreateURLRequest{Title: "Docs"})
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	resp, _ = suite.requestMap(http.MethodDelete, fmt.Sprintf("/urls/%d", urlID), sessionID, nil)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	events := suite.events("/me/audit?action=link.", sessionID)
+	suite.Require().Len(events, 3)
+	deleted, updated, created := events[0], events[1], events[2]
+
+	suite.Equal(models.AuditLinkCreate, created.Action)
+	suite.Equal(models.ActorUser, created.ActorType)
+	suite.Equal(user.ID, *created.ActorID)
+	suite.Equal(urlID, created.TargetID)
+	suite.NotNil(created.WorkspaceID)
+	suite.Equal("203.0.113.7", created.IPAddress)
+	suite.Equal("audit-test/1.0", created.UserAgent)
+	suite.Nil(created.Changes["title"].From)
+	suite.Equal("Docs", created.Changes["title"].To)
+
+	suite.Equal(models.AuditLinkUpdate, updated.Action)
+	suite.Equal(map[string]models.AuditChange{
+		"original_url": {From: "https://example.com/old", To: "https://example.com/new"},
+	}, updated.Changes)
+
+	suite.Equal(models.AuditLinkDelete, deleted.Action)
+	suite.Equal("https://example.com/new", deleted.Changes["original_url"].From)
+	suite.Nil(deleted.Changes["original_url"].To)
+}
+
+func (suite *AuditTestSuite) TestTokenActorIsRecorded() {
+	user, sessionID := suite.signIn("token@example.com")
+	plaintext, token, err := suite.tokenService.CreateToken(user.ID, &models.CreateAPITokenRequest{
+		Name:   "ci",
+		Scopes: []string{models.ScopeLinksWrite},
+	})
+	suite.Require().NoError(err)
+
+	resp, _ := suite.requestMap(http.MethodPost, "/urls", "", models.CreateURLRequest{OriginalURL: "https://example.com/"}, "Authorization", "Bearer "+plaintext)
+	suite.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	events := suite.events("/me/audit?actor_type=token", sessionID)
+	suite.Require().Len(events, 1)
+	suite.Equal(user.ID, *events[0].ActorID)
+	suite.Require().NotNil(events[0].TokenID)
+	suite.Equal(token.ID, *events[0].TokenID)
+}
+
+func (suite *AuditTestSuite) TestRejectedChangeLeavesNoEvent() {
+	_, err := services.NewUsageService(suite.cfg, nil).SeedPlans()
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.db.Model(&models.Plan{}).Where("code = ?", models.PlanFree).Update("monthly_links", 1).Error)
+	_, sessionID := suite.signIn("quota@example.com")
+
+	resp, _ := suite.requestMap(http.MethodPost, "/urls", sessionID, models.CreateURLRequest{OriginalURL: "https://example.com/"})
+	suite.Require().Equal(http.StatusCreated, resp.StatusCode)
+	resp, _ = suite.requestMap(http.MethodPost, "/urls", sessionID, models.CreateURLRequest{OriginalURL: "https://example.com/"})
+	suite.Require().Equal(http.StatusPaymentRequired, resp.StatusCode)
+
+	suite.Len(suite.events("/me/audit", sessionID), 1)
+}
+
+func (suite *AuditTestSuite) TestEventsAreAppendOnly() {
+	user, _ := suite.signIn("immutable@example.com")
+	url, err := services.NewURLService().CreateURL(&models.CreateURLRequest{OriginalURL: "https://example.com/"}, &user.ID)
+	suite.Require().NoError(err)
+
+	var event models.AuditEvent
+	suite.Require().NoError(suite.db.Where("target_id = ?", url.ID).First(&event).Error)
+	suite.ErrorIs(suite.db.Model(&event).Update("action", "link.nothing").Error, models.ErrAuditAppendOnly)
+	suite.ErrorIs(suite.db.Delete(&event).Error, models.ErrAuditAppendOnly)
+
+	var count int64
+	suite.db.Model(&models.AuditEvent{}).Where("action = ?", models.AuditLinkCreate).Count(&count)
+	suite.Equal(int64(1), count)
+}
+
+func (suite *AuditTestSuite) TestWorkspaceAuditLog() {
+	owner, ownerSession := suite.signIn("owner@example.com")
+	editor, editorSession := suite.signIn("editor@example.com")
+	workspace, err := suite.workspaceService.CreateWorkspace(owner.ID, &models.WorkspaceRequest{Name: "Marketing"})
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.db.Create(&models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: editor.ID, Role: models.RoleEditor}).Error)
+
+	resp, _ := suite.requestMap(http.MethodPost, "/urls", editorSession, models.CreateURLRequest{OriginalURL: "https://example.com/", WorkspaceID: &workspace.ID})
+	suite.Require().Equal(http.StatusCreated, resp.StatusCode)
+	resp, _ = suite.requestMap(http.MethodPut, fmt.Sprintf("/workspaces/%d", workspace.ID), ownerSession, models.WorkspaceRequest{Name: "Growth"})
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	path := fmt.Sprintf("/workspaces/%d/audit", workspace.ID)
+	events := suite.events(path, ownerSession)
+	suite.Require().Len(events, 2)
+	suite.Equal(models.AuditWorkspaceUpdate, events[0].Action)
+	suite.Equal(models.AuditChange{From: "Marketing", To: "Growth"}, events[0].Changes["name"])
+	suite.Equal(models.AuditLinkCreate, events[1].Action)
+	suite.Equal(editor.ID, *events[1].ActorID)
+
+	// Filters combine.
+	suite.Len(suite.events(path+fmt.Sprintf("?actor_id=%d&target_type=url", editor.ID), ownerSession), 1)
+	suite.Len(suite.events(path+fmt.Sprintf("?actor_id=%d&target_type=url", owner.ID), ownerSession), 0)
+	since := url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))
+	suite.Empty(suite.events(path+"?since="+since, ownerSession))
+
+	resp, body := suite.requestMap(http.MethodGet, path+"?since=yesterday", ownerSession, nil)
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+	suite.Equal("invalid_filter", body["error"])
+
+	resp, _ = suite.requestMap(http.MethodGet, path, editorSession, nil)
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+}
+
+func (suite *AuditTestSuite) TestAdminAndAuthEventsReachAccountLog() {
+	now := time.Now()
+	admin := models.User{Name: "Admin", Email: "admin@example.com", EmailVerifiedAt: &now, IsAdmin: true}
+	suite.Require().NoError(suite.db.Create(&admin).Error)
+	user, sessionID := suite.signIn("member@example.com")
+	link, err := services.NewURLService().CreateURL(&models.CreateURLRequest{OriginalURL: "https://example.com/"}, &user.ID)
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.adminService.DisableURL(admin.ID, link.ID, "Spam", "198.51.100.9"))
+
+	events := suite.events("/me/audit?actor_type=admin", sessionID)
+	suite.Require().Len(events, 1)
+	suite.Equal(models.AdminActionDisableURL, events[0].Action)
+	suite.Equal(admin.ID, *events[0].ActorID)
+	suite.Equal("Spam", events[0].Reason)
+	suite.Equal("198.51.100.9", events[0].IPAddress)
+
+	suite.Require().NoError(suite.db.Model(&user).Update("password_hash", "$2a$10$invalidinvalidinvalidinvalidinvalidinvalidinvalidinvali").Error)
+	_, err = suite.passwordService.Login(user.Email, "wrong password 1", "192.0.2.44")
+	suite.ErrorIs(err, services.ErrInvalidCredentials)
+
+	events = suite.events("/me/audit?action="+models.AuditLoginFailed, sessionID)
+	suite.Require().Len(events, 1)
+	suite.Equal(models.ActorAnonymous, events[0].ActorType)
+	suite.Equal("192.0.2.44", events[0].IPAddress)
+}
+
+func TestAuditTestSuite(t *testing.T) {
+	suite.Run(t, new(AuditTestSuite))
+}
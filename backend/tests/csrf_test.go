@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+)
+
+type CSRFTestSuite struct {
+	suite.Suite
+	app      *fiber.App
+	sessions *middleware.SessionManager
+	store    *middleware.SimpleSessionStore
+}
+
+func (suite *CSRFTestSuite) SetupTest() {
+	cfg := &config.Config{
+		SessionSecret: "test-session-secret",
+		Environment:   "test",
+		FrontendURL:   "http://localhost:3000",
+	}
+
+	suite.store = middleware.NewSimpleSessionStore()
+	suite.sessions = middleware.NewSessionManager(suite.store, cfg)
+	csrf := middleware.NewCSRFProtection(cfg, suite.sessions)
+	csrfHandler := handlers.NewCSRFHandler(csrf)
+
+	suite.app = fiber.New()
+	api := suite.app.Group("/api/v1", csrf.Middleware())
+	api.Get("/auth/csrf", csrfHandler.GetToken)
+	api.Delete("/urls/:id", suite.sessions.AuthMiddleware(), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	for _, id := range []string{"alice-session", "bob-session"} {
+		now := time.Now()
+		suite.Require().NoError(suite.store.Save(id, &middleware.SessionData{
+			UserID: 1, CreatedAt: now, LastActiveAt: now, ExpiresAt: now.Add(time.Hour),
+		}))
+	}
+}
+
+func (suite *CSRFTestSuite) TearDownTest() {
+	suite.store.Close()
+}
+
+// csrfToken fetches a token for the given session, or for no session.
+func (suite *CSRFTestSuite) csrfToken(sessionID string) string {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/csrf", nil)
+	if sessionID != "" {
+		req.Header.Set("Cookie", "session_id="+suite.sessions.CookieValue(sessionID))
+	}
+	resp, err := suite.app.Test(req)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Data struct {
+			CSRFToken string `json:"csrf_token"`
+		} `json:"data"`
+	}
+	suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&body))
+	suite.Require().NotEmpty(body.Data.CSRFToken)
+	return body.Data.CSRFToken
+}
+
+// deleteURL sends a cookie-authenticated DELETE and returns the status and
+// error code.
+func (suite *CSRFTestSuite) deleteURL(sessionID string, headers map[string]string) (int, string) {
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/urls/1", nil)
+	if sessionID != "" {
+		req.Header.Set("Cookie", "session_id="+suite.sessions.CookieValue(sessionID))
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := suite.app.Test(req)
+	suite.Require().NoError(err)
+
+	var errResp models.ErrorResponse
+	json.NewDecoder(resp.Body).Decode(&errResp)
+	return resp.StatusCode, errResp.Error
+}
+
+func (suite *CSRFTestSuite) TestValidTokenAndOrigin() {
+	token := suite.csrfToken("alice-session")
+
+	status, _ := suite.deleteURL("alice-session", map[string]string{
+		"Origin":       "http://localhost:3000",
+		"X-CSRF-Token": token,
+	})
+	suite.Equal(http.StatusNoContent, status)
+
+	// Referer stands in for a missing Origin.
+	status, _ = suite.deleteURL("alice-session", map[string]string{
+		"Referer":      "http://localhost:3000/dashboard",
+		"X-CSRF-Token": token,
+	})
+	suite.Equal(http.StatusNoContent, status)
+}
+
+func (suite *CSRFTestSuite) TestRejections() {
+	token := suite.csrfToken("alice-session")
+
+	cases := []struct {
+		headers map[string]string
+		code    string
+	}{
+		{map[string]string{"Origin": "http://localhost:3000"}, "csrf_token_missing"},
+		{map[string]string{"Origin": "http://localhost:3000", "X-CSRF-Token": "garbage"}, "csrf_token_invalid"},
+		{map[string]string{"Origin": "http://localhost:3000", "X-CSRF-Token": suite.csrfToken("bob-session")}, "csrf_token_invalid"},
+		{map[string]string{"Origin": "http://localhost:3000", "X-CSRF-Token": suite.csrfToken("")}, "csrf_token_invalid"},
+		{map[string]string{"Origin": "https://evil.example", "X-CSRF-Token": token}, "csrf_origin_mismatch"},
+		{map[string]string{"Origin": "null", "X-CSRF-Token": token}, "csrf_origin_mismatch"},
+		{map[string]string{"Referer": "https://evil.example/page", "X-CSRF-Token": token}, "csrf_origin_mismatch"},
+		{map[string]string{"X-CSRF-Token": token}, "csrf_origin_missing"},
+	}
+
+	for _, tc := range cases {
+		status, code := suite.deleteURL("alice-session", tc.headers)
+		suite.Equal(http.StatusForbidden, status, tc.code)
+		suite.Equal(tc.code, code)
+	}
+}
+
+func (suite *CSRFTestSuite) TestBearerRequestsAreExempt() {
+	// No Origin and no CSRF token: the request reaches authentication, which
+	// rejects the unknown API token rather than the CSRF check.
+	status, code := suite.deleteURL("", map[string]string{"Authorization": "Bearer usk_unknown"})
+	suite.Equal(http.StatusUnauthorized, status)
+	suite.Equal("unauthorized", code)
+}
+
+func (suite *CSRFTestSuite) TestRequestsWithoutSessionSkipTokenCheck() {
+	status, code := suite.deleteURL("", map[string]string{"Origin": "http://localhost:3000"})
+	suite.Equal(http.StatusUnauthorized, status)
+	suite.Equal("unauthorized", code)
+
+	status, code = suite.deleteURL("", map[string]string{"Origin": "https://evil.example"})
+	suite.Equal(http.StatusForbidden, status)
+	suite.Equal("csrf_origin_mismatch", code)
+}
+
+func TestCSRFTestSuite(t *testing.T) {
+	suite.Run(t, new(CSRFTestSuite))
+}
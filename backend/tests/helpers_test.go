@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/mail"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+// apiSuite is embedded by the suites that call the API as signed-in users.
+// Each suite builds app on db in its SetupSuite, with sessions kept in
+// sessionStore.
+type apiSuite struct {
+	suite.Suite
+	app          *fiber.App
+	db           *gorm.DB
+	cfg          *config.Config
+	sessionStore *middleware.SimpleSessionStore
+	sessions     *middleware.SessionManager
+
+	// headers are sent with every request, as name and value pairs.
+	headers []string
+}
+
+// signIn creates a verified user with a session and returns both.
+func (s *apiSuite) signIn(email string) (models.User, string) {
+	now := time.Now()
+	return s.signInAs(models.User{Name: "User", Email: email, EmailVerifiedAt: &now})
+}
+
+// signInAs creates user with a session and returns both.
+func (s *apiSuite) signInAs(user models.User) (models.User, string) {
+	s.Require().NoError(s.db.Create(&user).Error)
+
+	now := time.Now()
+	sessionID := "session-" + user.Email
+	s.sessionStore.Sessions[sessionID] = &middleware.SessionData{
+		UserID:    user.ID,
+		UserEmail: user.Email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+	return user, sessionID
+}
+
+// send makes a request with body encoded as JSON, signed in with sessionID
+// unless it is empty. headers are added as name and value pairs.
+func (s *apiSuite) send(method, path, sessionID string, body interface{}, headers ...string) *http.Response {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if sessionID != "" {
+		req.Header.Set("Cookie", "session_id="+s.sessions.CookieValue(sessionID))
+	}
+	headers = append(append([]string{}, s.headers...), headers...)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := s.app.Test(req)
+	s.Require().NoError(err)
+	return resp
+}
+
+// request sends a request and decodes the response into out, if given,
+// returning the status code.
+func (s *apiSuite) request(method, path, sessionID string, body interface{}, out interface{}) int {
+	resp := s.send(method, path, sessionID, body)
+	if out != nil {
+		s.Require().NoError(json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
+
+// requestMap sends a request and returns the response with its body
+// decoded into a map, for checking individual fields.
+func (s *apiSuite) requestMap(method, path, sessionID string, body interface{}, headers ...string) (*http.Response, map[string]interface{}) {
+	resp := s.send(method, path, sessionID, body, headers...)
+
+	var decoded map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp, decoded
+}
+
+// mockMailer keeps sent messages in memory for inspection.
+type mockMailer struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (m *mockMailer) Send(msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Sent returns the messages sent so far.
+func (m *mockMailer) Sent() []mail.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]mail.Message(nil), m.sent...)
+}
@@ -0,0 +1,431 @@
+package tests
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/identity"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// mockOIDCServer is a minimal OpenID Connect provider. Tests register the
+// code they will redeem along with the PKCE challenge and nonce from the
+// login URL, and may tamper with the ID token claims it issues.
+type mockOIDCServer struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	mu     sync.Mutex
+	codes  map[string]mockAuthorization
+	claims map[string]interface{}
+}
+
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+func newMockOIDCServer(clientID string) *mockOIDCServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	m := &mockOIDCServer{
+		key:      key,
+		clientID: clientID,
+		codes:    make(map[string]mockAuthorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", m.token)
+	m.server = httptest.NewServer(mux)
+
+	return m
+}
+
+func (m *mockOIDCServer) authorize(code string, authURL string) {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		panic(err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.codes[code] = mockAuthorization{
+		challenge: parsed.Query().Get("code_challenge"),
+		nonce:     parsed.Query().Get("nonce"),
+	}
+}
+
+func (m *mockOIDCServer) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	m.mu.Lock()
+	auth, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	overrides := m.claims
+	m.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != auth.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := map[string]interface{}{
+		"iss":            m.server.URL,
+		"sub":            "oidc-user-1",
+		"aud":            m.clientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          auth.nonce,
+		"email":          "oidc@example.com",
+		"email_verified": true,
+		"name":           "OIDC User",
+	}
+	for k, v := range overrides {
+		claims[k] = v
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     m.sign(claims),
+	})
+}
+
+func (m *mockOIDCServer) sign(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test-key", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+type IdentityTestSuite struct {
+	suite.Suite
+	app          *fiber.App
+	db           *gorm.DB
+	oidc         *mockOIDCServer
+	sessionStore *middleware.SimpleSessionStore
+	sessions     *middleware.SessionManager
+}
+
+func (suite *IdentityTestSuite) SetupSuite() {
+	cfg := &config.Config{
+		GoogleClientID: "test-client-id",
+		Environment:    "test",
+		FrontendURL:    "http://localhost:3000",
+	}
+
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+
+	suite.oidc = newMockOIDCServer("acme-client")
+
+	providers := identity.NewRegistry(cfg)
+	providers.Register(identity.NewOIDCProvider(identity.OIDCConfig{
+		Name:         "acme",
+		IssuerURL:    suite.oidc.server.URL,
+		ClientID:     "acme-client",
+		ClientSecret: "acme-secret",
+		RedirectURL:  cfg.FrontendURL + "/auth/callback",
+	}))
+
+	suite.sessionStore = middleware.NewSimpleSessionStore()
+	suite.sessions = middleware.NewSessionManager(suite.sessionStore, cfg)
+	oauthHandler := handlers.NewOAuthHandler(services.NewAuthService(), cfg, suite.sessions, providers, services.NewAuditService())
+
+	suite.app = fiber.New()
+	suite.app.Get("/auth/providers", oauthHandler.Providers)
+	suite.app.Get("/auth/login", oauthHandler.Login)
+	suite.app.Get("/auth/callback", oauthHandler.Callback)
+
+	me := suite.app.Group("/me", suite.sessions.AuthMiddleware())
+	me.Get("/identities", oauthHandler.ListIdentities)
+	me.Get("/identities/link", oauthHandler.LinkIdentity)
+	me.Delete("/identities/:id", oauthHandler.UnlinkIdentity)
+}
+
+func (suite *IdentityTestSuite) TearDownSuite() {
+	suite.oidc.server.Close()
+}
+
+func (suite *IdentityTestSuite) TearDownTest() {
+	suite.oidc.claims = nil
+	suite.db.Exec("DELETE FROM user_identities")
+	suite.db.Exec("DELETE FROM users")
+}
+
+// login starts a login with the mock provider and completes its callback.
+func (suite *IdentityTestSuite) login() *http.Response {
+	return suite.runFlow("/auth/login?provider=acme", "", "")
+}
+
+// runFlow starts a provider flow at path and completes its callback. The
+// session cookies sent with each step are set when non-empty.
+func (suite *IdentityTestSuite) runFlow(path, startSession, callbackSession string) *http.Response {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if startSession != "" {
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: suite.sessions.CookieValue(startSession)})
+	}
+	resp, err := suite.app.Test(req)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Data struct {
+			AuthURL string `json:"auth_url"`
+		} `json:"data"`
+	}
+	suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&body))
+	suite.True(strings.HasPrefix(body.Data.AuthURL, suite.oidc.server.URL+"/authorize?"))
+
+	authURL, _ := url.Parse(body.Data.AuthURL)
+	suite.Equal("S256", authURL.Query().Get("code_challenge_method"))
+	suite.NotEmpty(authURL.Query().Get("nonce"))
+
+	suite.oidc.authorize("test-code", body.Data.AuthURL)
+
+	req = httptest.NewRequest(http.MethodGet, "/auth/callback?code=test-code&state="+url.QueryEscape(authURL.Query().Get("state")), nil)
+	for _, cookie := range resp.Cookies() {
+		req.AddCookie(cookie)
+	}
+	if callbackSession != "" {
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: suite.sessions.CookieValue(callbackSession)})
+	}
+	resp, err = suite.app.Test(req)
+	suite.Require().NoError(err)
+	return resp
+}
+
+func (suite *IdentityTestSuite) TestProvidersAreListed() {
+	resp, err := suite.app.Test(httptest.NewRequest(http.MethodGet, "/auth/providers", nil))
+	suite.Require().NoError(err)
+
+	var body struct {
+		Data struct {
+			Providers []string `json:"providers"`
+		} `json:"data"`
+	}
+	suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&body))
+	suite.Equal([]string{"acme", "google"}, body.Data.Providers)
+}
+
+func (suite *IdentityTestSuite) TestUnknownProviderIsRejected() {
+	resp, err := suite.app.Test(httptest.NewRequest(http.MethodGet, "/auth/login?provider=myspace", nil))
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func (suite *IdentityTestSuite) TestOIDCLogin() {
+	resp := suite.login()
+	suite.Equal(http.StatusOK, resp.StatusCode)
+
+	var user models.User
+	suite.Require().NoError(suite.db.Where("email = ?", "oidc@example.com").First(&user).Error)
+	suite.Equal("OIDC User", user.Name)
+
+	var sessionCookie bool
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "session_id" && cookie.Value != "" {
+			sessionCookie = true
+		}
+	}
+	suite.True(sessionCookie)
+}
+
+func (suite *IdentityTestSuite) TestInvalidIDTokensAreRejected() {
+	cases := map[string]map[string]interface{}{
+		"wrong audience": {"aud": "someone-else"},
+		"wrong issuer":   {"iss": "https://evil.example.com"},
+		"wrong nonce":    {"nonce": "replayed"},
+		"expired":        {"exp": time.Now().Add(-time.Hour).Unix()},
+	}
+
+	for name, claims := range cases {
+		suite.oidc.claims = claims
+		resp := suite.login()
+		suite.Equal(http.StatusBadRequest, resp.StatusCode, name)
+	}
+
+	var count int64
+	suite.db.Model(&models.User{}).Count(&count)
+	suite.Zero(count)
+}
+
+func (suite *IdentityTestSuite) createUser(email string) models.User {
+	user := models.User{Name: "Existing", Email: email}
+	suite.Require().NoError(suite.db.Create(&user).Error)
+	return user
+}
+
+func (suite *IdentityTestSuite) TestVerifiedEmailLinksExistingAccount() {
+	user := suite.createUser("oidc@example.com")
+
+	resp := suite.login()
+	suite.Equal(http.StatusOK, resp.StatusCode)
+
+	var linked models.UserIdentity
+	suite.Require().NoError(suite.db.First(&linked).Error)
+	suite.Equal(user.ID, linked.UserID)
+	suite.Equal("acme", linked.Provider)
+	suite.Equal("oidc-user-1", linked.Subject)
+}
+
+func (suite *IdentityTestSuite) TestUnverifiedEmailCannotTakeOverAccount() {
+	suite.createUser("oidc@example.com")
+	suite.oidc.claims = map[string]interface{}{"email_verified": false}
+
+	resp := suite.login()
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+
+	var count int64
+	suite.db.Model(&models.UserIdentity{}).Count(&count)
+	suite.Zero(count)
+}
+
+func (suite *IdentityTestSuite) TestLoginResolvesBySubject() {
+	suite.Equal(http.StatusOK, suite.login().StatusCode)
+
+	// The provider now reports a different, unverified email for the same
+	// subject; the identity still resolves to the original account.
+	suite.oidc.claims = map[string]interface{}{"email": "renamed@example.com", "email_verified": false}
+	suite.Equal(http.StatusOK, suite.login().StatusCode)
+
+	var users []models.User
+	suite.db.Find(&users)
+	suite.Require().Len(users, 1)
+	suite.Equal("oidc@example.com", users[0].Email)
+}
+
+func (suite *IdentityTestSuite) TestExplicitLinkAndUnlink() {
+	user := suite.createUser("someone@example.com")
+	now := time.Now()
+	suite.sessionStore.Save("link-session", &middleware.SessionData{
+		UserID: user.ID, CreatedAt: now, LastActiveAt: now, ExpiresAt: now.Add(time.Hour),
+	})
+
+	// An explicit link works even though the provider email is unverified
+	// and differs from the account email.
+	suite.oidc.claims = map[string]interface{}{"email_verified": false}
+	resp := suite.runFlow("/me/identities/link?provider=acme", "link-session", "link-session")
+	suite.Equal(http.StatusOK, resp.StatusCode)
+
+	// Without the session the link flow cannot complete.
+	resp = suite.runFlow("/me/identities/link?provider=acme", "link-session", "")
+	suite.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	var identities []models.UserIdentity
+	suite.Require().NoError(suite.db.Where("user_id = ?", user.ID).Find(&identities).Error)
+	suite.Require().Len(identities, 1)
+	first := identities[0]
+
+	request := func(method, path string) *http.Response {
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: suite.sessions.CookieValue("link-session")})
+		resp, err := suite.app.Test(req)
+		suite.Require().NoError(err)
+		return resp
+	}
+
+	suite.Equal(http.StatusConflict, request(http.MethodDelete, fmt.Sprintf("/me/identities/%d", first.ID)).StatusCode)
+
+	suite.Require().NoError(suite.db.Create(&models.UserIdentity{
+		UserID: user.ID, Provider: "google", Subject: "google-sub",
+	}).Error)
+	suite.Equal(http.StatusOK, request(http.MethodDelete, fmt.Sprintf("/me/identities/%d", first.ID)).StatusCode)
+
+	resp = request(http.MethodGet, "/me/identities")
+	var listed struct {
+		Data []models.UserIdentity `json:"data"`
+	}
+	suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&listed))
+	suite.Require().Len(listed.Data, 1)
+	suite.Equal("google", listed.Data[0].Provider)
+}
+
+func (suite *IdentityTestSuite) TestImpersonatorCannotLinkIdentity() {
+	user := suite.createUser("viewed@example.com")
+	admin := suite.createUser("admin@example.com")
+	now := time.Now()
+	suite.sessionStore.Save("own-session", &middleware.SessionData{
+		UserID: user.ID, CreatedAt: now, LastActiveAt: now, ExpiresAt: now.Add(time.Hour),
+	})
+	suite.sessionStore.Save("impersonation", &middleware.SessionData{
+		UserID: user.ID, ImpersonatorID: admin.ID, CreatedAt: now, LastActiveAt: now, ExpiresAt: now.Add(time.Hour),
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/me/identities/link?provider=acme", nil)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: suite.sessions.CookieValue("impersonation")})
+	resp, err := suite.app.Test(req)
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+
+	// Nor can they finish a link flow the user started.
+	resp = suite.runFlow("/me/identities/link?provider=acme", "own-session", "impersonation")
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+
+	var count int64
+	suite.db.Model(&models.UserIdentity{}).Where("user_id = ?", user.ID).Count(&count)
+	suite.Zero(count)
+}
+
+func TestIdentityTestSuite(t *testing.T) {
+	suite.Run(t, new(IdentityTestSuite))
+}
@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type LiveClicksTestSuite struct {
+	apiSuite
+	urlService *services.URLService
+	clickHub   *services.ClickHub
+	baseURL    string
+}
+
+func (suite *LiveClicksTestSuite) SetupSuite() {
+	suite.cfg = &config.Config{
+		SessionSecret: "test-session-secret",
+		Environment:   "test",
+		FrontendURL:   "http://localhost:3000",
+	}
+
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	// Clicks are recorded in the background while the stream is open; every
+	// connection to ":memory:" would otherwise open a separate database.
+	sqlDB, err := suite.db.DB()
+	suite.Require().NoError(err)
+	sqlDB.SetMaxOpenConns(1)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+
+	suite.urlService = services.NewURLService()
+	suite.sessionStore = middleware.NewSimpleSessionStore()
+	suite.sessions = middleware.NewSessionManager(suite.sessionStore, suite.cfg)
+}
+
+// SetupTest serves a fresh hub on a real listener, since app.Test waits for
+// the whole response and a stream only ends when its hub closes.
+func (suite *LiveClicksTestSuite) SetupTest() {
+	suite.clickHub = services.NewClickHub(0)
+	urlHandler := handlers.NewURLHandler(suite.urlService, suite.clickHub, suite.cfg)
+
+	suite.app = fiber.New(fiber.Config{DisableStartupMessage: true})
+	suite.app.Get("/urls/:id/analytics/live", suite.sessions.AuthMiddleware(), urlHandler.StreamClickEvents)
+	suite.app.Get("/:shortCode", urlHandler.RedirectURL)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	suite.baseURL = "http://" + ln.Addr().String()
+	go suite.app.Listener(ln)
+}
+
+func (suite *LiveClicksTestSuite) TearDownTest() {
+	suite.clickHub.Close()
+	suite.app.Shutdown()
+
+	for _, table := range []string{"analytics", "workspace_members", "workspaces", "urls", "users"} {
+		suite.db.Exec("DELETE FROM " + table)
+	}
+}
+
+// openStream connects to a link's live stream and returns the response once
+// the opening heartbeat has arrived.
+func (suite *LiveClicksTestSuite) openStream(urlID uint, sessionID string) (*http.Response, *bufio.Reader) {
+	req, err := http.NewRequest(http.MethodGet, suite.baseURL+fmt.Sprintf("/urls/%d/analytics/live", urlID), nil)
+	suite.Require().NoError(err)
+	req.Header.Set("Cookie", "session_id="+suite.sessions.CookieValue(sessionID))
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	suite.Equal("heartbeat", suite.nextEvent(reader, nil))
+	return resp, reader
+}
+
+// nextEvent reads the next Server-Sent Event, decoding its data into out if
+// given, and returns its name.
+func (suite *LiveClicksTestSuite) nextEvent(reader *bufio.Reader, out interface{}) string {
+	var name string
+	for {
+		line, err := reader.ReadString('\n')
+		suite.Require().NoError(err)
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && out != nil:
+			suite.Require().NoError(json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), out))
+		case line == "" && name != "":
+			return name
+		}
+	}
+}
+
+func (suite *LiveClicksTestSuite) TestStreamDeliversClicks() {
+	user, sessionID := suite.signIn("live@example.com")
+	url, err := suite.urlService.CreateURL(&models.CreateURLRequest{OriginalURL: "https://example.com/live"}, &user.ID)
+	suite.Require().NoError(err)
+
+	resp, reader := suite.openStream(url.ID, sessionID)
+	defer resp.Body.Close()
+
+	redirect := suite.send(http.MethodGet, "/"+url.ShortCode, "", nil, "User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148")
+	suite.Equal(http.StatusFound, redirect.StatusCode)
+
+	var event models.ClickEvent
+	suite.Equal("click", suite.nextEvent(reader, &event))
+	suite.Equal(url.ID, event.URLID)
+	suite.Equal("mobile", event.Device)
+
+	// Closing the hub, as the server does on shutdown, ends the stream.
+	suite.clickHub.Close()
+	_, err = io.ReadAll(reader)
+	suite.NoError(err)
+}
+
+func (suite *LiveClicksTestSuite) TestStreamIsLimitedToMembers() {
+	owner, _ := suite.signIn("owner@example.com")
+	url, err := suite.urlService.CreateURL(&models.CreateURLRequest{OriginalURL: "https://example.com/private"}, &owner.ID)
+	suite.Require().NoError(err)
+
+	_, otherSession := suite.signIn("other@example.com")
+	resp := suite.send(http.MethodGet, fmt.Sprintf("/urls/%d/analytics/live", url.ID), otherSession, nil)
+	suite.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func TestLiveClicksTestSuite(t *testing.T) {
+	suite.Run(t, new(LiveClicksTestSuite))
+}
@@ -10,6 +10,7 @@ import (
 	"url-shortener-backend/internal/config"
 	"url-shortener-backend/internal/database"
 	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/identity"
 	"url-shortener-backend/internal/middleware"
 	"url-shortener-backend/internal/models"
 	"url-shortener-backend/internal/services"
@@ -26,6 +27,7 @@ type OAuthTestSuite struct {
 	db           *gorm.DB
 	config       *config.Config
 	sessionStore *middleware.SimpleSessionStore
+	sessions     *middleware.SessionManager
 }
 
 func (suite *OAuthTestSuite) SetupSuite() {
@@ -49,9 +51,10 @@ func (suite *OAuthTestSuite) SetupSuite() {
 	authService := services.NewAuthService()
 	urlService := services.NewURLService()
 	
-	suite.sessionStore = middleware.NewSimpleSessionStore(suite.config)
-	oauthHandler := handlers.NewOAuthHandler(authService, suite.config, suite.sessionStore)
-	urlHandler := handlers.NewURLHandler(urlService)
+	suite.sessionStore = middleware.NewSimpleSessionStore()
+	suite.sessions = middleware.NewSessionManager(suite.sessionStore, suite.config)
+	oauthHandler := handlers.NewOAuthHandler(authService, suite.config, suite.sessions, identity.NewRegistry(suite.config), services.NewAuditService())
+	urlHandler := handlers.NewURLHandler(urlService, services.NewClickHub(0), suite.config)
 
 	suite.app = fiber.New()
 	
@@ -60,12 +63,12 @@ func (suite *OAuthTestSuite) SetupSuite() {
 	auth.Get("/login", oauthHandler.Login)
 	auth.Get("/callback", oauthHandler.Callback)
 	auth.Post("/logout", oauthHandler.Logout)
-	auth.Get("/profile", suite.sessionStore.AuthMiddleware(), oauthHandler.GetProfile)
+	auth.Get("/profile", suite.sessions.AuthMiddleware(), oauthHandler.GetProfile)
 	
 	// URL routes
 	urls := suite.app.Group("/urls")
-	urls.Post("/", suite.sessionStore.OptionalAuthMiddleware(), urlHandler.CreateURL)
-	urls.Get("/", suite.sessionStore.AuthMiddleware(), urlHandler.GetUserURLs)
+	urls.Post("/", suite.sessions.OptionalAuthMiddleware(), urlHandler.CreateURL)
+	urls.Get("/", suite.sessions.AuthMiddleware(), urlHandler.GetUserURLs)
 	urls.Get("/:shortCode/info", urlHandler.GetURLInfo)
 	
 	suite.app.Get("/:shortCode", urlHandler.RedirectURL)
@@ -123,6 +126,7 @@ func (suite *OAuthTestSuite) TestCreateURLWithSession() {
 		UserID:    user.ID,
 		UserEmail: user.Email,
 		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
 	}
 	
 	reqBody := models.CreateURLRequest{
@@ -133,7 +137,7 @@ func (suite *OAuthTestSuite) TestCreateURLWithSession() {
 	body, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest(http.MethodPost, "/urls/", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Cookie", "session_id="+sessionID)
+	req.Header.Set("Cookie", "session_id="+suite.sessions.CookieValue(sessionID))
 
 	resp, err := suite.app.Test(req)
 	suite.Require().NoError(err)
@@ -0,0 +1,344 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type OrganizeTestSuite struct {
+	apiSuite
+	urlService       *services.URLService
+	workspaceService *services.WorkspaceService
+}
+
+func (suite *OrganizeTestSuite) SetupSuite() {
+	suite.cfg = &config.Config{
+		SessionSecret: "test-session-secret",
+		Environment:   "test",
+		FrontendURL:   "http://localhost:3000",
+	}
+
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+
+	suite.urlService = services.NewURLService()
+	suite.workspaceService = services.NewWorkspaceService(suite.cfg, &mockMailer{})
+	suite.sessionStore = middleware.NewSimpleSessionStore()
+	suite.sessions = middleware.NewSessionManager(suite.sessionStore, suite.cfg)
+
+	urlHandler := handlers.NewURLHandler(suite.urlService, services.NewClickHub(0), suite.cfg)
+	tagHandler := handlers.NewTagHandler(services.NewTagService(), services.NewAnalyticsService())
+	folderHandler := handlers.NewFolderHandler(services.NewFolderService())
+
+	suite.app = fiber.New()
+	urls := suite.app.Group("/urls", suite.sessions.AuthMiddleware())
+	urls.Post("/", urlHandler.CreateURL)
+	urls.Get("/", urlHandler.GetUserURLs)
+	urls.Post("/bulk/tag", tagHandler.TagURLs)
+	urls.Post("/bulk/untag", tagHandler.UntagURLs)
+	urls.Post("/bulk/move", folderHandler.MoveURLs)
+
+	tags := suite.app.Group("/tags", suite.sessions.AuthMiddleware())
+	tags.Get("/", tagHandler.ListTags)
+	tags.Post("/", tagHandler.CreateTag)
+	tags.Put("/:id", tagHandler.UpdateTag)
+	tags.Delete("/:id", tagHandler.DeleteTag)
+	tags.Get("/:id/stats", tagHandler.GetTagStats)
+
+	folders := suite.app.Group("/folders", suite.sessions.AuthMiddleware())
+	folders.Get("/", folderHandler.ListFolders)
+	folders.Post("/", folderHandler.CreateFolder)
+	folders.Put("/:id", folderHandler.UpdateFolder)
+	folders.Delete("/:id", folderHandler.DeleteFolder)
+}
+
+func (suite *OrganizeTestSuite) TearDownTest() {
+	for _, table := range []string{"url_tags", "tags", "folders", "analytics", "analytics_daily", "url_revisions", "audit_events", "webhook_deliveries", "webhooks", "usage_counters", "usage_events", "workspace_members", "workspaces", "urls", "users"} {
+		suite.db.Exec("DELETE FROM " + table)
+	}
+}
+
+func (suite *OrganizeTestSuite) createTag(sessionID string, req models.TagRequest) models.Tag {
+	var body struct {
+		Data models.Tag `json:"data"`
+	}
+	suite.Require().Equal(http.StatusCreated, suite.request(http.MethodPost, "/tags", sessionID, req, &body))
+	return body.Data
+}
+
+func (suite *OrganizeTestSuite) createFolder(sessionID string, req models.FolderRequest) models.Folder {
+	var body struct {
+		Data models.Folder `json:"data"`
+	}
+	suite.Require().Equal(http.StatusCreated, suite.request(http.MethodPost, "/folders", sessionID, req, &body))
+	return body.Data
+}
+
+func (suite *OrganizeTestSuite) createURL(userID uint, req models.CreateURLRequest) *models.URL {
+	url, err := suite.urlService.CreateURL(&req, &userID)
+	suite.Require().NoError(err)
+	return url
+}
+
+// listURLs returns the IDs of the links matched by the query string.
+func (suite *OrganizeTestSuite) listURLs(query, sessionID string) []uint {
+	var body struct {
+		Data struct {
+			URLs  []models.URL `json:"urls"`
+			Total int64        `json:"total"`
+		} `json:"data"`
+	}
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodGet, "/urls?"+query, sessionID, nil, &body))
+	suite.Require().Equal(int64(len(body.Data.URLs)), body.Data.Total)
+
+	ids := []uint{}
+	for _, url := range body.Data.URLs {
+		ids = append(ids, url.ID)
+	}
+	return ids
+}
+
+func (suite *OrganizeTestSuite) TestTagsFilterLinks() {
+	user, sessionID := suite.signIn("tags@example.com")
+	launch := suite.createTag(sessionID, models.TagRequest{Name: " Launch ", Color: "#FF8800"})
+	suite.Equal("Launch", launch.Name)
+	suite.Equal("#ff8800", launch.Color)
+	docs := suite.createTag(sessionID, models.TagRequest{Name: "docs"})
+
+	var failure models.ErrorResponse
+	suite.Equal(http.StatusConflict, suite.request(http.MethodPost, "/tags", sessionID, models.TagRequest{Name: "LAUNCH"}, &failure))
+	suite.Equal("create_failed", failure.Error)
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodPost, "/tags", sessionID, models.TagRequest{Name: "x", Color: "orange"}, nil))
+
+	both := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/both", TagIDs: []uint{launch.ID, docs.ID}})
+	suite.Len(both.Tags, 2)
+	onlyLaunch := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/launch"})
+	suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/none"})
+
+	var changed struct {
+		Data struct {
+			Changed int64 `json:"changed"`
+		} `json:"data"`
+	}
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodPost, "/urls/bulk/tag", sessionID,
+		models.BulkTagRequest{URLIDs: []uint{both.ID, onlyLaunch.ID}, TagIDs: []uint{launch.ID}}, &changed))
+	suite.Equal(int64(1), changed.Data.Changed)
+
+	suite.ElementsMatch([]uint{both.ID, onlyLaunch.ID}, suite.listURLs(fmt.Sprintf("tag_id=%d", launch.ID), sessionID))
+	suite.Equal([]uint{both.ID}, suite.listURLs(fmt.Sprintf("tag_id=%d,%d", launch.ID, docs.ID), sessionID))
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodGet, "/urls?tag_id=launch", sessionID, nil, nil))
+
+	var listed struct {
+		Data []models.Tag `json:"data"`
+	}
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodGet, "/tags", sessionID, nil, &listed))
+	suite.Require().Len(listed.Data, 2)
+	suite.Equal("Launch", listed.Data[0].Name)
+	suite.Equal(int64(2), *listed.Data[0].LinkCount)
+	suite.Equal(int64(1), *listed.Data[1].LinkCount)
+
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodPost, "/urls/bulk/untag", sessionID,
+		models.BulkTagRequest{URLIDs: []uint{both.ID}, TagIDs: []uint{launch.ID, docs.ID}}, &changed))
+	suite.Equal(int64(2), changed.Data.Changed)
+
+	suite.Equal(http.StatusOK, suite.request(http.MethodPut, fmt.Sprintf("/tags/%d", launch.ID), sessionID, models.TagRequest{Name: "Release"}, nil))
+	suite.Equal(http.StatusConflict, suite.request(http.MethodPut, fmt.Sprintf("/tags/%d", launch.ID), sessionID, models.TagRequest{Name: "Docs"}, nil))
+	suite.Equal(http.StatusOK, suite.request(http.MethodDelete, fmt.Sprintf("/tags/%d", launch.ID), sessionID, nil, nil))
+	suite.Empty(suite.listURLs(fmt.Sprintf("tag_id=%d", launch.ID), sessionID))
+
+	var attached int64
+	suite.db.Model(&models.URLTag{}).Count(&attached)
+	suite.Zero(attached)
+}
+
+func (suite *OrganizeTestSuite) TestFolderTree() {
+	user, sessionID := suite.signIn("folders@example.com")
+	campaigns := suite.createFolder(sessionID, models.FolderRequest{Name: "Campaigns"})
+	spring := suite.createFolder(sessionID, models.FolderRequest{Name: "Spring", ParentID: &campaigns.ID})
+	suite.Equal(http.StatusConflict, suite.request(http.MethodPost, "/folders", sessionID, models.FolderRequest{Name: "spring", ParentID: &campaigns.ID}, nil))
+	// The same name is fine under a different parent.
+	suite.createFolder(sessionID, models.FolderRequest{Name: "Spring"})
+
+	inSpring := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/spring", FolderID: &spring.ID})
+	suite.Equal(spring.ID, *inSpring.FolderID)
+	inCampaigns := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/campaigns"})
+	unfiled := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/unfiled"})
+
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodPost, "/urls/bulk/move", sessionID,
+		models.MoveURLsRequest{URLIDs: []uint{inCampaigns.ID}, FolderID: &campaigns.ID}, nil))
+
+	suite.Equal([]uint{inCampaigns.ID}, suite.listURLs(fmt.Sprintf("folder_id=%d", campaigns.ID), sessionID))
+	suite.ElementsMatch([]uint{inSpring.ID, inCampaigns.ID}, suite.listURLs(fmt.Sprintf("folder_id=%d&subfolders=true", campaigns.ID), sessionID))
+	suite.Equal([]uint{unfiled.ID}, suite.listURLs("folder_id=none", sessionID))
+
+	var failure models.ErrorResponse
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodPut, fmt.Sprintf("/folders/%d", campaigns.ID), sessionID,
+		models.FolderRequest{Name: "Campaigns", ParentID: &spring.ID}, &failure))
+	suite.Equal(services.ErrFolderCycle.Error(), failure.Message)
+
+	// Moving Spring to the top level clashes with the other Spring.
+	suite.Equal(http.StatusConflict, suite.request(http.MethodPut, fmt.Sprintf("/folders/%d", spring.ID), sessionID,
+		models.FolderRequest{Name: "Spring"}, nil))
+	suite.Equal(http.StatusOK, suite.request(http.MethodPut, fmt.Sprintf("/folders/%d", spring.ID), sessionID,
+		models.FolderRequest{Name: "Spring 2026"}, nil))
+	suite.Equal(http.StatusOK, suite.request(http.MethodPut, fmt.Sprintf("/folders/%d", spring.ID), sessionID,
+		models.FolderRequest{Name: "Spring 2026", ParentID: &campaigns.ID}, nil))
+
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodDelete, fmt.Sprintf("/folders/%d", campaigns.ID), sessionID, nil, nil))
+	suite.ElementsMatch([]uint{inSpring.ID, inCampaigns.ID, unfiled.ID}, suite.listURLs("folder_id=none", sessionID))
+
+	var listed struct {
+		Data []models.Folder `json:"data"`
+	}
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodGet, "/folders", sessionID, nil, &listed))
+	suite.Require().Len(listed.Data, 1)
+	suite.Equal("Spring", listed.Data[0].Name)
+}
+
+func (suite *OrganizeTestSuite) TestBulkChangesNeedEditorsInOneWorkspace() {
+	owner, ownerSession := suite.signIn("owner@example.com")
+	viewer, viewerSession := suite.signIn("viewer@example.com")
+	team, err := suite.workspaceService.CreateWorkspace(owner.ID, &models.WorkspaceRequest{Name: "Team"})
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.db.Create(&models.WorkspaceMember{WorkspaceID: team.ID, UserID: viewer.ID, Role: models.RoleViewer}).Error)
+
+	teamTag := suite.createTag(ownerSession, models.TagRequest{Name: "Team", WorkspaceID: &team.ID})
+	personalTag := suite.createTag(ownerSession, models.TagRequest{Name: "Mine"})
+	teamLink := suite.createURL(owner.ID, models.CreateURLRequest{OriginalURL: "https://example.com/team", WorkspaceID: &team.ID})
+	personalLink := suite.createURL(owner.ID, models.CreateURLRequest{OriginalURL: "https://example.com/mine"})
+
+	suite.Equal(http.StatusForbidden, suite.request(http.MethodPost, "/urls/bulk/tag", viewerSession,
+		models.BulkTagRequest{URLIDs: []uint{teamLink.ID}, TagIDs: []uint{teamTag.ID}}, nil))
+	suite.Equal(http.StatusForbidden, suite.request(http.MethodPost, "/tags", viewerSession, models.TagRequest{Name: "Nope", WorkspaceID: &team.ID}, nil))
+	suite.Equal(http.StatusNotFound, suite.request(http.MethodPost, "/urls/bulk/tag", viewerSession,
+		models.BulkTagRequest{URLIDs: []uint{personalLink.ID}, TagIDs: []uint{teamTag.ID}}, nil))
+
+	var failure models.ErrorResponse
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodPost, "/urls/bulk/tag", ownerSession,
+		models.BulkTagRequest{URLIDs: []uint{teamLink.ID, personalLink.ID}, TagIDs: []uint{teamTag.ID}}, &failure))
+	suite.Equal(services.ErrWorkspaceMismatch.Error(), failure.Message)
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodPost, "/urls/bulk/tag", ownerSession,
+		models.BulkTagRequest{TagIDs: []uint{teamTag.ID}}, nil))
+	suite.Equal(http.StatusNotFound, suite.request(http.MethodPost, "/urls", ownerSession,
+		models.CreateURLRequest{OriginalURL: "https://example.com/", TagIDs: []uint{personalTag.ID}, WorkspaceID: &team.ID}, nil))
+
+	// Nothing was attached by the rejected requests.
+	var attached int64
+	suite.db.Model(&models.URLTag{}).Count(&attached)
+	suite.Zero(attached)
+
+	// Viewers see the team's tags but not the owner's personal ones.
+	var listed struct {
+		Data []models.Tag `json:"data"`
+	}
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodGet, "/tags", viewerSession, nil, &listed))
+	suite.Require().Len(listed.Data, 1)
+	suite.Equal(teamTag.ID, listed.Data[0].ID)
+}
+
+func (suite *OrganizeTestSuite) TestBulkChangesAreAudited() {
+	owner, sessionID := suite.signIn("audited@example.com")
+	team, err := suite.workspaceService.CreateWorkspace(owner.ID, &models.WorkspaceRequest{Name: "Team"})
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.db.Create(&models.Webhook{
+		WorkspaceID: team.ID, URL: "https://hooks.example.com/", Events: []string{models.WebhookLinkUpdated}, Active: true, Secret: "secret",
+	}).Error)
+
+	tag := suite.createTag(sessionID, models.TagRequest{Name: "Launch", WorkspaceID: &team.ID})
+	folder := suite.createFolder(sessionID, models.FolderRequest{Name: "Campaigns", WorkspaceID: &team.ID})
+	first := suite.createURL(owner.ID, models.CreateURLRequest{OriginalURL: "https://example.com/first", WorkspaceID: &team.ID})
+	second := suite.createURL(owner.ID, models.CreateURLRequest{OriginalURL: "https://example.com/second", WorkspaceID: &team.ID})
+	both := []uint{first.ID, second.ID}
+
+	updates := func() []models.AuditEvent {
+		var events []models.AuditEvent
+		suite.Require().NoError(suite.db.Where("action = ?", models.AuditLinkUpdate).Order("id").Find(&events).Error)
+		return events
+	}
+
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodPost, "/urls/bulk/tag", sessionID, models.BulkTagRequest{URLIDs: both, TagIDs: []uint{tag.ID}}, nil))
+	events := updates()
+	suite.Require().Len(events, 2)
+	suite.Equal(first.ID, events[0].TargetID)
+	suite.Equal(owner.ID, *events[0].ActorID)
+	suite.Equal(team.ID, *events[0].WorkspaceID)
+	suite.Nil(events[0].Changes["tags"].From)
+	suite.Equal([]interface{}{"Launch"}, events[0].Changes["tags"].To)
+
+	// Tagging links that already have the tag changes nothing.
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodPost, "/urls/bulk/tag", sessionID, models.BulkTagRequest{URLIDs: both, TagIDs: []uint{tag.ID}}, nil))
+	suite.Len(updates(), 2)
+
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodPost, "/urls/bulk/untag", sessionID, models.BulkTagRequest{URLIDs: []uint{first.ID}, TagIDs: []uint{tag.ID}}, nil))
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodPost, "/urls/bulk/move", sessionID, models.MoveURLsRequest{URLIDs: both, FolderID: &folder.ID}, nil))
+	events = updates()
+	suite.Require().Len(events, 5)
+	suite.Equal(first.ID, events[2].TargetID)
+	suite.Nil(events[2].Changes["tags"].To)
+	suite.Equal(float64(folder.ID), events[4].Changes["folder_id"].To)
+
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodDelete, fmt.Sprintf("/folders/%d", folder.ID), sessionID, nil, nil))
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodDelete, fmt.Sprintf("/tags/%d", tag.ID), sessionID, nil, nil))
+	events = updates()
+	suite.Require().Len(events, 8)
+	suite.Equal(second.ID, events[7].TargetID)
+	suite.Contains(events[7].Changes, "tags")
+
+	var deliveries int64
+	suite.db.Model(&models.WebhookDelivery{}).Where("event = ?", models.WebhookLinkUpdated).Count(&deliveries)
+	suite.Equal(int64(8), deliveries)
+}
+
+func (suite *OrganizeTestSuite) TestTagStats() {
+	user, sessionID := suite.signIn("stats@example.com")
+	tag := suite.createTag(sessionID, models.TagRequest{Name: "Launch"})
+	first := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/1", TagIDs: []uint{tag.ID}})
+	second := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/2", TagIDs: []uint{tag.ID}})
+	untagged := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/3"})
+
+	now := time.Now().UTC()
+	for _, click := range []models.Analytics{
+		{URLID: first.ID, IPAddress: "203.0.113.1", ClickedAt: now.Add(-time.Hour)},
+		{URLID: first.ID, IPAddress: "203.0.113.2", ClickedAt: now.Add(-time.Hour)},
+		{URLID: second.ID, IPAddress: "203.0.113.1", ClickedAt: now.Add(-2 * time.Hour)},
+		{URLID: untagged.ID, IPAddress: "203.0.113.3", ClickedAt: now.Add(-time.Hour)},
+	} {
+		suite.Require().NoError(suite.db.Create(&click).Error)
+	}
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -10)
+	suite.Require().NoError(suite.db.Create(&models.AnalyticsDaily{URLID: second.ID, Date: day, Clicks: 5, UniqueClicks: 2}).Error)
+
+	var body struct {
+		Data models.TagStats `json:"data"`
+	}
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodGet, fmt.Sprintf("/tags/%d/stats", tag.ID), sessionID, nil, &body))
+	suite.Equal(int64(2), body.Data.Links)
+	suite.Equal(int64(8), body.Data.TotalClicks)
+	suite.Require().Len(body.Data.TopLinks, 2)
+	suite.Equal(second.ID, body.Data.TopLinks[0].URLID)
+	suite.Equal(int64(6), body.Data.TopLinks[0].Clicks)
+
+	_, outsiderSession := suite.signIn("outsider@example.com")
+	suite.Equal(http.StatusNotFound, suite.request(http.MethodGet, fmt.Sprintf("/tags/%d/stats", tag.ID), outsiderSession, nil, nil))
+}
+
+func TestOrganizeTestSuite(t *testing.T) {
+	suite.Run(t, new(OrganizeTestSuite))
+}
@@ -0,0 +1,219 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/identity"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var mailTokenPattern = regexp.MustCompile(`token=(\S+)`)
+
+type PasswordAuthTestSuite struct {
+	suite.Suite
+	app    *fiber.App
+	db     *gorm.DB
+	cfg    *config.Config
+	mailer *mockMailer
+}
+
+func (suite *PasswordAuthTestSuite) SetupSuite() {
+	suite.cfg = &config.Config{
+		SessionSecret: "test-session-secret",
+		Environment:   "test",
+		FrontendURL:   "http://localhost:3000",
+	}
+
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+}
+
+// SetupTest builds a fresh service and rate limiter so login throttling does
+// not carry over between tests.
+func (suite *PasswordAuthTestSuite) SetupTest() {
+	database.DB = suite.db
+	suite.mailer = &mockMailer{}
+
+	sessions := middleware.NewSessionManager(middleware.NewSimpleSessionStore(), suite.cfg)
+	passwordService := services.NewPasswordAuthService(suite.cfg, suite.mailer)
+	passwordService.SetSessionRevoker(sessions)
+	passwordHandler := handlers.NewPasswordAuthHandler(passwordService, sessions, services.NewAuditService())
+
+	limits := middleware.NewRateLimits(suite.cfg, nil, middleware.DefaultRateLimitPolicies(suite.cfg)...)
+
+	suite.app = fiber.New()
+	auth := suite.app.Group("/auth")
+	auth.Post("/register", passwordHandler.Register)
+	auth.Post("/login", limits.Handler(middleware.RateLimitAuth), passwordHandler.Login)
+	auth.Post("/verify-email", passwordHandler.VerifyEmail)
+	auth.Post("/password/forgot", passwordHandler.ForgotPassword)
+	auth.Post("/password/reset", passwordHandler.ResetPassword)
+}
+
+func (suite *PasswordAuthTestSuite) TearDownTest() {
+	suite.db.Exec("DELETE FROM user_identities")
+	suite.db.Exec("DELETE FROM users")
+}
+
+func (suite *PasswordAuthTestSuite) post(path string, body interface{}) *http.Response {
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(body)
+
+	req := httptest.NewRequest(http.MethodPost, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := suite.app.Test(req)
+	suite.Require().NoError(err)
+	return resp
+}
+
+// lastMailToken returns the token from the most recent email.
+func (suite *PasswordAuthTestSuite) lastMailToken() string {
+	sent := suite.mailer.Sent()
+	suite.Require().NotEmpty(sent)
+	match := mailTokenPattern.FindStringSubmatch(sent[len(sent)-1].Body)
+	suite.Require().Len(match, 2)
+	return match[1]
+}
+
+func (suite *PasswordAuthTestSuite) register(email, password string) {
+	resp := suite.post("/auth/register", models.RegisterRequest{Email: email, Password: password, Name: "Local"})
+	suite.Require().Equal(http.StatusCreated, resp.StatusCode)
+	suite.Require().Equal(http.StatusOK, suite.post("/auth/verify-email", models.VerifyEmailRequest{Token: suite.lastMailToken()}).StatusCode)
+}
+
+func (suite *PasswordAuthTestSuite) TestRegisterVerifyAndLogin() {
+	resp := suite.post("/auth/register", models.RegisterRequest{Email: "New@Example.com", Password: "correct horse 42", Name: "New"})
+	suite.Equal(http.StatusCreated, resp.StatusCode)
+
+	var user models.User
+	suite.Require().NoError(suite.db.First(&user).Error)
+	suite.Equal("new@example.com", user.Email)
+	suite.NotContains(user.PasswordHash, "correct horse")
+
+	login := models.LoginRequest{Email: "new@example.com", Password: "correct horse 42"}
+	suite.Equal(http.StatusForbidden, suite.post("/auth/login", login).StatusCode)
+
+	suite.Equal(http.StatusBadRequest, suite.post("/auth/verify-email", models.VerifyEmailRequest{Token: suite.lastMailToken() + "x"}).StatusCode)
+	suite.Equal(http.StatusOK, suite.post("/auth/verify-email", models.VerifyEmailRequest{Token: suite.lastMailToken()}).StatusCode)
+
+	resp = suite.post("/auth/login", login)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.NotEmpty(resp.Cookies())
+
+	resp = suite.post("/auth/register", models.RegisterRequest{Email: "new@example.com", Password: "another pass 42"})
+	suite.Equal(http.StatusConflict, resp.StatusCode)
+}
+
+func (suite *PasswordAuthTestSuite) TestPasswordPolicy() {
+	for _, password := range []string{"short1", "onlyletterslong", "password123", "alice-secret-9", string(make([]byte, 80))} {
+		suite.Error(services.ValidatePassword(password, "alice@example.com"), password)
+	}
+	suite.NoError(services.ValidatePassword("tr0ub4dor&3x", "alice@example.com"))
+}
+
+func (suite *PasswordAuthTestSuite) TestLockoutAfterRepeatedFailures() {
+	suite.register("locked@example.com", "right password 1")
+
+	for i := 1; i < 5; i++ {
+		resp := suite.post("/auth/login", models.LoginRequest{Email: "locked@example.com", Password: "wrong password 1"})
+		suite.Equal(http.StatusUnauthorized, resp.StatusCode)
+	}
+	resp := suite.post("/auth/login", models.LoginRequest{Email: "locked@example.com", Password: "wrong password 1"})
+	suite.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	var user models.User
+	suite.Require().NoError(suite.db.Where("email = ?", "locked@example.com").First(&user).Error)
+	suite.Require().NotNil(user.LockedUntil)
+
+	// Even the right password is refused while locked, with the same answer
+	// as a wrong one so the lockout does not reveal that the account exists.
+	right := models.LoginRequest{Email: "locked@example.com", Password: "right password 1"}
+	resp = suite.post("/auth/login", right)
+	suite.Equal(http.StatusUnauthorized, resp.StatusCode)
+	var failure models.ErrorResponse
+	suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&failure))
+	suite.Equal("invalid_credentials", failure.Error)
+
+	suite.db.Model(&user).Update("locked_until", time.Now().Add(-time.Minute))
+	suite.Equal(http.StatusOK, suite.post("/auth/login", right).StatusCode)
+}
+
+func (suite *PasswordAuthTestSuite) TestLoginThrottlePerClient() {
+	for i := 0; i < 30; i++ {
+		resp := suite.post("/auth/login", models.LoginRequest{Email: "not-an-email", Password: "whatever"})
+		suite.Equal(http.StatusUnauthorized, resp.StatusCode)
+	}
+	resp := suite.post("/auth/login", models.LoginRequest{Email: "not-an-email", Password: "whatever"})
+	suite.Equal(http.StatusTooManyRequests, resp.StatusCode)
+}
+
+func (suite *PasswordAuthTestSuite) TestPasswordReset() {
+	suite.register("reset@example.com", "old password 1")
+
+	suite.Equal(http.StatusOK, suite.post("/auth/password/forgot", models.EmailRequest{Email: "reset@example.com"}).StatusCode)
+	token := suite.lastMailToken()
+
+	// Unknown emails get the same answer and no mail.
+	sent := len(suite.mailer.Sent())
+	suite.Equal(http.StatusOK, suite.post("/auth/password/forgot", models.EmailRequest{Email: "nobody@example.com"}).StatusCode)
+	suite.Len(suite.mailer.Sent(), sent)
+
+	suite.Equal(http.StatusOK, suite.post("/auth/password/reset", models.ResetPasswordRequest{Token: token, Password: "new password 2"}).StatusCode)
+	suite.Equal(http.StatusBadRequest, suite.post("/auth/password/reset", models.ResetPasswordRequest{Token: token, Password: "third password 3"}).StatusCode)
+
+	suite.Equal(http.StatusUnauthorized, suite.post("/auth/login", models.LoginRequest{Email: "reset@example.com", Password: "old password 1"}).StatusCode)
+	suite.Equal(http.StatusOK, suite.post("/auth/login", models.LoginRequest{Email: "reset@example.com", Password: "new password 2"}).StatusCode)
+}
+
+func (suite *PasswordAuthTestSuite) TestVerifiedProviderLoginDropsUnverifiedPassword() {
+	// An attacker pre-registers the victim's email but cannot verify it.
+	resp := suite.post("/auth/register", models.RegisterRequest{Email: "victim@example.com", Password: "attacker pass 1"})
+	suite.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	_, err := services.NewAuthService().LoginWithIdentity(&identity.UserInfo{
+		Provider:      "google",
+		Subject:       "victim-subject",
+		Email:         "victim@example.com",
+		EmailVerified: true,
+	})
+	suite.Require().NoError(err)
+
+	var user models.User
+	suite.Require().NoError(suite.db.Where("email = ?", "victim@example.com").First(&user).Error)
+	suite.Empty(user.PasswordHash)
+	suite.NotNil(user.EmailVerifiedAt)
+}
+
+func (suite *PasswordAuthTestSuite) TestProductionRequiresSMTPMail() {
+	cfg := &config.Config{Environment: "production", SessionSecret: "a-properly-long-random-session-secret-value"}
+	for _, driver := range []string{"", "log", "file"} {
+		cfg.MailDriver = driver
+		suite.Error(cfg.Validate(), driver)
+	}
+
+	cfg.MailDriver = "smtp"
+	suite.NoError(cfg.Validate())
+}
+
+func TestPasswordAuthTestSuite(t *testing.T) {
+	suite.Run(t, new(PasswordAuthTestSuite))
+}
@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/middleware"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimitTestSuite struct {
+	suite.Suite
+}
+
+// limitedApp serves /ping behind the named policy. The X-User header stands
+// in for the auth middleware.
+func (suite *RateLimitTestSuite) limitedApp(cfg *config.Config, store middleware.RateLimitStore, policies ...middleware.RateLimitPolicy) *fiber.App {
+	limits := middleware.NewRateLimits(cfg, store, policies...)
+	suite.T().Cleanup(limits.Close)
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if user := c.Get("X-User"); user != "" {
+			id, _ := strconv.Atoi(user)
+			c.Locals("user_id", uint(id))
+		}
+		return c.Next()
+	})
+	app.Get("/ping", limits.Handler(policies[0].Name), func(c *fiber.Ctx) error {
+		return c.SendString("pong")
+	})
+	return app
+}
+
+func (suite *RateLimitTestSuite) get(app *fiber.App, user string) *http.Response {
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	resp, err := app.Test(req)
+	suite.Require().NoError(err)
+	return resp
+}
+
+func (suite *RateLimitTestSuite) TestHeadersAndRejection() {
+	app := suite.limitedApp(&config.Config{}, nil, middleware.RateLimitPolicy{
+		Name: "test", Limit: 3, Window: time.Minute, Key: middleware.KeyByIP,
+	})
+
+	for i := 2; i >= 0; i-- {
+		resp := suite.get(app, "")
+		suite.Require().Equal(http.StatusOK, resp.StatusCode)
+		suite.Equal("3", resp.Header.Get("RateLimit-Limit"))
+		suite.Equal(strconv.Itoa(i), resp.Header.Get("RateLimit-Remaining"))
+		suite.Equal("3;w=60", resp.Header.Get("RateLimit-Policy"))
+		suite.Empty(resp.Header.Get("Retry-After"))
+	}
+
+	resp := suite.get(app, "")
+	suite.Equal(http.StatusTooManyRequests, resp.StatusCode)
+	suite.Equal("0", resp.Header.Get("RateLimit-Remaining"))
+	suite.Equal("20", resp.Header.Get("Retry-After"), "one token refills every 20 seconds")
+	suite.Equal("60", resp.Header.Get("RateLimit-Reset"))
+}
+
+func (suite *RateLimitTestSuite) TestKeyedByUser() {
+	app := suite.limitedApp(&config.Config{}, nil, middleware.RateLimitPolicy{
+		Name: "test", Limit: 1, Window: time.Minute, Key: middleware.KeyByUser,
+	})
+
+	suite.Equal(http.StatusOK, suite.get(app, "1").StatusCode)
+	suite.Equal(http.StatusTooManyRequests, suite.get(app, "1").StatusCode)
+	suite.Equal(http.StatusOK, suite.get(app, "2").StatusCode, "users have separate budgets")
+	suite.Equal(http.StatusOK, suite.get(app, "").StatusCode, "anonymous requests are counted by IP")
+}
+
+func (suite *RateLimitTestSuite) TestConfigOverride() {
+	cfg := &config.Config{RateLimitPolicies: map[string]config.RateLimitSetting{"test": {Limit: 5, Window: 10}}}
+	app := suite.limitedApp(cfg, nil, middleware.RateLimitPolicy{Name: "test", Limit: 1, Window: time.Minute})
+
+	resp := suite.get(app, "")
+	suite.Equal("5;w=10", resp.Header.Get("RateLimit-Policy"))
+	suite.Equal("4", resp.Header.Get("RateLimit-Remaining"))
+}
+
+func (suite *RateLimitTestSuite) TestUnknownPolicyPanics() {
+	limits := middleware.NewRateLimits(&config.Config{}, nil)
+	suite.Panics(func() { limits.Handler("missing") })
+}
+
+// take removes a token from key's bucket, allowing limit per window.
+func (suite *RateLimitTestSuite) take(store middleware.RateLimitStore, key string, limit int, window time.Duration) middleware.RateLimitResult {
+	result, err := store.Take(context.Background(), key, limit, window)
+	suite.Require().NoError(err)
+	return result
+}
+
+func (suite *RateLimitTestSuite) TestTokensRefill() {
+	store := middleware.NewMemoryRateLimitStore(0)
+	defer store.Close()
+
+	suite.True(suite.take(store, "k", 2, 200*time.Millisecond).Allowed)
+	suite.True(suite.take(store, "k", 2, 200*time.Millisecond).Allowed)
+	result := suite.take(store, "k", 2, 200*time.Millisecond)
+	suite.False(result.Allowed)
+	suite.Greater(result.RetryAfter, time.Duration(0))
+
+	time.Sleep(150 * time.Millisecond)
+	suite.True(suite.take(store, "k", 2, 200*time.Millisecond).Allowed)
+}
+
+func (suite *RateLimitTestSuite) TestKeysAreBounded() {
+	store := middleware.NewMemoryRateLimitStore(128)
+	defer store.Close()
+
+	for i := 0; i < 10000; i++ {
+		suite.take(store, fmt.Sprintf("client-%d", i), 1, time.Hour)
+	}
+	suite.LessOrEqual(store.Len(), 128)
+}
+
+func (suite *RateLimitTestSuite) redisStore(server *miniredis.Miniredis) *middleware.RedisRateLimitStore {
+	return middleware.NewRedisRateLimitStore(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+}
+
+func (suite *RateLimitTestSuite) TestRedisGCRA() {
+	server := miniredis.RunT(suite.T())
+	server.SetTime(time.Unix(1700000000, 0))
+	store := suite.redisStore(server)
+	defer store.Close()
+
+	for i := 2; i >= 0; i-- {
+		result := suite.take(store, "k", 3, time.Minute)
+		suite.Require().True(result.Allowed)
+		suite.Equal(i, result.Remaining)
+	}
+
+	result := suite.take(store, "k", 3, time.Minute)
+	suite.False(result.Allowed)
+	suite.Equal(20*time.Second, result.RetryAfter)
+	suite.Equal(time.Minute, result.Reset)
+
+	server.SetTime(time.Unix(1700000020, 0))
+	suite.True(suite.take(store, "k", 3, time.Minute).Allowed, "one token refills every 20 seconds")
+	suite.False(suite.take(store, "k", 3, time.Minute).Allowed)
+	suite.True(suite.take(store, "other", 3, time.Minute).Allowed, "keys are independent")
+}
+
+func (suite *RateLimitTestSuite) TestRedisSharedBetweenReplicas() {
+	server := miniredis.RunT(suite.T())
+	policy := middleware.RateLimitPolicy{Name: "test", Limit: 2, Window: time.Minute, Key: middleware.KeyByIP}
+	first := suite.limitedApp(&config.Config{}, suite.redisStore(server), policy)
+	second := suite.limitedApp(&config.Config{}, suite.redisStore(server), policy)
+
+	suite.Equal(http.StatusOK, suite.get(first, "").StatusCode)
+	suite.Equal(http.StatusOK, suite.get(second, "").StatusCode)
+	suite.Equal(http.StatusTooManyRequests, suite.get(first, "").StatusCode)
+	suite.Equal(http.StatusTooManyRequests, suite.get(second, "").StatusCode)
+}
+
+func (suite *RateLimitTestSuite) TestStoreUnavailable() {
+	policy := middleware.RateLimitPolicy{Name: "test", Limit: 1, Window: time.Minute}
+
+	server := miniredis.RunT(suite.T())
+	failOpen := suite.limitedApp(&config.Config{RateLimitFailOpen: true}, suite.redisStore(server), policy)
+	failClosed := suite.limitedApp(&config.Config{RateLimitFailOpen: false}, suite.redisStore(server), policy)
+	server.Close()
+
+	resp := suite.get(failOpen, "")
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.Empty(resp.Header.Get("RateLimit-Limit"))
+	suite.Equal(http.StatusOK, suite.get(failOpen, "").StatusCode)
+
+	resp = suite.get(failClosed, "")
+	suite.Equal(http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestRateLimitTestSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitTestSuite))
+}
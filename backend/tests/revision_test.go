@@ -0,0 +1,248 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type RevisionTestSuite struct {
+	apiSuite
+	urlService       *services.URLService
+	workspaceService *services.WorkspaceService
+}
+
+func (suite *RevisionTestSuite) SetupSuite() {
+	suite.cfg = &config.Config{
+		SessionSecret: "test-session-secret",
+		Environment:   "test",
+		FrontendURL:   "http://localhost:3000",
+	}
+
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+
+	suite.urlService = services.NewURLService()
+	suite.workspaceService = services.NewWorkspaceService(suite.cfg, &mockMailer{})
+	suite.sessionStore = middleware.NewSimpleSessionStore()
+	suite.sessions = middleware.NewSessionManager(suite.sessionStore, suite.cfg)
+
+	urlHandler := handlers.NewURLHandler(suite.urlService, services.NewClickHub(0), suite.cfg)
+
+	suite.app = fiber.New()
+	suite.app.Post("/urls", suite.sessions.OptionalAuthMiddleware(), urlHandler.CreateURL)
+	suite.app.Put("/urls/:id", suite.sessions.AuthMiddleware(), urlHandler.UpdateURL)
+	suite.app.Get("/urls/:id/revisions", suite.sessions.AuthMiddleware(), urlHandler.ListRevisions)
+	suite.app.Get("/urls/:id/revisions/diff", suite.sessions.AuthMiddleware(), urlHandler.DiffRevisions)
+	suite.app.Post("/urls/:id/revisions/:number/rollback", suite.sessions.AuthMiddleware(), urlHandler.RollbackURL)
+}
+
+func (suite *RevisionTestSuite) TearDownTest() {
+	for _, table := range []string{"url_revisions", "analytics", "analytics_daily", "audit_events", "usage_counters", "usage_events", "workspace_members", "workspaces", "urls", "users"} {
+		suite.db.Exec("DELETE FROM " + table)
+	}
+}
+
+func (suite *RevisionTestSuite) revisions(urlID uint, sessionID string) []models.URLRevision {
+	var body struct {
+		Data struct {
+			Revisions []models.URLRevision `json:"revisions"`
+			Total     int64                `json:"total"`
+		} `json:"data"`
+	}
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodGet, fmt.Sprintf("/urls/%d/revisions", urlID), sessionID, nil, &body))
+	suite.Require().Equal(int64(len(body.Data.Revisions)), body.Data.Total)
+	return body.Data.Revisions
+}
+
+func (suite *RevisionTestSuite) diff(path, sessionID string) models.URLRevisionDiff {
+	var body struct {
+		Data models.URLRevisionDiff `json:"data"`
+	}
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodGet, path, sessionID, nil, &body))
+	return body.Data
+}
+
+func (suite *RevisionTestSuite) TestUpdateDiffAndRollback() {
+	user, sessionID := suite.signIn("owner@example.com")
+
+	var created struct {
+		Data models.URL `json:"data"`
+	}
+	suite.Require().Equal(http.StatusCreated, suite.request(http.MethodPost, "/urls", sessionID, models.CreateURLRequest{OriginalURL: "https://example.com/v1", Title: "Launch"}, &created))
+	urlID := created.Data.ID
+	suite.Equal(1, created.Data.Revision)
+
+	path := fmt.Sprintf("/urls/%d", urlID)
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodPut, path, sessionID, models.CreateURLRequest{OriginalURL: "https://example.com/v2"}, nil))
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodPut, path, sessionID, models.CreateURLRequest{Title: "Launch week"}, nil))
+	// Saving without changing anything starts no revision.
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodPut, path, sessionID, models.CreateURLRequest{Title: "Launch week"}, nil))
+
+	revisions := suite.revisions(urlID, sessionID)
+	suite.Require().Len(revisions, 3)
+	suite.Equal([]int{3, 2, 1}, []int{revisions[0].Number, revisions[1].Number, revisions[2].Number})
+	suite.Equal("https://example.com/v1", revisions[2].OriginalURL)
+	suite.Equal(user.ID, *revisions[0].CreatedByID)
+
+	latest := suite.diff(path+"/revisions/diff", sessionID)
+	suite.Equal(2, latest.From)
+	suite.Equal(3, latest.To)
+	suite.Equal(map[string]models.AuditChange{"title": {From: "Launch", To: "Launch week"}}, latest.Changes)
+
+	first := suite.diff(path+"/revisions/diff?from=1&to=2", sessionID)
+	suite.Equal(map[string]models.AuditChange{"original_url": {From: "https://example.com/v1", To: "https://example.com/v2"}}, first.Changes)
+
+	var rolledBack struct {
+		Data models.URL `json:"data"`
+	}
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodPost, path+"/revisions/1/rollback", sessionID, nil, &rolledBack))
+	suite.Equal("https://example.com/v1", rolledBack.Data.OriginalURL)
+	suite.Equal("Launch", rolledBack.Data.Title)
+	suite.Equal(4, rolledBack.Data.Revision)
+
+	revisions = suite.revisions(urlID, sessionID)
+	suite.Require().Len(revisions, 4)
+	suite.Require().NotNil(revisions[0].RestoredFrom)
+	suite.Equal(1, *revisions[0].RestoredFrom)
+	suite.Empty(suite.diff(path+"/revisions/diff?from=1&to=4", sessionID).Changes)
+
+	var event models.AuditEvent
+	suite.Require().NoError(suite.db.Where("action = ?", models.AuditLinkRollback).First(&event).Error)
+	suite.Equal(urlID, event.TargetID)
+	suite.Equal("https://example.com/v1", event.Changes["original_url"].To)
+
+	var failure models.ErrorResponse
+	suite.Equal(http.StatusConflict, suite.request(http.MethodPost, path+"/revisions/4/rollback", sessionID, nil, &failure))
+	suite.Equal("rollback_failed", failure.Error)
+	suite.Equal(http.StatusNotFound, suite.request(http.MethodPost, path+"/revisions/9/rollback", sessionID, nil, nil))
+	suite.Equal(http.StatusNotFound, suite.request(http.MethodGet, path+"/revisions/diff?from=9", sessionID, nil, nil))
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodGet, path+"/revisions/diff?from=first", sessionID, nil, nil))
+}
+
+func (suite *RevisionTestSuite) TestClicksAreAttributedToLiveRevision() {
+	user, _ := suite.signIn("clicks@example.com")
+	url, err := suite.urlService.CreateURL(&models.CreateURLRequest{OriginalURL: "https://example.com/a"}, &user.ID)
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.urlService.RecordClick(url.ID, &models.Analytics{}))
+	suite.Require().NoError(suite.urlService.RecordClick(url.ID, &models.Analytics{}))
+	_, err = suite.urlService.UpdateURL(url.ID, user.ID, &models.CreateURLRequest{OriginalURL: "https://example.com/b"})
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.urlService.RecordClick(url.ID, &models.Analytics{}))
+
+	revisions, _, err := suite.urlService.ListRevisions(url.ID, user.ID, 10, 0)
+	suite.Require().NoError(err)
+	suite.Require().Len(revisions, 2)
+	suite.Equal(int64(1), revisions[0].Clicks)
+	suite.Equal(int64(2), revisions[1].Clicks)
+
+	events, _, err := suite.urlService.ListClickEvents(url.ID, user.ID, &models.ClickEventFilter{Revision: 1})
+	suite.Require().NoError(err)
+	suite.Len(events, 2)
+	suite.Equal(1, events[0]["revision"])
+}
+
+func (suite *RevisionTestSuite) TestViewersCannotRollBack() {
+	owner, ownerSession := suite.signIn("owner@example.com")
+	viewer, viewerSession := suite.signIn("viewer@example.com")
+	workspace, err := suite.workspaceService.CreateWorkspace(owner.ID, &models.WorkspaceRequest{Name: "Team"})
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.db.Create(&models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: viewer.ID, Role: models.RoleViewer}).Error)
+
+	url, err := suite.urlService.CreateURL(&models.CreateURLRequest{OriginalURL: "https://example.com/a", WorkspaceID: &workspace.ID}, &owner.ID)
+	suite.Require().NoError(err)
+	_, err = suite.urlService.UpdateURL(url.ID, owner.ID, &models.CreateURLRequest{OriginalURL: "https://example.com/b"})
+	suite.Require().NoError(err)
+
+	suite.Len(suite.revisions(url.ID, viewerSession), 2)
+	path := fmt.Sprintf("/urls/%d/revisions/1/rollback", url.ID)
+	suite.Equal(http.StatusForbidden, suite.request(http.MethodPost, path, viewerSession, nil, nil))
+
+	_, outsiderSession := suite.signIn("outsider@example.com")
+	suite.Equal(http.StatusNotFound, suite.request(http.MethodGet, fmt.Sprintf("/urls/%d/revisions", url.ID), outsiderSession, nil, nil))
+	suite.Equal(http.StatusOK, suite.request(http.MethodPost, path, ownerSession, nil, nil))
+}
+
+func (suite *RevisionTestSuite) TestEditsKeepModerationState() {
+	user, _ := suite.signIn("moderated@example.com")
+	url, err := suite.urlService.CreateURL(&models.CreateURLRequest{OriginalURL: "https://example.com/a"}, &user.ID)
+	suite.Require().NoError(err)
+
+	// Quarantine the link after each edit has loaded it but before it is
+	// written.
+	moderatedAt := time.Now()
+	suite.Require().NoError(suite.db.Callback().Update().Before("gorm:update").Register("test:moderate", func(db *gorm.DB) {
+		if db.Statement.Table == "urls" {
+			db.Session(&gorm.Session{NewDB: true}).Exec("UPDATE urls SET is_active = ?, quarantined_at = ? WHERE id = ?", false, moderatedAt, url.ID)
+		}
+	}))
+	defer suite.db.Callback().Update().Remove("test:moderate")
+
+	assertModerated := func() {
+		var stored models.URL
+		suite.Require().NoError(suite.db.First(&stored, url.ID).Error)
+		suite.False(stored.IsActive)
+		suite.NotNil(stored.QuarantinedAt)
+	}
+
+	_, err = suite.urlService.UpdateURL(url.ID, user.ID, &models.CreateURLRequest{OriginalURL: "https://example.com/b"})
+	suite.Require().NoError(err)
+	assertModerated()
+
+	suite.Require().NoError(suite.db.Exec("UPDATE urls SET is_active = ?, quarantined_at = NULL WHERE id = ?", true, url.ID).Error)
+	_, err = suite.urlService.RollbackURL(url.ID, user.ID, 1)
+	suite.Require().NoError(err)
+	assertModerated()
+
+	var stored models.URL
+	suite.Require().NoError(suite.db.First(&stored, url.ID).Error)
+	suite.Equal("https://example.com/a", stored.OriginalURL)
+	suite.Equal(3, stored.Revision)
+}
+
+func (suite *RevisionTestSuite) TestBackfillRecordsFirstRevision() {
+	user, _ := suite.signIn("legacy@example.com")
+	url := models.URL{OriginalURL: "https://example.com/legacy", ShortCode: "legacy", UserID: &user.ID, IsActive: true}
+	suite.Require().NoError(suite.db.Create(&url).Error)
+	suite.Require().NoError(suite.db.Create(&models.Analytics{URLID: url.ID, ClickedAt: time.Now()}).Error)
+	suite.Require().NoError(suite.db.Create(&models.AnalyticsDaily{URLID: url.ID, Date: time.Now().AddDate(0, 0, -100), Clicks: 4}).Error)
+
+	backfilled, err := suite.urlService.BackfillRevisions()
+	suite.Require().NoError(err)
+	suite.Equal(int64(1), backfilled)
+
+	var revision models.URLRevision
+	suite.Require().NoError(suite.db.Where("url_id = ?", url.ID).First(&revision).Error)
+	suite.Equal(1, revision.Number)
+	suite.Equal("https://example.com/legacy", revision.OriginalURL)
+	suite.Equal(int64(5), revision.Clicks)
+
+	var unattributed int64
+	suite.db.Model(&models.Analytics{}).Where("url_id = ? AND revision = 0", url.ID).Count(&unattributed)
+	suite.Zero(unattributed)
+
+	backfilled, err = suite.urlService.BackfillRevisions()
+	suite.Require().NoError(err)
+	suite.Zero(backfilled)
+}
+
+func TestRevisionTestSuite(t *testing.T) {
+	suite.Run(t, new(RevisionTestSuite))
+}
@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type SearchTestSuite struct {
+	apiSuite
+	urlService *services.URLService
+}
+
+func (suite *SearchTestSuite) SetupSuite() {
+	suite.cfg = &config.Config{
+		SessionSecret: "test-session-secret",
+		Environment:   "test",
+		FrontendURL:   "http://localhost:3000",
+	}
+
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+
+	suite.urlService = services.NewURLService()
+	suite.sessionStore = middleware.NewSimpleSessionStore()
+	suite.sessions = middleware.NewSessionManager(suite.sessionStore, suite.cfg)
+
+	urlHandler := handlers.NewURLHandler(suite.urlService, services.NewClickHub(0), suite.cfg)
+
+	suite.app = fiber.New()
+	urls := suite.app.Group("/urls", suite.sessions.AuthMiddleware())
+	urls.Get("/", urlHandler.GetUserURLs)
+}
+
+func (suite *SearchTestSuite) TearDownTest() {
+	for _, table := range []string{"url_tags", "tags", "folders", "analytics", "analytics_daily", "url_revisions", "audit_events", "usage_counters", "usage_events", "workspace_members", "workspaces", "urls", "users"} {
+		suite.db.Exec("DELETE FROM " + table)
+	}
+}
+
+func (suite *SearchTestSuite) createURL(userID uint, req models.CreateURLRequest) *models.URL {
+	url, err := suite.urlService.CreateURL(&req, &userID)
+	suite.Require().NoError(err)
+	return url
+}
+
+// listURLs returns the links matched by the query string, in order.
+func (suite *SearchTestSuite) listURLs(query, sessionID string) []models.URL {
+	var body struct {
+		Data struct {
+			URLs []models.URL `json:"urls"`
+		} `json:"data"`
+	}
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodGet, "/urls?"+query, sessionID, nil, &body))
+	return body.Data.URLs
+}
+
+func (suite *SearchTestSuite) listIDs(query, sessionID string) []uint {
+	ids := []uint{}
+	for _, url := range suite.listURLs(query, sessionID) {
+		ids = append(ids, url.ID)
+	}
+	return ids
+}
+
+func (suite *SearchTestSuite) click(urlID uint) {
+	suite.Require().NoError(suite.urlService.RecordClick(urlID, &models.Analytics{IPAddress: "203.0.113.7"}))
+}
+
+func (suite *SearchTestSuite) TestSearchMatchesLinkFields() {
+	user, sessionID := suite.signIn("search@example.com")
+	launch := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/spring", Title: "Spring Launch"})
+	alias := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/a", CustomAlias: "promo2026"})
+	docs := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://docs.example.org/guide", Description: "Setup guide"})
+	underscore := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/a_b"})
+	suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/axb"})
+
+	suite.Equal([]uint{launch.ID}, suite.listIDs("q=launch", sessionID))
+	suite.Equal([]uint{alias.ID}, suite.listIDs("q=PROMO", sessionID))
+	suite.Equal([]uint{docs.ID}, suite.listIDs("q=docs.example", sessionID))
+	suite.Equal([]uint{docs.ID}, suite.listIDs("q=guide", sessionID))
+	suite.Equal([]uint{launch.ID}, suite.listIDs("q="+launch.ShortCode, sessionID))
+
+	// Wildcards in the search are matched literally.
+	suite.Equal([]uint{underscore.ID}, suite.listIDs("q=a_b", sessionID))
+	suite.Empty(suite.listIDs("q=%25", sessionID))
+	suite.Empty(suite.listIDs("q=nothing+matches", sessionID))
+
+	// Other users' links are never searched.
+	_, otherSession := suite.signIn("other@example.com")
+	suite.Empty(suite.listIDs("q=launch", otherSession))
+}
+
+func (suite *SearchTestSuite) TestFiltersNarrowTheListing() {
+	user, sessionID := suite.signIn("filters@example.com")
+	live := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/live"})
+	paused := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/paused"})
+	expired := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/expired"})
+	suite.Require().NoError(suite.db.Model(&models.URL{}).Where("id = ?", paused.ID).Update("is_active", false).Error)
+	suite.Require().NoError(suite.db.Model(&models.URL{}).Where("id = ?", expired.ID).Update("expires_at", time.Now().Add(-time.Hour)).Error)
+	suite.click(live.ID)
+
+	suite.ElementsMatch([]uint{live.ID, expired.ID}, suite.listIDs("active=true", sessionID))
+	suite.Equal([]uint{paused.ID}, suite.listIDs("active=false", sessionID))
+	suite.Equal([]uint{expired.ID}, suite.listIDs("expired=true", sessionID))
+	suite.Equal([]uint{live.ID}, suite.listIDs("active=true&expired=false", sessionID))
+	suite.Equal([]uint{live.ID}, suite.listIDs("has_clicks=true", sessionID))
+	suite.ElementsMatch([]uint{paused.ID, expired.ID}, suite.listIDs("has_clicks=false", sessionID))
+
+	lastWeek := time.Now().AddDate(0, 0, -7)
+	suite.Require().NoError(suite.db.Model(&models.URL{}).Where("id = ?", paused.ID).UpdateColumn("created_at", lastWeek).Error)
+	from := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	suite.ElementsMatch([]uint{live.ID, expired.ID}, suite.listIDs("created_from="+from, sessionID))
+	suite.Equal([]uint{paused.ID}, suite.listIDs("created_to="+from, sessionID))
+
+	var failure models.ErrorResponse
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodGet, "/urls?protected=true", sessionID, nil, &failure))
+	suite.Equal("unsupported_filter", failure.Error)
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodGet, "/urls?active=maybe", sessionID, nil, &failure))
+	suite.Equal("invalid_filter", failure.Error)
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodGet, "/urls?created_from=yesterday", sessionID, nil, nil))
+}
+
+func (suite *SearchTestSuite) TestSortOptions() {
+	user, sessionID := suite.signIn("sort@example.com")
+	first := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/1"})
+	second := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/2"})
+	third := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/3"})
+
+	suite.click(first.ID)
+	suite.click(first.ID)
+	time.Sleep(10 * time.Millisecond)
+	suite.click(second.ID)
+
+	suite.Equal([]uint{third.ID, second.ID, first.ID}, suite.listIDs("", sessionID))
+	suite.Equal([]uint{first.ID, second.ID, third.ID}, suite.listIDs("sort=created&order=asc", sessionID))
+	suite.Equal([]uint{first.ID, second.ID, third.ID}, suite.listIDs("sort=clicks", sessionID))
+	suite.Equal([]uint{third.ID, second.ID, first.ID}, suite.listIDs("sort=clicks&order=asc", sessionID))
+
+	// Links never clicked sort last in either direction.
+	suite.Equal([]uint{second.ID, first.ID, third.ID}, suite.listIDs("sort=last_clicked", sessionID))
+	suite.Equal([]uint{first.ID, second.ID, third.ID}, suite.listIDs("sort=last_clicked&order=asc", sessionID))
+
+	// Clicks do not count as edits.
+	_, err := suite.urlService.UpdateURL(first.ID, user.ID, &models.CreateURLRequest{Title: "Edited"})
+	suite.Require().NoError(err)
+	edited := suite.listURLs("sort=updated", sessionID)[0]
+	suite.Equal(first.ID, edited.ID)
+	suite.Equal(int64(2), edited.ClickCount)
+
+	var failure models.ErrorResponse
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodGet, "/urls?sort=popularity", sessionID, nil, &failure))
+	suite.Equal(services.ErrInvalidSort.Error(), failure.Message)
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodGet, "/urls?order=sideways", sessionID, nil, nil))
+}
+
+func (suite *SearchTestSuite) TestListingIncludesClickCounts() {
+	user, sessionID := suite.signIn("counts@example.com")
+	url := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/counted"})
+	suite.click(url.ID)
+	suite.click(url.ID)
+
+	listed := suite.listURLs("", sessionID)
+	suite.Require().Len(listed, 1)
+	suite.Equal(int64(2), listed[0].ClickCount)
+	suite.Require().NotNil(listed[0].LastClickedAt)
+	clickedAt := *listed[0].LastClickedAt
+	suite.WithinDuration(time.Now(), clickedAt, time.Minute)
+
+	// Links clicked before the counter existed are summarized from their
+	// events and daily rollups.
+	suite.Require().NoError(suite.db.Model(&models.URL{}).Where("id = ?", url.ID).
+		UpdateColumns(map[string]interface{}{"click_count": 0, "last_clicked_at": nil}).Error)
+	day := time.Now().AddDate(0, 0, -3).Truncate(24 * time.Hour)
+	suite.Require().NoError(suite.db.Create(&models.AnalyticsDaily{URLID: url.ID, Date: day, Clicks: 5, UniqueClicks: 2}).Error)
+
+	backfilled, err := suite.urlService.BackfillClickCounts()
+	suite.Require().NoError(err)
+	suite.Equal(int64(1), backfilled)
+
+	listed = suite.listURLs("", sessionID)
+	suite.Equal(int64(7), listed[0].ClickCount)
+	suite.Require().NotNil(listed[0].LastClickedAt)
+	suite.True(clickedAt.Equal(*listed[0].LastClickedAt))
+
+	backfilled, err = suite.urlService.BackfillClickCounts()
+	suite.Require().NoError(err)
+	suite.Zero(backfilled)
+}
+
+func TestSearchTestSuite(t *testing.T) {
+	suite.Run(t, new(SearchTestSuite))
+}
@@ -0,0 +1,313 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/identity"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type SessionStoreTestSuite struct {
+	suite.Suite
+	db       *gorm.DB
+	store    *middleware.SQLSessionStore
+	sessions *middleware.SessionManager
+}
+
+func (suite *SessionStoreTestSuite) SetupSuite() {
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+
+	suite.store = middleware.NewSQLSessionStore(suite.db, 0)
+}
+
+func (suite *SessionStoreTestSuite) TearDownTest() {
+	suite.db.Exec("DELETE FROM sessions")
+}
+
+func (suite *SessionStoreTestSuite) TestSaveGetDelete() {
+	now := time.Now()
+	suite.Require().NoError(suite.store.Save("abc", &middleware.SessionData{
+		UserID:    7,
+		UserEmail: "a@example.com",
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}))
+
+	var stored models.Session
+	suite.Require().NoError(suite.db.First(&stored).Error)
+	suite.NotEqual("abc", stored.ID, "raw session IDs must not be stored")
+
+	data, err := suite.store.Get("abc")
+	suite.Require().NoError(err)
+	suite.Require().NotNil(data)
+	suite.Equal(uint(7), data.UserID)
+
+	suite.Require().NoError(suite.store.Delete("abc"))
+	data, err = suite.store.Get("abc")
+	suite.NoError(err)
+	suite.Nil(data)
+}
+
+func (suite *SessionStoreTestSuite) TestExpiredSessions() {
+	now := time.Now()
+	suite.Require().NoError(suite.store.Save("old", &middleware.SessionData{
+		UserID: 1, CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour),
+	}))
+	suite.Require().NoError(suite.store.Save("new", &middleware.SessionData{
+		UserID: 1, CreatedAt: now, ExpiresAt: now.Add(time.Hour),
+	}))
+
+	data, err := suite.store.Get("old")
+	suite.NoError(err)
+	suite.Nil(data)
+
+	removed, err := suite.store.DeleteExpired()
+	suite.Require().NoError(err)
+	suite.Equal(int64(1), removed)
+
+	var count int64
+	suite.db.Model(&models.Session{}).Count(&count)
+	suite.Equal(int64(1), count)
+}
+
+func (suite *SessionStoreTestSuite) TestSessionSurvivesRestart() {
+	cfg := &config.Config{Environment: "test"}
+
+	login := fiber.New()
+	login.Post("/login", func(c *fiber.Ctx) error {
+		sessions := middleware.NewSessionManager(suite.store, cfg)
+		_, err := sessions.CreateSession(c, 42, "restart@example.com")
+		return err
+	})
+	resp, err := login.Test(httptest.NewRequest(http.MethodPost, "/login", nil))
+	suite.Require().NoError(err)
+	cookies := resp.Cookies()
+	suite.Require().Len(cookies, 1)
+
+	// A fresh store and manager over the same database, as after a deploy.
+	restarted := middleware.NewSessionManager(middleware.NewSQLSessionStore(suite.db, 0), cfg)
+	app := fiber.New()
+	app.Get("/me", restarted.AuthMiddleware(), func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"user_id": c.Locals("user_id")})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.AddCookie(cookies[0])
+	resp, err = app.Test(req)
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func (suite *SessionStoreTestSuite) authedApp(cfg *config.Config) (*fiber.App, *middleware.SessionManager) {
+	sessions := middleware.NewSessionManager(suite.store, cfg)
+	suite.sessions = sessions
+	sessionHandler := handlers.NewSessionHandler(sessions, services.NewAuditService())
+
+	app := fiber.New()
+	me := app.Group("/me", sessions.AuthMiddleware())
+	me.Get("/sessions", sessionHandler.ListSessions)
+	me.Delete("/sessions", sessionHandler.RevokeAllSessions)
+	me.Delete("/sessions/:id", sessionHandler.RevokeSession)
+	return app, sessions
+}
+
+func (suite *SessionStoreTestSuite) request(app *fiber.App, method, path, sessionID string) *http.Response {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Cookie", "session_id="+suite.sessions.CookieValue(sessionID))
+	resp, err := app.Test(req)
+	suite.Require().NoError(err)
+	return resp
+}
+
+func (suite *SessionStoreTestSuite) TestSlidingExpirationIsCapped() {
+	cfg := &config.Config{Environment: "test", SessionIdleTimeout: 3600, SessionMaxLifetime: 7200}
+	app, _ := suite.authedApp(cfg)
+
+	now := time.Now()
+	suite.Require().NoError(suite.store.Save("active", &middleware.SessionData{
+		UserID:       1,
+		CreatedAt:    now.Add(-30 * time.Minute),
+		LastActiveAt: now.Add(-10 * time.Minute),
+		ExpiresAt:    now.Add(50 * time.Minute),
+	}))
+	suite.Require().NoError(suite.store.Save("ageing", &middleware.SessionData{
+		UserID:       1,
+		CreatedAt:    now.Add(-110 * time.Minute),
+		LastActiveAt: now.Add(-10 * time.Minute),
+		ExpiresAt:    now.Add(50 * time.Minute),
+	}))
+
+	suite.Equal(http.StatusOK, suite.request(app, http.MethodGet, "/me/sessions", "active").StatusCode)
+	suite.Equal(http.StatusOK, suite.request(app, http.MethodGet, "/me/sessions", "ageing").StatusCode)
+
+	active, _ := suite.store.Get("active")
+	suite.WithinDuration(now.Add(time.Hour), active.ExpiresAt, 5*time.Second)
+	suite.WithinDuration(now, active.LastActiveAt, 5*time.Second)
+
+	// The idle window would run past the absolute limit, so it is cut short.
+	ageing, _ := suite.store.Get("ageing")
+	suite.WithinDuration(now.Add(10*time.Minute), ageing.ExpiresAt, 5*time.Second)
+}
+
+func (suite *SessionStoreTestSuite) TestListAndRevokeSessions() {
+	app, _ := suite.authedApp(&config.Config{Environment: "test"})
+
+	now := time.Now()
+	for _, id := range []string{"laptop", "phone"} {
+		suite.Require().NoError(suite.store.Save(id, &middleware.SessionData{
+			UserID:       5,
+			IPAddress:    "203.0.113.9",
+			UserAgent:    "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)",
+			CreatedAt:    now,
+			LastActiveAt: now,
+			ExpiresAt:    now.Add(time.Hour),
+		}))
+	}
+
+	resp := suite.request(app, http.MethodGet, "/me/sessions", "laptop")
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var listed struct {
+		Data []models.SessionInfo `json:"data"`
+	}
+	suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&listed))
+	suite.Require().Len(listed.Data, 2)
+
+	var phoneKey string
+	for _, session := range listed.Data {
+		suite.Equal("203.0.113.9", session.IPAddress)
+		if session.ID == middleware.SessionKey("laptop") {
+			suite.True(session.Current)
+		} else {
+			suite.False(session.Current)
+			phoneKey = session.ID
+		}
+	}
+	suite.Equal(middleware.SessionKey("phone"), phoneKey)
+
+	suite.Equal(http.StatusOK, suite.request(app, http.MethodDelete, "/me/sessions/"+phoneKey, "laptop").StatusCode)
+	suite.Equal(http.StatusUnauthorized, suite.request(app, http.MethodGet, "/me/sessions", "phone").StatusCode)
+	suite.Equal(http.StatusNotFound, suite.request(app, http.MethodDelete, "/me/sessions/"+phoneKey, "laptop").StatusCode)
+
+	suite.Equal(http.StatusOK, suite.request(app, http.MethodDelete, "/me/sessions", "laptop").StatusCode)
+	suite.Equal(http.StatusUnauthorized, suite.request(app, http.MethodGet, "/me/sessions", "laptop").StatusCode)
+}
+
+func (suite *SessionStoreTestSuite) TestDisablingUserRevokesSessions() {
+	user := models.User{Name: "Disabled", Email: "disabled@example.com"}
+	suite.Require().NoError(suite.db.Create(&user).Error)
+	defer suite.db.Exec("DELETE FROM users")
+
+	now := time.Now()
+	suite.Require().NoError(suite.store.Save("doomed", &middleware.SessionData{
+		UserID: user.ID, CreatedAt: now, LastActiveAt: now, ExpiresAt: now.Add(time.Hour),
+	}))
+
+	_, sessions := suite.authedApp(&config.Config{Environment: "test"})
+	authService := services.NewAuthService()
+	authService.SetSessionRevoker(sessions)
+	suite.Require().NoError(authService.DisableUser(user.ID))
+
+	data, err := suite.store.Get("doomed")
+	suite.NoError(err)
+	suite.Nil(data)
+
+	_, err = authService.LoginWithIdentity(&identity.UserInfo{
+		Provider:      "google",
+		Subject:       "disabled-subject",
+		Email:         user.Email,
+		EmailVerified: true,
+	})
+	suite.ErrorIs(err, services.ErrAccountDisabled)
+}
+
+func (suite *SessionStoreTestSuite) TestForgedCookieRejected() {
+	app, _ := suite.authedApp(&config.Config{Environment: "test", SessionSecret: "current-secret"})
+
+	now := time.Now()
+	suite.Require().NoError(suite.store.Save("signed", &middleware.SessionData{
+		UserID: 1, CreatedAt: now, LastActiveAt: now, ExpiresAt: now.Add(time.Hour),
+	}))
+	suite.Equal(http.StatusOK, suite.request(app, http.MethodGet, "/me/sessions", "signed").StatusCode)
+
+	forged := middleware.NewSessionManager(suite.store, &config.Config{SessionSecret: "attacker-secret"})
+	for _, cookie := range []string{"signed", "signed.", "signed.!!!", forged.CookieValue("signed")} {
+		req := httptest.NewRequest(http.MethodGet, "/me/sessions", nil)
+		req.Header.Set("Cookie", "session_id="+cookie)
+		resp, err := app.Test(req)
+		suite.Require().NoError(err)
+		suite.Equal(http.StatusUnauthorized, resp.StatusCode, cookie)
+	}
+}
+
+func (suite *SessionStoreTestSuite) TestSecretRotationKeepsOldCookies() {
+	now := time.Now()
+	suite.Require().NoError(suite.store.Save("rotated", &middleware.SessionData{
+		UserID: 1, CreatedAt: now, LastActiveAt: now, ExpiresAt: now.Add(time.Hour),
+	}))
+	oldCookie := middleware.NewSessionManager(suite.store, &config.Config{SessionSecret: "old-secret"}).CookieValue("rotated")
+
+	app, sessions := suite.authedApp(&config.Config{
+		Environment:           "test",
+		SessionSecret:         "new-secret",
+		SessionSecretPrevious: []string{"old-secret"},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/me/sessions", nil)
+	req.Header.Set("Cookie", "session_id="+oldCookie)
+	resp, err := app.Test(req)
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+
+	// The response carries the cookie re-signed with the current secret.
+	var reissued string
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "session_id" {
+			reissued = cookie.Value
+		}
+	}
+	suite.Equal(sessions.CookieValue("rotated"), reissued)
+
+	// Once the old secret is retired its cookies stop working.
+	app, _ = suite.authedApp(&config.Config{Environment: "test", SessionSecret: "new-secret"})
+	req = httptest.NewRequest(http.MethodGet, "/me/sessions", nil)
+	req.Header.Set("Cookie", "session_id="+oldCookie)
+	resp, err = app.Test(req)
+	suite.Require().NoError(err)
+	suite.Equal(http.StatusUnauthorized, resp.StatusCode)
+}
+
+func (suite *SessionStoreTestSuite) TestProductionRequiresSessionSecret() {
+	cfg := &config.Config{Environment: "production", SessionSecret: config.DefaultSessionSecret, MailDriver: "smtp"}
+	suite.Error(cfg.Validate())
+
+	cfg.SessionSecret = "too-short"
+	suite.Error(cfg.Validate())
+
+	cfg.SessionSecret = "a-properly-long-random-session-secret-value"
+	suite.NoError(cfg.Validate())
+
+	// Development keeps working with the default.
+	suite.NoError((&config.Config{Environment: "development", SessionSecret: config.DefaultSessionSecret}).Validate())
+}
+
+func TestSessionStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(SessionStoreTestSuite))
+}
@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type TokenTestSuite struct {
+	suite.Suite
+	app          *fiber.App
+	db           *gorm.DB
+	sessionStore *middleware.SimpleSessionStore
+	sessions     *middleware.SessionManager
+	tokenService *services.TokenService
+	user         models.User
+}
+
+func (suite *TokenTestSuite) SetupSuite() {
+	cfg := &config.Config{
+		SessionSecret: "test-session-secret",
+		Environment:   "test",
+		FrontendURL:   "http://localhost:3000",
+	}
+
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+
+	suite.tokenService = services.NewTokenService()
+	suite.sessionStore = middleware.NewSimpleSessionStore()
+	suite.sessions = middleware.NewSessionManager(suite.sessionStore, cfg)
+	suite.sessions.SetTokenAuthenticator(suite.tokenService)
+
+	urlHandler := handlers.NewURLHandler(services.NewURLService(), services.NewClickHub(0), cfg)
+	tokenHandler := handlers.NewTokenHandler(suite.tokenService, services.NewAuditService())
+
+	suite.app = fiber.New()
+
+	me := suite.app.Group("/me", suite.sessions.AuthMiddleware(), middleware.SessionOnly())
+	me.Get("/tokens", tokenHandler.ListTokens)
+	me.Post("/tokens", tokenHandler.CreateToken)
+	me.Delete("/tokens/:id", tokenHandler.RevokeToken)
+
+	urls := suite.app.Group("/urls")
+	urls.Post("/", suite.sessions.OptionalAuthMiddleware(), middleware.RequireScope(models.ScopeLinksWrite), urlHandler.CreateURL)
+	urls.Get("/", suite.sessions.AuthMiddleware(), middleware.RequireScope(models.ScopeLinksRead), urlHandler.GetUserURLs)
+}
+
+func (suite *TokenTestSuite) SetupTest() {
+	suite.user = models.User{Name: "Token User", Email: "tokens@example.com"}
+	suite.Require().NoError(suite.db.Create(&suite.user).Error)
+
+	suite.sessionStore.Sessions["token-session"] = &middleware.SessionData{
+		UserID:    suite.user.ID,
+		UserEmail: suite.user.Email,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+}
+
+func (suite *TokenTestSuite) TearDownTest() {
+	suite.db.Exec("DELETE FROM api_tokens")
+	suite.db.Exec("DELETE FROM urls")
+	suite.db.Exec("DELETE FROM users")
+}
+
+func (suite *TokenTestSuite) request(method, path, bearer, cookie string, body interface{}) *http.Response {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	if cookie != "" {
+		req.Header.Set("Cookie", "session_id="+suite.sessions.CookieValue(cookie))
+	}
+
+	resp, err := suite.app.Test(req)
+	suite.Require().NoError(err)
+	return resp
+}
+
+func (suite *TokenTestSuite) createToken(scopes ...string) string {
+	plaintext, _, err := suite.tokenService.CreateToken(suite.user.ID, &models.CreateAPITokenRequest{
+		Name:   "ci",
+		Scopes: scopes,
+	})
+	suite.Require().NoError(err)
+	return plaintext
+}
+
+func (suite *TokenTestSuite) TestCreateTokenShowsPlaintextOnce() {
+	resp := suite.request(http.MethodPost, "/me/tokens", "", "token-session", models.CreateAPITokenRequest{
+		Name:   "slack bot",
+		Scopes: []string{models.ScopeLinksWrite},
+	})
+	suite.Equal(http.StatusCreated, resp.StatusCode)
+
+	var created struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&created))
+	suite.Contains(created.Data.Token, "usk_")
+
+	var stored models.APIToken
+	suite.Require().NoError(suite.db.First(&stored).Error)
+	suite.NotEqual(created.Data.Token, stored.TokenHash)
+
+	resp = suite.request(http.MethodGet, "/me/tokens", "", "token-session", nil)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var listed bytes.Buffer
+	listed.ReadFrom(resp.Body)
+	suite.NotContains(listed.String(), created.Data.Token)
+}
+
+func (suite *TokenTestSuite) TestBearerTokenCreatesLink() {
+	token := suite.createToken(models.ScopeLinksWrite)
+
+	resp := suite.request(http.MethodPost, "/urls/", token, "", models.CreateURLRequest{OriginalURL: "https://example.com"})
+	suite.Equal(http.StatusCreated, resp.StatusCode)
+
+	var url models.URL
+	suite.Require().NoError(suite.db.First(&url).Error)
+	suite.Require().NotNil(url.UserID)
+	suite.Equal(suite.user.ID, *url.UserID)
+
+	var stored models.APIToken
+	suite.Require().NoError(suite.db.First(&stored).Error)
+	suite.NotNil(stored.LastUsedAt)
+}
+
+func (suite *TokenTestSuite) TestMissingScopeIsForbidden() {
+	token := suite.createToken(models.ScopeLinksWrite)
+
+	resp := suite.request(http.MethodGet, "/urls/", token, "", nil)
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+}
+
+func (suite *TokenTestSuite) TestTokensCannotManageTokens() {
+	token := suite.createToken(models.ScopeLinksRead, models.ScopeLinksWrite, models.ScopeAnalyticsRead)
+
+	resp := suite.request(http.MethodGet, "/me/tokens", token, "", nil)
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+}
+
+func (suite *TokenTestSuite) TestRevokedAndExpiredTokensAreRejected() {
+	token := suite.createToken(models.ScopeLinksRead)
+
+	var stored models.APIToken
+	suite.Require().NoError(suite.db.First(&stored).Error)
+	suite.Require().NoError(suite.tokenService.RevokeToken(stored.ID, suite.user.ID))
+
+	resp := suite.request(http.MethodGet, "/urls/", token, "", nil)
+	suite.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	resp = suite.request(http.MethodPost, "/urls/", "usk_forged", "", models.CreateURLRequest{OriginalURL: "https://example.com"})
+	suite.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	_, _, err := suite.tokenService.CreateToken(suite.user.ID, &models.CreateAPITokenRequest{
+		Name:      "expired",
+		Scopes:    []string{models.ScopeLinksRead},
+		ExpiresAt: time.Now().Add(-time.Hour).Format(time.RFC3339),
+	})
+	suite.Error(err)
+}
+
+func (suite *TokenTestSuite) TestTokensOfDisabledOrDeletedUsersAreRejected() {
+	token := suite.createToken(models.ScopeLinksRead)
+	_, err := suite.tokenService.AuthenticateToken(token)
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.db.Model(&suite.user).Update("disabled_at", time.Now()).Error)
+	_, err = suite.tokenService.AuthenticateToken(token)
+	suite.ErrorIs(err, services.ErrInvalidToken)
+
+	suite.Require().NoError(suite.db.Model(&suite.user).Update("disabled_at", nil).Error)
+	suite.Require().NoError(suite.db.Delete(&suite.user).Error)
+	_, err = suite.tokenService.AuthenticateToken(token)
+	suite.ErrorIs(err, services.ErrInvalidToken)
+}
+
+func TestTokenTestSuite(t *testing.T) {
+	suite.Run(t, new(TokenTestSuite))
+}
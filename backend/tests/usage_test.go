@@ -0,0 +1,253 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/billing"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type UsageTestSuite struct {
+	apiSuite
+	sink             *billing.MemorySink
+	urlService       *services.URLService
+	usageService     *services.UsageService
+	retentionService *services.RetentionService
+}
+
+func (suite *UsageTestSuite) SetupSuite() {
+	suite.cfg = &config.Config{
+		SessionSecret:          "test-session-secret",
+		Environment:            "test",
+		FrontendURL:            "http://localhost:3000",
+		AnalyticsRetentionDays: 0,
+	}
+
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+
+	suite.sessionStore = middleware.NewSimpleSessionStore()
+	suite.sessions = middleware.NewSessionManager(suite.sessionStore, suite.cfg)
+	suite.sink = &billing.MemorySink{}
+	suite.urlService = services.NewURLService()
+	suite.usageService = services.NewUsageService(suite.cfg, suite.sink)
+	suite.retentionService = services.NewRetentionService(suite.cfg)
+
+	urlHandler := handlers.NewURLHandler(suite.urlService, services.NewClickHub(0), suite.cfg)
+	usageHandler := handlers.NewUsageHandler(suite.usageService)
+
+	suite.app = fiber.New()
+	suite.app.Post("/urls", suite.sessions.OptionalAuthMiddleware(), urlHandler.CreateURL)
+	suite.app.Get("/me/usage", suite.sessions.AuthMiddleware(), usageHandler.GetUsage)
+}
+
+func (suite *UsageTestSuite) SetupTest() {
+	_, err := suite.usageService.SeedPlans()
+	suite.Require().NoError(err)
+}
+
+func (suite *UsageTestSuite) TearDownTest() {
+	for _, table := range []string{"plans", "usage_counters", "usage_events", "analytics", "analytics_daily", "workspace_members", "workspaces", "urls", "users"} {
+		suite.db.Exec("DELETE FROM " + table)
+	}
+	suite.sink.Err = nil
+}
+
+func (suite *UsageTestSuite) setLimit(plan, column string, value int64) {
+	suite.Require().NoError(suite.db.Model(&models.Plan{}).Where("code = ?", plan).Update(column, value).Error)
+}
+
+// signInOnPlan creates a user on plan with a session and returns both.
+func (suite *UsageTestSuite) signInOnPlan(email, plan string) (models.User, string) {
+	return suite.signInAs(models.User{Name: "User", Email: email, PlanCode: plan})
+}
+
+func (suite *UsageTestSuite) createLink(sessionID string, req models.CreateURLRequest) (*http.Response, map[string]interface{}) {
+	return suite.requestMap(http.MethodPost, "/urls", sessionID, req)
+}
+
+func (suite *UsageTestSuite) getUsage(sessionID string) models.AccountUsage {
+	var body struct {
+		Data models.AccountUsage `json:"data"`
+	}
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodGet, "/me/usage", sessionID, nil, &body))
+	return body.Data
+}
+
+func (suite *UsageTestSuite) TestLinkQuotaEnforced() {
+	suite.setLimit(models.PlanFree, "monthly_links", 2)
+	_, sessionID := suite.signInOnPlan("free@example.com", models.PlanFree)
+
+	// Links without an alias must not collide on the empty alias.
+	for i := 0; i < 2; i++ {
+		resp, _ := suite.createLink(sessionID, models.CreateURLRequest{OriginalURL: "https://example.com/"})
+		suite.Require().Equal(http.StatusCreated, resp.StatusCode)
+	}
+
+	resp, body := suite.createLink(sessionID, models.CreateURLRequest{OriginalURL: "https://example.com/"})
+	suite.Equal(http.StatusPaymentRequired, resp.StatusCode)
+	suite.Equal("quota_exceeded", body["error"])
+
+	var links int64
+	suite.db.Model(&models.URL{}).Count(&links)
+	suite.Equal(int64(2), links)
+
+	// Anonymous links are not metered.
+	resp, _ = suite.createLink("", models.CreateURLRequest{OriginalURL: "https://example.com/"})
+	suite.Equal(http.StatusCreated, resp.StatusCode)
+}
+
+func (suite *UsageTestSuite) TestCustomAliasQuota() {
+	suite.setLimit(models.PlanFree, "monthly_custom_aliases", 1)
+	_, sessionID := suite.signInOnPlan("alias@example.com", models.PlanFree)
+
+	resp, _ := suite.createLink(sessionID, models.CreateURLRequest{OriginalURL: "https://example.com/", CustomAlias: "first"})
+	suite.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	resp, _ = suite.createLink(sessionID, models.CreateURLRequest{OriginalURL: "https://example.com/", CustomAlias: "second"})
+	suite.Equal(http.StatusPaymentRequired, resp.StatusCode)
+
+	resp, _ = suite.createLink(sessionID, models.CreateURLRequest{OriginalURL: "https://example.com/"})
+	suite.Equal(http.StatusCreated, resp.StatusCode)
+
+	usage := suite.getUsage(sessionID)
+	suite.Equal(int64(2), usage.LinksCreated.Used)
+	suite.Equal(int64(1), usage.CustomAliases.Used)
+}
+
+func (suite *UsageTestSuite) TestClicksBeyondQuotaAreNotTracked() {
+	suite.setLimit(models.PlanFree, "monthly_clicks", 2)
+	user, _ := suite.signInOnPlan("clicks@example.com", models.PlanFree)
+	url, err := suite.urlService.CreateURL(&models.CreateURLRequest{OriginalURL: "https://example.com/"}, &user.ID)
+	suite.Require().NoError(err)
+
+	for i := 0; i < 2; i++ {
+		suite.Require().NoError(suite.urlService.RecordClick(url.ID, &models.Analytics{}))
+	}
+	err = suite.urlService.RecordClick(url.ID, &models.Analytics{})
+	suite.True(errors.Is(err, services.ErrQuotaExceeded))
+
+	var clicks int64
+	suite.db.Model(&models.Analytics{}).Count(&clicks)
+	suite.Equal(int64(2), clicks)
+
+	usage, err := suite.usageService.GetUsage(user.ID, time.Now())
+	suite.Require().NoError(err)
+	suite.Equal(int64(2), usage.ClicksTracked.Used)
+}
+
+func (suite *UsageTestSuite) TestUsageReport() {
+	_, freeSession := suite.signInOnPlan("report@example.com", models.PlanFree)
+	resp, _ := suite.createLink(freeSession, models.CreateURLRequest{OriginalURL: "https://example.com/"})
+	suite.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	usage := suite.getUsage(freeSession)
+	suite.Equal(models.PlanFree, usage.Plan.Code)
+	suite.Equal(time.Now().UTC().Format("2006-01"), usage.Period)
+	suite.Equal(int64(1), usage.LinksCreated.Used)
+	suite.Require().NotNil(usage.LinksCreated.Limit)
+	suite.Equal(int64(100), *usage.LinksCreated.Limit)
+	suite.Require().NotNil(usage.BrandedDomains.Limit)
+	suite.Equal(int64(0), *usage.BrandedDomains.Limit)
+
+	_, businessSession := suite.signInOnPlan("business@example.com", models.PlanBusiness)
+	usage = suite.getUsage(businessSession)
+	suite.Equal(models.PlanBusiness, usage.Plan.Code)
+	suite.Nil(usage.LinksCreated.Limit)
+	suite.Nil(usage.ClicksTracked.Limit)
+}
+
+func (suite *UsageTestSuite) TestUsageExportedToBillingSink() {
+	user, _ := suite.signInOnPlan("billing@example.com", models.PlanPro)
+	url, err := suite.urlService.CreateURL(&models.CreateURLRequest{OriginalURL: "https://example.com/", CustomAlias: "billed"}, &user.ID)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.urlService.RecordClick(url.ID, &models.Analytics{}))
+
+	// A sink that is down leaves the events queued.
+	suite.sink.Err = errors.New("unavailable")
+	_, err = suite.usageService.ExportUsage(context.Background())
+	suite.Error(err)
+	suite.Empty(suite.sink.Events())
+
+	suite.sink.Err = nil
+	exported, err := suite.usageService.ExportUsage(context.Background())
+	suite.Require().NoError(err)
+	suite.Equal(3, exported)
+
+	metrics := map[string]int64{}
+	for _, event := range suite.sink.Events() {
+		suite.Equal(user.ID, event.UserID)
+		suite.Equal(models.PlanPro, event.Plan)
+		metrics[event.Metric] += event.Quantity
+	}
+	suite.Equal(map[string]int64{
+		models.MetricLinksCreated:  1,
+		models.MetricCustomAliases: 1,
+		models.MetricClicksTracked: 1,
+	}, metrics)
+
+	exported, err = suite.usageService.ExportUsage(context.Background())
+	suite.Require().NoError(err)
+	suite.Zero(exported)
+}
+
+func (suite *UsageTestSuite) TestBillingSinkIsOptIn() {
+	suite.IsType(billing.NoopSink{}, billing.NewSink(&config.Config{}))
+	suite.IsType(billing.NoopSink{}, billing.NewSink(&config.Config{BillingSink: "none"}))
+	suite.IsType(billing.LogSink{}, billing.NewSink(&config.Config{BillingSink: "log"}))
+	suite.IsType(&billing.HTTPSink{}, billing.NewSink(&config.Config{BillingSink: "http", BillingSinkURL: "https://billing.example.com/"}))
+}
+
+func (suite *UsageTestSuite) TestPlanCapsRetention() {
+	free, _ := suite.signInOnPlan("retention-free@example.com", models.PlanFree)
+	pro, _ := suite.signInOnPlan("retention-pro@example.com", models.PlanPro)
+
+	year := 365
+	suite.True(errors.Is(suite.retentionService.SetUserRetention(free.ID, &year), services.ErrRetentionLimit))
+	suite.NoError(suite.retentionService.SetUserRetention(pro.ID, &year))
+
+	// With no platform-wide limit the plan still applies.
+	days, _, err := suite.retentionService.GetUserRetention(free.ID)
+	suite.Require().NoError(err)
+	suite.Equal(90, days)
+
+	freeURL := models.URL{OriginalURL: "https://example.com/", ShortCode: "freeret", UserID: &free.ID, IsActive: true}
+	proURL := models.URL{OriginalURL: "https://example.com/", ShortCode: "proret", UserID: &pro.ID, IsActive: true}
+	suite.Require().NoError(suite.db.Create(&freeURL).Error)
+	suite.Require().NoError(suite.db.Create(&proURL).Error)
+
+	old := time.Now().UTC().AddDate(0, 0, -120)
+	for _, id := range []uint{freeURL.ID, proURL.ID} {
+		suite.Require().NoError(suite.db.Create(&models.Analytics{URLID: id, IPAddress: "198.51.100.0", ClickedAt: old}).Error)
+	}
+
+	purged, err := suite.retentionService.PurgeExpired(time.Now())
+	suite.Require().NoError(err)
+	suite.Equal(int64(1), purged)
+
+	var remaining []models.Analytics
+	suite.db.Find(&remaining)
+	suite.Require().Len(remaining, 1)
+	suite.Equal(proURL.ID, remaining[0].URLID)
+}
+
+func TestUsageTestSuite(t *testing.T) {
+	suite.Run(t, new(UsageTestSuite))
+}
@@ -0,0 +1,512 @@
+package tests
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// webhookReceiver is a local endpoint that records the deliveries it gets
+// and answers with a configurable status.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []receivedWebhook
+	server   *httptest.Server
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+	event  models.WebhookEvent
+}
+
+func newWebhookReceiver() *webhookReceiver {
+	receiver := &webhookReceiver{status: http.StatusOK}
+	receiver.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event models.WebhookEvent
+		json.Unmarshal(body, &event)
+
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.requests = append(receiver.requests, receivedWebhook{header: r.Header.Clone(), body: body, event: event})
+		w.WriteHeader(receiver.status)
+	}))
+	return receiver
+}
+
+func (r *webhookReceiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+func (r *webhookReceiver) received() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.requests...)
+}
+
+type WebhookTestSuite struct {
+	apiSuite
+	urlService     *services.URLService
+	webhookService *services.WebhookService
+	receiver       *webhookReceiver
+}
+
+func (suite *WebhookTestSuite) SetupSuite() {
+	suite.cfg = &config.Config{
+		SessionSecret:              "test-session-secret",
+		Environment:                "test",
+		FrontendURL:                "http://localhost:3000",
+		WebhookMaxAttempts:         3,
+		WebhookTimeout:             2,
+		WebhookAllowPrivateTargets: true,
+	}
+
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	// Deliveries to different webhooks are made on their own goroutines;
+	// every connection to ":memory:" would otherwise open a separate, empty
+	// database.
+	sqlDB, err := suite.db.DB()
+	suite.Require().NoError(err)
+	sqlDB.SetMaxOpenConns(1)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+
+	suite.urlService = services.NewURLService()
+	suite.webhookService = services.NewWebhookService(suite.cfg)
+	suite.sessionStore = middleware.NewSimpleSessionStore()
+	suite.sessions = middleware.NewSessionManager(suite.sessionStore, suite.cfg)
+
+	webhookHandler := handlers.NewWebhookHandler(suite.webhookService, services.NewAuditService())
+
+	suite.app = fiber.New()
+	webhooks := suite.app.Group("/webhooks", suite.sessions.AuthMiddleware())
+	webhooks.Get("/", webhookHandler.ListWebhooks)
+	webhooks.Post("/", webhookHandler.CreateWebhook)
+	webhooks.Put("/:id", webhookHandler.UpdateWebhook)
+	webhooks.Delete("/:id", webhookHandler.DeleteWebhook)
+	webhooks.Get("/:id/deliveries", webhookHandler.ListDeliveries)
+	webhooks.Post("/:id/deliveries/redeliver", webhookHandler.RedeliverDead)
+	webhooks.Post("/:id/deliveries/:deliveryId/redeliver", webhookHandler.Redeliver)
+}
+
+func (suite *WebhookTestSuite) SetupTest() {
+	suite.receiver = newWebhookReceiver()
+}
+
+func (suite *WebhookTestSuite) TearDownTest() {
+	suite.receiver.server.Close()
+	for _, table := range []string{"webhook_deliveries", "webhooks", "url_tags", "tags", "folders", "analytics", "analytics_daily", "url_revisions", "audit_events", "usage_counters", "usage_events", "workspace_members", "workspaces", "urls", "users"} {
+		suite.db.Exec("DELETE FROM " + table)
+	}
+}
+
+// createWebhook registers the suite's receiver and returns the webhook and
+// its signing secret.
+func (suite *WebhookTestSuite) createWebhook(sessionID string, req models.WebhookRequest) (models.Webhook, string) {
+	if req.URL == "" {
+		req.URL = suite.receiver.server.URL + "/hooks"
+	}
+	var body struct {
+		Data struct {
+			Secret  string         `json:"secret"`
+			Webhook models.Webhook `json:"webhook"`
+		} `json:"data"`
+	}
+	suite.Require().Equal(http.StatusCreated, suite.request(http.MethodPost, "/webhooks", sessionID, req, &body))
+	return body.Data.Webhook, body.Data.Secret
+}
+
+func (suite *WebhookTestSuite) createURL(userID uint, req models.CreateURLRequest) *models.URL {
+	url, err := suite.urlService.CreateURL(&req, &userID)
+	suite.Require().NoError(err)
+	return url
+}
+
+func (suite *WebhookTestSuite) deliveries(webhookID uint) []models.WebhookDelivery {
+	var deliveries []models.WebhookDelivery
+	suite.Require().NoError(suite.db.Where("webhook_id = ?", webhookID).Order("id").Find(&deliveries).Error)
+	return deliveries
+}
+
+func (suite *WebhookTestSuite) deliver(now time.Time) int {
+	delivered, err := suite.webhookService.DeliverDue(context.Background(), now)
+	suite.Require().NoError(err)
+	return delivered
+}
+
+func (suite *WebhookTestSuite) TestLinkEventsAreSignedAndDelivered() {
+	user, sessionID := suite.signIn("hooks@example.com")
+	webhook, secret := suite.createWebhook(sessionID, models.WebhookRequest{
+		Events: []string{models.WebhookLinkCreated, models.WebhookLinkUpdated, models.WebhookLinkDeleted},
+	})
+	suite.Contains(secret, "whsec_")
+	suite.True(webhook.Active)
+	suite.Equal(1.0, webhook.ClickSampleRate)
+
+	url := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/hooked"})
+	_, err := suite.urlService.UpdateURL(url.ID, user.ID, &models.CreateURLRequest{Title: "Hooked"})
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.urlService.DeleteURL(url.ID, user.ID))
+
+	// Nothing is sent until the delivery job runs.
+	suite.Empty(suite.receiver.received())
+	suite.Equal(3, suite.deliver(time.Now()))
+
+	received := suite.receiver.received()
+	suite.Require().Len(received, 3)
+	types := []string{}
+	for _, r := range received {
+		types = append(types, r.event.Type)
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(r.header.Get("X-Webhook-Timestamp") + "."))
+		mac.Write(r.body)
+		suite.Equal("sha256="+hex.EncodeToString(mac.Sum(nil)), r.header.Get("X-Webhook-Signature"))
+		suite.Equal(r.event.Type, r.header.Get("X-Webhook-Event"))
+		suite.Equal(r.event.ID, r.header.Get("X-Webhook-ID"))
+		suite.Equal("application/json", r.header.Get("Content-Type"))
+		suite.Equal(webhook.WorkspaceID, r.event.WorkspaceID)
+	}
+	suite.Equal([]string{models.WebhookLinkCreated, models.WebhookLinkUpdated, models.WebhookLinkDeleted}, types)
+
+	var updated struct {
+		Data models.WebhookLinkData `json:"data"`
+	}
+	suite.Require().NoError(json.Unmarshal(received[1].body, &updated))
+	suite.Equal(url.ID, updated.Data.Link.ID)
+	suite.Equal("Hooked", updated.Data.Changes["title"].To)
+
+	for _, delivery := range suite.deliveries(webhook.ID) {
+		suite.Equal(models.DeliveryDelivered, delivery.Status)
+		suite.Equal(1, delivery.Attempts)
+		suite.Equal(http.StatusOK, delivery.ResponseStatus)
+	}
+	suite.Zero(suite.deliver(time.Now()))
+}
+
+func (suite *WebhookTestSuite) TestEventFiltersAndClickSampling() {
+	user, sessionID := suite.signIn("clicks@example.com")
+	clicks, _ := suite.createWebhook(sessionID, models.WebhookRequest{Events: []string{models.WebhookClickRecorded}})
+	half := 0.5
+	sampled, _ := suite.createWebhook(sessionID, models.WebhookRequest{Events: []string{models.WebhookClickRecorded}, ClickSampleRate: &half})
+	paused := false
+	inactive, _ := suite.createWebhook(sessionID, models.WebhookRequest{Events: []string{models.WebhookClickRecorded}, Active: &paused})
+	suite.False(inactive.Active)
+
+	_, otherSession := suite.signIn("elsewhere@example.com")
+	elsewhere, _ := suite.createWebhook(otherSession, models.WebhookRequest{Events: models.WebhookEvents})
+
+	url := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/clicked"})
+	for i := 0; i < 40; i++ {
+		suite.Require().NoError(suite.urlService.RecordClick(url.ID, &models.Analytics{IPAddress: "203.0.113.9", Country: "NL"}))
+	}
+
+	suite.Len(suite.deliveries(clicks.ID), 40)
+	sampledCount := len(suite.deliveries(sampled.ID))
+	suite.Greater(sampledCount, 0)
+	suite.Less(sampledCount, 40)
+	suite.Empty(suite.deliveries(inactive.ID))
+	suite.Empty(suite.deliveries(elsewhere.ID))
+
+	var click struct {
+		Data models.WebhookClickData `json:"data"`
+	}
+	suite.Require().NoError(json.Unmarshal(suite.deliveries(clicks.ID)[0].Payload, &click))
+	suite.Equal(url.ID, click.Data.LinkID)
+	suite.Equal(url.ShortCode, click.Data.ShortCode)
+	suite.Equal("NL", click.Data.Country)
+	suite.NotContains(string(suite.deliveries(clicks.ID)[0].Payload), "203.0.113.9")
+
+	var failure models.ErrorResponse
+	zero := 0.0
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodPost, "/webhooks", sessionID,
+		models.WebhookRequest{URL: suite.receiver.server.URL, Events: []string{models.WebhookClickRecorded}, ClickSampleRate: &zero}, &failure))
+	suite.Equal(services.ErrInvalidSampleRate.Error(), failure.Message)
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodPost, "/webhooks", sessionID,
+		models.WebhookRequest{URL: suite.receiver.server.URL, Events: []string{"link.renamed"}}, nil))
+	suite.Equal(http.StatusBadRequest, suite.request(http.MethodPost, "/webhooks", sessionID,
+		models.WebhookRequest{URL: "ftp://example.com", Events: models.WebhookEvents}, nil))
+
+	// Other workspaces' webhooks are hidden.
+	suite.Equal(http.StatusNotFound, suite.request(http.MethodPut, fmt.Sprintf("/webhooks/%d", elsewhere.ID), sessionID,
+		models.WebhookRequest{URL: suite.receiver.server.URL, Events: models.WebhookEvents}, nil))
+}
+
+func (suite *WebhookTestSuite) TestUpdateReplacesEvents() {
+	user, sessionID := suite.signIn("update@example.com")
+	webhook, _ := suite.createWebhook(sessionID, models.WebhookRequest{Events: []string{models.WebhookLinkCreated}})
+	path := fmt.Sprintf("/webhooks/%d", webhook.ID)
+
+	update := models.WebhookRequest{
+		URL:    suite.receiver.server.URL + "/v2",
+		Events: []string{models.WebhookLinkUpdated, models.WebhookLinkDeleted},
+	}
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodPut, path, sessionID, update, nil))
+
+	reloaded, err := suite.webhookService.GetWebhook(webhook.ID, user.ID)
+	suite.Require().NoError(err)
+	suite.Equal(update.URL, reloaded.URL)
+	suite.Equal(update.Events, reloaded.Events)
+
+	update.Events = []string{models.WebhookLinkDeleted}
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodPut, path, sessionID, update, nil))
+	reloaded, err = suite.webhookService.GetWebhook(webhook.ID, user.ID)
+	suite.Require().NoError(err)
+	suite.Equal([]string{models.WebhookLinkDeleted}, reloaded.Events)
+
+	// Link writes in the workspace keep working and follow the new events.
+	url := suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/updated-hook"})
+	suite.Require().NoError(suite.urlService.DeleteURL(url.ID, user.ID))
+	deliveries := suite.deliveries(webhook.ID)
+	suite.Require().Len(deliveries, 1)
+	suite.Equal(models.WebhookLinkDeleted, deliveries[0].Event)
+}
+
+func (suite *WebhookTestSuite) TestPrivateTargetsAreRefused() {
+	user, _ := suite.signIn("private@example.com")
+	strict := services.NewWebhookService(&config.Config{WebhookTimeout: 2})
+
+	_, _, err := strict.CreateWebhook(user.ID, &models.WebhookRequest{URL: suite.receiver.server.URL, Events: models.WebhookEvents})
+	suite.ErrorIs(err, services.ErrPrivateWebhookURL)
+	for _, target := range []string{
+		"http://localhost:9000/hook",
+		"http://0.0.0.1/hook",
+		"http://100.64.0.1/hook",
+		"http://198.18.0.1/hook",
+		"http://[::ffff:10.0.0.1]/hook",
+		"http://[64:ff9b::a00:1]/hook",
+	} {
+		_, _, err = strict.CreateWebhook(user.ID, &models.WebhookRequest{URL: target, Events: models.WebhookEvents})
+		suite.ErrorIs(err, services.ErrPrivateWebhookURL, target)
+	}
+	_, _, err = strict.CreateWebhook(user.ID, &models.WebhookRequest{URL: "https://203.0.113.10/hook", Events: models.WebhookEvents})
+	suite.NoError(err)
+
+	// Endpoints are checked again when connecting, which also covers host
+	// names that resolve to private addresses.
+	_, webhook, err := suite.webhookService.CreateWebhook(user.ID, &models.WebhookRequest{URL: suite.receiver.server.URL, Events: models.WebhookEvents})
+	suite.Require().NoError(err)
+	suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/private"})
+
+	delivered, err := strict.DeliverDue(context.Background(), time.Now())
+	suite.Require().NoError(err)
+	suite.Zero(delivered)
+	suite.Empty(suite.receiver.received())
+	deliveries := suite.deliveries(webhook.ID)
+	suite.Require().Len(deliveries, 1)
+	suite.Equal(models.DeliveryPending, deliveries[0].Status)
+	suite.Contains(deliveries[0].LastError, services.ErrPrivateWebhookURL.Error())
+}
+
+func (suite *WebhookTestSuite) TestFailedDeliveriesBackOffAndDeadLetter() {
+	user, sessionID := suite.signIn("retries@example.com")
+	webhook, _ := suite.createWebhook(sessionID, models.WebhookRequest{Events: []string{models.WebhookLinkCreated}})
+	suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/flaky"})
+	suite.receiver.setStatus(http.StatusInternalServerError)
+
+	start := time.Now()
+	suite.Zero(suite.deliver(start))
+	delivery := suite.deliveries(webhook.ID)[0]
+	suite.Equal(models.DeliveryPending, delivery.Status)
+	suite.Equal(1, delivery.Attempts)
+	suite.Equal(http.StatusInternalServerError, delivery.ResponseStatus)
+	suite.WithinDuration(start.Add(30*time.Second), delivery.NextAttemptAt, time.Second)
+
+	// Not retried before the backoff has passed, then twice as long after
+	// each failure.
+	suite.Zero(suite.deliver(start.Add(20 * time.Second)))
+	suite.Len(suite.receiver.received(), 1)
+	suite.Zero(suite.deliver(start.Add(31 * time.Second)))
+	delivery = suite.deliveries(webhook.ID)[0]
+	suite.Equal(2, delivery.Attempts)
+	suite.WithinDuration(start.Add(91*time.Second), delivery.NextAttemptAt, time.Second)
+
+	suite.Zero(suite.deliver(start.Add(2 * time.Minute)))
+	delivery = suite.deliveries(webhook.ID)[0]
+	suite.Equal(models.DeliveryDead, delivery.Status)
+	suite.Equal(3, delivery.Attempts)
+	suite.Zero(suite.deliver(start.Add(time.Hour)))
+
+	var listed struct {
+		Data struct {
+			Deliveries []models.WebhookDelivery `json:"deliveries"`
+			Total      int64                    `json:"total"`
+		} `json:"data"`
+	}
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodGet, fmt.Sprintf("/webhooks/%d/deliveries?status=dead", webhook.ID), sessionID, nil, &listed))
+	suite.Equal(int64(1), listed.Data.Total)
+	suite.Equal(delivery.ID, listed.Data.Deliveries[0].ID)
+
+	redeliver := fmt.Sprintf("/webhooks/%d/deliveries/%d/redeliver", webhook.ID, delivery.ID)
+	suite.Equal(http.StatusAccepted, suite.request(http.MethodPost, redeliver, sessionID, nil, nil))
+	suite.Equal(http.StatusConflict, suite.request(http.MethodPost, redeliver, sessionID, nil, nil))
+
+	suite.receiver.setStatus(http.StatusNoContent)
+	suite.Equal(1, suite.deliver(time.Now()))
+	delivery = suite.deliveries(webhook.ID)[0]
+	suite.Equal(models.DeliveryDelivered, delivery.Status)
+	suite.Equal(1, delivery.Attempts)
+
+	// Every attempt carried the same event.
+	received := suite.receiver.received()
+	suite.Len(received, 4)
+	for _, r := range received {
+		suite.Equal(delivery.EventID, r.event.ID)
+	}
+}
+
+func (suite *WebhookTestSuite) TestFailedEventIsNotOvertaken() {
+	user, sessionID := suite.signIn("ordered@example.com")
+	webhook, _ := suite.createWebhook(sessionID, models.WebhookRequest{Events: []string{models.WebhookLinkCreated}})
+	suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/first"})
+	suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/second"})
+
+	suite.receiver.setStatus(http.StatusInternalServerError)
+	start := time.Now()
+	suite.Zero(suite.deliver(start))
+
+	// The second event is due but waits for the first one's retry.
+	suite.receiver.setStatus(http.StatusNoContent)
+	suite.Zero(suite.deliver(start.Add(10 * time.Second)))
+	suite.Equal(2, suite.deliver(start.Add(31*time.Second)))
+
+	deliveries := suite.deliveries(webhook.ID)
+	suite.Require().Len(deliveries, 2)
+	var eventIDs []string
+	for _, r := range suite.receiver.received() {
+		eventIDs = append(eventIDs, r.event.ID)
+	}
+	suite.Equal([]string{deliveries[0].EventID, deliveries[0].EventID, deliveries[1].EventID}, eventIDs)
+}
+
+func (suite *WebhookTestSuite) TestSlowEndpointDoesNotHoldUpOthers() {
+	// The slow endpoint never answers, so every attempt runs into the
+	// timeout.
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	defer slow.Close()
+
+	stuck, stuckSession := suite.signIn("stuck@example.com")
+	stuckHook, _ := suite.createWebhook(stuckSession, models.WebhookRequest{URL: slow.URL, Events: []string{models.WebhookLinkCreated}})
+	suite.createURL(stuck.ID, models.CreateURLRequest{OriginalURL: "https://example.com/stuck-1"})
+	suite.createURL(stuck.ID, models.CreateURLRequest{OriginalURL: "https://example.com/stuck-2"})
+
+	user, sessionID := suite.signIn("prompt@example.com")
+	suite.createWebhook(sessionID, models.WebhookRequest{Events: []string{models.WebhookLinkCreated}})
+	suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/prompt"})
+
+	start := time.Now()
+	suite.Equal(1, suite.deliver(start))
+	suite.Less(time.Since(start), 2*time.Duration(suite.cfg.WebhookTimeout)*time.Second)
+
+	received := suite.receiver.received()
+	suite.Require().Len(received, 1)
+	timestamp, err := strconv.ParseInt(received[0].header.Get("X-Webhook-Timestamp"), 10, 64)
+	suite.Require().NoError(err)
+	suite.WithinDuration(start, time.Unix(timestamp, 0), 2*time.Second)
+
+	// The stuck webhook's first delivery failed and its second waits for
+	// the next run.
+	deliveries := suite.deliveries(stuckHook.ID)
+	suite.Require().Len(deliveries, 2)
+	suite.Equal(1, deliveries[0].Attempts)
+	suite.NotEmpty(deliveries[0].LastError)
+	suite.True(deliveries[0].NextAttemptAt.After(time.Now().Add(20 * time.Second)))
+	suite.Zero(deliveries[1].Attempts)
+}
+
+func (suite *WebhookTestSuite) TestRedeliverAllDead() {
+	user, sessionID := suite.signIn("bulk@example.com")
+	webhook, _ := suite.createWebhook(sessionID, models.WebhookRequest{Events: []string{models.WebhookLinkCreated}})
+	suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/one"})
+	suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/two"})
+	suite.Require().NoError(suite.db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhook.ID).
+		Updates(map[string]interface{}{"status": models.DeliveryDead, "attempts": 3}).Error)
+
+	var queued struct {
+		Data struct {
+			Queued int64 `json:"queued"`
+		} `json:"data"`
+	}
+	suite.Require().Equal(http.StatusAccepted, suite.request(http.MethodPost, fmt.Sprintf("/webhooks/%d/deliveries/redeliver", webhook.ID), sessionID, nil, &queued))
+	suite.Equal(int64(2), queued.Data.Queued)
+	suite.Equal(2, suite.deliver(time.Now()))
+
+	// Deleting the webhook drops its deliveries.
+	suite.Require().Equal(http.StatusOK, suite.request(http.MethodDelete, fmt.Sprintf("/webhooks/%d", webhook.ID), sessionID, nil, nil))
+	suite.Empty(suite.deliveries(webhook.ID))
+}
+
+func (suite *WebhookTestSuite) TestExpiredLinksAreAnnouncedOnce() {
+	user, sessionID := suite.signIn("expiry@example.com")
+	webhook, _ := suite.createWebhook(sessionID, models.WebhookRequest{Events: []string{models.WebhookLinkExpired}})
+	expiring := suite.createURL(user.ID, models.CreateURLRequest{
+		OriginalURL: "https://example.com/expiring",
+		ExpiresAt:   time.Now().Add(time.Hour).Format(time.RFC3339),
+	})
+	suite.createURL(user.ID, models.CreateURLRequest{OriginalURL: "https://example.com/forever"})
+
+	queued, err := suite.webhookService.QueueExpiredLinks(time.Now())
+	suite.Require().NoError(err)
+	suite.Zero(queued)
+
+	later := time.Now().Add(2 * time.Hour)
+	queued, err = suite.webhookService.QueueExpiredLinks(later)
+	suite.Require().NoError(err)
+	suite.Equal(1, queued)
+	queued, err = suite.webhookService.QueueExpiredLinks(later)
+	suite.Require().NoError(err)
+	suite.Zero(queued)
+
+	deliveries := suite.deliveries(webhook.ID)
+	suite.Require().Len(deliveries, 1)
+	suite.Equal(models.WebhookLinkExpired, deliveries[0].Event)
+	var expired struct {
+		Data models.WebhookLinkData `json:"data"`
+	}
+	suite.Require().NoError(json.Unmarshal(deliveries[0].Payload, &expired))
+	suite.Equal(expiring.ID, expired.Data.Link.ID)
+
+	// A new expiry is announced when it passes in turn.
+	_, err = suite.urlService.UpdateURL(expiring.ID, user.ID, &models.CreateURLRequest{ExpiresAt: later.Add(time.Hour).Format(time.RFC3339)})
+	suite.Require().NoError(err)
+	queued, err = suite.webhookService.QueueExpiredLinks(later.Add(2 * time.Hour))
+	suite.Require().NoError(err)
+	suite.Equal(1, queued)
+	suite.Len(suite.deliveries(webhook.ID), 2)
+}
+
+func TestWebhookTestSuite(t *testing.T) {
+	suite.Run(t, new(WebhookTestSuite))
+}
@@ -0,0 +1,234 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"url-shortener-backend/internal/config"
+	"url-shortener-backend/internal/database"
+	"url-shortener-backend/internal/handlers"
+	"url-shortener-backend/internal/middleware"
+	"url-shortener-backend/internal/models"
+	"url-shortener-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type WorkspaceTestSuite struct {
+	apiSuite
+	mailer           *mockMailer
+	workspaceService *services.WorkspaceService
+}
+
+func (suite *WorkspaceTestSuite) SetupSuite() {
+	suite.cfg = &config.Config{
+		SessionSecret: "test-session-secret",
+		Environment:   "test",
+		FrontendURL:   "http://localhost:3000",
+	}
+
+	var err error
+	suite.db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	suite.Require().NoError(err)
+
+	database.DB = suite.db
+	suite.Require().NoError(database.AutoMigrate())
+
+	suite.mailer = &mockMailer{}
+	suite.workspaceService = services.NewWorkspaceService(suite.cfg, suite.mailer)
+	workspaceHandler := handlers.NewWorkspaceHandler(suite.workspaceService, services.NewAuditService())
+	urlHandler := handlers.NewURLHandler(services.NewURLService(), services.NewClickHub(0), suite.cfg)
+
+	suite.sessionStore = middleware.NewSimpleSessionStore()
+	suite.sessions = middleware.NewSessionManager(suite.sessionStore, suite.cfg)
+
+	suite.app = fiber.New()
+	urls := suite.app.Group("/urls", suite.sessions.AuthMiddleware())
+	urls.Post("/", urlHandler.CreateURL)
+	urls.Get("/", urlHandler.GetUserURLs)
+	urls.Put("/:id", urlHandler.UpdateURL)
+	urls.Delete("/:id", urlHandler.DeleteURL)
+
+	workspaces := suite.app.Group("/workspaces", suite.sessions.AuthMiddleware())
+	workspaces.Get("/", workspaceHandler.ListWorkspaces)
+	workspaces.Post("/", workspaceHandler.CreateWorkspace)
+	workspaces.Post("/invitations/accept", workspaceHandler.AcceptInvitation)
+	workspaces.Delete("/:id", workspaceHandler.DeleteWorkspace)
+	workspaces.Put("/:id/members/:userId", workspaceHandler.UpdateMember)
+	workspaces.Delete("/:id/members/:userId", workspaceHandler.RemoveMember)
+	workspaces.Post("/:id/invitations", workspaceHandler.Invite)
+}
+
+func (suite *WorkspaceTestSuite) TearDownTest() {
+	for _, table := range []string{"workspace_invitations", "workspace_members", "workspaces", "urls", "users"} {
+		suite.db.Exec("DELETE FROM " + table)
+	}
+}
+
+func (suite *WorkspaceTestSuite) createWorkspace(sessionID, name string) uint {
+	resp, body := suite.requestMap(http.MethodPost, "/workspaces/", sessionID, models.WorkspaceRequest{Name: name})
+	suite.Require().Equal(http.StatusCreated, resp.StatusCode)
+	return uint(body["data"].(map[string]interface{})["id"].(float64))
+}
+
+func (suite *WorkspaceTestSuite) invite(sessionID string, workspaceID uint, email, role string) string {
+	resp, _ := suite.requestMap(http.MethodPost, fmt.Sprintf("/workspaces/%d/invitations", workspaceID), sessionID,
+		models.InviteMemberRequest{Email: email, Role: role})
+	suite.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	sent := suite.mailer.Sent()
+	suite.Require().NotEmpty(sent)
+	suite.Equal(email, sent[len(sent)-1].To)
+	match := mailTokenPattern.FindStringSubmatch(sent[len(sent)-1].Body)
+	suite.Require().Len(match, 2)
+	return match[1]
+}
+
+func (suite *WorkspaceTestSuite) createLink(sessionID string, workspaceID *uint, alias string) (*http.Response, uint) {
+	resp, body := suite.requestMap(http.MethodPost, "/urls/", sessionID, models.CreateURLRequest{
+		OriginalURL: "https://example.com/" + alias,
+		CustomAlias: alias,
+		WorkspaceID: workspaceID,
+	})
+	if data, ok := body["data"].(map[string]interface{}); ok {
+		return resp, uint(data["id"].(float64))
+	}
+	return resp, 0
+}
+
+func (suite *WorkspaceTestSuite) TestMigrationMovesLinksIntoPersonalWorkspaces() {
+	user := models.User{Name: "Legacy", Email: "legacy@example.com"}
+	suite.Require().NoError(suite.db.Create(&user).Error)
+	legacy := models.URL{OriginalURL: "https://example.com/", ShortCode: "legacy1", CustomAlias: "legacy1", UserID: &user.ID, IsActive: true}
+	suite.Require().NoError(suite.db.Create(&legacy).Error)
+
+	migrated, err := suite.workspaceService.MigratePersonalWorkspaces()
+	suite.Require().NoError(err)
+	suite.EqualValues(1, migrated)
+
+	var workspace models.Workspace
+	suite.Require().NoError(suite.db.Where("personal_user_id = ?", user.ID).First(&workspace).Error)
+	var member models.WorkspaceMember
+	suite.Require().NoError(suite.db.Where("workspace_id = ? AND user_id = ?", workspace.ID, user.ID).First(&member).Error)
+	suite.Equal(models.RoleOwner, member.Role)
+
+	suite.Require().NoError(suite.db.First(&legacy, legacy.ID).Error)
+	suite.Equal(workspace.ID, *legacy.WorkspaceID)
+
+	// Running again changes nothing.
+	migrated, err = suite.workspaceService.MigratePersonalWorkspaces()
+	suite.Require().NoError(err)
+	suite.EqualValues(0, migrated)
+	var count int64
+	suite.db.Model(&models.Workspace{}).Count(&count)
+	suite.EqualValues(1, count)
+}
+
+func (suite *WorkspaceTestSuite) TestRolesControlSharedLinks() {
+	_, ownerSession := suite.signIn("owner@example.com")
+	bob, bobSession := suite.signIn("bob@example.com")
+	_, outsiderSession := suite.signIn("outsider@example.com")
+
+	workspaceID := suite.createWorkspace(ownerSession, "Campaigns")
+	resp, linkID := suite.createLink(ownerSession, &workspaceID, "spring")
+	suite.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	token := suite.invite(ownerSession, workspaceID, "bob@example.com", models.RoleViewer)
+	resp, _ = suite.requestMap(http.MethodPost, "/workspaces/invitations/accept", bobSession, models.AcceptInvitationRequest{Token: token})
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	// Viewers see the link but cannot change it or add links.
+	resp, body := suite.requestMap(http.MethodGet, fmt.Sprintf("/urls/?workspace_id=%d", workspaceID), bobSession, nil)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.EqualValues(1, body["data"].(map[string]interface{})["total"])
+
+	linkPath := fmt.Sprintf("/urls/%d", linkID)
+	resp, _ = suite.requestMap(http.MethodPut, linkPath, bobSession, models.CreateURLRequest{Title: "Edited"})
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+	resp, _ = suite.createLink(bobSession, &workspaceID, "bobs")
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+
+	// Outsiders cannot see the workspace or its links at all.
+	resp, body = suite.requestMap(http.MethodGet, "/urls/", outsiderSession, nil)
+	suite.EqualValues(0, body["data"].(map[string]interface{})["total"])
+	resp, _ = suite.requestMap(http.MethodDelete, linkPath, outsiderSession, nil)
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+	resp, _ = suite.createLink(outsiderSession, &workspaceID, "sneaky")
+	suite.Equal(http.StatusNotFound, resp.StatusCode)
+
+	// Promoted to editor, Bob can manage the shared link.
+	resp, _ = suite.requestMap(http.MethodPut, fmt.Sprintf("/workspaces/%d/members/%d", workspaceID, bob.ID), ownerSession,
+		models.UpdateMemberRequest{Role: models.RoleEditor})
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	resp, _ = suite.requestMap(http.MethodPut, linkPath, bobSession, models.CreateURLRequest{Title: "Edited"})
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	resp, _ = suite.requestMap(http.MethodDelete, linkPath, bobSession, nil)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func (suite *WorkspaceTestSuite) TestLinksDefaultToPersonalWorkspace() {
+	user, session := suite.signIn("solo@example.com")
+
+	resp, linkID := suite.createLink(session, nil, "solo")
+	suite.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	var link models.URL
+	suite.Require().NoError(suite.db.First(&link, linkID).Error)
+	var workspace models.Workspace
+	suite.Require().NoError(suite.db.First(&workspace, *link.WorkspaceID).Error)
+	suite.Equal(user.ID, *workspace.PersonalUserID)
+
+	resp, _ = suite.requestMap(http.MethodDelete, fmt.Sprintf("/workspaces/%d", workspace.ID), session, nil)
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func (suite *WorkspaceTestSuite) TestInvitationRules() {
+	_, ownerSession := suite.signIn("lead@example.com")
+	admin, adminSession := suite.signIn("admin@example.com")
+	_, otherSession := suite.signIn("other@example.com")
+
+	workspaceID := suite.createWorkspace(ownerSession, "Team")
+	token := suite.invite(ownerSession, workspaceID, "admin@example.com", models.RoleAdmin)
+
+	// Invitations only work for the invited address, and only once.
+	resp, _ := suite.requestMap(http.MethodPost, "/workspaces/invitations/accept", otherSession, models.AcceptInvitationRequest{Token: token})
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+	resp, _ = suite.requestMap(http.MethodPost, "/workspaces/invitations/accept", adminSession, models.AcceptInvitationRequest{Token: token})
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	resp, _ = suite.requestMap(http.MethodPost, "/workspaces/invitations/accept", adminSession, models.AcceptInvitationRequest{Token: token})
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	// Admins cannot hand out ownership.
+	resp, _ = suite.requestMap(http.MethodPost, fmt.Sprintf("/workspaces/%d/invitations", workspaceID), adminSession,
+		models.InviteMemberRequest{Email: "new@example.com", Role: models.RoleOwner})
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+	resp, _ = suite.requestMap(http.MethodPut, fmt.Sprintf("/workspaces/%d/members/%d", workspaceID, admin.ID), adminSession,
+		models.UpdateMemberRequest{Role: models.RoleOwner})
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+
+	// Existing members cannot be invited again.
+	resp, _ = suite.requestMap(http.MethodPost, fmt.Sprintf("/workspaces/%d/invitations", workspaceID), ownerSession,
+		models.InviteMemberRequest{Email: "admin@example.com", Role: models.RoleEditor})
+	suite.Equal(http.StatusConflict, resp.StatusCode)
+}
+
+func (suite *WorkspaceTestSuite) TestLastOwnerCannotLeave() {
+	owner, ownerSession := suite.signIn("only-owner@example.com")
+	workspaceID := suite.createWorkspace(ownerSession, "Solo team")
+
+	memberPath := fmt.Sprintf("/workspaces/%d/members/%d", workspaceID, owner.ID)
+	resp, _ := suite.requestMap(http.MethodDelete, memberPath, ownerSession, nil)
+	suite.Equal(http.StatusConflict, resp.StatusCode)
+	resp, _ = suite.requestMap(http.MethodPut, memberPath, ownerSession, models.UpdateMemberRequest{Role: models.RoleEditor})
+	suite.Equal(http.StatusConflict, resp.StatusCode)
+
+	resp, _ = suite.requestMap(http.MethodDelete, fmt.Sprintf("/workspaces/%d", workspaceID), ownerSession, nil)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func TestWorkspaceTestSuite(t *testing.T) {
+	suite.Run(t, new(WorkspaceTestSuite))
+}
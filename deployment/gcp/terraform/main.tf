@@ -231,6 +231,11 @@ resource "google_cloud_run_service" "backend" {
           value = "production"
         }
         
+        env {
+          name  = "MAIL_DRIVER"
+          value = "smtp"
+        }
+        
         env {
           name = "DATABASE_URL"
           value_from {
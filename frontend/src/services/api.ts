@@ -2,9 +2,32 @@ import axios from 'axios';
 import type { 
   User, 
   URL, 
+  URLRevision,
+  URLRevisionDiff,
+  URLListFilter,
+  Tag,
+  TagRequest,
+  TagStats,
+  Folder,
+  FolderRequest,
   Analytics, 
   URLStats, 
   CreateURLRequest, 
+  LoginRequest,
+  RegisterRequest,
+  Workspace,
+  WorkspaceMember,
+  WorkspaceRole,
+  DataExport,
+  AccountUsage,
+  AbuseReportRequest,
+  AuditFilter,
+  AuditPage,
+  Webhook,
+  WebhookRequest,
+  WebhookDelivery,
+  WebhookDeliveryPage,
+  WebhookDeliveryStatus,
   ApiResponse
 } from '@/types';
 
@@ -18,10 +41,41 @@ const api = axios.create({
   },
 });
 
-// OAuth uses session-based authentication, no need for token interceptors
+// Mutating requests carry a CSRF token bound to the current session. It is
+// fetched lazily and refetched when the session changes (sign-in/out).
+let csrfToken: string | null = null;
+
+const fetchCsrfToken = async (): Promise<string> => {
+  const res = await api.get('/auth/csrf');
+  csrfToken = res.data.data.csrf_token as string;
+  return csrfToken;
+};
+
+const isUnsafeMethod = (method?: string) =>
+  !['get', 'head', 'options'].includes((method || 'get').toLowerCase());
+
+api.interceptors.request.use(async (config) => {
+  if (isUnsafeMethod(config.method)) {
+    config.headers['X-CSRF-Token'] = csrfToken ?? (await fetchCsrfToken());
+  }
+  return config;
+});
+
 api.interceptors.response.use(
-  (response) => response,
-  (error) => {
+  (response) => {
+    if (/\/auth\/(login|logout)$/.test(response.config.url || '')) {
+      csrfToken = null;
+    }
+    return response;
+  },
+  async (error) => {
+    const config = error.config;
+    if (error.response?.status === 403 && error.response.data?.error === 'csrf_token_invalid' && !config._csrfRetried) {
+      // The session changed since the token was issued; retry once.
+      config._csrfRetried = true;
+      csrfToken = null;
+      return api(config);
+    }
     if (error.response?.status === 401) {
       // Clear any stored auth data, but don't redirect automatically
       localStorage.removeItem('token');
@@ -32,9 +86,12 @@ api.interceptors.response.use(
 );
 
 export const authApi = {
-  // Get Google OAuth login URL
-  getOAuthLoginUrl: (): Promise<ApiResponse<{ auth_url: string }>> =>
-    api.get('/auth/login').then(res => res.data),
+  // Get the OAuth login URL for an identity provider (defaults to Google)
+  getOAuthLoginUrl: (provider = 'google'): Promise<ApiResponse<{ auth_url: string; provider: string }>> =>
+    api.get(`/auth/login?provider=${encodeURIComponent(provider)}`).then(res => res.data),
+
+  getOAuthProviders: (): Promise<ApiResponse<{ providers: string[] }>> =>
+    api.get('/auth/providers').then(res => res.data),
   
   // Handle OAuth callback (called automatically by backend)
   handleOAuthCallback: (code: string, state: string): Promise<ApiResponse<User>> =>
@@ -45,19 +102,45 @@ export const authApi = {
   
   getProfile: (): Promise<ApiResponse<User>> =>
     api.get('/auth/profile').then(res => res.data),
+
+  register: (data: RegisterRequest): Promise<ApiResponse<User>> =>
+    api.post('/auth/register', data).then(res => res.data),
+
+  login: (data: LoginRequest): Promise<ApiResponse<{ user: User }>> =>
+    api.post('/auth/login', data).then(res => res.data),
+
+  verifyEmail: (token: string): Promise<ApiResponse> =>
+    api.post('/auth/verify-email', { token }).then(res => res.data),
+
+  forgotPassword: (email: string): Promise<ApiResponse> =>
+    api.post('/auth/password/forgot', { email }).then(res => res.data),
+
+  resetPassword: (token: string, password: string): Promise<ApiResponse> =>
+    api.post('/auth/password/reset', { token, password }).then(res => res.data),
 };
 
 export const urlApi = {
   createURL: (data: CreateURLRequest): Promise<ApiResponse<URL>> =>
     api.post('/urls', data).then(res => res.data),
   
-  getUserURLs: (limit = 10, offset = 0): Promise<ApiResponse<{
+  getUserURLs: (limit = 10, offset = 0, filter: URLListFilter = {}): Promise<ApiResponse<{
     urls: URL[];
     total: number;
     limit: number;
     offset: number;
   }>> =>
-    api.get(`/urls?limit=${limit}&offset=${offset}`).then(res => res.data),
+    api.get('/urls', {
+      params: { limit, offset, ...filter, tag_id: filter.tag_id?.join(',') || undefined },
+    }).then(res => res.data),
+  
+  tagURLs: (urlIds: number[], tagIds: number[]): Promise<ApiResponse<{ changed: number }>> =>
+    api.post('/urls/bulk/tag', { url_ids: urlIds, tag_ids: tagIds }).then(res => res.data),
+  
+  untagURLs: (urlIds: number[], tagIds: number[]): Promise<ApiResponse<{ changed: number }>> =>
+    api.post('/urls/bulk/untag', { url_ids: urlIds, tag_ids: tagIds }).then(res => res.data),
+  
+  moveURLs: (urlIds: number[], folderId: number | null): Promise<ApiResponse<{ changed: number }>> =>
+    api.post('/urls/bulk/move', { url_ids: urlIds, folder_id: folderId }).then(res => res.data),
   
   updateURL: (id: number, data: Partial<CreateURLRequest>): Promise<ApiResponse<URL>> =>
     api.put(`/urls/${id}`, data).then(res => res.data),
@@ -73,11 +156,134 @@ export const urlApi = {
     stats: URLStats;
   }>> =>
     api.get(`/urls/${id}/analytics`).then(res => res.data),
+  
+  getRevisions: (id: number, limit = 20, offset = 0): Promise<ApiResponse<{
+    revisions: URLRevision[];
+    total: number;
+    limit: number;
+    offset: number;
+  }>> =>
+    api.get(`/urls/${id}/revisions?limit=${limit}&offset=${offset}`).then(res => res.data),
+  
+  diffRevisions: (id: number, from?: number, to?: number): Promise<ApiResponse<URLRevisionDiff>> =>
+    api.get(`/urls/${id}/revisions/diff`, { params: { from, to } }).then(res => res.data),
+  
+  rollback: (id: number, revision: number): Promise<ApiResponse<URL>> =>
+    api.post(`/urls/${id}/revisions/${revision}/rollback`).then(res => res.data),
+};
+
+export const tagApi = {
+  list: (workspaceId?: number): Promise<ApiResponse<Tag[]>> =>
+    api.get('/tags', { params: { workspace_id: workspaceId } }).then(res => res.data),
+
+  create: (data: TagRequest): Promise<ApiResponse<Tag>> =>
+    api.post('/tags', data).then(res => res.data),
+
+  update: (id: number, data: TagRequest): Promise<ApiResponse<Tag>> =>
+    api.put(`/tags/${id}`, data).then(res => res.data),
+
+  remove: (id: number): Promise<ApiResponse> =>
+    api.delete(`/tags/${id}`).then(res => res.data),
+
+  getStats: (id: number, from?: string, to?: string): Promise<ApiResponse<TagStats>> =>
+    api.get(`/tags/${id}/stats`, { params: { from, to } }).then(res => res.data),
+};
+
+export const folderApi = {
+  list: (workspaceId?: number): Promise<ApiResponse<Folder[]>> =>
+    api.get('/folders', { params: { workspace_id: workspaceId } }).then(res => res.data),
+
+  create: (data: FolderRequest): Promise<ApiResponse<Folder>> =>
+    api.post('/folders', data).then(res => res.data),
+
+  update: (id: number, data: FolderRequest): Promise<ApiResponse<Folder>> =>
+    api.put(`/folders/${id}`, data).then(res => res.data),
+
+  remove: (id: number): Promise<ApiResponse> =>
+    api.delete(`/folders/${id}`).then(res => res.data),
+};
+
+export const workspaceApi = {
+  list: (): Promise<ApiResponse<Workspace[]>> =>
+    api.get('/workspaces').then(res => res.data),
+
+  create: (name: string): Promise<ApiResponse<Workspace>> =>
+    api.post('/workspaces', { name }).then(res => res.data),
+
+  listMembers: (id: number): Promise<ApiResponse<WorkspaceMember[]>> =>
+    api.get(`/workspaces/${id}/members`).then(res => res.data),
+
+  updateMember: (id: number, userId: number, role: WorkspaceRole): Promise<ApiResponse<WorkspaceMember>> =>
+    api.put(`/workspaces/${id}/members/${userId}`, { role }).then(res => res.data),
+
+  removeMember: (id: number, userId: number): Promise<ApiResponse> =>
+    api.delete(`/workspaces/${id}/members/${userId}`).then(res => res.data),
+
+  invite: (id: number, email: string, role: WorkspaceRole): Promise<ApiResponse> =>
+    api.post(`/workspaces/${id}/invitations`, { email, role }).then(res => res.data),
+
+  acceptInvitation: (token: string): Promise<ApiResponse<Workspace>> =>
+    api.post('/workspaces/invitations/accept', { token }).then(res => res.data),
+
+  getAuditLog: (id: number, filter: AuditFilter = {}): Promise<ApiResponse<AuditPage>> =>
+    api.get(`/workspaces/${id}/audit`, { params: filter }).then(res => res.data),
+};
+
+export const accountApi = {
+  getExport: (): Promise<ApiResponse<DataExport>> =>
+    api.get('/me/export').then(res => res.data),
+
+  requestExport: (): Promise<ApiResponse<DataExport>> =>
+    api.post('/me/export').then(res => res.data),
+
+  exportDownloadUrl: (): string =>
+    `${API_BASE_URL}/api/v1/me/export/download`,
+
+  deleteAccount: (): Promise<ApiResponse<{ scheduled_at: string }>> =>
+    api.delete('/me').then(res => res.data),
+
+  cancelDeletion: (): Promise<ApiResponse> =>
+    api.post('/me/deletion/cancel').then(res => res.data),
+
+  getUsage: (): Promise<ApiResponse<AccountUsage>> =>
+    api.get('/me/usage').then(res => res.data),
+
+  getAuditLog: (filter: AuditFilter = {}): Promise<ApiResponse<AuditPage>> =>
+    api.get('/me/audit', { params: filter }).then(res => res.data),
+};
+
+export const webhookApi = {
+  getWebhooks: (workspaceId?: number): Promise<ApiResponse<Webhook[]>> =>
+    api.get('/webhooks', { params: { workspace_id: workspaceId } }).then(res => res.data),
+
+  getWebhook: (id: number): Promise<ApiResponse<Webhook>> =>
+    api.get(`/webhooks/${id}`).then(res => res.data),
+
+  createWebhook: (data: WebhookRequest): Promise<ApiResponse<{ secret: string; webhook: Webhook }>> =>
+    api.post('/webhooks', data).then(res => res.data),
+
+  updateWebhook: (id: number, data: WebhookRequest): Promise<ApiResponse<Webhook>> =>
+    api.put(`/webhooks/${id}`, data).then(res => res.data),
+
+  deleteWebhook: (id: number): Promise<ApiResponse> =>
+    api.delete(`/webhooks/${id}`).then(res => res.data),
+
+  getDeliveries: (id: number, status?: WebhookDeliveryStatus, limit = 50, offset = 0): Promise<ApiResponse<WebhookDeliveryPage>> =>
+    api.get(`/webhooks/${id}/deliveries`, { params: { status, limit, offset } }).then(res => res.data),
+
+  redeliver: (id: number, deliveryId: number): Promise<ApiResponse<WebhookDelivery>> =>
+    api.post(`/webhooks/${id}/deliveries/${deliveryId}/redeliver`).then(res => res.data),
+
+  redeliverDead: (id: number): Promise<ApiResponse<{ queued: number }>> =>
+    api.post(`/webhooks/${id}/deliveries/redeliver`).then(res => res.data),
 };
 
 export const publicApi = {
   redirect: (shortCode: string): string =>
     `${API_BASE_URL}/${shortCode}`,
+
+  reportURL: (shortCode: string, data: AbuseReportRequest): Promise<ApiResponse> =>
+    axios.post(`${API_BASE_URL}/${shortCode}/report`, data).then(res => res.data),
 };
 
 export default api;
\ No newline at end of file
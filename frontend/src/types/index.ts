@@ -2,6 +2,9 @@ export interface User {
   id: number;
   email: string;
   name: string;
+  is_admin?: boolean;
+  plan?: string;
+  deletion_scheduled_at?: string;
   created_at: string;
   updated_at: string;
 }
@@ -13,14 +16,42 @@ export interface URL {
   custom_alias?: string;
   user_id?: number;
   user?: User;
+  workspace_id?: number;
+  folder_id?: number;
+  tags?: Tag[];
   title?: string;
   description?: string;
   expires_at?: string;
   is_active: boolean;
+  revision: number;
+  quarantined_at?: string;
+  click_count: number;
+  last_clicked_at?: string;
   created_at: string;
   updated_at: string;
 }
 
+export interface URLRevision {
+  id: number;
+  url_id: number;
+  number: number;
+  original_url: string;
+  title?: string;
+  description?: string;
+  expires_at?: string;
+  restored_from?: number;
+  created_by_id?: number;
+  clicks: number;
+  created_at: string;
+}
+
+export interface URLRevisionDiff {
+  url_id: number;
+  from: number;
+  to: number;
+  changes: Record<string, { from: unknown; to: unknown }>;
+}
+
 export interface Analytics {
   id: number;
   url_id: number;
@@ -49,6 +80,85 @@ export interface CreateURLRequest {
   title?: string;
   description?: string;
   expires_at?: string;
+  workspace_id?: number;
+  folder_id?: number;
+  tag_ids?: number[];
+}
+
+export interface URLListFilter {
+  workspace_id?: number;
+  tag_id?: number[];
+  folder_id?: number | 'none';
+  subfolders?: boolean;
+  q?: string;
+  active?: boolean;
+  expired?: boolean;
+  has_clicks?: boolean;
+  created_from?: string;
+  created_to?: string;
+  sort?: 'created' | 'updated' | 'clicks' | 'last_clicked';
+  order?: 'asc' | 'desc';
+}
+
+export interface Tag {
+  id: number;
+  workspace_id: number;
+  name: string;
+  color?: string;
+  link_count?: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface TagRequest {
+  workspace_id?: number;
+  name: string;
+  color?: string;
+}
+
+export interface TagStats {
+  tag_id: number;
+  from: string;
+  to: string;
+  links: number;
+  total_clicks: number;
+  unique_clicks: number;
+  top_links: { url_id: number; short_code: string; title?: string; clicks: number }[];
+}
+
+export interface Folder {
+  id: number;
+  workspace_id: number;
+  parent_id?: number;
+  name: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface FolderRequest {
+  workspace_id?: number;
+  parent_id: number | null;
+  name: string;
+}
+
+export type WorkspaceRole = 'owner' | 'admin' | 'editor' | 'viewer';
+
+export interface Workspace {
+  id: number;
+  name: string;
+  personal_user_id?: number;
+  role?: WorkspaceRole;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface WorkspaceMember {
+  id: number;
+  workspace_id: number;
+  user_id: number;
+  user?: User;
+  role: WorkspaceRole;
+  created_at: string;
 }
 
 export interface LoginRequest {
@@ -62,6 +172,141 @@ export interface RegisterRequest {
   name: string;
 }
 
+export interface DataExport {
+  id: number;
+  status: 'pending' | 'running' | 'ready' | 'failed';
+  size?: number;
+  error?: string;
+  created_at: string;
+  completed_at?: string;
+  expires_at?: string;
+}
+
+export interface Plan {
+  code: string;
+  name: string;
+  monthly_links: number;
+  monthly_custom_aliases: number;
+  monthly_clicks: number;
+  branded_domains: number;
+  analytics_retention_days: number;
+}
+
+export interface MetricUsage {
+  used: number;
+  limit: number | null;
+}
+
+export interface AccountUsage {
+  plan: Plan;
+  period: string;
+  period_start: string;
+  period_end: string;
+  links_created: MetricUsage;
+  custom_aliases: MetricUsage;
+  clicks_tracked: MetricUsage;
+  branded_domains: MetricUsage;
+}
+
+export type AuditActorType = 'user' | 'token' | 'admin' | 'anonymous';
+
+export interface AuditEvent {
+  id: number;
+  actor_type: AuditActorType;
+  actor_id?: number;
+  token_id?: number;
+  action: string;
+  target_type: string;
+  target_id: number;
+  account_id?: number;
+  workspace_id?: number;
+  changes?: Record<string, { from: unknown; to: unknown }>;
+  reason?: string;
+  ip_address?: string;
+  user_agent?: string;
+  created_at: string;
+}
+
+export interface AuditFilter {
+  action?: string;
+  actor_type?: AuditActorType;
+  actor_id?: number;
+  target_type?: string;
+  target_id?: number;
+  since?: string;
+  until?: string;
+  limit?: number;
+  offset?: number;
+}
+
+export interface AuditPage {
+  events: AuditEvent[];
+  total: number;
+  limit: number;
+  offset: number;
+}
+
+export type AbuseReportCategory = 'phishing' | 'malware' | 'spam' | 'illegal' | 'other';
+
+export interface AbuseReportRequest {
+  category: AbuseReportCategory;
+  details?: string;
+}
+
+export type WebhookEvent =
+  | 'link.created'
+  | 'link.updated'
+  | 'link.deleted'
+  | 'link.expired'
+  | 'click.recorded';
+
+export interface Webhook {
+  id: number;
+  workspace_id: number;
+  created_by_id?: number;
+  url: string;
+  description?: string;
+  events: WebhookEvent[];
+  click_sample_rate: number;
+  active: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface WebhookRequest {
+  workspace_id?: number;
+  url: string;
+  description?: string;
+  events: WebhookEvent[];
+  click_sample_rate?: number;
+  active?: boolean;
+}
+
+export type WebhookDeliveryStatus = 'pending' | 'delivered' | 'dead';
+
+export interface WebhookDelivery {
+  id: number;
+  webhook_id: number;
+  event_id: string;
+  event: WebhookEvent;
+  payload: any;
+  status: WebhookDeliveryStatus;
+  attempts: number;
+  next_attempt_at: string;
+  last_attempt_at?: string;
+  response_status?: number;
+  last_error?: string;
+  delivered_at?: string;
+  created_at: string;
+}
+
+export interface WebhookDeliveryPage {
+  deliveries: WebhookDelivery[];
+  total: number;
+  limit: number;
+  offset: number;
+}
+
 export interface AuthResponse {
   success: boolean;
   data: {